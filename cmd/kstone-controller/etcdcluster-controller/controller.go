@@ -39,8 +39,11 @@ import (
 	klog "k8s.io/klog/v2"
 
 	"tkestack.io/kstone/pkg/controllers/etcdcluster"
+	"tkestack.io/kstone/pkg/controllers/etcdrestore"
 	"tkestack.io/kstone/pkg/controllers/util"
 	"tkestack.io/kstone/pkg/k8s"
+	"tkestack.io/kstone/pkg/notifier"
+	_ "tkestack.io/kstone/pkg/notifier/providers" // register notification sink provider
 	"tkestack.io/kstone/pkg/signals"
 )
 
@@ -52,6 +55,7 @@ type EtcdClusterCommand struct {
 	leaseLockName      string
 	leaseLockNamespace string
 	enableProfiling    bool
+	notifierConfig     string
 }
 
 // NewEtcdClusterControllerCommand creates a *cobra.Command object with default parameters
@@ -81,6 +85,10 @@ through the apiserver and makes changes attempting to move the current state tow
 // Run start etcdcluster controller
 func (c *EtcdClusterCommand) Run() error {
 	stopCh := signals.SetupSignalHandler()
+	if err := notifier.Setup(c.notifierConfig); err != nil {
+		klog.Fatalf("Error loading notifier config: %v", err)
+		return err
+	}
 
 	config, err := clientcmd.BuildConfigFromFlags(c.masterURL, c.kubeconfig)
 	if err != nil {
@@ -100,6 +108,14 @@ func (c *EtcdClusterCommand) Run() error {
 		clusterClient,
 		kubeInformerFactory.Core().V1().Secrets(),
 		informerFactory.Kstone().V1alpha2().EtcdClusters(),
+		informerFactory.Kstone().V1alpha2().EtcdRestores(),
+	)
+	restoreController := etcdrestore.NewEtcdRestoreController(
+		kubeClient,
+		clusterClient,
+		kubeInformerFactory.Core().V1().Secrets(),
+		informerFactory.Kstone().V1alpha2().EtcdClusters(),
+		informerFactory.Kstone().V1alpha2().EtcdRestores(),
 	)
 	// notice that there is no need to run Start methods in a separate goroutine.
 	// (i.e. go kubeInformerFactory.Start(stopCh)
@@ -107,7 +123,7 @@ func (c *EtcdClusterCommand) Run() error {
 	kubeInformerFactory.Start(stopCh)
 	informerFactory.Start(stopCh)
 
-	leaderElectionConfig, err := c.makeLeaderElectionConfig(kubeClient, controller, stopCh)
+	leaderElectionConfig, err := c.makeLeaderElectionConfig(kubeClient, controller, restoreController, stopCh)
 	if err != nil {
 		klog.Fatalf("Error to generate leader election config: %v", err)
 		return err
@@ -172,9 +188,18 @@ func (c *EtcdClusterCommand) AddFlags(fs *pflag.FlagSet) {
 		"profiling",
 		true,
 		"enable profiling via web interface host:port/debug/pprof/.")
+	fs.StringVar(&c.notifierConfig,
+		"notifier-config",
+		"",
+		"the config file of the notification sinks, notifications are disabled if it is empty")
 }
 
-func (c *EtcdClusterCommand) makeLeaderElectionConfig(kubeClient *kubernetes.Clientset, controller *etcdcluster.ClusterController, stopCh <-chan struct{}) (*leaderelection.LeaderElectionConfig, error) {
+func (c *EtcdClusterCommand) makeLeaderElectionConfig(
+	kubeClient *kubernetes.Clientset,
+	controller *etcdcluster.ClusterController,
+	restoreController *etcdrestore.RestoreController,
+	stopCh <-chan struct{},
+) (*leaderelection.LeaderElectionConfig, error) {
 	if c.leaseLockName == "" {
 		klog.Fatal("unable to get lease lock resource name (missing lease-lock-name flag).")
 	}
@@ -220,6 +245,11 @@ func (c *EtcdClusterCommand) makeLeaderElectionConfig(kubeClient *kubernetes.Cli
 			OnStartedLeading: func(ctx context.Context) {
 				// we're notified when we start - this is where you would
 				// usually put your code
+				go func() {
+					if err := restoreController.Run(1, stopCh); err != nil {
+						klog.Fatalf("Error running restore controller: %s", err.Error())
+					}
+				}()
 				if err := controller.Run(2, stopCh); err != nil {
 					klog.Fatalf("Error running etcd controller: %s", err.Error())
 				}
@@ -41,6 +41,8 @@ import (
 	"tkestack.io/kstone/pkg/controllers/etcdinspection"
 	"tkestack.io/kstone/pkg/controllers/util"
 	"tkestack.io/kstone/pkg/k8s"
+	"tkestack.io/kstone/pkg/notifier"
+	_ "tkestack.io/kstone/pkg/notifier/providers" // register notification sink provider
 	"tkestack.io/kstone/pkg/signals"
 )
 
@@ -52,6 +54,7 @@ type EtcdInspectionCommand struct {
 	leaseLockName      string
 	leaseLockNamespace string
 	enableProfiling    bool
+	notifierConfig     string
 }
 
 // NewEtcdInspectionControllerCommand creates a *cobra.Command object with default parameters
@@ -81,6 +84,10 @@ through the apiserver and makes changes attempting to move the current state tow
 // Run start etcdinspection controller
 func (c *EtcdInspectionCommand) Run() error {
 	stopCh := signals.SetupSignalHandler()
+	if err := notifier.Setup(c.notifierConfig); err != nil {
+		klog.Fatalf("Error loading notifier config: %v", err)
+		return err
+	}
 	config, err := clientcmd.BuildConfigFromFlags(c.masterURL, c.kubeconfig)
 	if err != nil {
 		klog.Fatalf("Error building kubeconfig: %v", err)
@@ -171,6 +178,10 @@ func (c *EtcdInspectionCommand) AddFlags(fs *pflag.FlagSet) {
 		"profiling",
 		true,
 		"enable profiling via web interface host:port/debug/pprof/.")
+	fs.StringVar(&c.notifierConfig,
+		"notifier-config",
+		"",
+		"the config file of the notification sinks, notifications are disabled if it is empty")
 }
 
 func (c *EtcdInspectionCommand) makeLeaderElectionConfig(kubeClient *kubernetes.Clientset, controller *etcdinspection.InspectionController, stopCh <-chan struct{}) (*leaderelection.LeaderElectionConfig, error) {
@@ -30,6 +30,7 @@ import (
 
 	etcdclustercontroller "tkestack.io/kstone/cmd/kstone-controller/etcdcluster-controller"
 	etcdinspectioncontroller "tkestack.io/kstone/cmd/kstone-controller/etcdinspection-controller"
+	webhookserver "tkestack.io/kstone/cmd/kstone-controller/webhook-server"
 )
 
 func main() {
@@ -45,6 +46,7 @@ func main() {
 	cmd.AddCommand(
 		etcdclustercontroller.NewEtcdClusterControllerCommand(out),
 		etcdinspectioncontroller.NewEtcdInspectionControllerCommand(out),
+		webhookserver.NewWebhookServerCommand(out),
 	)
 
 	klog.InitFlags(nil)
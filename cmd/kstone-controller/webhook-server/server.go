@@ -0,0 +1,161 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package webhookserver
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	klog "k8s.io/klog/v2"
+
+	"tkestack.io/kstone/pkg/signals"
+	"tkestack.io/kstone/pkg/webhook"
+)
+
+type WebhookServerCommand struct {
+	out              io.Writer
+	kubeconfig       string
+	masterURL        string
+	port             int
+	serviceName      string
+	serviceNamespace string
+	secretName       string
+	crdName          string
+	webhookConfig    string
+}
+
+// NewWebhookServerCommand creates a *cobra.Command object with default parameters
+func NewWebhookServerCommand(out io.Writer) *cobra.Command {
+	cc := &WebhookServerCommand{out: out}
+	cmd := &cobra.Command{
+		Use:   "webhook",
+		Short: "run webhook server",
+		Long: `The webhook server serves the conversion webhook of etcdcluster resources, and the validating
+and mutating webhooks of etcdcluster and etcdinspection resources for the apiserver. It generates the
+serving certificate and injects its CA into the etcdcluster crd and the webhook configurations.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+				klog.V(1).Infof("FLAG: --%s=%q", flag.Name, flag.Value)
+			})
+			if err := cc.Run(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	fs := cmd.PersistentFlags()
+	cc.AddFlags(fs)
+	return cmd
+}
+
+// Run starts the webhook server
+func (c *WebhookServerCommand) Run() error {
+	stopCh := signals.SetupSignalHandler()
+	config, err := clientcmd.BuildConfigFromFlags(c.masterURL, c.kubeconfig)
+	if err != nil {
+		klog.Fatalf("Error building kubeconfig: %v", err)
+		return err
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		klog.Fatalf("Error building kubernetes clientset: %v", err)
+		return err
+	}
+	extClient, err := apiextensionsclientset.NewForConfig(config)
+	if err != nil {
+		klog.Fatalf("Error building apiextensions clientset: %v", err)
+		return err
+	}
+
+	certManager := webhook.NewCertManager(
+		kubeClient,
+		extClient,
+		c.serviceNamespace,
+		c.secretName,
+		c.serviceName,
+		c.crdName,
+		c.webhookConfig,
+	)
+	if err = certManager.Sync(); err != nil {
+		klog.Fatalf("Error ensuring serving certificate: %v", err)
+		return err
+	}
+	go certManager.Run(stopCh)
+
+	server := webhook.NewServer(c.port, certManager.GetCertificate)
+	return server.Run(stopCh)
+}
+
+func (c *WebhookServerCommand) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(
+		&c.kubeconfig,
+		"kubeconfig",
+		"k",
+		"",
+		"force to specify the kubeconfig",
+	)
+	fs.StringVar(
+		&c.masterURL,
+		"master",
+		"",
+		"The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.",
+	)
+	fs.IntVar(
+		&c.port,
+		"port",
+		webhook.DefaultPort,
+		"the port the webhook server listens on",
+	)
+	fs.StringVar(
+		&c.serviceName,
+		"service-name",
+		"kstone-webhook",
+		"the name of the webhook service, used as the host of the serving certificate",
+	)
+	fs.StringVar(
+		&c.serviceNamespace,
+		"service-namespace",
+		"kstone",
+		"the namespace of the webhook service and the certificate secret",
+	)
+	fs.StringVar(
+		&c.secretName,
+		"secret-name",
+		"kstone-webhook-certs",
+		"the secret name to store the serving certificate",
+	)
+	fs.StringVar(
+		&c.crdName,
+		"crd-name",
+		"etcdclusters.kstone.tkestack.io",
+		"the name of the etcdcluster crd to inject the caBundle of the conversion webhook",
+	)
+	fs.StringVar(
+		&c.webhookConfig,
+		"webhook-config-name",
+		"kstone-webhook",
+		"the name of the validating and mutating webhook configurations to inject the caBundle",
+	)
+}
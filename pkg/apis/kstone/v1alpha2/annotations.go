@@ -0,0 +1,125 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package v1alpha2
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The configurations of the cluster are stored in the annotations in v1alpha2,
+// they are typed fields of the spec since v1alpha3.
+const (
+	// AnnoBackup is the json of the backup config.
+	AnnoBackup = "backup"
+	// AnnoRequest is the json of the request inspection config.
+	AnnoRequest = "request"
+	// AnnoConsistency is the json of the consistency inspection config.
+	AnnoConsistency = "consistency"
+	// AnnoKeyspace is the json of the keyspace inspection config.
+	AnnoKeyspace = "keyspace"
+	// AnnoDefrag is the json of the thresholds of the defrag feature.
+	AnnoDefrag = "defrag"
+	// AnnoClientCertSecret is the secret of the client certificate, <namespace>/<name>.
+	AnnoClientCertSecret = "certName"
+	// AnnoImportedAddr is the client address of the imported cluster.
+	AnnoImportedAddr = "importedAddr"
+	// AnnoExtClientURL maps the client urls of members to the urls accessible
+	// by kstone, <url>-><url>,<url>-><url>.
+	AnnoExtClientURL = "extClientURL"
+	// AnnoQuotaBackendBytes is the quota-backend-bytes of the imported cluster,
+	// it overrides the value in the args of the cluster created by kstone.
+	AnnoQuotaBackendBytes = "quotaBackendBytes"
+	// AnnoAlertThresholds is the json of the thresholds of the alerts created
+	// by the monitor feature.
+	AnnoAlertThresholds = "alertThresholds"
+)
+
+// The annotations requesting the operations of the cluster, they are removed
+// by kstone once the operation is started.
+const (
+	// AnnoRestart requests a rolling restart of the members, the value is the
+	// time of the request.
+	AnnoRestart = "restart"
+)
+
+// ParseFeatureGates parses the feature gates annotation, <feature>=<bool>,<feature>=<bool>,
+// an error is returned with the valid items if any item is invalid.
+func ParseFeatureGates(gates string) (map[KStoneFeature]bool, error) {
+	features := make(map[KStoneFeature]bool)
+	if gates == "" {
+		return features, nil
+	}
+	var invalid []string
+	for _, item := range strings.Split(gates, ",") {
+		kv := strings.Split(item, "=")
+		if len(kv) != 2 {
+			invalid = append(invalid, item)
+			continue
+		}
+		enabled, err := strconv.ParseBool(kv[1])
+		if err != nil {
+			invalid = append(invalid, item)
+			continue
+		}
+		features[KStoneFeature(kv[0])] = enabled
+	}
+	if len(invalid) > 0 {
+		return features, fmt.Errorf("invalid feature gates %s", strings.Join(invalid, ","))
+	}
+	return features, nil
+}
+
+// FormatFeatureGates formats the feature gates annotation, features are sorted by name.
+func FormatFeatureGates(features map[KStoneFeature]bool) string {
+	items := make([]string, 0, len(features))
+	for name, enabled := range features {
+		items = append(items, fmt.Sprintf("%s=%t", name, enabled))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
+// ParseExtClientURLs parses the extClientURL annotation.
+func ParseExtClientURLs(urls string) (map[string]string, error) {
+	if urls == "" {
+		return nil, nil
+	}
+	urlMap := make(map[string]string)
+	for _, item := range strings.Split(urls, ",") {
+		eps := strings.Split(item, "->")
+		if len(eps) != 2 {
+			return urlMap, fmt.Errorf("invalid extensionClientURLs %s", item)
+		}
+		urlMap[eps[0]] = eps[1]
+	}
+	return urlMap, nil
+}
+
+// FormatExtClientURLs formats the extClientURL annotation, urls are sorted.
+func FormatExtClientURLs(urlMap map[string]string) string {
+	items := make([]string, 0, len(urlMap))
+	for from, to := range urlMap {
+		items = append(items, fmt.Sprintf("%s->%s", from, to))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,69 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package v1alpha2
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFeatureGates(t *testing.T) {
+	tests := []struct {
+		name    string
+		gates   string
+		want    map[KStoneFeature]bool
+		wantErr bool
+	}{
+		{name: "empty", gates: "", want: map[KStoneFeature]bool{}},
+		{
+			name:  "valid",
+			gates: "monitor=true,backup=false,healthy=1",
+			want:  map[KStoneFeature]bool{"monitor": true, "backup": false, "healthy": true},
+		},
+		{
+			name:    "invalid bool",
+			gates:   "monitor=yes,backup=true",
+			want:    map[KStoneFeature]bool{"backup": true},
+			wantErr: true,
+		},
+		{
+			name:    "missing value",
+			gates:   "monitor,backup=true",
+			want:    map[KStoneFeature]bool{"backup": true},
+			wantErr: true,
+		},
+		{
+			name:    "too many values",
+			gates:   "monitor=true=false",
+			want:    map[KStoneFeature]bool{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFeatureGates(tt.gates)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFeatureGates(%q) err is %v, wantErr %v", tt.gates, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFeatureGates(%q) = %v, want %v", tt.gates, got, tt.want)
+			}
+		})
+	}
+}
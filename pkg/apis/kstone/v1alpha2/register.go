@@ -53,6 +53,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&EtcdClusterList{},
 		&EtcdInspection{},
 		&EtcdInspectionList{},
+		&EtcdRestore{},
+		&EtcdRestoreList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
@@ -42,6 +42,7 @@ const (
 	EtcdCluterCreating   EtcdClusterPhase = "Creating"
 	EtcdClusterRunning   EtcdClusterPhase = "Running"
 	EtcdClusterUpdating  EtcdClusterPhase = "Updating"
+	EtcdClusterRestoring EtcdClusterPhase = "Restoring" // an EtcdRestore is writing the cluster
 	EtcdClusterDeleteing EtcdClusterPhase = "Deleting"
 	EtcdClusterDeleted   EtcdClusterPhase = "Deleted"
 	EtcdClusterUnknown   EtcdClusterPhase = "Unknown"   // connection refused or other errors
@@ -51,10 +52,25 @@ const (
 type EtcdClusterConditionType string
 
 const (
-	EtcdClusterConditionCreate EtcdClusterConditionType = "Create"
-	EtcdClusterConditionImport EtcdClusterConditionType = "Import"
-	EtcdClusterConditionUpdate EtcdClusterConditionType = "Update"
-	EtcdClusterConditionDelete EtcdClusterConditionType = "Delete"
+	EtcdClusterConditionCreate  EtcdClusterConditionType = "Create"
+	EtcdClusterConditionImport  EtcdClusterConditionType = "Import"
+	EtcdClusterConditionUpdate  EtcdClusterConditionType = "Update"
+	EtcdClusterConditionDelete  EtcdClusterConditionType = "Delete"
+	EtcdClusterConditionRestore EtcdClusterConditionType = "Restore"
+	EtcdClusterConditionBackup  EtcdClusterConditionType = "Backup"
+	EtcdClusterConditionRestart EtcdClusterConditionType = "Restart"
+)
+
+// The standard conditions of EtcdCluster, the conditions above are the operations recorded in the history.
+const (
+	// EtcdClusterConditionReady is true when the latest spec is reconciled and all the members are running.
+	EtcdClusterConditionReady EtcdClusterConditionType = "Ready"
+	// EtcdClusterConditionAvailable is true when the quorum of the members is running.
+	EtcdClusterConditionAvailable EtcdClusterConditionType = "Available"
+	// EtcdClusterConditionProgressing is true when an operation of the cluster is in progress.
+	EtcdClusterConditionProgressing EtcdClusterConditionType = "Progressing"
+	// EtcdClusterConditionDegraded is true when some members are not running or the last operation failed.
+	EtcdClusterConditionDegraded EtcdClusterConditionType = "Degraded"
 )
 
 // EtcdClusterCondition contains condition information for a EtcdCluster.
@@ -75,6 +91,12 @@ type EtcdClusterCondition struct {
 	// Human readable message indicating details about last transition.
 	// +optional
 	Message string `json:"message,omitempty" protobuf:"bytes,6,opt,name=message"`
+	// The generation of the EtcdCluster the condition is set based upon.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty" protobuf:"varint,7,opt,name=observedGeneration"`
+	// Last time the status of the condition changed.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty" protobuf:"bytes,8,opt,name=lastTransitionTime"`
 }
 
 type EtcdClusterType string
@@ -127,14 +149,19 @@ type AuthConfig struct {
 type KStoneFeature string
 
 const (
-	KStoneFeatureAnno                      = "featureGates"
-	KStoneFeatureMonitor     KStoneFeature = "monitor"
-	KStoneFeatureBackup      KStoneFeature = "backup"
-	KStoneFeatureHealthy     KStoneFeature = "healthy"
-	KStoneFeatureConsistency KStoneFeature = "consistency"
-	KStoneFeatureRequest     KStoneFeature = "request"
-	KStoneFeatureAlarm       KStoneFeature = "alarm"
-	KStoneFeatureBackupCheck KStoneFeature = "backupcheck"
+	KStoneFeatureAnno                       = "featureGates"
+	KStoneFeatureMonitor      KStoneFeature = "monitor"
+	KStoneFeatureBackup       KStoneFeature = "backup"
+	KStoneFeatureHealthy      KStoneFeature = "healthy"
+	KStoneFeatureConsistency  KStoneFeature = "consistency"
+	KStoneFeatureRequest      KStoneFeature = "request"
+	KStoneFeatureAlarm        KStoneFeature = "alarm"
+	KStoneFeatureBackupCheck  KStoneFeature = "backupcheck"
+	KStoneFeatureBackupVerify KStoneFeature = "backupverify"
+	KStoneFeatureDefrag       KStoneFeature = "defrag"
+	KStoneFeatureDBSize       KStoneFeature = "dbsize"
+	KStoneFeatureKeyspace     KStoneFeature = "keyspace"
+	KStoneFeatureScraper      KStoneFeature = "scraper"
 )
 
 // EtcdClusterStatus defines the actual state of EtcdCluster.
@@ -144,6 +171,10 @@ type EtcdClusterStatus struct {
 	Members            []MemberStatus           `json:"members,omitempty" protobuf:"bytes,3,rep,name=members"`
 	FeatureGatesStatus map[KStoneFeature]string `json:"featureGatesStatus,omitempty" protobuf:"bytes,4,rep,name=featureGatesStatus,castkey=KStoneFeature"`
 	ServiceName        string                   `json:"serviceName,omitempty" protobuf:"bytes,5,opt,name=serviceName"`
+	// ObservedGeneration is the latest generation of the spec reconciled by kstone.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty" protobuf:"varint,6,opt,name=observedGeneration"`
+	// History records the operations of the cluster, such as Create, Update, Restore and Backup.
+	History []EtcdClusterCondition `json:"history,omitempty" protobuf:"bytes,7,rep,name=history"`
 }
 
 type MemberPhase string
@@ -222,6 +253,38 @@ type EtcdInspectionRecord struct {
 	// Human readable message indicating details about last transition.
 	// +optional
 	Message string `json:"message,omitempty" protobuf:"bytes,4,opt,name=message"`
+	// Result is the result of the run.
+	// +optional
+	Result *EtcdInspectionResult `json:"result,omitempty" protobuf:"bytes,5,opt,name=result"`
+}
+
+// EtcdInspectionResultStatus is the verdict of an inspection run
+type EtcdInspectionResultStatus string
+
+const (
+	EtcdInspectionPass EtcdInspectionResultStatus = "Pass"
+	EtcdInspectionWarn EtcdInspectionResultStatus = "Warn"
+	EtcdInspectionFail EtcdInspectionResultStatus = "Fail"
+)
+
+// EtcdInspectionResult is the structured result of an inspection run
+type EtcdInspectionResult struct {
+	// Status is the worst status of the findings and the members.
+	Status EtcdInspectionResultStatus `json:"status" protobuf:"bytes,1,opt,name=status"`
+	// Findings are the human readable problems found, or a summary if nothing is wrong.
+	// +optional
+	Findings []string `json:"findings,omitempty" protobuf:"bytes,2,rep,name=findings"`
+	// Members are the results of each member.
+	// +optional
+	Members []EtcdInspectionMemberResult `json:"members,omitempty" protobuf:"bytes,3,rep,name=members"`
+}
+
+// EtcdInspectionMemberResult is the result of an inspection run on a member
+type EtcdInspectionMemberResult struct {
+	Endpoint string                     `json:"endpoint" protobuf:"bytes,1,opt,name=endpoint"`
+	Status   EtcdInspectionResultStatus `json:"status" protobuf:"bytes,2,opt,name=status"`
+	// +optional
+	Message string `json:"message,omitempty" protobuf:"bytes,3,opt,name=message"`
 }
 
 // EtcdInspectionStatus is the status for a EtcdInspectionStatus resource
@@ -230,6 +293,45 @@ type EtcdInspectionStatus struct {
 	Message         string                 `json:"message,omitempty" protobuf:"bytes,2,opt,name=message"`
 	Records         []EtcdInspectionRecord `json:"records,omitempty" protobuf:"bytes,3,rep,name=records"`
 	LastUpdatedTime metav1.Time            `json:"lastUpdatedTime,omitempty" protobuf:"bytes,4,opt,name=lastUpdatedTime"`
+	// Keyspace is the result of the last keyspace inspection.
+	// +optional
+	Keyspace *EtcdKeyspaceStatus `json:"keyspace,omitempty" protobuf:"bytes,5,opt,name=keyspace"`
+	// Result is the result of the last run, the results of the previous runs
+	// are kept in the records.
+	// +optional
+	Result *EtcdInspectionResult `json:"result,omitempty" protobuf:"bytes,6,opt,name=result"`
+}
+
+// EtcdKeyspaceStatus is the largest keys and prefixes of the keyspace
+type EtcdKeyspaceStatus struct {
+	// Revision is the revision of the keyspace analyzed.
+	Revision int64 `json:"revision" protobuf:"varint,1,opt,name=revision"`
+	// Prefix is the prefix of the keys analyzed.
+	Prefix string `json:"prefix,omitempty" protobuf:"bytes,2,opt,name=prefix"`
+	// Depth is the number of the path segments of the aggregated prefixes.
+	Depth     int   `json:"depth" protobuf:"varint,3,opt,name=depth"`
+	TotalKeys int64 `json:"totalKeys" protobuf:"varint,4,opt,name=totalKeys"`
+	// TotalBytes is the total size of the keys and the values.
+	TotalBytes int64 `json:"totalBytes" protobuf:"varint,5,opt,name=totalBytes"`
+	// TopKeys are the keys with the largest values.
+	TopKeys []EtcdKeyUsage `json:"topKeys,omitempty" protobuf:"bytes,6,rep,name=topKeys"`
+	// TopPrefixesByBytes are the prefixes with the largest total size.
+	TopPrefixesByBytes []EtcdPrefixUsage `json:"topPrefixesByBytes,omitempty" protobuf:"bytes,7,rep,name=topPrefixesByBytes"`
+	// TopPrefixesByKeys are the prefixes with the most keys.
+	TopPrefixesByKeys []EtcdPrefixUsage `json:"topPrefixesByKeys,omitempty" protobuf:"bytes,8,rep,name=topPrefixesByKeys"`
+}
+
+// EtcdKeyUsage is the value size of a key
+type EtcdKeyUsage struct {
+	Key       string `json:"key" protobuf:"bytes,1,opt,name=key"`
+	ValueSize int64  `json:"valueSize" protobuf:"varint,2,opt,name=valueSize"`
+}
+
+// EtcdPrefixUsage is the number of keys and the total size of a prefix
+type EtcdPrefixUsage struct {
+	Prefix string `json:"prefix" protobuf:"bytes,1,opt,name=prefix"`
+	Keys   int64  `json:"keys" protobuf:"varint,2,opt,name=keys"`
+	Bytes  int64  `json:"bytes" protobuf:"varint,3,opt,name=bytes"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -241,3 +343,102 @@ type EtcdInspectionList struct {
 
 	Items []EtcdInspection `json:"items" protobuf:"bytes,2,rep,name=items"`
 }
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +k8s:defaulter-gen=TypeMeta
+
+// EtcdRestore restores a backup file of an EtcdCluster into an empty EtcdCluster
+type EtcdRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+
+	Spec   EtcdRestoreSpec   `json:"spec,omitempty" protobuf:"bytes,2,opt,name=spec"`
+	Status EtcdRestoreStatus `json:"status,omitempty" protobuf:"bytes,3,opt,name=status"`
+}
+
+// EtcdRestoreSpec is the spec for a EtcdRestore resource
+type EtcdRestoreSpec struct {
+	// SourceCluster is the EtcdCluster that owns the backup files.
+	SourceCluster string `json:"sourceCluster" protobuf:"bytes,1,opt,name=sourceCluster"`
+	// TargetCluster is the EtcdCluster restored, it defaults to the source cluster.
+	// The target cluster must be empty unless overwrite is set, it is created from
+	// the spec of the source cluster if it does not exist and the source cluster is
+	// a kstone-etcd-operator cluster.
+	// +optional
+	TargetCluster string `json:"targetCluster,omitempty" protobuf:"bytes,2,opt,name=targetCluster"`
+	// BackupKey is the key of the backup file. For a point in time restore, it
+	// defaults to the latest backup file before the restore point.
+	// +optional
+	BackupKey string `json:"backupKey,omitempty" protobuf:"bytes,3,opt,name=backupKey"`
+	// TargetRevision restores the revision by replaying the persisted events
+	// on top of the backup file.
+	// +optional
+	TargetRevision int64 `json:"targetRevision,omitempty" protobuf:"varint,4,opt,name=targetRevision"`
+	// TargetTime restores the time by replaying the persisted events on top of
+	// the backup file.
+	// +optional
+	TargetTime *metav1.Time `json:"targetTime,omitempty" protobuf:"bytes,5,opt,name=targetTime"`
+	// Overwrite removes the keys and the leases of the target cluster before
+	// the restore. It is required to restore the source cluster in place.
+	// +optional
+	Overwrite bool `json:"overwrite,omitempty" protobuf:"varint,6,opt,name=overwrite"`
+}
+
+// EtcdRestorePhase is the phase of a EtcdRestore
+type EtcdRestorePhase string
+
+const (
+	// EtcdRestorePending is waiting for the clusters or the retry of a failed attempt.
+	EtcdRestorePending   EtcdRestorePhase = "Pending"
+	EtcdRestoreRunning   EtcdRestorePhase = "Running"
+	EtcdRestoreSucceeded EtcdRestorePhase = "Succeeded"
+	// EtcdRestoreFailed is set when the spec is invalid or all attempts failed.
+	EtcdRestoreFailed EtcdRestorePhase = "Failed"
+)
+
+// EtcdRestoreStatus is the status for a EtcdRestore resource
+type EtcdRestoreStatus struct {
+	Phase EtcdRestorePhase `json:"phase,omitempty" protobuf:"bytes,1,opt,name=phase,casttype=EtcdRestorePhase"`
+	// (brief) reason of the last failed attempt.
+	// +optional
+	Reason string `json:"reason,omitempty" protobuf:"bytes,2,opt,name=reason"`
+	// Human readable message of the current phase.
+	// +optional
+	Message string `json:"message,omitempty" protobuf:"bytes,3,opt,name=message"`
+	// Attempts is the number of the attempts started.
+	Attempts int32 `json:"attempts,omitempty" protobuf:"varint,4,opt,name=attempts"`
+	// TargetUID is the uid of the target cluster once the keys are written into it,
+	// the keys of the target cluster are only overwritten by the retries of the same restore.
+	// +optional
+	TargetUID string `json:"targetUID,omitempty" protobuf:"bytes,5,opt,name=targetUID"`
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty" protobuf:"bytes,6,opt,name=startTime"`
+	// LastAttemptTime is the start time of the last attempt, the next attempt
+	// after a failure is delayed by a backoff from it.
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty" protobuf:"bytes,12,opt,name=lastAttemptTime"`
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty" protobuf:"bytes,7,opt,name=completionTime"`
+	// BackupKey is the backup file restored.
+	// +optional
+	BackupKey string `json:"backupKey,omitempty" protobuf:"bytes,8,opt,name=backupKey"`
+	// Revision is the revision of the source cluster restored.
+	// +optional
+	Revision int64 `json:"revision,omitempty" protobuf:"varint,9,opt,name=revision"`
+	// Keys and Leases are the numbers of the keys and the leases written into the target cluster.
+	// +optional
+	Keys int64 `json:"keys,omitempty" protobuf:"varint,10,opt,name=keys"`
+	// +optional
+	Leases int64 `json:"leases,omitempty" protobuf:"varint,11,opt,name=leases"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// EtcdRestoreList is a list of EtcdRestore resources
+type EtcdRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata" protobuf:"bytes,1,opt,name=metadata"`
+
+	Items []EtcdRestore `json:"items" protobuf:"bytes,2,rep,name=items"`
+}
@@ -0,0 +1,224 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package v1alpha3
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+)
+
+// ConvertFromV1alpha2 converts the v1alpha2 EtcdCluster to v1alpha3, the
+// configurations in the annotations are moved to the typed fields of the spec,
+// the annotations failed to be parsed or with the fields unknown to the spec
+// are kept as they are, so they are not lost when converted back to v1alpha2.
+func ConvertFromV1alpha2(in *v1alpha2.EtcdCluster) (*EtcdCluster, error) {
+	out := &EtcdCluster{}
+	if err := convertByJSON(in, out); err != nil {
+		return nil, err
+	}
+	out.APIVersion = SchemeGroupVersion.String()
+
+	annotations := out.Annotations
+	if gates, found := annotations[v1alpha2.KStoneFeatureAnno]; found {
+		features, err := v1alpha2.ParseFeatureGates(gates)
+		if err == nil && len(features) == len(strings.Split(gates, ",")) {
+			out.Spec.FeatureGates = make(map[KStoneFeature]bool, len(features))
+			for name, enabled := range features {
+				out.Spec.FeatureGates[KStoneFeature(name)] = enabled
+			}
+			delete(annotations, v1alpha2.KStoneFeatureAnno)
+		}
+	}
+	if cfg, found := annotations[v1alpha2.AnnoBackup]; found {
+		backup := &BackupSpec{}
+		if err := unmarshalStrict(cfg, backup); err == nil {
+			out.Spec.Backup = backup
+			delete(annotations, v1alpha2.AnnoBackup)
+		}
+	}
+	if cfg, found := annotations[v1alpha2.AnnoRequest]; found {
+		request := &RequestSpec{}
+		if err := unmarshalStrict(cfg, request); err == nil {
+			out.Spec.Request = request
+			delete(annotations, v1alpha2.AnnoRequest)
+		}
+	}
+	if cfg, found := annotations[v1alpha2.AnnoKeyspace]; found {
+		keyspace := &KeyspaceSpec{}
+		if err := unmarshalStrict(cfg, keyspace); err == nil {
+			out.Spec.Keyspace = keyspace
+			delete(annotations, v1alpha2.AnnoKeyspace)
+		}
+	}
+	if cfg, found := annotations[v1alpha2.AnnoConsistency]; found {
+		consistency := &ConsistencySpec{}
+		if err := unmarshalStrict(cfg, consistency); err == nil {
+			out.Spec.Consistency = consistency
+			delete(annotations, v1alpha2.AnnoConsistency)
+		}
+	}
+	if cfg, found := annotations[v1alpha2.AnnoDefrag]; found {
+		defrag := &DefragSpec{}
+		if err := unmarshalStrict(cfg, defrag); err == nil {
+			out.Spec.Defrag = defrag
+			delete(annotations, v1alpha2.AnnoDefrag)
+		}
+	}
+	if cfg, found := annotations[v1alpha2.AnnoAlertThresholds]; found {
+		thresholds := &AlertThresholdsSpec{}
+		if err := unmarshalStrict(cfg, thresholds); err == nil {
+			out.Spec.AlertThresholds = thresholds
+			delete(annotations, v1alpha2.AnnoAlertThresholds)
+		}
+	}
+	if secret, found := annotations[v1alpha2.AnnoClientCertSecret]; found && secret != "" {
+		out.Spec.ClientCertSecret = secret
+		delete(annotations, v1alpha2.AnnoClientCertSecret)
+	}
+	if addr, found := annotations[v1alpha2.AnnoImportedAddr]; found && addr != "" {
+		out.Spec.ImportedAddr = addr
+		delete(annotations, v1alpha2.AnnoImportedAddr)
+	}
+	if urls, found := annotations[v1alpha2.AnnoExtClientURL]; found {
+		urlMap, err := v1alpha2.ParseExtClientURLs(urls)
+		if err == nil && len(urlMap) > 0 {
+			out.Spec.ExtClientURLs = urlMap
+			delete(annotations, v1alpha2.AnnoExtClientURL)
+		}
+	}
+	if quota, found := annotations[v1alpha2.AnnoQuotaBackendBytes]; found {
+		if bytes, err := strconv.ParseInt(quota, 10, 64); err == nil && bytes > 0 {
+			out.Spec.QuotaBackendBytes = bytes
+			delete(annotations, v1alpha2.AnnoQuotaBackendBytes)
+		}
+	}
+	if len(annotations) == 0 {
+		out.Annotations = nil
+	}
+	return out, nil
+}
+
+// ConvertToV1alpha2 converts the v1alpha3 EtcdCluster to v1alpha2, the typed
+// fields of the spec are stored in the annotations, the annotations set by
+// users are overwritten by the typed fields.
+func ConvertToV1alpha2(in *EtcdCluster) (*v1alpha2.EtcdCluster, error) {
+	out := &v1alpha2.EtcdCluster{}
+	if err := convertByJSON(in, out); err != nil {
+		return nil, err
+	}
+	out.APIVersion = v1alpha2.SchemeGroupVersion.String()
+
+	annotations := out.Annotations
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	spec := &in.Spec
+	if spec.FeatureGates != nil {
+		features := make(map[v1alpha2.KStoneFeature]bool, len(spec.FeatureGates))
+		for name, enabled := range spec.FeatureGates {
+			features[v1alpha2.KStoneFeature(name)] = enabled
+		}
+		annotations[v1alpha2.KStoneFeatureAnno] = v1alpha2.FormatFeatureGates(features)
+	}
+	if spec.Backup != nil {
+		data, err := json.Marshal(spec.Backup)
+		if err != nil {
+			return nil, err
+		}
+		annotations[v1alpha2.AnnoBackup] = string(data)
+	}
+	if spec.Request != nil {
+		data, err := json.Marshal(spec.Request)
+		if err != nil {
+			return nil, err
+		}
+		annotations[v1alpha2.AnnoRequest] = string(data)
+	}
+	if spec.Keyspace != nil {
+		data, err := json.Marshal(spec.Keyspace)
+		if err != nil {
+			return nil, err
+		}
+		annotations[v1alpha2.AnnoKeyspace] = string(data)
+	}
+	if spec.Consistency != nil {
+		data, err := json.Marshal(spec.Consistency)
+		if err != nil {
+			return nil, err
+		}
+		annotations[v1alpha2.AnnoConsistency] = string(data)
+	}
+	if spec.Defrag != nil {
+		data, err := json.Marshal(spec.Defrag)
+		if err != nil {
+			return nil, err
+		}
+		annotations[v1alpha2.AnnoDefrag] = string(data)
+	}
+	if spec.AlertThresholds != nil {
+		data, err := json.Marshal(spec.AlertThresholds)
+		if err != nil {
+			return nil, err
+		}
+		annotations[v1alpha2.AnnoAlertThresholds] = string(data)
+	}
+	if spec.ClientCertSecret != "" {
+		annotations[v1alpha2.AnnoClientCertSecret] = spec.ClientCertSecret
+	}
+	if spec.ImportedAddr != "" {
+		annotations[v1alpha2.AnnoImportedAddr] = spec.ImportedAddr
+	}
+	if len(spec.ExtClientURLs) > 0 {
+		annotations[v1alpha2.AnnoExtClientURL] = v1alpha2.FormatExtClientURLs(spec.ExtClientURLs)
+	}
+	if spec.QuotaBackendBytes > 0 {
+		annotations[v1alpha2.AnnoQuotaBackendBytes] = strconv.FormatInt(spec.QuotaBackendBytes, 10)
+	}
+	if len(annotations) > 0 {
+		out.Annotations = annotations
+	}
+	return out, nil
+}
+
+// unmarshalStrict parses the json configuration of the annotation, it fails if
+// the configuration has fields unknown to out
+func unmarshalStrict(cfg string, out interface{}) error {
+	decoder := json.NewDecoder(strings.NewReader(cfg))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(out); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return fmt.Errorf("unexpected data after the configuration")
+	}
+	return nil
+}
+
+// convertByJSON converts the fields shared by both versions, which have the same json names
+func convertByJSON(in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
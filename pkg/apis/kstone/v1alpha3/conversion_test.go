@@ -0,0 +1,208 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package v1alpha3
+
+import (
+	"reflect"
+	"testing"
+
+	backupapiv2 "github.com/coreos/etcd-operator/pkg/apis/etcd/v1beta2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+)
+
+func newV1alpha2Cluster(annotations map[string]string) *v1alpha2.EtcdCluster {
+	return &v1alpha2.EtcdCluster{
+		TypeMeta: metav1.TypeMeta{APIVersion: v1alpha2.SchemeGroupVersion.String(), Kind: "EtcdCluster"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "kstone",
+			Name:        "etcd",
+			Annotations: annotations,
+		},
+		Spec: v1alpha2.EtcdClusterSpec{
+			Name:    "etcd",
+			Size:    3,
+			Args:    []string{"--snapshot-count=10000"},
+			Version: "3.5.7",
+		},
+	}
+}
+
+func TestConvertFromV1alpha2(t *testing.T) {
+	in := newV1alpha2Cluster(map[string]string{
+		v1alpha2.KStoneFeatureAnno:     "healthy=true,backup=false",
+		v1alpha2.AnnoBackup:            `{"storageType":"S3","s3":{"path":"bucket/etcd","awsSecret":"s3"}}`,
+		v1alpha2.AnnoRequest:           `{"path":"/registry","persistEvents":true}`,
+		v1alpha2.AnnoKeyspace:          `{"depth":3,"topN":5}`,
+		v1alpha2.AnnoConsistency:       `{"deep":true}`,
+		v1alpha2.AnnoDefrag:            `{"fragmentationPercent":30}`,
+		v1alpha2.AnnoAlertThresholds:   `{"keyDiff":10}`,
+		v1alpha2.AnnoClientCertSecret:  "etcd-cert",
+		v1alpha2.AnnoImportedAddr:      "https://10.0.0.1:2379",
+		v1alpha2.AnnoExtClientURL:      "10.0.0.1:2379->etcd-0:2379",
+		v1alpha2.AnnoQuotaBackendBytes: "8589934592",
+		"owner":                        "team-a",
+	})
+	out, err := ConvertFromV1alpha2(in)
+	if err != nil {
+		t.Fatalf("failed to convert, err is %v", err)
+	}
+
+	if out.APIVersion != SchemeGroupVersion.String() {
+		t.Errorf("APIVersion = %s, want %s", out.APIVersion, SchemeGroupVersion.String())
+	}
+	if out.Name != "etcd" || out.Spec.Size != 3 || out.Spec.Version != "3.5.7" || !reflect.DeepEqual(out.Spec.Args, in.Spec.Args) {
+		t.Errorf("shared fields are not converted, got %+v", out)
+	}
+	if want := map[string]string{"owner": "team-a"}; !reflect.DeepEqual(out.Annotations, want) {
+		t.Errorf("Annotations = %v, want %v", out.Annotations, want)
+	}
+	wantSpec := EtcdClusterSpec{
+		FeatureGates: map[KStoneFeature]bool{"healthy": true, "backup": false},
+		Backup: &BackupSpec{
+			StorageType:  backupapiv2.BackupStorageTypeS3,
+			BackupSource: backupapiv2.BackupSource{S3: &backupapiv2.S3BackupSource{Path: "bucket/etcd", AWSSecret: "s3"}},
+		},
+		Request:           &RequestSpec{Path: "/registry", PersistEvents: true},
+		Keyspace:          &KeyspaceSpec{Depth: 3, TopN: 5},
+		Consistency:       &ConsistencySpec{Deep: true},
+		Defrag:            &DefragSpec{FragmentationPercent: 30},
+		AlertThresholds:   &AlertThresholdsSpec{KeyDiff: 10},
+		ClientCertSecret:  "etcd-cert",
+		ImportedAddr:      "https://10.0.0.1:2379",
+		ExtClientURLs:     map[string]string{"10.0.0.1:2379": "etcd-0:2379"},
+		QuotaBackendBytes: 8589934592,
+	}
+	got := out.Spec
+	got.Name, got.Size, got.Args, got.Version = "", 0, nil, ""
+	if !reflect.DeepEqual(got, wantSpec) {
+		t.Errorf("Spec = %+v, want %+v", got, wantSpec)
+	}
+}
+
+func TestConvertFromV1alpha2KeepsInvalidAnnotations(t *testing.T) {
+	annotations := map[string]string{
+		v1alpha2.KStoneFeatureAnno:     "healthy=true,backup",
+		v1alpha2.AnnoBackup:            `{"storageType":`,
+		v1alpha2.AnnoRequest:           `[]`,
+		v1alpha2.AnnoClientCertSecret:  "",
+		v1alpha2.AnnoExtClientURL:      "10.0.0.1:2379",
+		v1alpha2.AnnoQuotaBackendBytes: "8G",
+	}
+	in := newV1alpha2Cluster(annotations)
+	out, err := ConvertFromV1alpha2(in)
+	if err != nil {
+		t.Fatalf("failed to convert, err is %v", err)
+	}
+	if !reflect.DeepEqual(out.Annotations, annotations) {
+		t.Errorf("Annotations = %v, want %v", out.Annotations, annotations)
+	}
+	spec := out.Spec
+	if spec.FeatureGates != nil || spec.Backup != nil || spec.Request != nil || spec.ClientCertSecret != "" ||
+		spec.ExtClientURLs != nil || spec.QuotaBackendBytes != 0 {
+		t.Errorf("invalid annotations are converted, Spec = %+v", spec)
+	}
+
+	for _, quota := range []string{"0", "-1"} {
+		out, err = ConvertFromV1alpha2(newV1alpha2Cluster(map[string]string{v1alpha2.AnnoQuotaBackendBytes: quota}))
+		if err != nil || out.Spec.QuotaBackendBytes != 0 || out.Annotations[v1alpha2.AnnoQuotaBackendBytes] != quota {
+			t.Errorf("quota %s is converted, Spec = %+v, err is %v", quota, out.Spec, err)
+		}
+	}
+}
+
+func TestConvertFromV1alpha2WithoutAnnotations(t *testing.T) {
+	for _, annotations := range []map[string]string{nil, {v1alpha2.AnnoClientCertSecret: "etcd-cert"}} {
+		out, err := ConvertFromV1alpha2(newV1alpha2Cluster(annotations))
+		if err != nil {
+			t.Fatalf("failed to convert, err is %v", err)
+		}
+		if out.Annotations != nil {
+			t.Errorf("Annotations = %v, want nil", out.Annotations)
+		}
+	}
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	in := newV1alpha2Cluster(map[string]string{
+		v1alpha2.KStoneFeatureAnno:     "backup=false,healthy=true",
+		v1alpha2.AnnoRequest:           `{"path":"/registry"}`,
+		v1alpha2.AnnoExtClientURL:      "10.0.0.1:2379->etcd-0:2379,10.0.0.2:2379->etcd-1:2379",
+		v1alpha2.AnnoQuotaBackendBytes: "8589934592",
+		"owner":                        "team-a",
+	})
+	v3, err := ConvertFromV1alpha2(in)
+	if err != nil {
+		t.Fatalf("failed to convert to v1alpha3, err is %v", err)
+	}
+	v2, err := ConvertToV1alpha2(v3)
+	if err != nil {
+		t.Fatalf("failed to convert to v1alpha2, err is %v", err)
+	}
+	if !reflect.DeepEqual(v2, in) {
+		t.Errorf("round trip = %+v, want %+v", v2, in)
+	}
+}
+
+func TestConvertRoundTripKeepsUnknownFields(t *testing.T) {
+	annotations := map[string]string{
+		v1alpha2.AnnoBackup:  `{"storageType":"COS","cos":{"path":"bucket/etcd","storageClass":"ARCHIVE"}}`,
+		v1alpha2.AnnoRequest: `{"path":"/registry","sampleRate":10}`,
+		v1alpha2.AnnoDefrag:  `{"intervalInSecond":3600} {"intervalInSecond":60}`,
+	}
+	in := newV1alpha2Cluster(annotations)
+	v3, err := ConvertFromV1alpha2(in)
+	if err != nil {
+		t.Fatalf("failed to convert to v1alpha3, err is %v", err)
+	}
+	if v3.Spec.Backup != nil || v3.Spec.Request != nil || v3.Spec.Defrag != nil {
+		t.Errorf("annotations with unknown fields are converted, Spec = %+v", v3.Spec)
+	}
+	if !reflect.DeepEqual(v3.Annotations, annotations) {
+		t.Errorf("Annotations = %v, want %v", v3.Annotations, annotations)
+	}
+	v2, err := ConvertToV1alpha2(v3)
+	if err != nil {
+		t.Fatalf("failed to convert to v1alpha2, err is %v", err)
+	}
+	if !reflect.DeepEqual(v2, in) {
+		t.Errorf("round trip = %+v, want %+v", v2, in)
+	}
+}
+
+func TestConvertRoundTripKeepsInvalidFeatureGates(t *testing.T) {
+	for _, gates := range []string{"monitor=yes", "healthy=true,monitor=yes", "healthy=true,backup", ""} {
+		in := newV1alpha2Cluster(map[string]string{v1alpha2.KStoneFeatureAnno: gates})
+		v3, err := ConvertFromV1alpha2(in)
+		if err != nil {
+			t.Fatalf("failed to convert %q to v1alpha3, err is %v", gates, err)
+		}
+		if v3.Spec.FeatureGates != nil {
+			t.Errorf("invalid feature gates %q are converted to %v", gates, v3.Spec.FeatureGates)
+		}
+		v2, err := ConvertToV1alpha2(v3)
+		if err != nil {
+			t.Fatalf("failed to convert %q to v1alpha2, err is %v", gates, err)
+		}
+		if !reflect.DeepEqual(v2, in) {
+			t.Errorf("round trip of %q = %+v, want %+v", gates, v2, in)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package v1alpha3
+
+import (
+	backupapiv2 "github.com/coreos/etcd-operator/pkg/apis/etcd/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// EtcdCluster is the Schema for the etcdclusters API, the configurations
+// stored in the annotations of v1alpha2 are typed fields of the spec.
+type EtcdCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+
+	Spec   EtcdClusterSpec   `json:"spec,omitempty" protobuf:"bytes,2,opt,name=spec"`
+	Status EtcdClusterStatus `json:"status,omitempty" protobuf:"bytes,3,opt,name=status"`
+}
+
+type EtcdClusterPhase string
+
+const (
+	EtcdClusterInit      EtcdClusterPhase = "Initing"
+	EtcdCluterCreating   EtcdClusterPhase = "Creating"
+	EtcdClusterRunning   EtcdClusterPhase = "Running"
+	EtcdClusterUpdating  EtcdClusterPhase = "Updating"
+	EtcdClusterRestoring EtcdClusterPhase = "Restoring" // an EtcdRestore is writing the cluster
+	EtcdClusterDeleteing EtcdClusterPhase = "Deleting"
+	EtcdClusterDeleted   EtcdClusterPhase = "Deleted"
+	EtcdClusterUnknown   EtcdClusterPhase = "Unknown"   // connection refused or other errors
+	EtcdClusterUnhealthy EtcdClusterPhase = "UnHealthy" // node health check returns unhealthy
+)
+
+type EtcdClusterConditionType string
+
+const (
+	EtcdClusterConditionCreate  EtcdClusterConditionType = "Create"
+	EtcdClusterConditionImport  EtcdClusterConditionType = "Import"
+	EtcdClusterConditionUpdate  EtcdClusterConditionType = "Update"
+	EtcdClusterConditionDelete  EtcdClusterConditionType = "Delete"
+	EtcdClusterConditionRestore EtcdClusterConditionType = "Restore"
+	EtcdClusterConditionBackup  EtcdClusterConditionType = "Backup"
+	EtcdClusterConditionRestart EtcdClusterConditionType = "Restart"
+)
+
+// The standard conditions of EtcdCluster, the conditions above are the operations recorded in the history.
+const (
+	// EtcdClusterConditionReady is true when the latest spec is reconciled and all the members are running.
+	EtcdClusterConditionReady EtcdClusterConditionType = "Ready"
+	// EtcdClusterConditionAvailable is true when the quorum of the members is running.
+	EtcdClusterConditionAvailable EtcdClusterConditionType = "Available"
+	// EtcdClusterConditionProgressing is true when an operation of the cluster is in progress.
+	EtcdClusterConditionProgressing EtcdClusterConditionType = "Progressing"
+	// EtcdClusterConditionDegraded is true when some members are not running or the last operation failed.
+	EtcdClusterConditionDegraded EtcdClusterConditionType = "Degraded"
+)
+
+// EtcdClusterCondition contains condition information for a EtcdCluster.
+type EtcdClusterCondition struct {
+	// Type of EtcdCluster condition.
+	Type EtcdClusterConditionType `json:"type" protobuf:"bytes,1,opt,name=type,casttype=EtcdClusterPhase"`
+	// Status of the condition, one of True, False, Unknown.
+	Status corev1.ConditionStatus `json:"status" protobuf:"bytes,2,opt,name=status,casttype=k8s.io/api/core/v1.ConditionStatus"`
+	// Last time we got an update on a given condition.
+	// +optional
+	StartTime metav1.Time `json:"startTime,omitempty" protobuf:"bytes,3,opt,name=startTime"`
+	// Last time the condition transit from one status to another.
+	// +optional
+	EndTime metav1.Time `json:"endTime,omitempty" protobuf:"bytes,4,opt,name=endTime"`
+	// (brief) reason for the condition's last transition.
+	// +optional
+	Reason string `json:"reason,omitempty" protobuf:"bytes,5,opt,name=reason"`
+	// Human readable message indicating details about last transition.
+	// +optional
+	Message string `json:"message,omitempty" protobuf:"bytes,6,opt,name=message"`
+	// The generation of the EtcdCluster the condition is set based upon.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty" protobuf:"varint,7,opt,name=observedGeneration"`
+	// Last time the status of the condition changed.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty" protobuf:"bytes,8,opt,name=lastTransitionTime"`
+}
+
+type EtcdClusterType string
+
+const (
+	EtcdClusterKstone   EtcdClusterType = "kstone-etcd-operator"
+	EtcdClusterImported EtcdClusterType = "imported"
+)
+
+type EtcdStorageBackend string
+
+const (
+	EtcdStorageV2 EtcdStorageBackend = "v2"
+	EtcdStorageV3 EtcdStorageBackend = "v3"
+)
+
+// EtcdClusterSpec defines the desired state of EtcdCluster
+type EtcdClusterSpec struct {
+	Name        string `json:"name" protobuf:"bytes,1,opt,name=name"`               // etcd cluster name，uniqueKey
+	Description string `json:"description" protobuf:"bytes,2,opt,name=description"` // etcd description
+
+	AuthConfig AuthConfig `json:"authConfig,omitempty" protobuf:"bytes,3,opt,name=authConfig"` // tls config
+
+	DiskType string `json:"diskType" protobuf:"bytes,4,opt,name=diskType"`  // disk type, CLOUD_SSD/CLOUD_PREMIUM/CLOUD_BASIC
+	DiskSize uint   `json:"diskSize" protobuf:"varint,5,opt,name=diskSize"` // single node's disk size, unit: GB
+	Size     uint   `json:"size"  protobuf:"varint,6,opt,name=size"`        // etcd cluster member count: support 1, 3, 5, 7
+
+	Affinity       corev1.Affinity `json:"affinity,omitempty" protobuf:"bytes,7,opt,name=affinity"`
+	Args           []string        `json:"args,omitempty" protobuf:"bytes,8,rep,name=args"`
+	Env            []corev1.EnvVar `json:"env,omitempty" protobuf:"bytes,9,rep,name=env"` // etcd environment variables
+	StorageBackend string          `json:"storageBackend" protobuf:"bytes,7,opt,name=storageBackend"`
+	Version        string          `json:"version" protobuf:"bytes,10,opt,name=version"`                 // etcd version
+	Repository     string          `json:"repository,omitempty" protobuf:"bytes,11,opt,name=repository"` // etcd image
+
+	ClusterType EtcdClusterType `json:"clusterType" protobuf:"bytes,12,opt,name=clusterType,casttype=EtcdClusterType"` // ClusterType specifies the etcd cluster provider.
+
+	Resources corev1.ResourceRequirements `json:"resources,omitempty" protobuf:"bytes,13,opt,name=resources"` // Resources specifies requests and limits
+	// If specified, the pod's tolerations.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// FeatureGates enables or disables the kstone features of the cluster.
+	// +optional
+	FeatureGates map[KStoneFeature]bool `json:"featureGates,omitempty"`
+	// Backup configures the storage and the policy of the backup feature.
+	// +optional
+	Backup *BackupSpec `json:"backup,omitempty"`
+	// Request configures the request feature.
+	// +optional
+	Request *RequestSpec `json:"request,omitempty"`
+	// Keyspace configures the keyspace feature.
+	// +optional
+	Keyspace *KeyspaceSpec `json:"keyspace,omitempty"`
+	// Consistency configures the consistency feature.
+	// +optional
+	Consistency *ConsistencySpec `json:"consistency,omitempty"`
+	// Defrag configures the thresholds of the defrag feature.
+	// +optional
+	Defrag *DefragSpec `json:"defrag,omitempty"`
+	// ClientCertSecret is the secret of the client certificate used by kstone
+	// to connect the cluster, the format is <namespace>/<name>.
+	// +optional
+	ClientCertSecret string `json:"clientCertSecret,omitempty"`
+	// ImportedAddr is the client address of the imported cluster.
+	// +optional
+	ImportedAddr string `json:"importedAddr,omitempty"`
+	// ExtClientURLs maps the client urls of the members to the urls accessible by kstone.
+	// +optional
+	ExtClientURLs map[string]string `json:"extClientURLs,omitempty"`
+	// QuotaBackendBytes is the quota-backend-bytes of the imported cluster,
+	// it overrides the value in the args of the cluster created by kstone.
+	// +optional
+	QuotaBackendBytes int64 `json:"quotaBackendBytes,omitempty"`
+	// AlertThresholds overrides the thresholds of the alerts created by the monitor feature.
+	// +optional
+	AlertThresholds *AlertThresholdsSpec `json:"alertThresholds,omitempty"`
+}
+
+// BackupSpec defines the storage and the policy of the backup feature
+type BackupSpec struct {
+	StorageType              backupapiv2.BackupStorageType `json:"storageType"`
+	BackupPolicy             *backupapiv2.BackupPolicy     `json:"backupPolicy,omitempty"`
+	backupapiv2.BackupSource `json:",inline"`
+	// Retention is enforced by kstone instead of MaxBackups of etcd-operator if it is set.
+	// +optional
+	Retention *BackupRetention `json:"retention,omitempty"`
+	// Encryption encrypts the backup files before they are uploaded.
+	// +optional
+	Encryption *backupapiv2.BackupEncryption `json:"encryption,omitempty"`
+}
+
+// BackupRetention keeps the newest backup file of each of the latest N hours,
+// days, weeks and months.
+type BackupRetention struct {
+	Hourly  int `json:"hourly,omitempty"`
+	Daily   int `json:"daily,omitempty"`
+	Weekly  int `json:"weekly,omitempty"`
+	Monthly int `json:"monthly,omitempty"`
+	// MinAgeInSecond protects the backup files younger than it from deletion.
+	MinAgeInSecond int64 `json:"minAgeInSecond,omitempty"`
+}
+
+// RequestSpec defines the keys watched by the request feature
+type RequestSpec struct {
+	Path     string `json:"path,omitempty"`
+	Interval int    `json:"interval,omitempty"`
+	Prefix   bool   `json:"prefix,omitempty"`
+	// PersistEvents persists the watched events to the backup storage,
+	// which is required by the point in time restore.
+	PersistEvents bool `json:"persistEvents,omitempty"`
+	// SegmentIntervalInSecond is the max duration of a persisted event segment.
+	SegmentIntervalInSecond int `json:"segmentIntervalInSecond,omitempty"`
+	// Prefixes are the watched prefixes with their label rules, Path must not
+	// be set with them.
+	Prefixes []RequestPrefix `json:"prefixes,omitempty"`
+	// MaxLabels is the max number of the label values of the request metrics.
+	MaxLabels int `json:"maxLabels,omitempty"`
+}
+
+// RequestPrefix defines a watched prefix and the rule extracting the labels from its keys
+type RequestPrefix struct {
+	Path string `json:"path,omitempty"`
+	// Regex extracts the etcdPrefix and resourceName labels with its first two capture groups.
+	Regex string `json:"regex,omitempty"`
+	// Depth is the number of the key segments in the resourceName label.
+	Depth int `json:"depth,omitempty"`
+}
+
+// KeyspaceSpec defines the keys analyzed by the keyspace feature
+type KeyspaceSpec struct {
+	Prefix string `json:"prefix,omitempty"`
+	// Depth is the number of the path segments of the aggregated prefixes.
+	Depth int `json:"depth,omitempty"`
+	// TopN is the number of the largest keys and prefixes reported.
+	TopN int `json:"topN,omitempty"`
+}
+
+// ConsistencySpec defines the consistency checks of the consistency feature
+type ConsistencySpec struct {
+	Path     string `json:"path,omitempty"`
+	Interval int    `json:"interval,omitempty"`
+	// Deep compares the hashes of the keyspace of the members at the same revision.
+	Deep bool `json:"deep,omitempty"`
+	// DeepIntervalInSecond is the min interval of the deep checks.
+	DeepIntervalInSecond int `json:"deepIntervalInSecond,omitempty"`
+}
+
+// DefragSpec defines the thresholds of the defrag feature, the unset ones use the defaults
+type DefragSpec struct {
+	// FragmentationPercent is the percentage of the db size not in use, a
+	// member is defragmented if its fragmentation reaches it.
+	FragmentationPercent int `json:"fragmentationPercent,omitempty"`
+	// MinDBSize is the db size in bytes a member must reach to be defragmented.
+	MinDBSize int64 `json:"minDBSize,omitempty"`
+}
+
+// AlertThresholdsSpec defines the thresholds of the alerts, the unset ones use the defaults
+type AlertThresholdsSpec struct {
+	KeyDiff        int64 `json:"keyDiff,omitempty"`
+	RevisionDiff   int64 `json:"revisionDiff,omitempty"`
+	MinBackupFiles int64 `json:"minBackupFiles,omitempty"`
+	DBUsedPercent  int64 `json:"dbUsedPercent,omitempty"`
+	BackupKeyDiff  int64 `json:"backupKeyDiff,omitempty"`
+}
+
+// AuthConfig defines tls
+type AuthConfig struct {
+	EnableTLS bool     `json:"enableTLS,omitempty" protobuf:"varint,1,opt,name=enableTLS"`
+	SAN       []string `json:"san,omitempty" protobuf:"bytes,2,rep,name=san"`
+	TLSSecret string   `json:"tlsSecret,omitempty" protobuf:"bytes,3,opt,name=tlsSecret"`
+}
+
+type KStoneFeature string
+
+const (
+	KStoneFeatureMonitor      KStoneFeature = "monitor"
+	KStoneFeatureBackup       KStoneFeature = "backup"
+	KStoneFeatureHealthy      KStoneFeature = "healthy"
+	KStoneFeatureConsistency  KStoneFeature = "consistency"
+	KStoneFeatureRequest      KStoneFeature = "request"
+	KStoneFeatureAlarm        KStoneFeature = "alarm"
+	KStoneFeatureBackupCheck  KStoneFeature = "backupcheck"
+	KStoneFeatureBackupVerify KStoneFeature = "backupverify"
+	KStoneFeatureDefrag       KStoneFeature = "defrag"
+	KStoneFeatureDBSize       KStoneFeature = "dbsize"
+	KStoneFeatureKeyspace     KStoneFeature = "keyspace"
+	KStoneFeatureScraper      KStoneFeature = "scraper"
+)
+
+// EtcdClusterStatus defines the actual state of EtcdCluster.
+type EtcdClusterStatus struct {
+	Conditions         []EtcdClusterCondition   `json:"conditions,omitempty" protobuf:"bytes,1,rep,name=conditions"`
+	Phase              EtcdClusterPhase         `json:"phase" protobuf:"bytes,2,opt,name=phase,casttype=EtcdClusterPhase"`
+	Members            []MemberStatus           `json:"members,omitempty" protobuf:"bytes,3,rep,name=members"`
+	FeatureGatesStatus map[KStoneFeature]string `json:"featureGatesStatus,omitempty" protobuf:"bytes,4,rep,name=featureGatesStatus,castkey=KStoneFeature"`
+	ServiceName        string                   `json:"serviceName,omitempty" protobuf:"bytes,5,opt,name=serviceName"`
+	// ObservedGeneration is the latest generation of the spec reconciled by kstone.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty" protobuf:"varint,6,opt,name=observedGeneration"`
+	// History records the operations of the cluster, such as Create, Update, Restore and Backup.
+	History []EtcdClusterCondition `json:"history,omitempty" protobuf:"bytes,7,rep,name=history"`
+}
+
+type MemberPhase string
+
+const (
+	MemberPhaseUnStarted MemberPhase = "UnStarted" // etcd member list return unstarted
+	MemberPhaseUnKnown   MemberPhase = "UnKnown"   // endpoint can not be connected
+	MemberPhaseRunning   MemberPhase = "Running"   // health check returns healthy
+	MemberPhaseUnHealthy MemberPhase = "UnHealthy" // health check returns unhealthy
+)
+
+type EtcdMemberRole string
+
+const (
+	EtcdMemberLearner EtcdMemberRole = "Learner"
+	// kstone can get the list of etcd members, but cannot connect to the endpoint.
+	EtcdMemberUnKnown  EtcdMemberRole = "UnKnown"
+	EtcdMemberFollower EtcdMemberRole = "Follower"
+	EtcdMemberLeader   EtcdMemberRole = "Leader"
+)
+
+type MemberStatus struct {
+	Name               string         `json:"name" protobuf:"bytes,1,opt,name=name"`
+	MemberId           string         `json:"memberId" protobuf:"bytes,2,opt,name=memberId"`
+	Status             MemberPhase    `json:"status" protobuf:"bytes,3,opt,name=status,casttype=MemberPhase"`
+	Version            string         `json:"version" protobuf:"bytes,4,opt,name=version"`
+	Endpoint           string         `json:"endpoint" protobuf:"bytes,5,opt,name=endpoint"`
+	Port               string         `json:"port" protobuf:"bytes,6,opt,name=port"`
+	ClientUrl          string         `json:"clientUrl" protobuf:"bytes,7,opt,name=clientUrl"`
+	ExtensionClientUrl string         `json:"extensionClientUrl" protobuf:"bytes,8,opt,name=extensionClientUrl"`
+	Role               EtcdMemberRole `json:"role" protobuf:"bytes,9,opt,name=role,casttype=EtcdMemberRole"`
+	Errors             []string       `json:"errors,omitempty" protobuf:"bytes,10,rep,name=errors"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// EtcdClusterList contains a list of EtcdCluster
+type EtcdClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+	Items           []EtcdCluster `json:"items" protobuf:"bytes,2,rep,name=items"`
+}
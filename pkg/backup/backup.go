@@ -48,6 +48,10 @@ type Config struct {
 	StorageType              backupapiv2.BackupStorageType `json:"storageType"`
 	StoragePolicy            *backupapiv2.BackupPolicy     `json:"backupPolicy,omitempty"`
 	backupapiv2.BackupSource `json:",inline"`
+	// Retention is enforced by kstone instead of MaxBackups of etcd-operator if it is set.
+	Retention *RetentionPolicy `json:"retention,omitempty"`
+	// Encryption encrypts the backup files and the event segments before they are uploaded.
+	Encryption *backupapiv2.BackupEncryption `json:"encryption,omitempty"`
 }
 
 type Server struct {
@@ -56,7 +60,7 @@ type Server struct {
 }
 
 const (
-	AnnoBackupConfig = "backup"
+	AnnoBackupConfig = kstonev1alpha2.AnnoBackup
 	BackupGroup      = "etcd.database.coreos.com"
 	BackupVersion    = "v1beta2"
 	BackupResource   = "etcdbackups"
@@ -230,6 +234,14 @@ func (bak *Server) initEtcdBackup(cluster *kstonev1alpha2.EtcdCluster) (*backupa
 		return nil, err
 	}
 
+	backupPolicy := backupCfg.StoragePolicy
+	if backupCfg.Retention != nil && backupPolicy != nil {
+		// the backup files are deleted by kstone according to the retention policy
+		policy := *backupPolicy
+		policy.MaxBackups = 0
+		backupPolicy = &policy
+	}
+
 	backup := &backupapiv2.EtcdBackup{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       BackupKind,
@@ -245,11 +257,17 @@ func (bak *Server) initEtcdBackup(cluster *kstonev1alpha2.EtcdCluster) (*backupa
 			StorageType:   backupCfg.StorageType,
 			//ClientTLSSecret: secretName,
 			//InsecureSkipVerify: true,
-			BackupPolicy: backupCfg.StoragePolicy,
+			BackupPolicy: backupPolicy,
 			BackupSource: backupCfg.BackupSource,
 			//BasicAuthSecret: secretName,
+			Encryption: backupCfg.Encryption,
 		},
 	}
+	if backup.Spec.StorageType == backupapiv2.BackupStorageTypeHostPath &&
+		(backup.Spec.HostPath == nil || backup.Spec.HostPath.Path == "") {
+		// the backups of each cluster are saved in its own directory unless a path is configured
+		backup.Spec.HostPath = &backupapiv2.HostPathBackupSource{Path: HostPathDir(cluster)}
+	}
 	//load secretConfig
 	clientConfigGetter := etcd.NewClientConfigSecretGetter(util.NewSimpleClientBuilder(""))
 	klog.Infof("secretName: %s", secretName)
@@ -270,6 +288,20 @@ func (bak *Server) initEtcdBackup(cluster *kstonev1alpha2.EtcdCluster) (*backupa
 	return backup, nil
 }
 
+// HostPathDir returns the directory of the backups of the cluster relative to
+// HOST_PATH_NAME. It is <namespace>/<name>/ if no hostPath path is configured,
+// so the backups of different clusters never share a directory. Otherwise it
+// is the directory of the configured path, which is empty for the paths of the
+// previous versions, whose backups are saved directly under HOST_PATH_NAME.
+func HostPathDir(cluster *kstonev1alpha2.EtcdCluster) string {
+	cfg := &Config{}
+	if err := json.Unmarshal([]byte(cluster.Annotations[AnnoBackupConfig]), cfg); err == nil &&
+		cfg.HostPath != nil && cfg.HostPath.Path != "" {
+		return cfg.HostPath.Path[:strings.LastIndex(cfg.HostPath.Path, "/")+1]
+	}
+	return cluster.Namespace + "/" + cluster.Name + "/"
+}
+
 // Equal checks whether the backup resource needs to be updated
 func (bak *Server) Equal(cluster *kstonev1alpha2.EtcdCluster) bool {
 	namespace, name := cluster.Namespace, cluster.Name
@@ -0,0 +1,137 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/coreos/etcd-operator/pkg/backup/encryption"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+)
+
+// encryptedStorage decrypts the encrypted backup files when they are downloaded,
+// and encrypts the files uploaded by kstone if encryption is configured.
+type encryptedStorage struct {
+	Storage
+	kubeCli kubernetes.Interface
+}
+
+// newEncryptedStorage wraps the storage with encryption
+func newEncryptedStorage(storage Storage, kubeCli kubernetes.Interface) Storage {
+	return &encryptedStorage{
+		Storage: storage,
+		kubeCli: kubeCli,
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Get downloads the backup file and decrypts it if the key is suffixed with .<keyID>.enc.
+func (s *encryptedStorage) Get(cluster *v1alpha2.EtcdCluster, key string) (io.ReadCloser, error) {
+	if _, _, ok := encryption.ParseObjectName(key); !ok {
+		return s.Storage.Get(cluster, key)
+	}
+
+	rc, err := s.Storage.Get(cluster, key)
+	if err != nil {
+		return nil, err
+	}
+	r, err := encryption.NewDecryptReader(rc, s.keyFunc(cluster))
+	if err != nil {
+		rc.Close()
+		klog.Errorf("failed to decrypt backup file %s, cluster %s, err is %v", key, cluster.Name, err)
+		return nil, err
+	}
+	return readCloser{Reader: r, Closer: rc}, nil
+}
+
+// Put encrypts the data if encryption is configured, name is suffixed with .<keyID>.enc.
+func (s *encryptedStorage) Put(cluster *v1alpha2.EtcdCluster, name string, data io.Reader) error {
+	backupConfig, err := GetBackupConfig(cluster)
+	if err != nil {
+		return err
+	}
+	if backupConfig.Encryption == nil {
+		return s.Storage.Put(cluster, name, data)
+	}
+
+	keySecret, keyID := backupConfig.Encryption.EncryptionSecret, backupConfig.Encryption.KeyID
+	if keySecret == "" {
+		return fmt.Errorf("encryption secret is not configured, cluster %s", cluster.Name)
+	}
+	key, err := s.keyFunc(cluster)(keySecret, keyID)
+	if err != nil {
+		return err
+	}
+	r, err := encryption.NewEncryptReader(data, keySecret, keyID, key)
+	if err != nil {
+		return err
+	}
+	return s.Storage.Put(cluster, encryption.ObjectName(name, keyID), r)
+}
+
+// keyFunc returns the keys stored in the secrets in the namespace of cluster.
+// The key is looked up in the secret recorded in the backup file first, then in
+// the configured encryption secret, so the backup files are still decrypted
+// after the encryption is disabled or another secret is configured.
+func (s *encryptedStorage) keyFunc(cluster *v1alpha2.EtcdCluster) encryption.KeyFunc {
+	return func(keySecret, keyID string) ([]byte, error) {
+		var secretNames []string
+		if keySecret != "" {
+			secretNames = append(secretNames, keySecret)
+		}
+		backupConfig, err := GetBackupConfig(cluster)
+		if err == nil && backupConfig.Encryption != nil &&
+			backupConfig.Encryption.EncryptionSecret != "" && backupConfig.Encryption.EncryptionSecret != keySecret {
+			secretNames = append(secretNames, backupConfig.Encryption.EncryptionSecret)
+		}
+		if len(secretNames) == 0 {
+			return nil, fmt.Errorf("secret of key %s is unknown, cluster %s", keyID, cluster.Name)
+		}
+		if s.kubeCli == nil {
+			return nil, fmt.Errorf("kube client is required to get encryption secret, cluster %s", cluster.Name)
+		}
+
+		for _, secretName := range secretNames {
+			secret, err := s.kubeCli.CoreV1().Secrets(cluster.Namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+			if err != nil {
+				klog.Errorf("failed to get encryption secret %s, cluster %s, err is %v", secretName, cluster.Name, err)
+				if errors.IsNotFound(err) {
+					continue
+				}
+				return nil, err
+			}
+			if data, ok := secret.Data[keyID]; ok {
+				return encryption.ParseKey(data)
+			}
+		}
+		return nil, fmt.Errorf("key %s not found in encryption secrets %s", keyID, strings.Join(secretNames, ","))
+	}
+}
@@ -0,0 +1,259 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	backupapiv2 "github.com/coreos/etcd-operator/pkg/apis/etcd/v1beta2"
+	"github.com/coreos/etcd-operator/pkg/backup/encryption"
+	"github.com/coreos/etcd-operator/pkg/backup/writer"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+)
+
+// fileStorage gets the backup files from the directory, the key is the file name
+type fileStorage struct {
+	listStorage
+	dir string
+}
+
+func (s *fileStorage) Get(cluster *v1alpha2.EtcdCluster, key string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.dir, key))
+}
+
+func (s *fileStorage) Put(cluster *v1alpha2.EtcdCluster, name string, data io.Reader) error {
+	content, err := ioutil.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(s.dir, name), content, 0600)
+}
+
+func newEncryptionCluster(t *testing.T, keyID string) *v1alpha2.EtcdCluster {
+	return newBackupCluster(t, &backupapiv2.BackupEncryption{
+		EncryptionSecret: "backup-keys",
+		KeyID:            keyID,
+	})
+}
+
+func newBackupCluster(t *testing.T, e *backupapiv2.BackupEncryption) *v1alpha2.EtcdCluster {
+	cfg := &Config{
+		StorageType: backupapiv2.BackupStorageTypeHostPath,
+		Encryption:  e,
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	return &v1alpha2.EtcdCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "kstone",
+			Name:        "test",
+			Annotations: map[string]string{AnnoBackupConfig: string(data)},
+		},
+	}
+}
+
+func newKeySecretClient(keys map[string][]byte) *fake.Clientset {
+	return fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kstone", Name: "backup-keys"},
+		Data:       keys,
+	})
+}
+
+func readAll(t *testing.T, rc io.ReadCloser) []byte {
+	defer rc.Close()
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	return data
+}
+
+func TestEncryptedStorageGetFromEncryptWriter(t *testing.T) {
+	dir := t.TempDir()
+	oldKey := bytes.Repeat([]byte{1}, encryption.KeySize)
+	newKey := bytes.Repeat([]byte{2}, encryption.KeySize)
+	// the keys are saved in base64, the old key is kept after rotation
+	kubeCli := newKeySecretClient(map[string][]byte{
+		"old": []byte(base64.StdEncoding.EncodeToString(oldKey)),
+		"new": newKey,
+	})
+	cluster := newEncryptionCluster(t, "new")
+	storage := newEncryptedStorage(&fileStorage{dir: dir}, kubeCli)
+
+	snapshot := bytes.Repeat([]byte("snapshot"), 300*1024)
+	for keyID, key := range map[string][]byte{"old": oldKey, "new": newKey} {
+		w := encryption.NewWriter(writer.NewHostPathWriter(dir), "backup-keys", keyID, key)
+		if _, err := w.Write(context.TODO(), filepath.Join(dir, "etcdbackup-"+keyID), bytes.NewReader(snapshot)); err != nil {
+			t.Fatalf("err is %v", err)
+		}
+
+		name := encryption.ObjectName("etcdbackup-"+keyID, keyID)
+		encrypted, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("err is %v", err)
+		}
+		if bytes.Contains(encrypted, []byte("snapshot")) {
+			t.Errorf("expected backup file %s to be encrypted", name)
+		}
+
+		rc, err := storage.Get(cluster, name)
+		if err != nil {
+			t.Fatalf("err is %v", err)
+		}
+		if !bytes.Equal(readAll(t, rc), snapshot) {
+			t.Errorf("expected backup file %s to be decrypted", name)
+		}
+	}
+}
+
+func TestEncryptedStoragePutGet(t *testing.T) {
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{1}, encryption.KeySize)
+	cluster := newEncryptionCluster(t, "k1")
+	storage := newEncryptedStorage(&fileStorage{dir: dir}, newKeySecretClient(map[string][]byte{"k1": key}))
+
+	segment := []byte("event segment")
+	if err := storage.Put(cluster, "events-1-10", bytes.NewReader(segment)); err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	rc, err := storage.Get(cluster, "events-1-10.k1.enc")
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	if !bytes.Equal(readAll(t, rc), segment) {
+		t.Errorf("expected the event segment to be decrypted")
+	}
+
+	// a file which is not encrypted is got as it is
+	if err = ioutil.WriteFile(filepath.Join(dir, "etcdbackup-plain"), segment, 0600); err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	rc, err = storage.Get(cluster, "etcdbackup-plain")
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	if !bytes.Equal(readAll(t, rc), segment) {
+		t.Errorf("expected the plain backup file to be got as it is")
+	}
+}
+
+func TestEncryptedStorageGetWithWrongKey(t *testing.T) {
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{1}, encryption.KeySize)
+	w := encryption.NewWriter(writer.NewHostPathWriter(dir), "backup-keys", "k1", key)
+	if _, err := w.Write(context.TODO(), filepath.Join(dir, "etcdbackup"), bytes.NewReader([]byte("snapshot"))); err != nil {
+		t.Fatalf("err is %v", err)
+	}
+
+	tests := []struct {
+		name string
+		keys map[string][]byte
+	}{
+		{
+			name: "key rotated without keeping the old one",
+			keys: map[string][]byte{"k2": key},
+		},
+		{
+			name: "key changed",
+			keys: map[string][]byte{"k1": bytes.Repeat([]byte{2}, encryption.KeySize)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newEncryptedStorage(&fileStorage{dir: dir}, newKeySecretClient(tt.keys))
+			if _, err := storage.Get(newEncryptionCluster(t, "k2"), "etcdbackup.k1.enc"); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestEncryptedStorageGetAfterEncryptionChanged(t *testing.T) {
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{1}, encryption.KeySize)
+	w := encryption.NewWriter(writer.NewHostPathWriter(dir), "backup-keys", "k1", key)
+	if _, err := w.Write(context.TODO(), filepath.Join(dir, "etcdbackup"), bytes.NewReader([]byte("snapshot"))); err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	secret := func(name string, keys map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kstone", Name: name},
+			Data:       keys,
+		}
+	}
+
+	tests := []struct {
+		name       string
+		encryption *backupapiv2.BackupEncryption
+		secrets    []*corev1.Secret
+	}{
+		{
+			name:       "encryption disabled",
+			encryption: nil,
+			secrets:    []*corev1.Secret{secret("backup-keys", map[string][]byte{"k1": key})},
+		},
+		{
+			name:       "another secret configured",
+			encryption: &backupapiv2.BackupEncryption{EncryptionSecret: "new-keys", KeyID: "k2"},
+			secrets: []*corev1.Secret{
+				secret("backup-keys", map[string][]byte{"k1": key}),
+				secret("new-keys", map[string][]byte{"k2": bytes.Repeat([]byte{2}, encryption.KeySize)}),
+			},
+		},
+		{
+			name:       "old key moved to the configured secret",
+			encryption: &backupapiv2.BackupEncryption{EncryptionSecret: "new-keys", KeyID: "k2"},
+			secrets: []*corev1.Secret{
+				secret("new-keys", map[string][]byte{"k1": key, "k2": bytes.Repeat([]byte{2}, encryption.KeySize)}),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeCli := fake.NewSimpleClientset()
+			for _, s := range tt.secrets {
+				if _, err := kubeCli.CoreV1().Secrets(s.Namespace).Create(context.TODO(), s, metav1.CreateOptions{}); err != nil {
+					t.Fatalf("err is %v", err)
+				}
+			}
+			storage := newEncryptedStorage(&fileStorage{dir: dir}, kubeCli)
+			rc, err := storage.Get(newBackupCluster(t, tt.encryption), "etcdbackup.k1.enc")
+			if err != nil {
+				t.Fatalf("err is %v", err)
+			}
+			if !bytes.Equal(readAll(t, rc), []byte("snapshot")) {
+				t.Errorf("expected the backup file to be decrypted")
+			}
+		})
+	}
+}
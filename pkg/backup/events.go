@@ -0,0 +1,169 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/backup/encryption"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+const (
+	// EventSegmentPrefix is appended to the backup path to store the event segments.
+	EventSegmentPrefix = "_events/"
+	eventSegmentSuffix = ".events.gz"
+	backupTimeLayout   = "2006-01-02-15:04:05"
+)
+
+var (
+	eventSegmentRegexp = regexp.MustCompile(`_events/([0-9a-f]{16})_([0-9a-f]{16})\.events\.gz$`)
+	backupNameRegexp   = regexp.MustCompile(`_v(\d+)_(\d{4}-\d{2}-\d{2}-\d{2}:\d{2}:\d{2})(?:_(\d+\.\d+\.\d+[0-9A-Za-z.+-]*))?$`)
+)
+
+// Event is a watch event persisted in the backup storage.
+type Event struct {
+	Type     mvccpb.Event_EventType `json:"type"`
+	Key      []byte                 `json:"key"`
+	Value    []byte                 `json:"value,omitempty"`
+	Revision int64                  `json:"revision"`
+	// Lease is the lease of the key in the source cluster, 0 if the key has no lease.
+	Lease int64 `json:"lease,omitempty"`
+	// Time is the time when kstone received the event.
+	Time time.Time `json:"time"`
+}
+
+// EventSegment is a file of continuous events, it covers the revisions [From, To].
+type EventSegment struct {
+	Key  string
+	From int64
+	To   int64
+}
+
+// EventSegmentName returns the name of the segment covering revisions [from, to].
+func EventSegmentName(from, to int64) string {
+	return fmt.Sprintf("%s%016x_%016x%s", EventSegmentPrefix, from, to, eventSegmentSuffix)
+}
+
+// IsEventSegment checks whether the key is an event segment.
+func IsEventSegment(key string) bool {
+	return strings.Contains(key, EventSegmentPrefix)
+}
+
+// ParseEventSegment parses the revision range of the event segment, which may be encrypted.
+func ParseEventSegment(key string) (*EventSegment, bool) {
+	name, _, _ := encryption.ParseObjectName(key)
+	matches := eventSegmentRegexp.FindStringSubmatch(name)
+	if len(matches) != 3 {
+		return nil, false
+	}
+	from, err := strconv.ParseInt(matches[1], 16, 64)
+	if err != nil {
+		return nil, false
+	}
+	to, err := strconv.ParseInt(matches[2], 16, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &EventSegment{Key: key, From: from, To: to}, true
+}
+
+// SortEventSegments parses the keys and sorts the segments by revision.
+func SortEventSegments(keys []string) []*EventSegment {
+	segments := make([]*EventSegment, 0, len(keys))
+	for _, key := range keys {
+		if segment, ok := ParseEventSegment(key); ok {
+			segments = append(segments, segment)
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].From < segments[j].From
+	})
+	return segments
+}
+
+// ParseBackupName parses the revision, the time and the etcd version of the
+// periodic backup file, which is named as <path>_v<revision>_<time>_<version>
+// by etcd-operator, and suffixed with .<keyID>.enc if it is encrypted. The
+// version is empty for the backup files taken by the previous versions.
+func ParseBackupName(key string) (int64, time.Time, string, bool) {
+	name, _, _ := encryption.ParseObjectName(key)
+	matches := backupNameRegexp.FindStringSubmatch(name)
+	if len(matches) != 4 {
+		return 0, time.Time{}, "", false
+	}
+	rev, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return 0, time.Time{}, "", false
+	}
+	t, err := time.ParseInLocation(backupTimeLayout, matches[2], time.Local)
+	if err != nil {
+		return 0, time.Time{}, "", false
+	}
+	return rev, t, matches[3], true
+}
+
+// EncodeEvents encodes the events as gzipped json lines.
+func EncodeEvents(events []*Event) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	encoder := json.NewEncoder(zw)
+	for _, ev := range events {
+		if err := encoder.Encode(ev); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeEvents decodes the events encoded by EncodeEvents.
+func DecodeEvents(r io.Reader) ([]*Event, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	events := make([]*Event, 0)
+	decoder := json.NewDecoder(bufio.NewReader(zr))
+	for {
+		ev := &Event{}
+		err = decoder.Decode(ev)
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package backup
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/backup/encryption"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+func TestParseEventSegment(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		wantFrom int64
+		wantTo   int64
+		wantOK   bool
+	}{
+		{
+			name:     "segment",
+			key:      "bucket/etcd/etcdbackup" + EventSegmentName(1, 255),
+			wantFrom: 1,
+			wantTo:   255,
+			wantOK:   true,
+		},
+		{
+			name:     "encrypted segment",
+			key:      encryption.ObjectName("etcdbackup"+EventSegmentName(4096, 8191), "key-1"),
+			wantFrom: 4096,
+			wantTo:   8191,
+			wantOK:   true,
+		},
+		{
+			name:     "max revision",
+			key:      EventSegmentName(1<<62, 1<<63-1),
+			wantFrom: 1 << 62,
+			wantTo:   1<<63 - 1,
+			wantOK:   true,
+		},
+		{name: "backup file", key: "etcdbackup_v10_2023-01-02-15:04:05"},
+		{name: "short revision", key: "etcdbackup_events/1_2.events.gz"},
+		{name: "upper case revision", key: "etcdbackup_events/000000000000000A_000000000000000B.events.gz"},
+		{name: "revision overflow", key: "etcdbackup_events/ffffffffffffffff_ffffffffffffffff.events.gz"},
+		{name: "temporary file", key: "etcdbackup" + EventSegmentName(1, 2) + ".tmp"},
+		{name: "not under the events directory", key: "etcdbackup_0000000000000001_0000000000000002.events.gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segment, ok := ParseEventSegment(tt.key)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseEventSegment(%q) ok = %v, want %v", tt.key, ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if segment.Key != tt.key || segment.From != tt.wantFrom || segment.To != tt.wantTo {
+				t.Errorf("ParseEventSegment(%q) = %+v, want [%d, %d]", tt.key, segment, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestSortEventSegments(t *testing.T) {
+	keys := []string{
+		"etcdbackup" + EventSegmentName(256, 300),
+		"etcdbackup_v10_2023-01-02-15:04:05",
+		encryption.ObjectName("etcdbackup"+EventSegmentName(16, 255), "key-1"),
+		"etcdbackup" + EventSegmentName(1, 15),
+	}
+	var from []int64
+	for _, segment := range SortEventSegments(keys) {
+		from = append(from, segment.From)
+	}
+	if want := []int64{1, 16, 256}; !reflect.DeepEqual(from, want) {
+		t.Errorf("SortEventSegments() = %v, want %v", from, want)
+	}
+}
+
+func TestEncodeDecodeEvents(t *testing.T) {
+	events := []*Event{
+		{Type: mvccpb.PUT, Key: []byte("/a"), Value: []byte("1"), Revision: 2, Lease: 7, Time: time.Unix(100, 0).UTC()},
+		{Type: mvccpb.DELETE, Key: []byte("/a"), Revision: 3, Time: time.Unix(101, 0).UTC()},
+	}
+	data, err := EncodeEvents(events)
+	if err != nil {
+		t.Fatalf("failed to encode events, err is %v", err)
+	}
+	decoded, err := DecodeEvents(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode events, err is %v", err)
+	}
+	if !reflect.DeepEqual(decoded, events) {
+		t.Errorf("DecodeEvents() = %v, want %v", decoded, events)
+	}
+}
@@ -0,0 +1,209 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package backup
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/backup/encryption"
+)
+
+const (
+	// SortOrderAsc sorts backup files from the oldest to the newest.
+	SortOrderAsc = "asc"
+	// SortOrderDesc sorts backup files from the newest to the oldest.
+	SortOrderDesc = "desc"
+)
+
+// BackupObject is a backup file in the backup storage, it is the same for all providers.
+type BackupObject struct {
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+	// Revision is the etcd revision of periodic backup files, parsed from the key.
+	Revision int64 `json:"revision,omitempty"`
+	// EtcdVersion is the version of etcd which took the backup, parsed from the key
+	// of periodic backup files. It is empty for one-shot backup files and the
+	// backup files taken by the previous versions.
+	EtcdVersion string `json:"etcdVersion,omitempty"`
+	// CreatedTime is parsed from the key of periodic backup files,
+	// otherwise it is the last modified time reported by the backend.
+	CreatedTime time.Time `json:"createdTime"`
+	// Checksum is reported by the backend, such as the ETag of object storage.
+	Checksum string `json:"checksum,omitempty"`
+	Cluster  string `json:"cluster"`
+	// Encrypted is true if the backup file is encrypted by the key of KeyID.
+	Encrypted bool   `json:"encrypted,omitempty"`
+	KeyID     string `json:"keyID,omitempty"`
+}
+
+// ListOptions filters and paginates the backup files.
+type ListOptions struct {
+	// Prefix filters the backup files by key.
+	Prefix string
+	// Limit is the maximum number of backup files returned, 0 means unlimited.
+	Limit int
+	// Continue is returned by the previous page.
+	Continue string
+	// Order is SortOrderAsc or SortOrderDesc of the created time.
+	Order string
+}
+
+// BackupList is a page of backup files.
+type BackupList struct {
+	Items []BackupObject `json:"items"`
+	// Continue is set if there are more backup files.
+	Continue string `json:"continue,omitempty"`
+	// Total is the number of backup files matching the prefix.
+	Total int `json:"total"`
+}
+
+// continueToken is the position of the last backup file of the previous page.
+type continueToken struct {
+	CreatedTime time.Time `json:"createdTime"`
+	Key         string    `json:"key"`
+}
+
+// NewBackupObject generates the backup object, the revision, the created
+// time and the etcd version are parsed from the key of periodic backup files.
+func NewBackupObject(cluster, key string, size int64, lastModified time.Time, checksum string) BackupObject {
+	object := BackupObject{
+		Key:         key,
+		Size:        size,
+		CreatedTime: lastModified,
+		Checksum:    strings.Trim(checksum, `"`),
+		Cluster:     cluster,
+	}
+	if _, keyID, ok := encryption.ParseObjectName(key); ok {
+		object.Encrypted = true
+		object.KeyID = keyID
+	}
+	if rev, t, version, ok := ParseBackupName(key); ok {
+		object.Revision = rev
+		object.CreatedTime = t
+		object.EtcdVersion = version
+	}
+	return object
+}
+
+// BackupKeys returns the keys of backup files.
+func BackupKeys(objects []BackupObject) []string {
+	keys := make([]string, 0, len(objects))
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	return keys
+}
+
+// CountRecentBackups counts the backup files created in the duration.
+func CountRecentBackups(objects []BackupObject, duration time.Duration) int {
+	count := 0
+	for _, object := range objects {
+		if time.Since(object.CreatedTime) <= duration {
+			count++
+		}
+	}
+	return count
+}
+
+// ValidateListOptions checks the options and fills the default sort order.
+func ValidateListOptions(opts *ListOptions) error {
+	if opts.Limit < 0 {
+		return errors.New("limit must not be negative")
+	}
+	switch opts.Order {
+	case "":
+		opts.Order = SortOrderAsc
+	case SortOrderAsc, SortOrderDesc:
+	default:
+		return errors.New("order must be asc or desc")
+	}
+	if opts.Continue != "" {
+		if _, err := decodeContinue(opts.Continue); err != nil {
+			return errors.New("invalid continue token")
+		}
+	}
+	return nil
+}
+
+// PageBackupObjects filters the backup files by prefix, sorts them by created
+// time and returns the page after the continue token.
+func PageBackupObjects(objects []BackupObject, opts *ListOptions) (*BackupList, error) {
+	if err := ValidateListOptions(opts); err != nil {
+		return nil, err
+	}
+
+	items := make([]BackupObject, 0, len(objects))
+	for _, object := range objects {
+		if strings.HasPrefix(object.Key, opts.Prefix) {
+			items = append(items, object)
+		}
+	}
+	// the key breaks the tie of created time, so the order is total
+	less := func(a, b *BackupObject) bool {
+		if opts.Order == SortOrderDesc {
+			a, b = b, a
+		}
+		if !a.CreatedTime.Equal(b.CreatedTime) {
+			return a.CreatedTime.Before(b.CreatedTime)
+		}
+		return a.Key < b.Key
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return less(&items[i], &items[j])
+	})
+
+	list := &BackupList{Total: len(items)}
+	start := 0
+	if opts.Continue != "" {
+		token, _ := decodeContinue(opts.Continue)
+		last := &BackupObject{Key: token.Key, CreatedTime: token.CreatedTime}
+		start = sort.Search(len(items), func(i int) bool {
+			return less(last, &items[i])
+		})
+	}
+	end := len(items)
+	if opts.Limit > 0 && start+opts.Limit < end {
+		end = start + opts.Limit
+		list.Continue = encodeContinue(&items[end-1])
+	}
+	list.Items = items[start:end]
+	return list, nil
+}
+
+func encodeContinue(object *BackupObject) string {
+	data, _ := json.Marshal(&continueToken{CreatedTime: object.CreatedTime, Key: object.Key})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeContinue(s string) (*continueToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	token := &continueToken{}
+	if err = json.Unmarshal(data, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
@@ -0,0 +1,289 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package backup
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewBackupObject(t *testing.T) {
+	modified := time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC)
+	created := time.Date(2023, 1, 2, 15, 4, 5, 0, time.Local)
+	tests := []struct {
+		name        string
+		key         string
+		wantRev     int64
+		wantVersion string
+		wantCreated time.Time
+		wantKeyID   string
+	}{
+		{
+			name:        "periodic backup file",
+			key:         "etcdbackup_v10_2023-01-02-15:04:05_3.4.13",
+			wantRev:     10,
+			wantVersion: "3.4.13",
+			wantCreated: created,
+		},
+		{
+			name:        "pre-release version",
+			key:         "etcdbackup_v10_2023-01-02-15:04:05_3.5.0-rc.1",
+			wantRev:     10,
+			wantVersion: "3.5.0-rc.1",
+			wantCreated: created,
+		},
+		{
+			name:        "encrypted periodic backup file",
+			key:         "etcdbackup_v10_2023-01-02-15:04:05_3.4.13.key-1.enc",
+			wantRev:     10,
+			wantVersion: "3.4.13",
+			wantCreated: created,
+			wantKeyID:   "key-1",
+		},
+		{
+			name:        "periodic backup file without version",
+			key:         "etcdbackup_v10_2023-01-02-15:04:05",
+			wantRev:     10,
+			wantCreated: created,
+		},
+		{
+			name:        "encrypted periodic backup file without version",
+			key:         "etcdbackup_v10_2023-01-02-15:04:05.key-1.enc",
+			wantRev:     10,
+			wantCreated: created,
+			wantKeyID:   "key-1",
+		},
+		{
+			name:        "one-shot backup file",
+			key:         "etcdbackup_ondemand_2023-01-02-15:04:05",
+			wantCreated: modified,
+		},
+		{
+			name:        "invalid version",
+			key:         "etcdbackup_v10_2023-01-02-15:04:05_latest",
+			wantCreated: modified,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object := NewBackupObject("etcd", tt.key, 100, modified, `"etag"`)
+			if object.Revision != tt.wantRev || object.EtcdVersion != tt.wantVersion || !object.CreatedTime.Equal(tt.wantCreated) {
+				t.Errorf("expected revision %d, version %q and created time %v, got %d, %q and %v",
+					tt.wantRev, tt.wantVersion, tt.wantCreated, object.Revision, object.EtcdVersion, object.CreatedTime)
+			}
+			if object.Encrypted != (tt.wantKeyID != "") || object.KeyID != tt.wantKeyID {
+				t.Errorf("expected key ID %q, got %q", tt.wantKeyID, object.KeyID)
+			}
+			if object.Checksum != "etag" || object.Size != 100 || object.Cluster != "etcd" {
+				t.Errorf("unexpected object %+v", object)
+			}
+		})
+	}
+}
+
+func newPageObjects(keys ...string) []BackupObject {
+	base := time.Date(2023, 1, 2, 15, 0, 0, 0, time.UTC)
+	objects := make([]BackupObject, 0, len(keys))
+	for i, key := range keys {
+		objects = append(objects, BackupObject{Key: key, CreatedTime: base.Add(time.Duration(i) * time.Minute)})
+	}
+	return objects
+}
+
+// pageAll lists all the pages and returns the keys of each page
+func pageAll(t *testing.T, objects []BackupObject, opts ListOptions) [][]string {
+	var pages [][]string
+	for {
+		list, err := PageBackupObjects(objects, &opts)
+		if err != nil {
+			t.Fatalf("err is %v", err)
+		}
+		pages = append(pages, BackupKeys(list.Items))
+		if list.Continue == "" {
+			return pages
+		}
+		opts.Continue = list.Continue
+		if len(pages) > len(objects)+1 {
+			t.Fatalf("too many pages %v", pages)
+		}
+	}
+}
+
+func TestPageBackupObjects(t *testing.T) {
+	objects := newPageObjects("a", "b", "c", "d", "e")
+	tests := []struct {
+		name string
+		opts ListOptions
+		want [][]string
+	}{
+		{
+			name: "unlimited",
+			opts: ListOptions{},
+			want: [][]string{{"a", "b", "c", "d", "e"}},
+		},
+		{
+			name: "pages",
+			opts: ListOptions{Limit: 2},
+			want: [][]string{{"a", "b"}, {"c", "d"}, {"e"}},
+		},
+		{
+			name: "last page is full",
+			opts: ListOptions{Limit: 5},
+			want: [][]string{{"a", "b", "c", "d", "e"}},
+		},
+		{
+			name: "pages in desc order",
+			opts: ListOptions{Limit: 2, Order: SortOrderDesc},
+			want: [][]string{{"e", "d"}, {"c", "b"}, {"a"}},
+		},
+		{
+			name: "prefix",
+			opts: ListOptions{Limit: 1, Prefix: "c"},
+			want: [][]string{{"c"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pageAll(t, objects, tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected pages %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPageBackupObjectsFirstAndLastPage(t *testing.T) {
+	objects := newPageObjects("a", "b", "c")
+
+	first, err := PageBackupObjects(objects, &ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	if first.Total != 3 || first.Continue == "" || !reflect.DeepEqual(BackupKeys(first.Items), []string{"a", "b"}) {
+		t.Fatalf("unexpected first page %+v", first)
+	}
+
+	last, err := PageBackupObjects(objects, &ListOptions{Limit: 2, Continue: first.Continue})
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	if last.Total != 3 || last.Continue != "" || !reflect.DeepEqual(BackupKeys(last.Items), []string{"c"}) {
+		t.Fatalf("unexpected last page %+v", last)
+	}
+
+	// the token of the last backup file returns an empty page
+	end := encodeContinue(&objects[2])
+	empty, err := PageBackupObjects(objects, &ListOptions{Limit: 2, Continue: end})
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	if len(empty.Items) != 0 || empty.Continue != "" {
+		t.Errorf("expected an empty page, got %+v", empty)
+	}
+}
+
+func TestPageBackupObjectsInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ListOptions
+	}{
+		{name: "not base64", opts: ListOptions{Continue: "!!!"}},
+		{name: "not json", opts: ListOptions{Continue: "bm90IGpzb24"}},
+		{name: "negative limit", opts: ListOptions{Limit: -1}},
+		{name: "invalid order", opts: ListOptions{Order: "random"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := PageBackupObjects(newPageObjects("a"), &tt.opts); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestPageBackupObjectsAfterDelete(t *testing.T) {
+	objects := newPageObjects("a", "b", "c", "d", "e", "f")
+	first, err := PageBackupObjects(objects, &ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		deleted []string
+		want    []string
+	}{
+		{
+			// the backup file of the token is deleted by the retention
+			name:    "last backup file of the page deleted",
+			deleted: []string{"b"},
+			want:    []string{"c", "d"},
+		},
+		{
+			name:    "whole page deleted",
+			deleted: []string{"a", "b"},
+			want:    []string{"c", "d"},
+		},
+		{
+			name:    "next backup file deleted",
+			deleted: []string{"c"},
+			want:    []string{"d", "e"},
+		},
+		{
+			name:    "all following backup files deleted",
+			deleted: []string{"c", "d", "e", "f"},
+			want:    []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleted := make(map[string]bool)
+			for _, key := range tt.deleted {
+				deleted[key] = true
+			}
+			var remaining []BackupObject
+			for _, object := range objects {
+				if !deleted[object.Key] {
+					remaining = append(remaining, object)
+				}
+			}
+
+			list, err := PageBackupObjects(remaining, &ListOptions{Limit: 2, Continue: first.Continue})
+			if err != nil {
+				t.Fatalf("err is %v", err)
+			}
+			if got := BackupKeys(list.Items); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPageBackupObjectsSameCreatedTime(t *testing.T) {
+	created := time.Date(2023, 1, 2, 15, 0, 0, 0, time.UTC)
+	var objects []BackupObject
+	for _, key := range []string{"c", "a", "d", "b"} {
+		objects = append(objects, BackupObject{Key: key, CreatedTime: created})
+	}
+	// the key breaks the tie, no backup file is returned twice or skipped
+	want := [][]string{{"a", "b"}, {"c", "d"}}
+	if got := pageAll(t, objects, ListOptions{Limit: 2}); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected pages %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,329 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	backupapiv2 "github.com/coreos/etcd-operator/pkg/apis/etcd/v1beta2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	platformscheme "tkestack.io/kstone/pkg/generated/clientset/versioned/scheme"
+)
+
+const (
+	// LabelOnDemandBackup is the label of one-shot etcdbackups created on demand, the value is the cluster name.
+	LabelOnDemandBackup = "kstone.tkestack.io/ondemand-backup"
+	// AnnoOnDemandBackupRecorded is set once the result of the on-demand backup is recorded on the cluster.
+	AnnoOnDemandBackupRecorded = "kstone.tkestack.io/ondemand-backup-recorded"
+
+	// OnDemandBackupInfix is appended to the backup path with the created time,
+	// on-demand backup files are named as <path>_ondemand_<time>.
+	OnDemandBackupInfix = "_ondemand_"
+	// maxOnDemandBackups is the number of recorded on-demand etcdbackups kept for each cluster.
+	maxOnDemandBackups = 10
+	// defaultOnDemandBackupTimeout is the timeout of the backup operator if the backup policy has no timeout.
+	defaultOnDemandBackupTimeout = time.Minute
+	// onDemandBackupGracePeriod is added to the timeout of the on-demand backup, the etcdbackup
+	// not finished by then is regarded as failed, e.g. the backup operator is down.
+	onDemandBackupGracePeriod = 10 * time.Minute
+)
+
+// ErrOnDemandBackupRunning is returned if an on-demand backup of the cluster is running.
+var ErrOnDemandBackupRunning = errors.New("an on-demand backup is running")
+
+type OnDemandBackupPhase string
+
+const (
+	OnDemandBackupRunning   OnDemandBackupPhase = "Running"
+	OnDemandBackupSucceeded OnDemandBackupPhase = "Succeeded"
+	OnDemandBackupFailed    OnDemandBackupPhase = "Failed"
+)
+
+// OnDemandBackupStatus is the status of an on-demand backup, Name is the handle to poll it.
+type OnDemandBackupStatus struct {
+	Name    string              `json:"name"`
+	Cluster string              `json:"cluster"`
+	Phase   OnDemandBackupPhase `json:"phase"`
+	// Path is the path of the backup file in the backup storage.
+	Path        string      `json:"path"`
+	CreatedTime metav1.Time `json:"createdTime"`
+	// Revision, EtcdVersion and FinishedTime are set if the backup succeeded.
+	Revision     int64        `json:"revision,omitempty"`
+	EtcdVersion  string       `json:"etcdVersion,omitempty"`
+	FinishedTime *metav1.Time `json:"finishedTime,omitempty"`
+	// Reason is the error if the backup failed.
+	Reason string `json:"reason,omitempty"`
+}
+
+// NewOnDemandBackupStatus generates the status of the on-demand etcdbackup
+func NewOnDemandBackupStatus(backup *backupapiv2.EtcdBackup) *OnDemandBackupStatus {
+	status := &OnDemandBackupStatus{
+		Name:        backup.Name,
+		Cluster:     backup.Labels[LabelOnDemandBackup],
+		Phase:       OnDemandBackupRunning,
+		Path:        backupSourcePath(backup.Spec.StorageType, &backup.Spec.BackupSource),
+		CreatedTime: backup.CreationTimestamp,
+	}
+	switch {
+	case backup.Status.Succeeded:
+		status.Phase = OnDemandBackupSucceeded
+		status.Revision = backup.Status.EtcdRevision
+		status.EtcdVersion = backup.Status.EtcdVersion
+		finished := backup.Status.LastSuccessDate
+		status.FinishedTime = &finished
+	case backup.Status.Reason != "":
+		status.Phase = OnDemandBackupFailed
+		status.Reason = backup.Status.Reason
+	case !backup.CreationTimestamp.IsZero() && time.Now().After(onDemandBackupDeadline(backup)):
+		status.Phase = OnDemandBackupFailed
+		status.Reason = fmt.Sprintf("backup is not finished after %s, check the backup operator and the backup storage",
+			onDemandBackupDeadline(backup).Sub(backup.CreationTimestamp.Time))
+	}
+	return status
+}
+
+// onDemandBackupDeadline returns the time by which the on-demand etcdbackup should be finished
+func onDemandBackupDeadline(backup *backupapiv2.EtcdBackup) time.Time {
+	timeout := defaultOnDemandBackupTimeout
+	if policy := backup.Spec.BackupPolicy; policy != nil && policy.TimeoutInSecond > 0 {
+		timeout = time.Duration(policy.TimeoutInSecond) * time.Second
+	}
+	return backup.CreationTimestamp.Add(timeout + onDemandBackupGracePeriod)
+}
+
+// IsOnDemandBackupRecorded checks whether the result of the on-demand etcdbackup is recorded on the cluster
+func IsOnDemandBackupRecorded(backup *backupapiv2.EtcdBackup) bool {
+	_, found := backup.Annotations[AnnoOnDemandBackupRecorded]
+	return found
+}
+
+// CreateOnDemandBackup creates a one-shot etcdbackup of the cluster, the
+// periodic etcdbackup is not changed. The etcdbackups are named as
+// <cluster>-ondemand-<sequence>, the sequence is the next one of the newest
+// etcdbackup, so the concurrent requests create the same etcdbackup and only
+// one of them succeeds, the others get ErrOnDemandBackupRunning.
+func (bak *Server) CreateOnDemandBackup(cluster *kstonev1alpha2.EtcdCluster) (*OnDemandBackupStatus, error) {
+	backups, err := bak.ListOnDemandBackups(cluster)
+	if err != nil {
+		return nil, err
+	}
+	var seq int64
+	for _, backup := range backups {
+		if NewOnDemandBackupStatus(backup).Phase == OnDemandBackupRunning {
+			return nil, ErrOnDemandBackupRunning
+		}
+		if n, ok := onDemandBackupSeq(cluster.Name, backup.Name); ok && n > seq {
+			seq = n
+		}
+	}
+
+	backup, err := bak.initEtcdBackup(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Local()
+	backup.Name = onDemandBackupName(cluster.Name, seq+1)
+	labels := make(map[string]string, len(cluster.Labels)+1)
+	for k, v := range cluster.Labels {
+		labels[k] = v
+	}
+	labels[LabelOnDemandBackup] = cluster.Name
+	backup.Labels = labels
+
+	// a backup policy without interval makes a one-shot backup, the timeout is kept
+	if backup.Spec.BackupPolicy != nil {
+		policy := *backup.Spec.BackupPolicy
+		policy.BackupIntervalInSecond = 0
+		policy.MaxBackups = 0
+		backup.Spec.BackupPolicy = &policy
+	}
+	suffix := OnDemandBackupInfix + now.Format(backupTimeLayout)
+	backup.Spec.BackupSource = onDemandBackupSource(backup.Spec.StorageType, &backup.Spec.BackupSource, suffix)
+
+	err = controllerutil.SetOwnerReference(cluster, backup, platformscheme.Scheme)
+	if err != nil {
+		klog.Errorf("set reference failed, err is %v, backup is %s", err, backup.Name)
+		return nil, err
+	}
+	obj, err := bak.decodeBackupObj(backup)
+	if err != nil {
+		return nil, fmt.Errorf("decode backup failed, err is %v", err)
+	}
+	obj, err = bak.cli.Resource(BackupSchema).Namespace(backup.Namespace).Create(context.TODO(), obj, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		// created by a concurrent request
+		return nil, ErrOnDemandBackupRunning
+	}
+	if err != nil {
+		klog.Errorf("failed to create on-demand backup %s, cluster %s, err is %v", backup.Name, cluster.Name, err)
+		return nil, err
+	}
+	klog.Infof("create on-demand backup %s, cluster %s", backup.Name, cluster.Name)
+
+	created, err := bak.encodeBackupObj(obj)
+	if err != nil {
+		return nil, err
+	}
+	if created.CreationTimestamp.IsZero() {
+		created.CreationTimestamp = metav1.NewTime(now)
+	}
+	return NewOnDemandBackupStatus(created), nil
+}
+
+// GetOnDemandBackup gets the on-demand etcdbackup of the cluster
+func (bak *Server) GetOnDemandBackup(cluster *kstonev1alpha2.EtcdCluster, name string) (*backupapiv2.EtcdBackup, error) {
+	backup, err := bak.GetEtcdBackup(name, cluster.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if backup.Labels[LabelOnDemandBackup] != cluster.Name {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Group: BackupGroup, Resource: BackupResource}, name)
+	}
+	return backup, nil
+}
+
+// ListOnDemandBackups lists the on-demand etcdbackups of the cluster, ordered by created time.
+func (bak *Server) ListOnDemandBackups(cluster *kstonev1alpha2.EtcdCluster) ([]*backupapiv2.EtcdBackup, error) {
+	list, err := bak.cli.Resource(BackupSchema).Namespace(cluster.Namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", LabelOnDemandBackup, cluster.Name),
+	})
+	if err != nil {
+		klog.Errorf("failed to list on-demand backups, cluster %s, err is %v", cluster.Name, err)
+		return nil, err
+	}
+
+	backups := make([]*backupapiv2.EtcdBackup, 0, len(list.Items))
+	for i := range list.Items {
+		backup, err := bak.encodeBackupObj(&list.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, backup)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreationTimestamp.Before(&backups[j].CreationTimestamp)
+	})
+	return backups, nil
+}
+
+// MarkOnDemandBackupRecorded marks the result of the on-demand etcdbackup recorded on the cluster
+func (bak *Server) MarkOnDemandBackupRecorded(backup *backupapiv2.EtcdBackup) error {
+	if backup.Annotations == nil {
+		backup.Annotations = make(map[string]string)
+	}
+	backup.Annotations[AnnoOnDemandBackupRecorded] = metav1.Now().Format(time.RFC3339)
+	_, err := bak.UpdateEtcdBackup(backup)
+	return err
+}
+
+// CleanOnDemandBackups deletes the recorded on-demand etcdbackups except the
+// newest ones, the backup files are not deleted.
+func (bak *Server) CleanOnDemandBackups(backups []*backupapiv2.EtcdBackup) error {
+	recorded := make([]*backupapiv2.EtcdBackup, 0, len(backups))
+	for _, backup := range backups {
+		if IsOnDemandBackupRecorded(backup) {
+			recorded = append(recorded, backup)
+		}
+	}
+	for i := 0; i < len(recorded)-maxOnDemandBackups; i++ {
+		err := bak.DeleteEtcdBackup(recorded[i].Name, recorded[i].Namespace)
+		if err != nil {
+			klog.Errorf("failed to delete on-demand backup %s, err is %v", recorded[i].Name, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// onDemandBackupName returns the name of the on-demand etcdbackup of the sequence
+func onDemandBackupName(cluster string, seq int64) string {
+	return fmt.Sprintf("%s-ondemand-%d", cluster, seq)
+}
+
+// onDemandBackupSeq parses the sequence of the on-demand etcdbackup
+func onDemandBackupSeq(cluster, name string) (int64, bool) {
+	suffix := strings.TrimPrefix(name, cluster+"-ondemand-")
+	if suffix == name {
+		return 0, false
+	}
+	seq, err := strconv.ParseInt(suffix, 10, 64)
+	if err != nil || seq < 0 {
+		return 0, false
+	}
+	return seq, true
+}
+
+// onDemandBackupSource appends the suffix to the path of the backup source,
+// so the on-demand backup files never overwrite each other.
+func onDemandBackupSource(
+	storageType backupapiv2.BackupStorageType,
+	source *backupapiv2.BackupSource,
+	suffix string,
+) backupapiv2.BackupSource {
+	s := source.DeepCopy()
+	switch {
+	case storageType == backupapiv2.BackupStorageTypeS3 && s.S3 != nil:
+		s.S3.Path += suffix
+	case storageType == backupapiv2.BackupStorageTypeABS && s.ABS != nil:
+		s.ABS.Path += suffix
+	case storageType == backupapiv2.BackupStorageTypeGCS && s.GCS != nil:
+		s.GCS.Path += suffix
+	case storageType == backupapiv2.BackupStorageTypeOSS && s.OSS != nil:
+		s.OSS.Path += suffix
+	case storageType == backupapiv2.BackupStorageTypeCOS && s.COS != nil:
+		s.COS.Path += suffix
+	case storageType == backupapiv2.BackupStorageTypeHostPath && s.HostPath != nil:
+		// one-shot backups are saved as etcdbackup<suffix> under the directory of the cluster
+		s.HostPath.Path += suffix
+	}
+	return *s
+}
+
+// backupSourcePath returns the path of the backup source
+func backupSourcePath(storageType backupapiv2.BackupStorageType, source *backupapiv2.BackupSource) string {
+	switch {
+	case storageType == backupapiv2.BackupStorageTypeS3 && source.S3 != nil:
+		return source.S3.Path
+	case storageType == backupapiv2.BackupStorageTypeABS && source.ABS != nil:
+		return source.ABS.Path
+	case storageType == backupapiv2.BackupStorageTypeGCS && source.GCS != nil:
+		return source.GCS.Path
+	case storageType == backupapiv2.BackupStorageTypeOSS && source.OSS != nil:
+		return source.OSS.Path
+	case storageType == backupapiv2.BackupStorageTypeCOS && source.COS != nil:
+		return source.COS.Path
+	case storageType == backupapiv2.BackupStorageTypeHostPath && source.HostPath != nil:
+		i := strings.LastIndex(source.HostPath.Path, "/") + 1
+		return source.HostPath.Path[:i] + "etcdbackup" + source.HostPath.Path[i:]
+	}
+	return ""
+}
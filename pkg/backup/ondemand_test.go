@@ -0,0 +1,103 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package backup
+
+import (
+	"testing"
+	"time"
+
+	backupapiv2 "github.com/coreos/etcd-operator/pkg/apis/etcd/v1beta2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOnDemandBackupSeq(t *testing.T) {
+	tests := []struct {
+		name    string
+		backup  string
+		wantSeq int64
+		wantOK  bool
+	}{
+		{name: "sequence", backup: onDemandBackupName("etcd", 7), wantSeq: 7, wantOK: true},
+		{name: "other cluster", backup: "etcd-1-ondemand-7"},
+		{name: "periodic backup", backup: "etcd"},
+		{name: "not a number", backup: "etcd-ondemand-x"},
+		{name: "negative", backup: "etcd-ondemand--1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seq, ok := onDemandBackupSeq("etcd", tt.backup)
+			if ok != tt.wantOK || seq != tt.wantSeq {
+				t.Errorf("onDemandBackupSeq(%s) = %d, %v, want %d, %v", tt.backup, seq, ok, tt.wantSeq, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNewOnDemandBackupStatusPhase(t *testing.T) {
+	backup := func(age time.Duration, timeout int64, status backupapiv2.BackupStatus) *backupapiv2.EtcdBackup {
+		return &backupapiv2.EtcdBackup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "etcd-ondemand-1",
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+			},
+			Spec: backupapiv2.BackupSpec{
+				BackupPolicy: &backupapiv2.BackupPolicy{TimeoutInSecond: timeout},
+			},
+			Status: status,
+		}
+	}
+	tests := []struct {
+		name   string
+		backup *backupapiv2.EtcdBackup
+		want   OnDemandBackupPhase
+	}{
+		{
+			name:   "running",
+			backup: backup(time.Minute, 0, backupapiv2.BackupStatus{}),
+			want:   OnDemandBackupRunning,
+		},
+		{
+			name:   "not finished after the default timeout",
+			backup: backup(time.Hour, 0, backupapiv2.BackupStatus{}),
+			want:   OnDemandBackupFailed,
+		},
+		{
+			name:   "running within the configured timeout",
+			backup: backup(time.Hour, 7200, backupapiv2.BackupStatus{}),
+			want:   OnDemandBackupRunning,
+		},
+		{
+			name:   "succeeded",
+			backup: backup(time.Hour, 0, backupapiv2.BackupStatus{Succeeded: true}),
+			want:   OnDemandBackupSucceeded,
+		},
+		{
+			name:   "failed",
+			backup: backup(time.Minute, 0, backupapiv2.BackupStatus{Reason: "failed"}),
+			want:   OnDemandBackupFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewOnDemandBackupStatus(tt.backup); got.Phase != tt.want {
+				t.Errorf("NewOnDemandBackupStatus() phase = %s, want %s, reason %s", got.Phase, tt.want, got.Reason)
+			}
+		})
+	}
+}
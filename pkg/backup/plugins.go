@@ -55,5 +55,17 @@ func GetBackupStorageProvider(name string, config *StorageConfig) (Storage, erro
 	if !found {
 		return nil, errors.New("fatal error,backup storage provider not found")
 	}
-	return f(config)
+	storage, err := f(config)
+	if err != nil {
+		return nil, err
+	}
+	return newEncryptedStorage(storage, config.KubeCli), nil
+}
+
+// IsBackupStorageProviderRegistered checks whether the specified backup storage provider is registered
+func IsBackupStorageProviderRegistered(name string) bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	_, found := Providers[name]
+	return found
 }
@@ -0,0 +1,225 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package abs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/coreos/etcd-operator/pkg/apis/etcd/v1beta2"
+	backuputil "github.com/coreos/etcd-operator/pkg/backup/util"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/backup"
+)
+
+const (
+	ProviderName = string(v1beta2.BackupStorageTypeABS)
+)
+
+type StorageABS struct {
+	kubeCli kubernetes.Interface
+}
+
+func init() {
+	backup.RegisterBackupStorageFactory(ProviderName, func(config *backup.StorageConfig) (backup.Storage, error) {
+		return NewABSBackupProvider(config), nil
+	})
+}
+
+func NewABSBackupProvider(config *backup.StorageConfig) backup.Storage {
+	return &StorageABS{
+		kubeCli: config.KubeCli,
+	}
+}
+
+func (c *StorageABS) List(cluster *v1alpha2.EtcdCluster) ([]backup.BackupObject, error) {
+	container, key, err := c.newContainer(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	blobs, err := listBlobs(container, key)
+	if err != nil {
+		klog.Errorf("failed to list abs blobs, cluster %s, err is %v", cluster.Name, err)
+		return nil, err
+	}
+
+	objects := make([]backup.BackupObject, 0, len(blobs))
+	for _, blob := range blobs {
+		if !backup.IsEventSegment(blob.Name) {
+			objects = append(objects, backup.NewBackupObject(
+				cluster.Name,
+				blob.Name,
+				blob.Properties.ContentLength,
+				time.Time(blob.Properties.LastModified),
+				blob.Properties.ContentMD5,
+			))
+		}
+	}
+	return objects, nil
+}
+
+func (c *StorageABS) Get(cluster *v1alpha2.EtcdCluster, key string) (io.ReadCloser, error) {
+	container, prefix, err := c.newContainer(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if err = backup.CheckBackupKey(key, prefix); err != nil {
+		return nil, err
+	}
+
+	rc, err := container.GetBlobReference(key).Get(nil)
+	if err != nil {
+		klog.Errorf("failed to get abs blob %s, err is %v", key, err)
+		return nil, err
+	}
+	return rc, nil
+}
+
+func (c *StorageABS) Delete(cluster *v1alpha2.EtcdCluster, key string) error {
+	container, prefix, err := c.newContainer(cluster)
+	if err != nil {
+		return err
+	}
+	if err = backup.CheckBackupKey(key, prefix); err != nil {
+		return err
+	}
+
+	err = container.GetBlobReference(key).Delete(nil)
+	if err != nil {
+		klog.Errorf("failed to delete abs blob %s, err is %v", key, err)
+		return err
+	}
+	return nil
+}
+
+func (c *StorageABS) Put(cluster *v1alpha2.EtcdCluster, name string, data io.Reader) error {
+	container, key, err := c.newContainer(cluster)
+	if err != nil {
+		return err
+	}
+
+	key += name
+	err = container.GetBlobReference(key).CreateBlockBlobFromReader(data, nil)
+	if err != nil {
+		klog.Errorf("failed to put abs blob %s, err is %v", key, err)
+		return err
+	}
+	return nil
+}
+
+func (c *StorageABS) ListEventSegments(cluster *v1alpha2.EtcdCluster) ([]string, error) {
+	container, key, err := c.newContainer(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	blobs, err := listBlobs(container, key+backup.EventSegmentPrefix)
+	if err != nil {
+		klog.Errorf("failed to list event segments, cluster %s, err is %v", cluster.Name, err)
+		return nil, err
+	}
+	keys := make([]string, 0, len(blobs))
+	for _, blob := range blobs {
+		keys = append(keys, blob.Name)
+	}
+	return keys, nil
+}
+
+// newContainer generates the abs container and the key of backup path
+func (c *StorageABS) newContainer(cluster *v1alpha2.EtcdCluster) (*storage.Container, string, error) {
+	// get backup config
+	backupConfig, err := backup.GetBackupConfig(cluster)
+	if err != nil {
+		klog.Errorf("failed to get backup config,cluster %s,err is %v", cluster.Name, err)
+		return nil, "", err
+	}
+	if backupConfig.ABS == nil {
+		return nil, "", errors.New("abs backup source is not set")
+	}
+
+	containerName, key, err := backuputil.ParseBucketAndKey(backupConfig.ABS.Path)
+	if err != nil {
+		klog.Errorf("failed to parse abs path %s, err is %v", backupConfig.ABS.Path, err)
+		return nil, "", err
+	}
+
+	secret, err := c.kubeCli.CoreV1().Secrets(cluster.Namespace).Get(context.TODO(), backupConfig.ABS.ABSSecret, v1.GetOptions{})
+	if err != nil {
+		klog.Errorf(err.Error())
+		return nil, "", err
+	}
+
+	cloud := azure.PublicCloud
+	if cloudName := string(secret.Data[v1beta2.AzureCloudKey]); cloudName != "" {
+		cloud, err = azure.EnvironmentFromName(cloudName)
+		if err != nil {
+			klog.Errorf("failed to parse azure environment %s, err is %v", cloudName, err)
+			return nil, "", err
+		}
+	}
+
+	client, err := storage.NewBasicClientOnSovereignCloud(
+		string(secret.Data[v1beta2.AzureSecretStorageAccount]),
+		string(secret.Data[v1beta2.AzureSecretStorageKey]),
+		cloud,
+	)
+	if err != nil {
+		klog.Errorf("failed to create abs client, err is %v", err)
+		return nil, "", err
+	}
+
+	blobService := client.GetBlobService()
+	container := blobService.GetContainerReference(containerName)
+	exists, err := container.Exists()
+	if err != nil {
+		klog.Errorf("failed to check abs container %s, err is %v", containerName, err)
+		return nil, "", err
+	}
+	if !exists {
+		return nil, "", fmt.Errorf("container %s does not exist", containerName)
+	}
+	return container, key, nil
+}
+
+// listBlobs lists all blobs with the prefix in container
+func listBlobs(container *storage.Container, prefix string) ([]storage.Blob, error) {
+	blobs := make([]storage.Blob, 0)
+	params := storage.ListBlobsParameters{Prefix: prefix}
+	for {
+		resp, err := container.ListBlobs(params)
+		if err != nil {
+			return nil, err
+		}
+		blobs = append(blobs, resp.Blobs...)
+		if resp.NextMarker == "" {
+			return blobs, nil
+		}
+		params.Marker = resp.NextMarker
+	}
+}
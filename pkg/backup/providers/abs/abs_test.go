@@ -0,0 +1,67 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package abs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/coreos/etcd-operator/pkg/apis/etcd/v1beta2"
+
+	"tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/backup"
+	"tkestack.io/kstone/pkg/backup/storagetest"
+)
+
+// TestStorageABS runs against Azurite, which listens on 127.0.0.1:10000 as
+// the storage emulator account of the sdk, e.g.
+//
+//	docker run -d -p 10000:10000 mcr.microsoft.com/azure-storage/azurite \
+//	  azurite-blob --blobHost 0.0.0.0 --skipApiVersionCheck
+//	AZURITE=1 go test ./pkg/backup/providers/abs/
+func TestStorageABS(t *testing.T) {
+	if os.Getenv("AZURITE") == "" {
+		t.Skip("AZURITE is not set")
+	}
+
+	const container = "kstone-test"
+	client, err := storage.NewEmulatorClient()
+	if err != nil {
+		t.Fatalf("failed to create abs client, err is %v", err)
+	}
+	blobService := client.GetBlobService()
+	_, err = blobService.GetContainerReference(container).CreateIfNotExists(nil)
+	if err != nil {
+		t.Fatalf("failed to create container %s, err is %v", container, err)
+	}
+
+	const secret = "abs-secret"
+	kubeCli := storagetest.NewSecretClient(secret, map[string]string{
+		v1beta2.AzureSecretStorageAccount: storage.StorageEmulatorAccountName,
+		v1beta2.AzureSecretStorageKey:     storage.StorageEmulatorAccountKey,
+	})
+	run := storagetest.RunID()
+	newCluster := func(name string) *v1alpha2.EtcdCluster {
+		return storagetest.NewCluster(t, name, storagetest.NewConfig(v1beta2.BackupStorageTypeABS, func(s *v1beta2.BackupSource) {
+			s.ABS = &v1beta2.ABSBackupSource{Path: container + "/" + run + "/" + name + "/etcdbackup", ABSSecret: secret}
+		}))
+	}
+	storagetest.TestStorage(t, NewABSBackupProvider(&backup.StorageConfig{KubeCli: kubeCli}), newCluster("a"), newCluster("b"))
+}
@@ -22,6 +22,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -35,7 +36,6 @@ import (
 
 	"tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
 	"tkestack.io/kstone/pkg/backup"
-	featureutil "tkestack.io/kstone/pkg/featureprovider/util"
 )
 
 const (
@@ -58,27 +58,117 @@ func NewCOSBackupProvider(config *backup.StorageConfig) backup.Storage {
 	}
 }
 
-func (c *StorageCOS) List(cluster *v1alpha2.EtcdCluster) (interface{}, error) {
+func (c *StorageCOS) List(cluster *v1alpha2.EtcdCluster) ([]backup.BackupObject, error) {
+	client, prefix, err := c.newClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	cosObjects, err := listObjects(client, prefix)
+	if err != nil {
+		klog.Errorf(err.Error())
+		return nil, err
+	}
+
+	objects := make([]backup.BackupObject, 0, len(cosObjects))
+	for _, object := range cosObjects {
+		if backup.IsEventSegment(object.Key) {
+			continue
+		}
+		lastModified, err := time.Parse("2006-01-02T15:04:05Z", object.LastModified)
+		if err != nil {
+			return nil, errors.New("can not parse COS time")
+		}
+		objects = append(objects, backup.NewBackupObject(cluster.Name, object.Key, object.Size, lastModified, object.ETag))
+	}
+	return objects, nil
+}
+
+func (c *StorageCOS) Delete(cluster *v1alpha2.EtcdCluster, key string) error {
+	client, prefix, err := c.newClient(cluster)
+	if err != nil {
+		return err
+	}
+	if err = backup.CheckBackupKey(key, prefix); err != nil {
+		return err
+	}
+
+	_, err = client.Object.Delete(context.Background(), key)
+	if err != nil {
+		klog.Errorf("failed to delete cos object %s, err is %v", key, err)
+		return err
+	}
+	return nil
+}
+
+func (c *StorageCOS) Put(cluster *v1alpha2.EtcdCluster, name string, data io.Reader) error {
+	client, prefix, err := c.newClient(cluster)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Object.Put(context.Background(), prefix+name, data, nil)
+	if err != nil {
+		klog.Errorf("failed to put cos object %s, err is %v", prefix+name, err)
+		return err
+	}
+	return nil
+}
+
+func (c *StorageCOS) ListEventSegments(cluster *v1alpha2.EtcdCluster) ([]string, error) {
+	client, prefix, err := c.newClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := listObjects(client, prefix+backup.EventSegmentPrefix)
+	if err != nil {
+		klog.Errorf("failed to list event segments, cluster %s, err is %v", cluster.Name, err)
+		return nil, err
+	}
+	keys := make([]string, 0, len(objects))
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	return keys, nil
+}
+
+func (c *StorageCOS) Get(cluster *v1alpha2.EtcdCluster, key string) (io.ReadCloser, error) {
+	client, prefix, err := c.newClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if err = backup.CheckBackupKey(key, prefix); err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Object.Get(context.Background(), key, nil)
+	if err != nil {
+		klog.Errorf("failed to get cos object %s, err is %v", key, err)
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// newClient generates the cos client and the backup file prefix of cluster
+func (c *StorageCOS) newClient(cluster *v1alpha2.EtcdCluster) (*tencentCOS.Client, string, error) {
 	// get backup config
 	backupConfig, err := backup.GetBackupConfig(cluster)
 	if err != nil {
 		klog.Errorf("failed to get backup config,cluster %s,err is %v", cluster.Name, err)
-		return nil, err
+		return nil, "", err
 	}
 	klog.V(3).Infof("backup config is %v", backupConfig)
 
 	secret, err := c.kubeCli.CoreV1().Secrets(cluster.Namespace).Get(context.TODO(), backupConfig.COS.COSSecret, v1.GetOptions{})
 	if err != nil {
 		klog.Errorf(err.Error())
-		return nil, err
+		return nil, "", err
 	}
 
 	secretID := string(secret.Data["secret-id"])
 	secretKey := string(secret.Data["secret-key"])
 
-	klog.Info(secretID)
-	klog.Info(secretKey)
-
 	cosPath := backupConfig.COS.Path
 	if !strings.Contains(cosPath, "https://") {
 		cosPath = fmt.Sprintf("https://%s", cosPath)
@@ -92,34 +182,24 @@ func (c *StorageCOS) List(cluster *v1alpha2.EtcdCluster) (interface{}, error) {
 			SecretKey: secretKey,
 		},
 	})
-
-	result, _, err := client.Bucket.Get(context.Background(), &tencentCOS.BucketGetOptions{
-		Prefix: strings.TrimLeft(b.BucketURL.Path, "/"),
-	})
-	if err != nil {
-		klog.Errorf(err.Error())
-		return nil, err
-	}
-
-	return result.Contents, nil
+	return client, strings.TrimLeft(b.BucketURL.Path, "/"), nil
 }
 
-func (c *StorageCOS) Stat(objects interface{}) (int, error) {
-	cosObjects, ok := objects.([]tencentCOS.Object)
-	if !ok {
-		return 0, errors.New("can not decode objects to COS objects")
+// listObjects lists all objects with the prefix in bucket
+func listObjects(client *tencentCOS.Client, prefix string) ([]tencentCOS.Object, error) {
+	objects := make([]tencentCOS.Object, 0)
+	opt := &tencentCOS.BucketGetOptions{
+		Prefix: prefix,
 	}
-	backupFiles := 0
-	for i := len(cosObjects) - 1; i >= 0; i-- {
-		lastModifiedTime, err := time.Parse("2006-01-02T15:04:05Z", cosObjects[i].LastModified)
+	for {
+		result, _, err := client.Bucket.Get(context.Background(), opt)
 		if err != nil {
-			return 0, errors.New("can not parse COS time")
+			return nil, err
 		}
-		timeElapse := int64(time.Since(lastModifiedTime).Seconds())
-		if timeElapse > featureutil.OneDaySeconds {
-			break
+		objects = append(objects, result.Contents...)
+		if !result.IsTruncated {
+			return objects, nil
 		}
-		backupFiles++
+		opt.Marker = result.NextMarker
 	}
-	return backupFiles, nil
 }
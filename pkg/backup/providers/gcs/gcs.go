@@ -0,0 +1,227 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package gcs
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+
+	"cloud.google.com/go/storage"
+	"github.com/coreos/etcd-operator/pkg/apis/etcd/v1beta2"
+	backuputil "github.com/coreos/etcd-operator/pkg/backup/util"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/backup"
+)
+
+const (
+	ProviderName = string(v1beta2.BackupStorageTypeGCS)
+)
+
+type StorageGCS struct {
+	kubeCli kubernetes.Interface
+}
+
+func init() {
+	backup.RegisterBackupStorageFactory(ProviderName, func(config *backup.StorageConfig) (backup.Storage, error) {
+		return NewGCSBackupProvider(config), nil
+	})
+}
+
+func NewGCSBackupProvider(config *backup.StorageConfig) backup.Storage {
+	return &StorageGCS{
+		kubeCli: config.KubeCli,
+	}
+}
+
+func (c *StorageGCS) List(cluster *v1alpha2.EtcdCluster) ([]backup.BackupObject, error) {
+	client, bucket, key, err := c.newClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	attrs, err := listObjects(client.Bucket(bucket), key)
+	if err != nil {
+		klog.Errorf("failed to list gcs objects, cluster %s, err is %v", cluster.Name, err)
+		return nil, err
+	}
+
+	objects := make([]backup.BackupObject, 0, len(attrs))
+	for _, object := range attrs {
+		if !backup.IsEventSegment(object.Name) {
+			objects = append(objects, backup.NewBackupObject(cluster.Name, object.Name, object.Size, object.Updated, hex.EncodeToString(object.MD5)))
+		}
+	}
+	return objects, nil
+}
+
+func (c *StorageGCS) Get(cluster *v1alpha2.EtcdCluster, key string) (io.ReadCloser, error) {
+	client, bucket, prefix, err := c.newClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if err = backup.CheckBackupKey(key, prefix); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	reader, err := client.Bucket(bucket).Object(key).NewReader(context.Background())
+	if err != nil {
+		client.Close()
+		klog.Errorf("failed to get gcs object %s, err is %v", key, err)
+		return nil, err
+	}
+	return &objectReader{Reader: reader, client: client}, nil
+}
+
+func (c *StorageGCS) Delete(cluster *v1alpha2.EtcdCluster, key string) error {
+	client, bucket, prefix, err := c.newClient(cluster)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if err = backup.CheckBackupKey(key, prefix); err != nil {
+		return err
+	}
+
+	err = client.Bucket(bucket).Object(key).Delete(context.Background())
+	if err != nil {
+		klog.Errorf("failed to delete gcs object %s, err is %v", key, err)
+		return err
+	}
+	return nil
+}
+
+func (c *StorageGCS) Put(cluster *v1alpha2.EtcdCluster, name string, data io.Reader) error {
+	client, bucket, key, err := c.newClient(cluster)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	key += name
+	w := client.Bucket(bucket).Object(key).NewWriter(context.Background())
+	if _, err = io.Copy(w, data); err != nil {
+		w.Close()
+		klog.Errorf("failed to put gcs object %s, err is %v", key, err)
+		return err
+	}
+	if err = w.Close(); err != nil {
+		klog.Errorf("failed to put gcs object %s, err is %v", key, err)
+		return err
+	}
+	return nil
+}
+
+func (c *StorageGCS) ListEventSegments(cluster *v1alpha2.EtcdCluster) ([]string, error) {
+	client, bucket, key, err := c.newClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	attrs, err := listObjects(client.Bucket(bucket), key+backup.EventSegmentPrefix)
+	if err != nil {
+		klog.Errorf("failed to list event segments, cluster %s, err is %v", cluster.Name, err)
+		return nil, err
+	}
+	keys := make([]string, 0, len(attrs))
+	for _, object := range attrs {
+		keys = append(keys, object.Name)
+	}
+	return keys, nil
+}
+
+// newClient generates the gcs client, the bucket and the key of backup path
+func (c *StorageGCS) newClient(cluster *v1alpha2.EtcdCluster) (*storage.Client, string, string, error) {
+	// get backup config
+	backupConfig, err := backup.GetBackupConfig(cluster)
+	if err != nil {
+		klog.Errorf("failed to get backup config,cluster %s,err is %v", cluster.Name, err)
+		return nil, "", "", err
+	}
+	if backupConfig.GCS == nil {
+		return nil, "", "", errors.New("gcs backup source is not set")
+	}
+
+	bucket, key, err := backuputil.ParseBucketAndKey(backupConfig.GCS.Path)
+	if err != nil {
+		klog.Errorf("failed to parse gcs path %s, err is %v", backupConfig.GCS.Path, err)
+		return nil, "", "", err
+	}
+
+	// the default application credentials are used if the secret is omitted
+	var options []option.ClientOption
+	if backupConfig.GCS.GCPSecret != "" {
+		secret, err := c.kubeCli.CoreV1().Secrets(cluster.Namespace).Get(context.TODO(), backupConfig.GCS.GCPSecret, v1.GetOptions{})
+		if err != nil {
+			klog.Errorf(err.Error())
+			return nil, "", "", err
+		}
+		if accessToken, ok := secret.Data[v1beta2.GCPAccessToken]; ok {
+			options = append(options, option.WithTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: string(accessToken)})))
+		} else if credentials, ok := secret.Data[v1beta2.GCPCredentialsJson]; ok {
+			options = append(options, option.WithCredentialsJSON(credentials))
+		}
+	}
+
+	client, err := storage.NewClient(context.Background(), options...)
+	if err != nil {
+		klog.Errorf("failed to create gcs client, err is %v", err)
+		return nil, "", "", err
+	}
+	return client, bucket, key, nil
+}
+
+// listObjects lists all objects with the prefix in bucket
+func listObjects(bucket *storage.BucketHandle, prefix string) ([]*storage.ObjectAttrs, error) {
+	objects := make([]*storage.ObjectAttrs, 0)
+	it := bucket.Objects(context.Background(), &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, attrs)
+	}
+}
+
+// objectReader closes the gcs client along with the object reader
+type objectReader struct {
+	*storage.Reader
+	client *storage.Client
+}
+
+func (r *objectReader) Close() error {
+	err := r.Reader.Close()
+	r.client.Close()
+	return err
+}
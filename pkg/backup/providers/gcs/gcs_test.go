@@ -0,0 +1,63 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package gcs
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/coreos/etcd-operator/pkg/apis/etcd/v1beta2"
+
+	"tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/backup"
+	"tkestack.io/kstone/pkg/backup/storagetest"
+)
+
+// TestStorageGCS runs against fake-gcs-server, e.g.
+//
+//	docker run -d -p 4443:4443 fsouza/fake-gcs-server -scheme http
+//	STORAGE_EMULATOR_HOST=localhost:4443 go test ./pkg/backup/providers/gcs/
+func TestStorageGCS(t *testing.T) {
+	if os.Getenv("STORAGE_EMULATOR_HOST") == "" {
+		t.Skip("STORAGE_EMULATOR_HOST is not set")
+	}
+
+	const bucket = "kstone-test"
+	client, err := storage.NewClient(context.Background())
+	if err != nil {
+		t.Fatalf("failed to create gcs client, err is %v", err)
+	}
+	defer client.Close()
+	if _, err = client.Bucket(bucket).Attrs(context.Background()); err == storage.ErrBucketNotExist {
+		err = client.Bucket(bucket).Create(context.Background(), "kstone", nil)
+	}
+	if err != nil {
+		t.Fatalf("failed to create bucket %s, err is %v", bucket, err)
+	}
+
+	run := storagetest.RunID()
+	newCluster := func(name string) *v1alpha2.EtcdCluster {
+		return storagetest.NewCluster(t, name, storagetest.NewConfig(v1beta2.BackupStorageTypeGCS, func(s *v1beta2.BackupSource) {
+			s.GCS = &v1beta2.GCSBackupSource{Path: bucket + "/" + run + "/" + name + "/etcdbackup"}
+		}))
+	}
+	storagetest.TestStorage(t, NewGCSBackupProvider(&backup.StorageConfig{}), newCluster("a"), newCluster("b"))
+}
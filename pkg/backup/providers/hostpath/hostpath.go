@@ -0,0 +1,218 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package hostpath
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/coreos/etcd-operator/pkg/apis/etcd/v1beta2"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/backup"
+)
+
+const (
+	ProviderName = string(v1beta2.BackupStorageTypeHostPath)
+
+	// HostPathEnv is the env of the backup directory, it must be the same as
+	// etcd-operator and the directory must be mounted into kstone.
+	HostPathEnv = "HOST_PATH_NAME"
+	// backupFilePrefix is the prefix of backup files written by etcd-operator.
+	backupFilePrefix = "etcdbackup"
+)
+
+type StorageHostPath struct {
+	kubeCli kubernetes.Interface
+}
+
+func init() {
+	backup.RegisterBackupStorageFactory(ProviderName, func(config *backup.StorageConfig) (backup.Storage, error) {
+		return NewHostPathBackupProvider(config), nil
+	})
+}
+
+func NewHostPathBackupProvider(config *backup.StorageConfig) backup.Storage {
+	return &StorageHostPath{
+		kubeCli: config.KubeCli,
+	}
+}
+
+func (c *StorageHostPath) List(cluster *v1alpha2.EtcdCluster) ([]backup.BackupObject, error) {
+	prefix, legacy, err := backupPrefix(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := listBackupFiles(cluster, prefix)
+	if err != nil {
+		return nil, err
+	}
+	// fall back to the backup files saved by the previous versions before
+	// the first backup file is written to the directory of the cluster
+	if len(objects) == 0 && legacy != "" {
+		return listBackupFiles(cluster, legacy)
+	}
+	return objects, nil
+}
+
+func (c *StorageHostPath) Get(cluster *v1alpha2.EtcdCluster, key string) (io.ReadCloser, error) {
+	prefix, legacy, err := backupPrefix(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if !hasPrefix(key, prefix) && !(legacy != "" && hasPrefix(key, legacy)) {
+		return nil, fmt.Errorf("%s is not a backup file", key)
+	}
+
+	f, err := os.Open(key)
+	if err != nil {
+		klog.Errorf("failed to open backup file %s, err is %v", key, err)
+		return nil, err
+	}
+	return f, nil
+}
+
+func (c *StorageHostPath) Delete(cluster *v1alpha2.EtcdCluster, key string) error {
+	prefix, legacy, err := backupPrefix(cluster)
+	if err != nil {
+		return err
+	}
+	if legacy != "" && hasPrefix(key, legacy) {
+		// the legacy files may belong to any cluster, they are never deleted by kstone
+		return fmt.Errorf("%s is shared by all clusters, it must be deleted manually", key)
+	}
+	if !hasPrefix(key, prefix) {
+		return fmt.Errorf("%s is not a backup file", key)
+	}
+
+	err = os.Remove(key)
+	if err != nil {
+		klog.Errorf("failed to delete backup file %s, err is %v", key, err)
+		return err
+	}
+	return nil
+}
+
+func (c *StorageHostPath) Put(cluster *v1alpha2.EtcdCluster, name string, data io.Reader) error {
+	prefix, _, err := backupPrefix(cluster)
+	if err != nil {
+		return err
+	}
+
+	key := prefix + name
+	if err = os.MkdirAll(filepath.Dir(key), 0755); err != nil {
+		klog.Errorf("failed to create directory of %s, err is %v", key, err)
+		return err
+	}
+	// write to a temporary file first, readers never see a partial file
+	tmp := key + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		klog.Errorf("failed to create %s, err is %v", tmp, err)
+		return err
+	}
+	_, err = io.Copy(f, data)
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, key)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		klog.Errorf("failed to write %s, err is %v", key, err)
+		return err
+	}
+	return nil
+}
+
+func (c *StorageHostPath) ListEventSegments(cluster *v1alpha2.EtcdCluster) ([]string, error) {
+	prefix, _, err := backupPrefix(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := prefix + backup.EventSegmentPrefix
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		klog.Errorf("failed to list event segments, cluster %s, err is %v", cluster.Name, err)
+		return nil, err
+	}
+	keys := make([]string, 0, len(files))
+	for _, file := range files {
+		if !file.IsDir() {
+			keys = append(keys, dir+file.Name())
+		}
+	}
+	return keys, nil
+}
+
+// listBackupFiles lists the backup files whose path starts with prefix.
+func listBackupFiles(cluster *v1alpha2.EtcdCluster, prefix string) ([]backup.BackupObject, error) {
+	files, err := ioutil.ReadDir(filepath.Dir(prefix))
+	if os.IsNotExist(err) {
+		return []backup.BackupObject{}, nil
+	}
+	if err != nil {
+		klog.Errorf("failed to list backup files, cluster %s, err is %v", cluster.Name, err)
+		return nil, err
+	}
+
+	objects := make([]backup.BackupObject, 0, len(files))
+	for _, file := range files {
+		key := filepath.Join(filepath.Dir(prefix), file.Name())
+		if file.IsDir() || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		objects = append(objects, backup.NewBackupObject(cluster.Name, key, file.Size(), file.ModTime(), ""))
+	}
+	return objects, nil
+}
+
+func hasPrefix(key, prefix string) bool {
+	return strings.HasPrefix(filepath.Clean(key), prefix)
+}
+
+// backupPrefix returns the path prefix of the backup files of the cluster,
+// etcd-operator writes them as ${HOST_PATH_NAME}<dir>etcdbackup_v<revision>_<time>[_<version>],
+// where dir is backup.HostPathDir. The legacy prefix is ${HOST_PATH_NAME}etcdbackup,
+// where the previous versions saved the backup files of all clusters, it is
+// empty if it is the same as the prefix.
+func backupPrefix(cluster *v1alpha2.EtcdCluster) (string, string, error) {
+	hostPath := os.Getenv(HostPathEnv)
+	if hostPath == "" {
+		return "", "", fmt.Errorf("%s env must be set", HostPathEnv)
+	}
+	prefix := filepath.Clean(hostPath + backup.HostPathDir(cluster) + backupFilePrefix)
+	legacy := filepath.Clean(hostPath + backupFilePrefix)
+	if prefix == legacy {
+		return prefix, "", nil
+	}
+	return prefix, legacy, nil
+}
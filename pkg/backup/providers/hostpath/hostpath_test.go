@@ -0,0 +1,248 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package hostpath
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/apis/etcd/v1beta2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/backup"
+	"tkestack.io/kstone/pkg/backup/storagetest"
+)
+
+const testBackupName = "etcdbackup_v10_2023-01-02-15:04:05"
+
+func newTestCluster(namespace, name string) *v1alpha2.EtcdCluster {
+	return &v1alpha2.EtcdCluster{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+}
+
+// setupHostPath sets HOST_PATH_NAME to a temporary directory and writes a
+// periodic backup file for each cluster as etcd-operator does.
+func setupHostPath(t *testing.T, clusters ...*v1alpha2.EtcdCluster) (string, func()) {
+	root := t.TempDir() + "/"
+	old, found := os.LookupEnv(HostPathEnv)
+	os.Setenv(HostPathEnv, root)
+	for _, cluster := range clusters {
+		dir := root + backup.HostPathDir(cluster)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(dir+testBackupName, []byte(cluster.Name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root, func() {
+		if found {
+			os.Setenv(HostPathEnv, old)
+		} else {
+			os.Unsetenv(HostPathEnv)
+		}
+	}
+}
+
+func TestListIsolatesClusters(t *testing.T) {
+	a, b := newTestCluster("kstone", "a"), newTestCluster("kstone", "ab")
+	root, cleanup := setupHostPath(t, a, b)
+	defer cleanup()
+	storage := NewHostPathBackupProvider(&backup.StorageConfig{})
+
+	// an event segment is a directory under the backup dir, it is not listed
+	if err := storage.Put(a, backup.EventSegmentName(11, 20), strings.NewReader("events")); err != nil {
+		t.Fatalf("failed to put event segment, err is %v", err)
+	}
+
+	objects, err := storage.List(a)
+	if err != nil {
+		t.Fatalf("failed to list, err is %v", err)
+	}
+	want := []string{root + "kstone/a/" + testBackupName}
+	if keys := backup.BackupKeys(objects); !reflect.DeepEqual(keys, want) {
+		t.Errorf("List() = %v, want %v", keys, want)
+	}
+	if objects[0].Revision != 10 {
+		t.Errorf("Revision = %d, want 10", objects[0].Revision)
+	}
+
+	objects, err = storage.List(newTestCluster("other", "a"))
+	if err != nil || len(objects) != 0 {
+		t.Errorf("List() of a cluster without backups = %v, %v, want empty", objects, err)
+	}
+}
+
+func TestGetDeleteRejectOtherClusters(t *testing.T) {
+	a, b := newTestCluster("kstone", "a"), newTestCluster("kstone", "b")
+	root, cleanup := setupHostPath(t, a, b)
+	defer cleanup()
+	storage := NewHostPathBackupProvider(&backup.StorageConfig{})
+	keyA := root + "kstone/a/" + testBackupName
+	keyB := root + "kstone/b/" + testBackupName
+
+	for _, key := range []string{keyB, root + "kstone/a/../b/" + testBackupName, root + "kstone/a/other"} {
+		if _, err := storage.Get(a, key); err == nil {
+			t.Errorf("Get(%s) succeeded, want error", key)
+		}
+		if err := storage.Delete(a, key); err == nil {
+			t.Errorf("Delete(%s) succeeded, want error", key)
+		}
+	}
+	if _, err := os.Stat(keyB); err != nil {
+		t.Errorf("backup file of the other cluster is deleted, err is %v", err)
+	}
+
+	r, err := storage.Get(a, keyA)
+	if err != nil {
+		t.Fatalf("failed to get %s, err is %v", keyA, err)
+	}
+	data, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || string(data) != "a" {
+		t.Errorf("Get() = %q, %v, want %q", data, err, "a")
+	}
+
+	if err = storage.Delete(a, keyA); err != nil {
+		t.Fatalf("failed to delete %s, err is %v", keyA, err)
+	}
+	if _, err = os.Stat(keyA); !os.IsNotExist(err) {
+		t.Errorf("%s is not deleted, err is %v", keyA, err)
+	}
+}
+
+func TestPutAndListEventSegments(t *testing.T) {
+	a, b := newTestCluster("kstone", "a"), newTestCluster("kstone", "b")
+	root, cleanup := setupHostPath(t)
+	defer cleanup()
+	storage := NewHostPathBackupProvider(&backup.StorageConfig{})
+
+	names := []string{backup.EventSegmentName(1, 10), backup.EventSegmentName(11, 20)}
+	for _, name := range names {
+		if err := storage.Put(a, name, strings.NewReader(name)); err != nil {
+			t.Fatalf("failed to put %s, err is %v", name, err)
+		}
+	}
+
+	keys, err := storage.ListEventSegments(a)
+	if err != nil {
+		t.Fatalf("failed to list event segments, err is %v", err)
+	}
+	want := []string{root + "kstone/a/etcdbackup" + names[0], root + "kstone/a/etcdbackup" + names[1]}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("ListEventSegments() = %v, want %v", keys, want)
+	}
+	// no temporary files are left
+	files, _ := filepath.Glob(root + "kstone/a/etcdbackup_events/*.tmp")
+	if len(files) != 0 {
+		t.Errorf("temporary files %v are left", files)
+	}
+
+	keys, err = storage.ListEventSegments(b)
+	if err != nil || len(keys) != 0 {
+		t.Errorf("ListEventSegments() of the other cluster = %v, %v, want empty", keys, err)
+	}
+}
+
+func TestHostPathEnvRequired(t *testing.T) {
+	_, cleanup := setupHostPath(t)
+	defer cleanup()
+	os.Unsetenv(HostPathEnv)
+	storage := NewHostPathBackupProvider(&backup.StorageConfig{})
+
+	if _, err := storage.List(newTestCluster("kstone", "a")); err == nil {
+		t.Error("List() succeeded without HOST_PATH_NAME, want error")
+	}
+}
+
+func TestStorageHostPath(t *testing.T) {
+	_, cleanup := setupHostPath(t)
+	defer cleanup()
+
+	cfg := storagetest.NewConfig(v1beta2.BackupStorageTypeHostPath, func(s *v1beta2.BackupSource) {})
+	a, b := storagetest.NewCluster(t, "a", cfg), storagetest.NewCluster(t, "b", cfg)
+	storagetest.TestStorage(t, NewHostPathBackupProvider(&backup.StorageConfig{}), a, b)
+}
+
+func TestConfiguredPath(t *testing.T) {
+	a := newTestCluster("kstone", "a")
+	a.Annotations = map[string]string{
+		backup.AnnoBackupConfig: `{"storageType":"HostPath","hostPath":{"path":"backups/a/etcd"}}`,
+	}
+	root, cleanup := setupHostPath(t, a)
+	defer cleanup()
+	storage := NewHostPathBackupProvider(&backup.StorageConfig{})
+
+	if dir := backup.HostPathDir(a); dir != "backups/a/" {
+		t.Errorf("HostPathDir() = %s, want backups/a/", dir)
+	}
+	objects, err := storage.List(a)
+	if err != nil {
+		t.Fatalf("failed to list, err is %v", err)
+	}
+	want := []string{root + "backups/a/" + testBackupName}
+	if keys := backup.BackupKeys(objects); !reflect.DeepEqual(keys, want) {
+		t.Errorf("List() = %v, want %v", keys, want)
+	}
+}
+
+func TestLegacyLayout(t *testing.T) {
+	a, b := newTestCluster("kstone", "a"), newTestCluster("kstone", "b")
+	root, cleanup := setupHostPath(t, b)
+	defer cleanup()
+	storage := NewHostPathBackupProvider(&backup.StorageConfig{})
+	// the previous versions saved the backup files of all clusters directly under HOST_PATH_NAME
+	legacyKey := root + testBackupName
+	if err := ioutil.WriteFile(legacyKey, []byte("legacy"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	objects, err := storage.List(a)
+	if err != nil {
+		t.Fatalf("failed to list, err is %v", err)
+	}
+	if keys := backup.BackupKeys(objects); !reflect.DeepEqual(keys, []string{legacyKey}) {
+		t.Errorf("List() = %v, want %v", keys, []string{legacyKey})
+	}
+	r, err := storage.Get(a, legacyKey)
+	if err != nil {
+		t.Fatalf("failed to get %s, err is %v", legacyKey, err)
+	}
+	r.Close()
+	if err = storage.Delete(a, legacyKey); err == nil {
+		t.Errorf("Delete(%s) succeeded, want error", legacyKey)
+	}
+	if _, err = os.Stat(legacyKey); err != nil {
+		t.Errorf("legacy backup file is deleted, err is %v", err)
+	}
+
+	// the legacy files are not listed once the cluster has its own backup files
+	objects, err = storage.List(b)
+	if err != nil {
+		t.Fatalf("failed to list, err is %v", err)
+	}
+	want := []string{root + "kstone/b/" + testBackupName}
+	if keys := backup.BackupKeys(objects); !reflect.DeepEqual(keys, want) {
+		t.Errorf("List() = %v, want %v", keys, want)
+	}
+}
@@ -0,0 +1,214 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package oss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	aliyunOSS "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/coreos/etcd-operator/pkg/apis/etcd/v1beta2"
+	backuputil "github.com/coreos/etcd-operator/pkg/backup/util"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/backup"
+)
+
+const (
+	ProviderName = string(v1beta2.BackupStorageTypeOSS)
+
+	// DefaultEndpoint is the same as the default endpoint of etcd-operator
+	DefaultEndpoint = "http://oss-cn-hangzhou.aliyuncs.com"
+)
+
+type StorageOSS struct {
+	kubeCli kubernetes.Interface
+}
+
+func init() {
+	backup.RegisterBackupStorageFactory(ProviderName, func(config *backup.StorageConfig) (backup.Storage, error) {
+		return NewOSSBackupProvider(config), nil
+	})
+}
+
+func NewOSSBackupProvider(config *backup.StorageConfig) backup.Storage {
+	return &StorageOSS{
+		kubeCli: config.KubeCli,
+	}
+}
+
+func (c *StorageOSS) List(cluster *v1alpha2.EtcdCluster) ([]backup.BackupObject, error) {
+	bucket, key, err := c.newBucket(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := listObjects(bucket, key)
+	if err != nil {
+		klog.Errorf("failed to list oss objects, cluster %s, err is %v", cluster.Name, err)
+		return nil, err
+	}
+
+	objects := make([]backup.BackupObject, 0, len(result))
+	for _, object := range result {
+		if !backup.IsEventSegment(object.Key) {
+			objects = append(objects, backup.NewBackupObject(cluster.Name, object.Key, object.Size, object.LastModified, object.ETag))
+		}
+	}
+	return objects, nil
+}
+
+func (c *StorageOSS) Get(cluster *v1alpha2.EtcdCluster, key string) (io.ReadCloser, error) {
+	bucket, prefix, err := c.newBucket(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if err = backup.CheckBackupKey(key, prefix); err != nil {
+		return nil, err
+	}
+
+	rc, err := bucket.GetObject(key)
+	if err != nil {
+		klog.Errorf("failed to get oss object %s, err is %v", key, err)
+		return nil, err
+	}
+	return rc, nil
+}
+
+func (c *StorageOSS) Delete(cluster *v1alpha2.EtcdCluster, key string) error {
+	bucket, prefix, err := c.newBucket(cluster)
+	if err != nil {
+		return err
+	}
+	if err = backup.CheckBackupKey(key, prefix); err != nil {
+		return err
+	}
+
+	err = bucket.DeleteObject(key)
+	if err != nil {
+		klog.Errorf("failed to delete oss object %s, err is %v", key, err)
+		return err
+	}
+	return nil
+}
+
+func (c *StorageOSS) Put(cluster *v1alpha2.EtcdCluster, name string, data io.Reader) error {
+	bucket, key, err := c.newBucket(cluster)
+	if err != nil {
+		return err
+	}
+
+	key += name
+	err = bucket.PutObject(key, data)
+	if err != nil {
+		klog.Errorf("failed to put oss object %s, err is %v", key, err)
+		return err
+	}
+	return nil
+}
+
+func (c *StorageOSS) ListEventSegments(cluster *v1alpha2.EtcdCluster) ([]string, error) {
+	bucket, key, err := c.newBucket(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := listObjects(bucket, key+backup.EventSegmentPrefix)
+	if err != nil {
+		klog.Errorf("failed to list event segments, cluster %s, err is %v", cluster.Name, err)
+		return nil, err
+	}
+	keys := make([]string, 0, len(objects))
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	return keys, nil
+}
+
+// newBucket generates the oss bucket and the key of backup path
+func (c *StorageOSS) newBucket(cluster *v1alpha2.EtcdCluster) (*aliyunOSS.Bucket, string, error) {
+	// get backup config
+	backupConfig, err := backup.GetBackupConfig(cluster)
+	if err != nil {
+		klog.Errorf("failed to get backup config,cluster %s,err is %v", cluster.Name, err)
+		return nil, "", err
+	}
+	if backupConfig.OSS == nil {
+		return nil, "", errors.New("oss backup source is not set")
+	}
+
+	bucketName, key, err := backuputil.ParseBucketAndKey(backupConfig.OSS.Path)
+	if err != nil {
+		klog.Errorf("failed to parse oss path %s, err is %v", backupConfig.OSS.Path, err)
+		return nil, "", err
+	}
+
+	secret, err := c.kubeCli.CoreV1().Secrets(cluster.Namespace).Get(context.TODO(), backupConfig.OSS.OSSSecret, v1.GetOptions{})
+	if err != nil {
+		klog.Errorf(err.Error())
+		return nil, "", err
+	}
+	accessKeyID, ok := secret.Data[v1beta2.AlibabaCloudSecretCredentialsAccessKeyID]
+	if !ok {
+		return nil, "", fmt.Errorf("%s not found in secret %s", v1beta2.AlibabaCloudSecretCredentialsAccessKeyID, secret.Name)
+	}
+	accessKeySecret, ok := secret.Data[v1beta2.AlibabaCloudSecretCredentialsAccessKeySecret]
+	if !ok {
+		return nil, "", fmt.Errorf("%s not found in secret %s", v1beta2.AlibabaCloudSecretCredentialsAccessKeySecret, secret.Name)
+	}
+
+	endpoint := backupConfig.OSS.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	client, err := aliyunOSS.New(endpoint, string(accessKeyID), string(accessKeySecret))
+	if err != nil {
+		klog.Errorf("failed to create oss client, err is %v", err)
+		return nil, "", err
+	}
+
+	bucket, err := client.Bucket(bucketName)
+	if err != nil {
+		klog.Errorf("failed to get oss bucket %s, err is %v", bucketName, err)
+		return nil, "", err
+	}
+	return bucket, key, nil
+}
+
+// listObjects lists all objects with the prefix in bucket
+func listObjects(bucket *aliyunOSS.Bucket, prefix string) ([]aliyunOSS.ObjectProperties, error) {
+	objects := make([]aliyunOSS.ObjectProperties, 0)
+	marker := ""
+	for {
+		result, err := bucket.ListObjects(aliyunOSS.Prefix(prefix), aliyunOSS.Marker(marker))
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, result.Objects...)
+		if !result.IsTruncated {
+			return objects, nil
+		}
+		marker = result.NextMarker
+	}
+}
@@ -0,0 +1,59 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package oss
+
+import (
+	"os"
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/apis/etcd/v1beta2"
+
+	"tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/backup"
+	"tkestack.io/kstone/pkg/backup/storagetest"
+)
+
+// TestStorageOSS runs against the bucket OSS_TEST_BUCKET of an OSS compatible
+// endpoint, there is no official emulator of OSS, e.g.
+//
+//	OSS_TEST_ENDPOINT=oss-cn-hangzhou.aliyuncs.com OSS_TEST_BUCKET=kstone-test \
+//	OSS_TEST_ACCESS_KEY_ID=... OSS_TEST_ACCESS_KEY_SECRET=... go test ./pkg/backup/providers/oss/
+func TestStorageOSS(t *testing.T) {
+	bucket := os.Getenv("OSS_TEST_BUCKET")
+	if bucket == "" {
+		t.Skip("OSS_TEST_BUCKET is not set")
+	}
+
+	const secret = "oss-secret"
+	kubeCli := storagetest.NewSecretClient(secret, map[string]string{
+		v1beta2.AlibabaCloudSecretCredentialsAccessKeyID:     os.Getenv("OSS_TEST_ACCESS_KEY_ID"),
+		v1beta2.AlibabaCloudSecretCredentialsAccessKeySecret: os.Getenv("OSS_TEST_ACCESS_KEY_SECRET"),
+	})
+	run := storagetest.RunID()
+	newCluster := func(name string) *v1alpha2.EtcdCluster {
+		return storagetest.NewCluster(t, name, storagetest.NewConfig(v1beta2.BackupStorageTypeOSS, func(s *v1beta2.BackupSource) {
+			s.OSS = &v1beta2.OSSBackupSource{
+				Path:      bucket + "/" + run + "/" + name + "/etcdbackup",
+				OSSSecret: secret,
+				Endpoint:  os.Getenv("OSS_TEST_ENDPOINT"),
+			}
+		}))
+	}
+	storagetest.TestStorage(t, NewOSSBackupProvider(&backup.StorageConfig{KubeCli: kubeCli}), newCluster("a"), newCluster("b"))
+}
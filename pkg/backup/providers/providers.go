@@ -19,8 +19,16 @@
 package providers
 
 import (
+	// import abs provider
+	_ "tkestack.io/kstone/pkg/backup/providers/abs"
 	// import cos provider
 	_ "tkestack.io/kstone/pkg/backup/providers/cos"
+	// import gcs provider
+	_ "tkestack.io/kstone/pkg/backup/providers/gcs"
+	// import hostpath provider
+	_ "tkestack.io/kstone/pkg/backup/providers/hostpath"
+	// import oss provider
+	_ "tkestack.io/kstone/pkg/backup/providers/oss"
 	// import s3 provider
 	_ "tkestack.io/kstone/pkg/backup/providers/s3"
 )
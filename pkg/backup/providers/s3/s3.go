@@ -19,21 +19,18 @@
 package s3
 
 import (
-	"context"
-	"errors"
-	"fmt"
-	"strings"
-	"time"
+	"io"
 
+	"github.com/aws/aws-sdk-go/aws"
 	awsS3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/coreos/etcd-operator/pkg/apis/etcd/v1beta2"
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	backuputil "github.com/coreos/etcd-operator/pkg/backup/util"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 
 	"tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
 	"tkestack.io/kstone/pkg/backup"
-	featureutil "tkestack.io/kstone/pkg/featureprovider/util"
 )
 
 const (
@@ -56,68 +53,148 @@ func NewS3BackupProvider(config *backup.StorageConfig) backup.Storage {
 	}
 }
 
-func (c *StorageS3) List(cluster *v1alpha2.EtcdCluster) (interface{}, error) {
-	// get backup config
-	backupConfig, err := backup.GetBackupConfig(cluster)
+func (c *StorageS3) List(cluster *v1alpha2.EtcdCluster) ([]backup.BackupObject, error) {
+	cli, bucket, key, err := c.newClient(cluster)
 	if err != nil {
-		klog.Errorf("failed to get backup config,cluster %s,err is %v", cluster.Name, err)
 		return nil, err
 	}
-	klog.V(3).Infof("backup config is %v", backupConfig)
+	defer cli.Close()
 
-	secret, err := c.kubeCli.CoreV1().Secrets(cluster.Namespace).Get(context.TODO(), backupConfig.S3.AWSSecret, v1.GetOptions{})
+	s3Objects, err := listObjects(cli, bucket, key)
 	if err != nil {
-		klog.Errorf(err.Error())
+		klog.Errorf("failed to list objects, error: %s", err.Error())
 		return nil, err
 	}
 
-	bucket := strings.Split(backupConfig.S3.Path, "/")
-	if len(bucket) < 1 {
-		err = fmt.Errorf("s3.path=%s, not valid, bucket not set", backupConfig.S3.Path)
-		klog.Errorf(err.Error())
+	objects := make([]backup.BackupObject, 0, len(s3Objects))
+	for _, object := range s3Objects {
+		if backup.IsEventSegment(*object.Key) {
+			continue
+		}
+		objects = append(objects, backup.NewBackupObject(
+			cluster.Name,
+			aws.StringValue(object.Key),
+			aws.Int64Value(object.Size),
+			aws.TimeValue(object.LastModified),
+			aws.StringValue(object.ETag),
+		))
+	}
+	return objects, nil
+}
+
+func (c *StorageS3) Get(cluster *v1alpha2.EtcdCluster, key string) (io.ReadCloser, error) {
+	cli, bucket, prefix, err := c.newClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+	if err = backup.CheckBackupKey(key, prefix); err != nil {
 		return nil, err
 	}
 
-	cli, err := NewClientFromSecret(c.kubeCli, cluster.Namespace, backupConfig.S3.Endpoint, backupConfig.S3.AWSSecret, backupConfig.S3.ForcePathStyle)
+	resp, err := cli.S3.GetObject(&awsS3.GetObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
 	if err != nil {
-		klog.Errorf(err.Error())
+		klog.Errorf("failed to get s3 object %s, err is %v", key, err)
 		return nil, err
 	}
+	return resp.Body, nil
+}
+
+func (c *StorageS3) Delete(cluster *v1alpha2.EtcdCluster, key string) error {
+	cli, bucket, prefix, err := c.newClient(cluster)
+	if err != nil {
+		return err
+	}
 	defer cli.Close()
+	if err = backup.CheckBackupKey(key, prefix); err != nil {
+		return err
+	}
 
-	klog.Info(fmt.Sprintf("secret name: %s, data: %v", secret.Name, secret.Data))
+	_, err = cli.S3.DeleteObject(&awsS3.DeleteObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		klog.Errorf("failed to delete s3 object %s, err is %v", key, err)
+		return err
+	}
+	return nil
+}
 
-	i := 0
-	var resultObjs []*awsS3.Object
-	err = cli.S3.ListObjectsPages(&awsS3.ListObjectsInput{
-		Bucket: &bucket[0],
-	}, func(p *awsS3.ListObjectsOutput, last bool) (shouldContinue bool) {
-		i++
-		resultObjs = append(resultObjs, p.Contents...)
-		return true
+func (c *StorageS3) Put(cluster *v1alpha2.EtcdCluster, name string, data io.Reader) error {
+	cli, bucket, key, err := c.newClient(cluster)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	key += name
+	_, err = s3manager.NewUploaderWithClient(cli.S3).Upload(&s3manager.UploadInput{
+		Bucket: &bucket,
+		Key:    &key,
+		Body:   data,
 	})
 	if err != nil {
-		klog.Errorf("failed to list objects, error: %s", err.Error())
+		klog.Errorf("failed to put s3 object %s, err is %v", key, err)
+		return err
+	}
+	return nil
+}
+
+func (c *StorageS3) ListEventSegments(cluster *v1alpha2.EtcdCluster) ([]string, error) {
+	cli, bucket, key, err := c.newClient(cluster)
+	if err != nil {
 		return nil, err
 	}
-	klog.Infof("Total Page %d", i)
+	defer cli.Close()
 
-	return resultObjs, nil
+	objects, err := listObjects(cli, bucket, key+backup.EventSegmentPrefix)
+	if err != nil {
+		klog.Errorf("failed to list event segments, cluster %s, err is %v", cluster.Name, err)
+		return nil, err
+	}
+	keys := make([]string, 0, len(objects))
+	for _, object := range objects {
+		keys = append(keys, *object.Key)
+	}
+	return keys, nil
 }
 
-func (c *StorageS3) Stat(objects interface{}) (int, error) {
-	s3Objects, ok := objects.([]*awsS3.Object)
-	if !ok {
-		return 0, errors.New("can not decode objects to S3 objects")
-	}
-	backupFiles := 0
-	for i := len(s3Objects) - 1; i >= 0; i-- {
-		lastModifiedTime := *s3Objects[i].LastModified
-		timeElapse := int64(time.Since(lastModifiedTime).Seconds())
-		if timeElapse > featureutil.OneDaySeconds {
-			break
-		}
-		backupFiles++
+// newClient generates the s3 client, the bucket and the key of backup path
+func (c *StorageS3) newClient(cluster *v1alpha2.EtcdCluster) (*ClientS3Wrapper, string, string, error) {
+	// get backup config
+	backupConfig, err := backup.GetBackupConfig(cluster)
+	if err != nil {
+		klog.Errorf("failed to get backup config,cluster %s,err is %v", cluster.Name, err)
+		return nil, "", "", err
+	}
+
+	bucket, key, err := backuputil.ParseBucketAndKey(backupConfig.S3.Path)
+	if err != nil {
+		klog.Errorf("failed to parse s3 path %s, err is %v", backupConfig.S3.Path, err)
+		return nil, "", "", err
 	}
-	return backupFiles, nil
+
+	cli, err := NewClientFromSecret(c.kubeCli, cluster.Namespace, backupConfig.S3.Endpoint, backupConfig.S3.AWSSecret, backupConfig.S3.ForcePathStyle)
+	if err != nil {
+		klog.Errorf(err.Error())
+		return nil, "", "", err
+	}
+	return cli, bucket, key, nil
+}
+
+// listObjects lists all objects with the prefix in bucket
+func listObjects(cli *ClientS3Wrapper, bucket, prefix string) ([]*awsS3.Object, error) {
+	objects := make([]*awsS3.Object, 0)
+	err := cli.S3.ListObjectsPages(&awsS3.ListObjectsInput{
+		Bucket: &bucket,
+		Prefix: &prefix,
+	}, func(p *awsS3.ListObjectsOutput, last bool) (shouldContinue bool) {
+		objects = append(objects, p.Contents...)
+		return true
+	})
+	return objects, err
 }
@@ -0,0 +1,571 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+)
+
+const (
+	AnnoRestoreConfig = "restore"
+	// AnnoRestoreRecorded is set once the result of the EtcdRestore is recorded on the target cluster.
+	AnnoRestoreRecorded = "kstone.tkestack.io/restore-recorded"
+
+	// restoreTxnOps is the number of put operations in a transaction,
+	// it must not exceed the --max-txn-ops of etcd, which defaults to 128.
+	restoreTxnOps = 128
+	// restoreTxnBytes keeps a transaction below the --max-request-bytes of etcd.
+	restoreTxnBytes = 1024 * 1024
+	// restorePageKeys is the number of the keys read from the ephemeral member at a time.
+	restorePageKeys   = 1000
+	restoreTxnTimeout = 30 * time.Second
+)
+
+// ErrTargetNotEmpty is returned if the target cluster of a restore has keys.
+var ErrTargetNotEmpty = errors.New("target cluster is not empty")
+
+// RestoreResult is the result of a finished restore.
+type RestoreResult struct {
+	BackupKey string `json:"backupKey"`
+	// Revision is the revision of the source cluster restored.
+	Revision int64 `json:"revision"`
+	Keys     int64 `json:"keys"`
+	Leases   int64 `json:"leases"`
+}
+
+// GetRestoreConfig gets the restore request of cluster from the restore
+// annotation, it returns nil if no restore is requested. The cluster is
+// the target of the restore, and the source cluster defaults to it.
+func GetRestoreConfig(cluster *kstonev1alpha2.EtcdCluster) (*kstonev1alpha2.EtcdRestoreSpec, error) {
+	cfg, found := cluster.Annotations[AnnoRestoreConfig]
+	if !found || cfg == "" {
+		return nil, nil
+	}
+
+	spec := &kstonev1alpha2.EtcdRestoreSpec{}
+	err := json.Unmarshal([]byte(cfg), spec)
+	if err != nil {
+		klog.Errorf("failed to parse restore config, cluster %s, err is %v", cluster.Name, err)
+		return nil, err
+	}
+	if spec.SourceCluster == "" {
+		spec.SourceCluster = cluster.Name
+	}
+	spec.TargetCluster = cluster.Name
+	if err = ValidateRestoreSpec(spec); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// RestoreTarget returns the name of the target cluster of the EtcdRestore.
+func RestoreTarget(restore *kstonev1alpha2.EtcdRestore) string {
+	if restore.Spec.TargetCluster != "" {
+		return restore.Spec.TargetCluster
+	}
+	return restore.Spec.SourceCluster
+}
+
+// IsRestoreFinished checks whether the EtcdRestore succeeded or failed.
+func IsRestoreFinished(restore *kstonev1alpha2.EtcdRestore) bool {
+	return restore.Status.Phase == kstonev1alpha2.EtcdRestoreSucceeded ||
+		restore.Status.Phase == kstonev1alpha2.EtcdRestoreFailed
+}
+
+// IsRestoreRecorded checks whether the result of the EtcdRestore is recorded on the target cluster.
+func IsRestoreRecorded(restore *kstonev1alpha2.EtcdRestore) bool {
+	_, found := restore.Annotations[AnnoRestoreRecorded]
+	return found
+}
+
+// ValidateRestoreSpec checks the restore request.
+func ValidateRestoreSpec(spec *kstonev1alpha2.EtcdRestoreSpec) error {
+	if spec.SourceCluster == "" {
+		return errors.New("sourceCluster of restore is empty")
+	}
+	if spec.TargetRevision < 0 {
+		return errors.New("targetRevision of restore must not be negative")
+	}
+	if spec.BackupKey == "" && !isPointInTime(spec) {
+		return errors.New("backupKey of restore is empty")
+	}
+	target := spec.TargetCluster
+	if target == "" {
+		target = spec.SourceCluster
+	}
+	if target == spec.SourceCluster && !spec.Overwrite {
+		return fmt.Errorf("restoring cluster %s in place removes all its keys, set overwrite to confirm", target)
+	}
+	return nil
+}
+
+// isPointInTime checks whether the persisted events are replayed.
+func isPointInTime(spec *kstonev1alpha2.EtcdRestoreSpec) bool {
+	return spec.TargetRevision > 0 || spec.TargetTime != nil
+}
+
+// beyond checks whether the event happened after the restore point.
+func beyond(spec *kstonev1alpha2.EtcdRestoreSpec, ev *Event) bool {
+	if spec.TargetRevision > 0 && ev.Revision > spec.TargetRevision {
+		return true
+	}
+	return spec.TargetTime != nil && ev.Time.After(spec.TargetTime.Time)
+}
+
+// DownloadSnapshot downloads the backup file of source cluster to a
+// temporary file and verifies its checksum and bbolt pages, the caller is
+// responsible for removing the returned file. It also returns whether the
+// backup file carries a checksum.
+func DownloadSnapshot(storage Storage, source *kstonev1alpha2.EtcdCluster, key string) (string, bool, error) {
+	rc, err := storage.Get(source, key)
+	if err != nil {
+		klog.Errorf("failed to download backup file %s, cluster %s, err is %v", key, source.Name, err)
+		return "", false, err
+	}
+	defer rc.Close()
+
+	path, err := SaveSnapshotFile(rc)
+	if err != nil {
+		klog.Errorf("failed to save backup file %s, cluster %s, err is %v", key, source.Name, err)
+		return "", false, err
+	}
+	hashChecked, err := VerifySnapshotHash(path)
+	if err == nil {
+		err = CheckSnapshotIntegrity(path)
+	}
+	if err != nil {
+		klog.Errorf("failed to verify backup file %s, cluster %s, err is %v", key, source.Name, err)
+		os.Remove(path)
+		return "", false, err
+	}
+	return path, hashChecked, nil
+}
+
+// StagedRestore is a backup file restored into an ephemeral member, the
+// persisted events are replayed into it for a point in time restore.
+type StagedRestore struct {
+	member *ephemeralMember
+	// BackupKey is the backup file restored.
+	BackupKey string
+	// Revision is the revision of the source cluster restored.
+	Revision int64
+}
+
+// StageRestore downloads and verifies the backup file of source cluster, and
+// restores it with etcdutl into an ephemeral member. No cluster is written
+// until the staged restore is copied, the caller must close it.
+func StageRestore(
+	storage Storage,
+	source *kstonev1alpha2.EtcdCluster,
+	spec *kstonev1alpha2.EtcdRestoreSpec,
+) (*StagedRestore, error) {
+	key, err := selectBackupFile(storage, source, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	path, hashChecked, err := DownloadSnapshot(storage, source, key)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path)
+
+	member, err := startEphemeralMember(path, !hashChecked)
+	if err != nil {
+		klog.Errorf("failed to restore backup file %s, cluster %s, err is %v", key, source.Name, err)
+		return nil, err
+	}
+	staged := &StagedRestore{member: member, BackupKey: key}
+
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTxnTimeout)
+	resp, err := member.cli.Get(ctx, "\x00", clientv3.WithFromKey(), clientv3.WithCountOnly())
+	cancel()
+	if err != nil {
+		staged.Close()
+		return nil, err
+	}
+	staged.Revision = resp.Header.Revision
+
+	if isPointInTime(spec) {
+		staged.Revision, err = ReplayEvents(storage, source, member.cli, staged.Revision, spec)
+		if err != nil {
+			staged.Close()
+			return nil, err
+		}
+	}
+	return staged, nil
+}
+
+// Close stops the ephemeral member and removes its data.
+func (s *StagedRestore) Close() {
+	s.member.Close()
+}
+
+// CopyTo copies the keys and the leases into the cluster that cli connects to.
+// The cluster must be empty unless overwrite is set, which removes its keys
+// and leases first. It is set if the restore asks to overwrite the cluster,
+// or for the retries of a restore that wrote the cluster.
+func (s *StagedRestore) CopyTo(cli *clientv3.Client, overwrite bool) (*RestoreResult, error) {
+	err := cleanTarget(cli, overwrite)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RestoreResult{BackupKey: s.BackupKey, Revision: s.Revision}
+	result.Keys, result.Leases, err = copyKeyspace(s.member.cli, cli)
+	if err != nil {
+		klog.Errorf("failed to copy keys into target cluster, err is %v", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// CheckTargetEmpty returns ErrTargetNotEmpty if the cluster that cli connects to has keys.
+func CheckTargetEmpty(cli *clientv3.Client) error {
+	return cleanTarget(cli, false)
+}
+
+// selectBackupFile returns the backup file to restore, for a point in time
+// restore without backup key, it is the latest backup file before the restore point.
+func selectBackupFile(storage Storage, source *kstonev1alpha2.EtcdCluster, spec *kstonev1alpha2.EtcdRestoreSpec) (string, error) {
+	if spec.BackupKey != "" {
+		return spec.BackupKey, nil
+	}
+
+	objects, err := storage.List(source)
+	if err != nil {
+		klog.Errorf("failed to list backup files, cluster %s, err is %v", source.Name, err)
+		return "", err
+	}
+
+	selected, selectedRev := "", int64(-1)
+	for _, object := range objects {
+		// only periodic backup files have revisions
+		if object.Revision == 0 {
+			continue
+		}
+		if spec.TargetRevision > 0 && object.Revision > spec.TargetRevision {
+			continue
+		}
+		if spec.TargetTime != nil && object.CreatedTime.After(spec.TargetTime.Time) {
+			continue
+		}
+		if object.Revision > selectedRev {
+			selected, selectedRev = object.Key, object.Revision
+		}
+	}
+	if selected == "" {
+		return "", fmt.Errorf("no backup file found before the restore point, cluster %s", source.Name)
+	}
+	return selected, nil
+}
+
+// ReplayEvents applies the persisted events after revision base to the cluster
+// that cli connects to, until the restore point. It returns the revision of
+// the source cluster restored.
+func ReplayEvents(
+	storage Storage,
+	source *kstonev1alpha2.EtcdCluster,
+	cli *clientv3.Client,
+	base int64,
+	spec *kstonev1alpha2.EtcdRestoreSpec,
+) (int64, error) {
+	keys, err := storage.ListEventSegments(source)
+	if err != nil {
+		klog.Errorf("failed to list event segments, cluster %s, err is %v", source.Name, err)
+		return 0, err
+	}
+
+	restored, next, dropped := base, base+1, 0
+	defer func() {
+		if dropped > 0 {
+			klog.Warningf("%d replayed keys are restored without lease, their leases are granted after the backup file, cluster %s", dropped, source.Name)
+		}
+	}()
+	for _, segment := range SortEventSegments(keys) {
+		if segment.To < next {
+			continue
+		}
+		if segment.From > next {
+			return 0, fmt.Errorf("event history is missing between revision %d and %d", next, segment.From-1)
+		}
+
+		events, err := downloadEvents(storage, source, segment.Key)
+		if err != nil {
+			return 0, err
+		}
+		for _, ev := range events {
+			if ev.Revision < next {
+				continue
+			}
+			if beyond(spec, ev) {
+				return restored, nil
+			}
+			leaseFound, err := applyEvent(cli, ev)
+			if err != nil {
+				klog.Errorf("failed to replay event of revision %d, cluster %s, err is %v", ev.Revision, source.Name, err)
+				return 0, err
+			}
+			if !leaseFound {
+				dropped++
+			}
+			restored = ev.Revision
+		}
+		next = segment.To + 1
+	}
+
+	if spec.TargetRevision >= next {
+		return 0, fmt.Errorf("event history ends at revision %d, before the target revision %d", next-1, spec.TargetRevision)
+	}
+	if spec.TargetTime != nil {
+		klog.Warningf("event history ends at revision %d before the target time, cluster %s", next-1, source.Name)
+	}
+	return restored, nil
+}
+
+// applyEvent writes the event into the cluster. The lease of the key is kept
+// if it exists, i.e. it is granted before the backup file, otherwise the key
+// is written without lease and false is returned.
+func applyEvent(cli *clientv3.Client, ev *Event) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTxnTimeout)
+	defer cancel()
+
+	if ev.Type == mvccpb.DELETE {
+		_, err := cli.Delete(ctx, string(ev.Key))
+		return true, err
+	}
+	if ev.Lease != 0 {
+		_, err := cli.Put(ctx, string(ev.Key), string(ev.Value), clientv3.WithLease(clientv3.LeaseID(ev.Lease)))
+		if err != rpctypes.ErrLeaseNotFound {
+			return true, err
+		}
+	}
+	_, err := cli.Put(ctx, string(ev.Key), string(ev.Value))
+	return ev.Lease == 0, err
+}
+
+// downloadEvents downloads and decodes the event segment.
+func downloadEvents(storage Storage, source *kstonev1alpha2.EtcdCluster, key string) ([]*Event, error) {
+	rc, err := storage.Get(source, key)
+	if err != nil {
+		klog.Errorf("failed to download event segment %s, cluster %s, err is %v", key, source.Name, err)
+		return nil, err
+	}
+	defer rc.Close()
+
+	events, err := DecodeEvents(rc)
+	if err != nil {
+		klog.Errorf("failed to decode event segment %s, cluster %s, err is %v", key, source.Name, err)
+		return nil, err
+	}
+	return events, nil
+}
+
+// cleanTarget checks the target cluster is empty, or removes its keys and
+// leases if overwrite is set.
+func cleanTarget(cli *clientv3.Client, overwrite bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTxnTimeout)
+	defer cancel()
+
+	if !overwrite {
+		resp, err := cli.Get(ctx, "\x00", clientv3.WithFromKey(), clientv3.WithCountOnly())
+		if err != nil {
+			klog.Errorf("failed to count keys of target cluster, err is %v", err)
+			return err
+		}
+		if resp.Count > 0 {
+			return fmt.Errorf("%w, it has %d keys, set overwrite to remove them", ErrTargetNotEmpty, resp.Count)
+		}
+		return nil
+	}
+
+	leases, err := cli.Leases(ctx)
+	if err != nil {
+		klog.Errorf("failed to list leases of target cluster, err is %v", err)
+		return err
+	}
+	for _, lease := range leases.Leases {
+		if err = revokeLease(cli, lease.ID); err != nil {
+			klog.Errorf("failed to revoke lease %x of target cluster, err is %v", lease.ID, err)
+			return err
+		}
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), restoreTxnTimeout)
+	defer cancel()
+	_, err = cli.Delete(ctx, "\x00", clientv3.WithFromKey())
+	if err != nil {
+		klog.Errorf("failed to clean keys of target cluster, err is %v", err)
+		return err
+	}
+	return nil
+}
+
+// revokeLease revokes the lease, an expired lease is ignored.
+func revokeLease(cli *clientv3.Client, id clientv3.LeaseID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTxnTimeout)
+	defer cancel()
+	_, err := cli.Revoke(ctx, id)
+	if err == rpctypes.ErrLeaseNotFound {
+		return nil
+	}
+	return err
+}
+
+// copyKeyspace copies the keys of the latest revision and their leases page
+// by page, the leases are granted again in the target cluster with their TTL.
+// The keys whose leases expired during the copy are skipped.
+func copyKeyspace(from, to *clientv3.Client) (int64, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTxnTimeout)
+	resp, err := from.Get(ctx, "\x00", clientv3.WithFromKey(), clientv3.WithCountOnly())
+	cancel()
+	if err != nil {
+		return 0, 0, err
+	}
+	rev := resp.Header.Revision
+
+	leaseIDs, err := copyLeases(from, to)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	w := &txnWriter{cli: to}
+	keys, key := int64(0), "\x00"
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), restoreTxnTimeout)
+		resp, err := from.Get(ctx, key, clientv3.WithFromKey(), clientv3.WithRev(rev), clientv3.WithLimit(restorePageKeys))
+		cancel()
+		if err != nil {
+			return keys, int64(len(leaseIDs)), err
+		}
+		for _, kv := range resp.Kvs {
+			var opts []clientv3.OpOption
+			if kv.Lease != 0 {
+				id, found := leaseIDs[clientv3.LeaseID(kv.Lease)]
+				if !found {
+					continue
+				}
+				opts = append(opts, clientv3.WithLease(id))
+			}
+			if err = w.put(kv.Key, kv.Value, opts...); err != nil {
+				return keys, int64(len(leaseIDs)), err
+			}
+			keys++
+		}
+		if !resp.More || len(resp.Kvs) == 0 {
+			break
+		}
+		key = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+	return keys, int64(len(leaseIDs)), w.flush()
+}
+
+// copyLeases grants the unexpired leases of from in to, and returns the
+// ids of the granted leases by the original ids.
+func copyLeases(from, to *clientv3.Client) (map[clientv3.LeaseID]clientv3.LeaseID, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTxnTimeout)
+	resp, err := from.Leases(ctx)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[clientv3.LeaseID]clientv3.LeaseID, len(resp.Leases))
+	for _, lease := range resp.Leases {
+		id, err := copyLease(from, to, lease.ID)
+		if err != nil {
+			return nil, err
+		}
+		if id != clientv3.NoLease {
+			ids[lease.ID] = id
+		}
+	}
+	return ids, nil
+}
+
+// copyLease grants the lease of from in to with its TTL, it returns NoLease
+// if the lease expired.
+func copyLease(from, to *clientv3.Client, id clientv3.LeaseID) (clientv3.LeaseID, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTxnTimeout)
+	defer cancel()
+
+	ttl, err := from.TimeToLive(ctx, id)
+	if err != nil {
+		return clientv3.NoLease, err
+	}
+	// TTL is -1 if the lease expired
+	if ttl.TTL <= 0 {
+		return clientv3.NoLease, nil
+	}
+	granted, err := to.Grant(ctx, ttl.TTL)
+	if err != nil {
+		klog.Errorf("failed to grant lease in target cluster, err is %v", err)
+		return clientv3.NoLease, err
+	}
+	return granted.ID, nil
+}
+
+// txnWriter writes keys in transactions bounded by restoreTxnOps and restoreTxnBytes.
+type txnWriter struct {
+	cli  *clientv3.Client
+	ops  []clientv3.Op
+	size int
+}
+
+// put adds the key to the pending transaction, which is committed when full.
+func (w *txnWriter) put(key, value []byte, opts ...clientv3.OpOption) error {
+	if len(w.ops) > 0 && w.size+len(key)+len(value) > restoreTxnBytes {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+	w.ops = append(w.ops, clientv3.OpPut(string(key), string(value), opts...))
+	w.size += len(key) + len(value)
+	if len(w.ops) == restoreTxnOps {
+		return w.flush()
+	}
+	return nil
+}
+
+// flush commits the pending transaction.
+func (w *txnWriter) flush() error {
+	if len(w.ops) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTxnTimeout)
+	defer cancel()
+	_, err := w.cli.Txn(ctx).Then(w.ops...).Commit()
+	if err != nil {
+		klog.Errorf("failed to write keys into target cluster, err is %v", err)
+		return err
+	}
+	w.ops, w.size = w.ops[:0], 0
+	return nil
+}
@@ -0,0 +1,140 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package backup
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+)
+
+func TestValidateRestoreSpec(t *testing.T) {
+	now := metav1.Now()
+	tests := []struct {
+		name    string
+		spec    kstonev1alpha2.EtcdRestoreSpec
+		wantErr bool
+	}{
+		{
+			name:    "another cluster",
+			spec:    kstonev1alpha2.EtcdRestoreSpec{SourceCluster: "a", TargetCluster: "b", BackupKey: "key"},
+			wantErr: false,
+		},
+		{
+			name:    "another cluster with overwrite",
+			spec:    kstonev1alpha2.EtcdRestoreSpec{SourceCluster: "a", TargetCluster: "b", BackupKey: "key", Overwrite: true},
+			wantErr: false,
+		},
+		{
+			name:    "in place with overwrite",
+			spec:    kstonev1alpha2.EtcdRestoreSpec{SourceCluster: "a", BackupKey: "key", Overwrite: true},
+			wantErr: false,
+		},
+		{
+			name:    "in place without overwrite",
+			spec:    kstonev1alpha2.EtcdRestoreSpec{SourceCluster: "a", BackupKey: "key"},
+			wantErr: true,
+		},
+		{
+			name:    "target is source without overwrite",
+			spec:    kstonev1alpha2.EtcdRestoreSpec{SourceCluster: "a", TargetCluster: "a", BackupKey: "key"},
+			wantErr: true,
+		},
+		{
+			name:    "point in time by revision",
+			spec:    kstonev1alpha2.EtcdRestoreSpec{SourceCluster: "a", TargetCluster: "b", TargetRevision: 100},
+			wantErr: false,
+		},
+		{
+			name:    "point in time by time",
+			spec:    kstonev1alpha2.EtcdRestoreSpec{SourceCluster: "a", TargetCluster: "b", TargetTime: &now},
+			wantErr: false,
+		},
+		{
+			name:    "source cluster empty",
+			spec:    kstonev1alpha2.EtcdRestoreSpec{TargetCluster: "b", BackupKey: "key"},
+			wantErr: true,
+		},
+		{
+			name:    "backup key empty",
+			spec:    kstonev1alpha2.EtcdRestoreSpec{SourceCluster: "a", TargetCluster: "b"},
+			wantErr: true,
+		},
+		{
+			name:    "negative revision",
+			spec:    kstonev1alpha2.EtcdRestoreSpec{SourceCluster: "a", TargetCluster: "b", TargetRevision: -1},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRestoreSpec(&tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected wantErr %v, err is %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGetRestoreConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		wantSource string
+		wantErr    bool
+	}{
+		{
+			name:       "in place with overwrite",
+			annotation: `{"backupKey": "key", "overwrite": true}`,
+			wantSource: "test",
+		},
+		{
+			name:       "in place without overwrite",
+			annotation: `{"backupKey": "key"}`,
+			wantErr:    true,
+		},
+		{
+			name:       "from another cluster",
+			annotation: `{"sourceCluster": "source", "backupKey": "key"}`,
+			wantSource: "source",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &kstonev1alpha2.EtcdCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test",
+					Annotations: map[string]string{AnnoRestoreConfig: tt.annotation},
+				},
+			}
+			spec, err := GetRestoreConfig(cluster)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected wantErr %v, err is %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if spec.SourceCluster != tt.wantSource || spec.TargetCluster != "test" {
+				t.Errorf("expected source %s and target test, got %s and %s", tt.wantSource, spec.SourceCluster, spec.TargetCluster)
+			}
+		})
+	}
+}
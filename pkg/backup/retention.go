@@ -0,0 +1,280 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+)
+
+const (
+	RetainReasonLatest  = "latest"
+	RetainReasonMinAge  = "minAge"
+	RetainReasonHourly  = "hourly"
+	RetainReasonDaily   = "daily"
+	RetainReasonWeekly  = "weekly"
+	RetainReasonMonthly = "monthly"
+	// RetainReasonOneShot is the reason of one-shot backup files, they are never
+	// deleted by the retention policy.
+	RetainReasonOneShot = "oneShot"
+)
+
+// RetentionPolicy keeps the newest periodic backup file of each of the latest
+// N hours, days, weeks and months, other periodic backup files are deleted once
+// they are older than MinAgeInSecond.
+type RetentionPolicy struct {
+	Hourly         int   `json:"hourly,omitempty" form:"hourly"`
+	Daily          int   `json:"daily,omitempty" form:"daily"`
+	Weekly         int   `json:"weekly,omitempty" form:"weekly"`
+	Monthly        int   `json:"monthly,omitempty" form:"monthly"`
+	MinAgeInSecond int64 `json:"minAgeInSecond,omitempty" form:"minAgeInSecond"`
+}
+
+// RetainedObject is a backup file kept by the retention policy.
+type RetainedObject struct {
+	BackupObject `json:",inline"`
+	// Reasons are the rules keeping the backup file.
+	Reasons []string `json:"reasons"`
+}
+
+// RetentionPlan is the result of applying the retention policy to the backup
+// files and the event segments.
+type RetentionPlan struct {
+	Keep   []RetainedObject `json:"keep"`
+	Delete []BackupObject   `json:"delete"`
+	// DeleteEventSegments are the keys of the event segments older than the
+	// oldest periodic backup file kept, no restore needs them.
+	DeleteEventSegments []string `json:"deleteEventSegments"`
+}
+
+// retentionRule keeps the newest backup file of each of the latest count periods.
+type retentionRule struct {
+	reason string
+	count  int
+	period func(t time.Time) string
+}
+
+// Validate checks the retention policy.
+func (p *RetentionPolicy) Validate() error {
+	if p.Hourly < 0 || p.Daily < 0 || p.Weekly < 0 || p.Monthly < 0 || p.MinAgeInSecond < 0 {
+		return errors.New("retention policy must not be negative")
+	}
+	if p.Hourly == 0 && p.Daily == 0 && p.Weekly == 0 && p.Monthly == 0 {
+		return errors.New("at least one of hourly, daily, weekly and monthly must be set")
+	}
+	return nil
+}
+
+func (p *RetentionPolicy) rules() []retentionRule {
+	return []retentionRule{
+		{
+			reason: RetainReasonHourly,
+			count:  p.Hourly,
+			period: func(t time.Time) string { return t.Format("2006-01-02T15") },
+		},
+		{
+			reason: RetainReasonDaily,
+			count:  p.Daily,
+			period: func(t time.Time) string { return t.Format("2006-01-02") },
+		},
+		{
+			reason: RetainReasonWeekly,
+			count:  p.Weekly,
+			period: func(t time.Time) string {
+				year, week := t.ISOWeek()
+				return fmt.Sprintf("%d-%02d", year, week)
+			},
+		},
+		{
+			reason: RetainReasonMonthly,
+			count:  p.Monthly,
+			period: func(t time.Time) string { return t.Format("2006-01") },
+		},
+	}
+}
+
+// PlanRetention applies the retention policy to the backup files and the event
+// segments, the newest periodic backup file and one-shot backup files are
+// always kept, the event segments are kept as long as the backup files need them.
+func PlanRetention(objects []BackupObject, segments []string, policy *RetentionPolicy, now time.Time) *RetentionPlan {
+	sorted := make([]BackupObject, len(objects))
+	copy(sorted, objects)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedTime.After(sorted[j].CreatedTime)
+	})
+
+	reasons := make(map[string][]string)
+	periodic := make([]BackupObject, 0, len(sorted))
+	for _, object := range sorted {
+		// only periodic backup files have revisions
+		if object.Revision == 0 {
+			reasons[object.Key] = append(reasons[object.Key], RetainReasonOneShot)
+			continue
+		}
+		if len(periodic) == 0 {
+			reasons[object.Key] = append(reasons[object.Key], RetainReasonLatest)
+		}
+		if now.Sub(object.CreatedTime) < time.Duration(policy.MinAgeInSecond)*time.Second {
+			reasons[object.Key] = append(reasons[object.Key], RetainReasonMinAge)
+		}
+		periodic = append(periodic, object)
+	}
+
+	for _, rule := range policy.rules() {
+		if rule.count <= 0 {
+			continue
+		}
+		periods := make(map[string]bool)
+		for _, object := range periodic {
+			period := rule.period(object.CreatedTime.In(time.Local))
+			if periods[period] {
+				continue
+			}
+			if len(periods) >= rule.count {
+				break
+			}
+			periods[period] = true
+			reasons[object.Key] = append(reasons[object.Key], rule.reason)
+		}
+	}
+
+	plan := &RetentionPlan{
+		Keep:   make([]RetainedObject, 0),
+		Delete: make([]BackupObject, 0),
+	}
+	kept := make([]BackupObject, 0, len(sorted))
+	for _, object := range sorted {
+		if len(reasons[object.Key]) > 0 {
+			plan.Keep = append(plan.Keep, RetainedObject{BackupObject: object, Reasons: reasons[object.Key]})
+			kept = append(kept, object)
+		} else {
+			plan.Delete = append(plan.Delete, object)
+		}
+	}
+	plan.DeleteEventSegments = expiredEventSegments(kept, segments)
+	return plan
+}
+
+// EnforceRetention deletes the backup files and the event segments of cluster
+// which are not kept by the retention policy, nothing is deleted if dryRun is true.
+func EnforceRetention(
+	storage Storage,
+	cluster *kstonev1alpha2.EtcdCluster,
+	policy *RetentionPolicy,
+	dryRun bool,
+) (*RetentionPlan, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	objects, err := storage.List(cluster)
+	if err != nil {
+		klog.Errorf("failed to list backup files, cluster %s, err is %v", cluster.Name, err)
+		return nil, err
+	}
+	segments, err := storage.ListEventSegments(cluster)
+	if err != nil {
+		klog.Errorf("failed to list event segments, cluster %s, err is %v", cluster.Name, err)
+		return nil, err
+	}
+	plan := PlanRetention(objects, segments, policy, time.Now())
+	if dryRun {
+		return plan, nil
+	}
+
+	var lastErr error
+	deleted := make([]BackupObject, 0, len(plan.Delete))
+	for _, object := range plan.Delete {
+		if err = storage.Delete(cluster, object.Key); err != nil {
+			lastErr = err
+			continue
+		}
+		klog.V(2).Infof("delete backup file %s by retention policy, cluster %s", object.Key, cluster.Name)
+		deleted = append(deleted, object)
+	}
+	plan.Delete = deleted
+
+	// the event segments are deleted after the backup files, so they are
+	// never missing for a backup file which fails to be deleted
+	plan.DeleteEventSegments, err = deleteEventSegments(storage, cluster, plan.DeleteEventSegments)
+	if err != nil {
+		lastErr = err
+	}
+	return plan, lastErr
+}
+
+// expiredEventSegments returns the keys of the event segments whose revisions
+// are all before the oldest periodic backup file of objects, no restore needs
+// them. Nothing is expired if there is no periodic backup file.
+func expiredEventSegments(objects []BackupObject, segments []string) []string {
+	var oldest int64
+	for _, object := range objects {
+		if object.Revision > 0 && (oldest == 0 || object.Revision < oldest) {
+			oldest = object.Revision
+		}
+	}
+	expired := make([]string, 0)
+	if oldest == 0 {
+		return expired
+	}
+	for _, segment := range SortEventSegments(segments) {
+		if segment.To < oldest {
+			expired = append(expired, segment.Key)
+		}
+	}
+	return expired
+}
+
+// PruneEventSegments deletes the event segments which are not needed by the
+// periodic backup files of cluster, it is used if the backup files are rotated
+// by MaxBackups of etcd-operator instead of the retention policy.
+func PruneEventSegments(storage Storage, cluster *kstonev1alpha2.EtcdCluster) ([]string, error) {
+	objects, err := storage.List(cluster)
+	if err != nil {
+		klog.Errorf("failed to list backup files, cluster %s, err is %v", cluster.Name, err)
+		return nil, err
+	}
+	segments, err := storage.ListEventSegments(cluster)
+	if err != nil {
+		klog.Errorf("failed to list event segments, cluster %s, err is %v", cluster.Name, err)
+		return nil, err
+	}
+	return deleteEventSegments(storage, cluster, expiredEventSegments(objects, segments))
+}
+
+// deleteEventSegments deletes the event segments and returns the deleted ones.
+func deleteEventSegments(storage Storage, cluster *kstonev1alpha2.EtcdCluster, keys []string) ([]string, error) {
+	var lastErr error
+	deleted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if err := storage.Delete(cluster, key); err != nil {
+			lastErr = err
+			continue
+		}
+		klog.V(2).Infof("delete event segment %s, cluster %s", key, cluster.Name)
+		deleted = append(deleted, key)
+	}
+	return deleted, lastErr
+}
@@ -0,0 +1,296 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package backup
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// retentionNow is a Wednesday in the ISO week 11 of 2023
+var retentionNow = time.Date(2023, 3, 15, 12, 30, 0, 0, time.Local)
+
+func at(month time.Month, day, hour, min int) time.Time {
+	return time.Date(2023, month, day, hour, min, 0, 0, time.Local)
+}
+
+// periodicBackup is named as etcd-operator names periodic backup files
+func periodicBackup(rev int64, t time.Time) BackupObject {
+	key := fmt.Sprintf("etcdbackup_v%d_%s", rev, t.Format(backupTimeLayout))
+	// the created time is parsed from the key instead of the last modified time
+	return NewBackupObject("test", key, 1, retentionNow, "")
+}
+
+func oneShotBackup(key string, t time.Time) BackupObject {
+	return NewBackupObject("test", key, 1, t, "")
+}
+
+// label names periodic backup files by revision in the expected results
+func label(object BackupObject) string {
+	if object.Revision > 0 {
+		return fmt.Sprintf("v%d", object.Revision)
+	}
+	return object.Key
+}
+
+func TestPlanRetention(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     RetentionPolicy
+		objects    []BackupObject
+		wantKeep   map[string][]string
+		wantDelete []string
+	}{
+		{
+			name:   "hourly",
+			policy: RetentionPolicy{Hourly: 2},
+			objects: []BackupObject{
+				periodicBackup(1, at(3, 15, 10, 40)),
+				periodicBackup(2, at(3, 15, 11, 20)),
+				periodicBackup(3, at(3, 15, 11, 50)),
+				periodicBackup(4, at(3, 15, 12, 10)),
+			},
+			wantKeep: map[string][]string{
+				"v4": {RetainReasonLatest, RetainReasonHourly},
+				"v3": {RetainReasonHourly},
+			},
+			wantDelete: []string{"v2", "v1"},
+		},
+		{
+			name:   "daily",
+			policy: RetentionPolicy{Daily: 2},
+			objects: []BackupObject{
+				periodicBackup(5, at(3, 15, 12, 0)),
+				periodicBackup(4, at(3, 15, 6, 0)),
+				periodicBackup(3, at(3, 14, 23, 0)),
+				periodicBackup(2, at(3, 14, 1, 0)),
+				periodicBackup(1, at(3, 13, 12, 0)),
+			},
+			wantKeep: map[string][]string{
+				"v5": {RetainReasonLatest, RetainReasonDaily},
+				"v3": {RetainReasonDaily},
+			},
+			wantDelete: []string{"v4", "v2", "v1"},
+		},
+		{
+			// 3/13 is the Monday of week 11, 3/12 and 3/6 are in week 10
+			name:   "weekly",
+			policy: RetentionPolicy{Weekly: 2},
+			objects: []BackupObject{
+				periodicBackup(5, at(3, 15, 0, 0)),
+				periodicBackup(4, at(3, 13, 0, 0)),
+				periodicBackup(3, at(3, 12, 23, 0)),
+				periodicBackup(2, at(3, 6, 0, 0)),
+				periodicBackup(1, at(3, 5, 0, 0)),
+			},
+			wantKeep: map[string][]string{
+				"v5": {RetainReasonLatest, RetainReasonWeekly},
+				"v3": {RetainReasonWeekly},
+			},
+			wantDelete: []string{"v4", "v2", "v1"},
+		},
+		{
+			name:   "monthly",
+			policy: RetentionPolicy{Monthly: 2},
+			objects: []BackupObject{
+				periodicBackup(4, at(3, 15, 0, 0)),
+				periodicBackup(3, at(3, 1, 0, 0)),
+				periodicBackup(2, at(2, 28, 0, 0)),
+				periodicBackup(1, at(1, 31, 0, 0)),
+			},
+			wantKeep: map[string][]string{
+				"v4": {RetainReasonLatest, RetainReasonMonthly},
+				"v2": {RetainReasonMonthly},
+			},
+			wantDelete: []string{"v3", "v1"},
+		},
+		{
+			name:   "combined buckets",
+			policy: RetentionPolicy{Hourly: 1, Daily: 2, Monthly: 2},
+			objects: []BackupObject{
+				periodicBackup(4, at(3, 15, 12, 10)),
+				periodicBackup(3, at(3, 15, 11, 0)),
+				periodicBackup(2, at(3, 14, 20, 0)),
+				periodicBackup(1, at(2, 20, 0, 0)),
+			},
+			wantKeep: map[string][]string{
+				"v4": {RetainReasonLatest, RetainReasonHourly, RetainReasonDaily, RetainReasonMonthly},
+				"v2": {RetainReasonDaily},
+				"v1": {RetainReasonMonthly},
+			},
+			wantDelete: []string{"v3"},
+		},
+		{
+			name:   "min age",
+			policy: RetentionPolicy{Daily: 1, MinAgeInSecond: 3600},
+			objects: []BackupObject{
+				periodicBackup(3, at(3, 15, 12, 10)),
+				periodicBackup(2, at(3, 15, 11, 40)),
+				periodicBackup(1, at(3, 15, 11, 0)),
+			},
+			wantKeep: map[string][]string{
+				"v3": {RetainReasonLatest, RetainReasonMinAge, RetainReasonDaily},
+				"v2": {RetainReasonMinAge},
+			},
+			wantDelete: []string{"v1"},
+		},
+		{
+			name:   "one-shot backups are always kept",
+			policy: RetentionPolicy{Hourly: 1},
+			objects: []BackupObject{
+				periodicBackup(2, at(3, 15, 11, 0)),
+				periodicBackup(1, at(3, 15, 10, 0)),
+				oneShotBackup("etcdbackup_ondemand_2023-03-15-12:20:00", at(3, 15, 12, 20)),
+				oneShotBackup("etcdbackup_ondemand_2023-01-01-00:00:00", at(1, 1, 0, 0)),
+			},
+			wantKeep: map[string][]string{
+				"etcdbackup_ondemand_2023-03-15-12:20:00": {RetainReasonOneShot},
+				"v2": {RetainReasonLatest, RetainReasonHourly},
+				"etcdbackup_ondemand_2023-01-01-00:00:00": {RetainReasonOneShot},
+			},
+			wantDelete: []string{"v1"},
+		},
+		{
+			name:   "unparseable names are kept as one-shot backups",
+			policy: RetentionPolicy{Hourly: 1},
+			objects: []BackupObject{
+				periodicBackup(1, at(3, 15, 12, 0)),
+				oneShotBackup("etcdbackup_v1_2023-03-15", at(1, 1, 0, 0)),
+				oneShotBackup("etcdbackup_vx_2023-03-15-12:00:00", at(1, 1, 0, 0)),
+				oneShotBackup("manual.db", at(1, 1, 0, 0)),
+			},
+			wantKeep: map[string][]string{
+				"v1":                                {RetainReasonLatest, RetainReasonHourly},
+				"etcdbackup_v1_2023-03-15":          {RetainReasonOneShot},
+				"etcdbackup_vx_2023-03-15-12:00:00": {RetainReasonOneShot},
+				"manual.db":                         {RetainReasonOneShot},
+			},
+			wantDelete: []string{},
+		},
+		{
+			name:       "no backup files",
+			policy:     RetentionPolicy{Hourly: 1},
+			wantKeep:   map[string][]string{},
+			wantDelete: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := PlanRetention(tt.objects, nil, &tt.policy, retentionNow)
+
+			keep := make(map[string][]string, len(plan.Keep))
+			for _, object := range plan.Keep {
+				keep[label(object.BackupObject)] = object.Reasons
+			}
+			if !reflect.DeepEqual(keep, tt.wantKeep) {
+				t.Errorf("Keep = %v, want %v", keep, tt.wantKeep)
+			}
+			deleted := make([]string, 0, len(plan.Delete))
+			for _, object := range plan.Delete {
+				deleted = append(deleted, label(object))
+			}
+			if !reflect.DeepEqual(deleted, tt.wantDelete) {
+				t.Errorf("Delete = %v, want %v", deleted, tt.wantDelete)
+			}
+		})
+	}
+}
+
+func TestRetentionPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetentionPolicy
+		wantErr bool
+	}{
+		{name: "hourly", policy: RetentionPolicy{Hourly: 24}},
+		{name: "empty", policy: RetentionPolicy{MinAgeInSecond: 60}, wantErr: true},
+		{name: "negative", policy: RetentionPolicy{Daily: 7, Weekly: -1}, wantErr: true},
+		{name: "negative min age", policy: RetentionPolicy{Daily: 7, MinAgeInSecond: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() err is %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlanRetentionEventSegments(t *testing.T) {
+	objects := []BackupObject{
+		periodicBackup(30, at(3, 15, 12, 10)),
+		periodicBackup(20, at(3, 15, 11, 0)),
+		periodicBackup(10, at(3, 14, 20, 0)),
+	}
+	segments := []string{
+		"etcd" + EventSegmentName(1, 9),
+		"etcd" + EventSegmentName(10, 19),
+		"etcd" + EventSegmentName(20, 29),
+		"etcd" + EventSegmentName(30, 39),
+	}
+	// v10 is deleted, the segments before the oldest kept v20 are not needed
+	plan := PlanRetention(objects, segments, &RetentionPolicy{Hourly: 2}, retentionNow)
+	if want := segments[:2]; !reflect.DeepEqual(plan.DeleteEventSegments, want) {
+		t.Errorf("DeleteEventSegments = %v, want %v", plan.DeleteEventSegments, want)
+	}
+}
+
+func TestExpiredEventSegments(t *testing.T) {
+	segments := []string{
+		"etcd" + EventSegmentName(21, 30),
+		"etcd" + EventSegmentName(1, 9),
+		"etcd" + EventSegmentName(10, 20),
+	}
+	tests := []struct {
+		name    string
+		objects []BackupObject
+		want    []string
+	}{
+		{
+			name:    "no backup file",
+			objects: nil,
+			want:    []string{},
+		},
+		{
+			name:    "only one-shot backup files",
+			objects: []BackupObject{oneShotBackup("etcdbackup_ondemand_2023-03-15-12:00:00", at(3, 15, 12, 0))},
+			want:    []string{},
+		},
+		{
+			name:    "oldest backup file in a segment",
+			objects: []BackupObject{periodicBackup(25, at(3, 15, 12, 0)), periodicBackup(15, at(3, 15, 11, 0))},
+			want:    []string{segments[1]},
+		},
+		{
+			name:    "oldest backup file after segments",
+			objects: []BackupObject{periodicBackup(21, at(3, 15, 12, 0))},
+			want:    []string{segments[1], segments[2]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expiredEventSegments(tt.objects, segments); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expiredEventSegments() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package backup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+const (
+	// snapshotHashSize is the size of the sha256 checksum appended to
+	// the snapshot by the etcd maintenance api.
+	snapshotHashSize = sha256.Size
+)
+
+var keyBucketName = []byte("key")
+
+// SaveSnapshotFile writes the snapshot stream to a temporary file and
+// returns its path, the caller is responsible for removing it.
+func SaveSnapshotFile(r io.Reader) (string, error) {
+	f, err := ioutil.TempFile("", "kstone-snapshot-")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err = io.Copy(f, r); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err = f.Sync(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// VerifySnapshotHash checks the sha256 checksum appended to the snapshot file.
+// It returns false if the file carries no checksum, for example when it is
+// copied from the member data dir instead of being saved through the api.
+func VerifySnapshotHash(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	size := st.Size()
+	// bolt db files are page aligned, a snapshot with hash has 32 trailing bytes.
+	if size%512 != snapshotHashSize {
+		return false, nil
+	}
+
+	h := sha256.New()
+	if _, err = io.CopyN(h, f, size-snapshotHashSize); err != nil {
+		return false, err
+	}
+	expected := make([]byte, snapshotHashSize)
+	if _, err = io.ReadFull(f, expected); err != nil {
+		return false, err
+	}
+	if !bytes.Equal(h.Sum(nil), expected) {
+		return true, errors.New("snapshot hash mismatch")
+	}
+	return true, nil
+}
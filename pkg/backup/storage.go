@@ -1,6 +1,10 @@
 package backup
 
 import (
+	"fmt"
+	"io"
+	"strings"
+
 	"k8s.io/client-go/kubernetes"
 
 	"tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
@@ -9,12 +13,36 @@ import (
 // Storage is an abstract, pluggable interface for etcd backup storage.
 type Storage interface {
 	// List gets all backup files from object storage.
-	List(cluster *v1alpha2.EtcdCluster) (interface{}, error)
+	List(cluster *v1alpha2.EtcdCluster) ([]BackupObject, error)
+
+	// Get downloads the specified backup file from object storage.
+	Get(cluster *v1alpha2.EtcdCluster, key string) (io.ReadCloser, error)
+
+	// Delete deletes the specified backup file from object storage.
+	Delete(cluster *v1alpha2.EtcdCluster, key string) error
 
-	// Stat counts the number of backup file in the last day.
-	Stat(objects interface{}) (int, error)
+	// Put uploads the data to object storage, name is appended to the backup path.
+	Put(cluster *v1alpha2.EtcdCluster, name string, data io.Reader) error
+
+	// ListEventSegments gets the keys of all event segments from object storage.
+	ListEventSegments(cluster *v1alpha2.EtcdCluster) ([]string, error)
 }
 
 type StorageConfig struct {
 	KubeCli kubernetes.Interface
 }
+
+// CheckBackupKey checks whether the key is under the backup path prefix of the
+// cluster, so a cluster never gets or deletes the files of other clusters
+// sharing the bucket.
+func CheckBackupKey(key, prefix string) error {
+	if !strings.HasPrefix(key, prefix) {
+		return fmt.Errorf("%s is not under the backup path %s", key, prefix)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." {
+			return fmt.Errorf("%s is not under the backup path %s", key, prefix)
+		}
+	}
+	return nil
+}
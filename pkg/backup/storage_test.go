@@ -0,0 +1,43 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package backup
+
+import "testing"
+
+func TestCheckBackupKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		prefix  string
+		wantErr bool
+	}{
+		{name: "periodic backup", key: "kstone/etcd/etcdbackup_v10_2023-01-02-15:04:05", prefix: "kstone/etcd/etcdbackup"},
+		{name: "event segment", key: "kstone/etcd/etcdbackup" + EventSegmentName(11, 20), prefix: "kstone/etcd/etcdbackup"},
+		{name: "whole bucket", key: "etcdbackup_v10_2023-01-02-15:04:05", prefix: ""},
+		{name: "other cluster", key: "kstone/other/etcdbackup_v10_2023-01-02-15:04:05", prefix: "kstone/etcd/etcdbackup", wantErr: true},
+		{name: "parent directory", key: "kstone/etcd/etcdbackup/../../other/etcdbackup", prefix: "kstone/etcd/etcdbackup", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckBackupKey(tt.key, tt.prefix); (err != nil) != tt.wantErr {
+				t.Errorf("CheckBackupKey(%s, %s) = %v, wantErr %v", tt.key, tt.prefix, err, tt.wantErr)
+			}
+		})
+	}
+}
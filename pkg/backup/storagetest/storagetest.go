@@ -0,0 +1,195 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+// Package storagetest provides the common tests of backup storage providers.
+package storagetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	backupapiv2 "github.com/coreos/etcd-operator/pkg/apis/etcd/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/backup"
+)
+
+const (
+	// testNamespace is the namespace of the clusters and the secrets.
+	testNamespace = "kstone"
+	// testBackupName is appended to the backup path as etcd-operator names periodic backups.
+	testBackupName = "_v10_2023-01-02-15:04:05_3.4.13"
+)
+
+// RunID returns a unique path segment, so the runs against a shared emulator never see each other.
+func RunID() string {
+	return fmt.Sprintf("kstone-test-%d", time.Now().UnixNano())
+}
+
+// NewCluster returns a cluster with the backup config annotation.
+func NewCluster(t *testing.T, name string, cfg *backup.Config) *v1alpha2.EtcdCluster {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &v1alpha2.EtcdCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   testNamespace,
+			Name:        name,
+			Annotations: map[string]string{backup.AnnoBackupConfig: string(data)},
+		},
+	}
+}
+
+// NewConfig returns the backup config of the storage type, set fills the source.
+func NewConfig(storageType backupapiv2.BackupStorageType, set func(*backupapiv2.BackupSource)) *backup.Config {
+	cfg := &backup.Config{StorageType: storageType}
+	set(&cfg.BackupSource)
+	return cfg
+}
+
+// NewSecretClient returns a fake kube client with the secret of the storage credentials.
+func NewSecretClient(name string, data map[string]string) kubernetes.Interface {
+	return fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: name},
+		Data:       stringToBytes(data),
+	})
+}
+
+func stringToBytes(data map[string]string) map[string][]byte {
+	result := make(map[string][]byte, len(data))
+	for k, v := range data {
+		result[k] = []byte(v)
+	}
+	return result
+}
+
+// TestStorage puts backup files and event segments of both clusters, and
+// checks that each cluster only lists, gets and deletes its own files, the
+// keys of the other cluster are rejected by Get and Delete. The
+// backup paths of the clusters must be different and empty.
+func TestStorage(t *testing.T, storage backup.Storage, a, b *v1alpha2.EtcdCluster) {
+	for _, cluster := range []*v1alpha2.EtcdCluster{a, b} {
+		if err := storage.Put(cluster, testBackupName, strings.NewReader(cluster.Name)); err != nil {
+			t.Fatalf("failed to put backup file of %s, err is %v", cluster.Name, err)
+		}
+	}
+	segments := []string{backup.EventSegmentName(1, 9), backup.EventSegmentName(11, 20), backup.EventSegmentName(21, 30)}
+	for _, name := range segments {
+		if err := storage.Put(a, name, strings.NewReader(name)); err != nil {
+			t.Fatalf("failed to put event segment %s, err is %v", name, err)
+		}
+	}
+	defer cleanup(t, storage, a, b)
+
+	objects, err := storage.List(a)
+	if err != nil {
+		t.Fatalf("failed to list backup files, err is %v", err)
+	}
+	if len(objects) != 1 || !strings.HasSuffix(objects[0].Key, testBackupName) {
+		t.Fatalf("List() = %v, want only the backup file of %s", backup.BackupKeys(objects), a.Name)
+	}
+	if objects[0].Revision != 10 || objects[0].EtcdVersion != "3.4.13" {
+		t.Errorf("Revision, EtcdVersion = %d, %q, want 10, 3.4.13", objects[0].Revision, objects[0].EtcdVersion)
+	}
+	key := objects[0].Key
+
+	r, err := storage.Get(a, key)
+	if err != nil {
+		t.Fatalf("failed to get %s, err is %v", key, err)
+	}
+	data, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || string(data) != a.Name {
+		t.Errorf("Get(%s) = %q, %v, want %q", key, data, err, a.Name)
+	}
+
+	// the files of the other cluster sharing the bucket are never got or deleted
+	others, err := storage.List(b)
+	if err != nil || len(others) != 1 {
+		t.Fatalf("List() of %s = %v, %v, want its backup file", b.Name, backup.BackupKeys(others), err)
+	}
+	if r, err = storage.Get(a, others[0].Key); err == nil {
+		r.Close()
+		t.Errorf("Get(%s) of %s succeeded, want an error", others[0].Key, a.Name)
+	}
+	if err = storage.Delete(a, others[0].Key); err == nil {
+		t.Errorf("Delete(%s) of %s succeeded, want an error", others[0].Key, a.Name)
+	}
+
+	keys, err := storage.ListEventSegments(a)
+	if err != nil {
+		t.Fatalf("failed to list event segments, err is %v", err)
+	}
+	sorted := backup.SortEventSegments(keys)
+	if len(keys) != len(segments) || len(sorted) != len(segments) || sorted[0].From != 1 || sorted[1].From != 11 || sorted[2].From != 21 {
+		t.Errorf("ListEventSegments() = %v, want %v", keys, segments)
+	}
+	keys, err = storage.ListEventSegments(b)
+	if err != nil || len(keys) != 0 {
+		t.Errorf("ListEventSegments() of %s = %v, %v, want empty", b.Name, keys, err)
+	}
+
+	// the event segment before the backup file of revision 10 is not needed by
+	// any restore, it is previewed by the retention policy and then pruned
+	plan, err := backup.EnforceRetention(storage, a, &backup.RetentionPolicy{Hourly: 1}, true)
+	if err != nil || len(plan.DeleteEventSegments) != 1 || !strings.HasSuffix(plan.DeleteEventSegments[0], segments[0]) {
+		t.Errorf("EnforceRetention() dry run = %+v, %v, want to delete %s", plan, err, segments[0])
+	}
+	if keys, err = storage.ListEventSegments(a); err != nil || len(keys) != len(segments) {
+		t.Errorf("ListEventSegments() after dry run = %v, %v, want %v", keys, err, segments)
+	}
+	pruned, err := backup.PruneEventSegments(storage, a)
+	if err != nil || len(pruned) != 1 || !strings.HasSuffix(pruned[0], segments[0]) {
+		t.Errorf("PruneEventSegments() = %v, %v, want %s", pruned, err, segments[0])
+	}
+	if keys, err = storage.ListEventSegments(a); err != nil || len(keys) != len(segments)-1 {
+		t.Errorf("ListEventSegments() after prune = %v, %v, want %v", keys, err, segments[1:])
+	}
+
+	if err = storage.Delete(a, key); err != nil {
+		t.Fatalf("failed to delete %s, err is %v", key, err)
+	}
+	if objects, err = storage.List(a); err != nil || len(objects) != 0 {
+		t.Errorf("List() after delete = %v, %v, want empty", backup.BackupKeys(objects), err)
+	}
+	if objects, err = storage.List(b); err != nil || len(objects) != 1 {
+		t.Errorf("List() of %s = %v, %v, want its backup file", b.Name, backup.BackupKeys(objects), err)
+	}
+}
+
+// cleanup deletes the files put by TestStorage.
+func cleanup(t *testing.T, storage backup.Storage, clusters ...*v1alpha2.EtcdCluster) {
+	for _, cluster := range clusters {
+		objects, _ := storage.List(cluster)
+		keys, _ := storage.ListEventSegments(cluster)
+		for _, key := range append(backup.BackupKeys(objects), keys...) {
+			if err := storage.Delete(cluster, key); err != nil {
+				t.Logf("failed to delete %s, err is %v", key, err)
+			}
+		}
+	}
+}
@@ -2,8 +2,10 @@ package backup
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 
+	"github.com/coreos/etcd-operator/pkg/backup/encryption"
 	klog "k8s.io/klog/v2"
 	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
 )
@@ -30,3 +32,29 @@ func GetBackupConfig(cluster *kstonev1alpha2.EtcdCluster) (*Config, error) {
 	}
 	return backupConfig, nil
 }
+
+// Validate checks the backup config.
+func (cfg *Config) Validate() error {
+	if !IsBackupStorageProviderRegistered(string(cfg.StorageType)) {
+		return fmt.Errorf("unknown storageType %q", cfg.StorageType)
+	}
+	if policy := cfg.StoragePolicy; policy != nil {
+		if policy.BackupIntervalInSecond < 0 || policy.MaxBackups < 0 || policy.TimeoutInSecond < 0 {
+			return errors.New("backupPolicy must not be negative")
+		}
+	}
+	if cfg.Retention != nil {
+		if err := cfg.Retention.Validate(); err != nil {
+			return err
+		}
+	}
+	if cfg.Encryption != nil {
+		if cfg.Encryption.EncryptionSecret == "" {
+			return errors.New("encryptionSecret of encryption is empty")
+		}
+		if err := encryption.ValidateKeyID(cfg.Encryption.KeyID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/etcdutl/v3/snapshot"
+	"go.etcd.io/etcd/server/v3/embed"
+	"go.uber.org/zap"
+	"k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+)
+
+const (
+	ephemeralMemberName = "kstone-ephemeral"
+	ephemeralStartTime  = 60 * time.Second
+	ephemeralReadTime   = 30 * time.Second
+)
+
+// VerifyResult is the result of a restore drill.
+type VerifyResult struct {
+	BackupKey string `json:"backupKey"`
+	// Size is the size of the backup file in bytes.
+	Size int64 `json:"size"`
+	// HashChecked is false if the backup file carries no checksum.
+	HashChecked bool `json:"hashChecked"`
+	// Revision and Keys are read from the ephemeral member restored from the backup file.
+	Revision int64 `json:"revision"`
+	Keys     int64 `json:"keys"`
+}
+
+// LatestBackupKey returns the newest backup file of cluster.
+func LatestBackupKey(storage Storage, cluster *kstonev1alpha2.EtcdCluster) (string, error) {
+	objects, err := storage.List(cluster)
+	if err != nil {
+		klog.Errorf("failed to list backup files, cluster %s, err is %v", cluster.Name, err)
+		return "", err
+	}
+	if len(objects) == 0 {
+		return "", fmt.Errorf("no backup file found, cluster %s", cluster.Name)
+	}
+
+	// ordered by created time, the backup files created at the same time by revision
+	sort.Slice(objects, func(i, j int) bool {
+		if !objects[i].CreatedTime.Equal(objects[j].CreatedTime) {
+			return objects[i].CreatedTime.Before(objects[j].CreatedTime)
+		}
+		return objects[i].Revision < objects[j].Revision
+	})
+	return objects[len(objects)-1].Key, nil
+}
+
+// VerifyBackup downloads the newest backup file of cluster, checks its hash and
+// bbolt integrity, then restores it into an ephemeral single-member etcd and
+// reads the revision and the number of keys from it.
+func VerifyBackup(storage Storage, cluster *kstonev1alpha2.EtcdCluster) (*VerifyResult, error) {
+	key, err := LatestBackupKey(storage, cluster)
+	if err != nil {
+		return nil, err
+	}
+	result := &VerifyResult{BackupKey: key}
+
+	rc, err := storage.Get(cluster, key)
+	if err != nil {
+		klog.Errorf("failed to download backup file %s, cluster %s, err is %v", key, cluster.Name, err)
+		return result, err
+	}
+	path, err := SaveSnapshotFile(rc)
+	rc.Close()
+	if err != nil {
+		return result, err
+	}
+	defer os.Remove(path)
+
+	st, err := os.Stat(path)
+	if err != nil {
+		return result, err
+	}
+	result.Size = st.Size()
+	if result.Size == 0 {
+		return result, errors.New("backup file is empty")
+	}
+
+	result.HashChecked, err = VerifySnapshotHash(path)
+	if err != nil {
+		return result, err
+	}
+
+	err = CheckSnapshotIntegrity(path)
+	if err != nil {
+		return result, err
+	}
+
+	result.Revision, result.Keys, err = restoreEphemeralMember(path, !result.HashChecked)
+	return result, err
+}
+
+// CheckSnapshotIntegrity checks the consistency of the bbolt pages in snapshot.
+func CheckSnapshotIntegrity(path string) error {
+	db, err := bolt.Open(path, 0400, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot, err is %v", err)
+	}
+	defer db.Close()
+
+	return db.View(func(tx *bolt.Tx) error {
+		var errs []error
+		for err := range tx.Check() {
+			errs = append(errs, err)
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("snapshot is corrupted, %d errors found, first is %v", len(errs), errs[0])
+		}
+		if tx.Bucket(keyBucketName) == nil {
+			return errors.New("key bucket not found in snapshot")
+		}
+		return nil
+	})
+}
+
+// restoreEphemeralMember restores the snapshot into a single-member etcd
+// started in process, returns its revision and the number of keys.
+func restoreEphemeralMember(path string, skipHashCheck bool) (int64, int64, error) {
+	member, err := startEphemeralMember(path, skipHashCheck)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer member.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), ephemeralReadTime)
+	defer cancel()
+	resp, err := member.cli.Get(ctx, "\x00", clientv3.WithFromKey(), clientv3.WithCountOnly())
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read ephemeral etcd, err is %v", err)
+	}
+	return resp.Header.Revision, resp.Count, nil
+}
+
+// ephemeralMember is a single-member etcd started in process from a snapshot.
+type ephemeralMember struct {
+	dir  string
+	etcd *embed.Etcd
+	cli  *clientv3.Client
+}
+
+// startEphemeralMember restores the snapshot into a temporary data dir with
+// etcdutl and starts a single-member etcd on it, the caller must close it.
+func startEphemeralMember(path string, skipHashCheck bool) (member *ephemeralMember, err error) {
+	dir, err := ioutil.TempDir("", "kstone-ephemeral-")
+	if err != nil {
+		return nil, err
+	}
+	member = &ephemeralMember{dir: dir}
+	defer func() {
+		if err != nil {
+			member.Close()
+		}
+	}()
+
+	peerURL, err := localURL()
+	if err != nil {
+		return nil, err
+	}
+	clientURL, err := localURL()
+	if err != nil {
+		return nil, err
+	}
+
+	dataDir := filepath.Join(dir, "data")
+	err = snapshot.NewV3(zap.NewNop()).Restore(snapshot.RestoreConfig{
+		SnapshotPath:        path,
+		Name:                ephemeralMemberName,
+		OutputDataDir:       dataDir,
+		PeerURLs:            []string{peerURL.String()},
+		InitialCluster:      fmt.Sprintf("%s=%s", ephemeralMemberName, peerURL.String()),
+		InitialClusterToken: ephemeralMemberName,
+		SkipHashCheck:       skipHashCheck,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore snapshot, err is %v", err)
+	}
+
+	cfg := embed.NewConfig()
+	cfg.Name = ephemeralMemberName
+	cfg.Dir = dataDir
+	cfg.LogLevel = "error"
+	cfg.LPUrls, cfg.APUrls = []url.URL{*peerURL}, []url.URL{*peerURL}
+	cfg.LCUrls, cfg.ACUrls = []url.URL{*clientURL}, []url.URL{*clientURL}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+	member.etcd, err = embed.StartEtcd(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start ephemeral etcd, err is %v", err)
+	}
+
+	select {
+	case <-member.etcd.Server.ReadyNotify():
+	case err = <-member.etcd.Err():
+		return nil, fmt.Errorf("ephemeral etcd failed, err is %v", err)
+	case <-time.After(ephemeralStartTime):
+		return nil, errors.New("ephemeral etcd took too long to start")
+	}
+
+	member.cli, err = clientv3.New(clientv3.Config{
+		Endpoints:   []string{clientURL.String()},
+		DialTimeout: ephemeralReadTime,
+		Logger:      zap.NewNop(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// Close stops the member and removes its data dir.
+func (m *ephemeralMember) Close() {
+	if m.cli != nil {
+		m.cli.Close()
+	}
+	if m.etcd != nil {
+		m.etcd.Close()
+	}
+	os.RemoveAll(m.dir)
+}
+
+// localURL returns an url of a free local port.
+func localURL() (*url.URL, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+	return url.Parse("http://" + l.Addr().String())
+}
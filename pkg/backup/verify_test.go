@@ -0,0 +1,102 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package backup
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+)
+
+// listStorage only lists the objects
+type listStorage struct {
+	objects []BackupObject
+}
+
+func (s *listStorage) List(cluster *v1alpha2.EtcdCluster) ([]BackupObject, error) {
+	return s.objects, nil
+}
+
+func (s *listStorage) Get(cluster *v1alpha2.EtcdCluster, key string) (io.ReadCloser, error) {
+	return nil, nil
+}
+
+func (s *listStorage) Delete(cluster *v1alpha2.EtcdCluster, key string) error {
+	return nil
+}
+
+func (s *listStorage) Put(cluster *v1alpha2.EtcdCluster, name string, data io.Reader) error {
+	return nil
+}
+
+func (s *listStorage) ListEventSegments(cluster *v1alpha2.EtcdCluster) ([]string, error) {
+	return nil, nil
+}
+
+func TestLatestBackupKey(t *testing.T) {
+	base := time.Date(2023, 1, 2, 15, 0, 0, 0, time.UTC)
+	object := func(key string, rev int64, minutes int) BackupObject {
+		return BackupObject{Key: key, Revision: rev, CreatedTime: base.Add(time.Duration(minutes) * time.Minute)}
+	}
+	tests := []struct {
+		name    string
+		objects []BackupObject
+		want    string
+	}{
+		{
+			name:    "newest by created time",
+			objects: []BackupObject{object("b", 20, 2), object("c", 30, 3), object("a", 10, 1)},
+			want:    "c",
+		},
+		{
+			// a one-shot backup between periodic backups, comparing the
+			// periodic ones by revision made the order depend on the input
+			name:    "one-shot between periodic backups",
+			objects: []BackupObject{object("periodic-new", 10, 3), object("ondemand", 0, 2), object("periodic-old", 50, 1)},
+			want:    "periodic-new",
+		},
+		{
+			name:    "newest one-shot backup",
+			objects: []BackupObject{object("periodic", 10, 1), object("ondemand", 0, 2)},
+			want:    "ondemand",
+		},
+		{
+			name:    "same created time ordered by revision",
+			objects: []BackupObject{object("b", 20, 1), object("a", 10, 1)},
+			want:    "b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LatestBackupKey(&listStorage{objects: tt.objects}, &v1alpha2.EtcdCluster{})
+			if err != nil {
+				t.Fatalf("LatestBackupKey() err is %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("LatestBackupKey() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := LatestBackupKey(&listStorage{}, &v1alpha2.EtcdCluster{}); err == nil {
+		t.Error("LatestBackupKey() of no backup files succeeded, want error")
+	}
+}
@@ -19,6 +19,9 @@
 package clusterprovider
 
 import (
+	"errors"
+	"time"
+
 	"k8s.io/client-go/dynamic"
 
 	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
@@ -26,6 +29,9 @@ import (
 	"tkestack.io/kstone/pkg/etcd"
 )
 
+// ErrRestartNotSupported is returned by the providers which can not restart the members
+var ErrRestartNotSupported = errors.New("restarting members is not supported by the cluster provider")
+
 // Cluster is an abstract, pluggable interface for etcd clusters.
 type Cluster interface {
 
@@ -55,6 +61,12 @@ type Cluster interface {
 
 	// Status gets the cluster status
 	Status(config *etcd.ClientConfig, cluster *kstonev1alpha2.EtcdCluster) (kstonev1alpha2.EtcdClusterStatus, error)
+
+	// MemberStartTime gets the time when the member was started last time,
+	// returns zero time if the member is being restarted
+	MemberStartTime(cluster *kstonev1alpha2.EtcdCluster, member *kstonev1alpha2.MemberStatus) (time.Time, error)
+	// RestartMember restarts the member
+	RestartMember(cluster *kstonev1alpha2.EtcdCluster, member *kstonev1alpha2.MemberStatus) error
 }
 
 type ClusterContext struct {
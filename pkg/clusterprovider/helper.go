@@ -19,7 +19,6 @@
 package clusterprovider
 
 import (
-	"fmt"
 	"strings"
 
 	"k8s.io/klog/v2"
@@ -49,22 +48,18 @@ func GetStorageMemberEndpoints(cluster *kstonev1alpha2.EtcdCluster) []string {
 	return endpoints
 }
 
-// populateExtensionCientURLMap generate extensionClientURLs map
-func populateExtensionCientURLMap(extensionClientURLs string) (map[string]string, error) {
-	urlMap := make(map[string]string)
-	if extensionClientURLs == "" {
-		return nil, nil
-	}
-	items := strings.Split(extensionClientURLs, ",")
-	for i := 0; i < len(items); i++ {
-		eps := strings.Split(items[i], "->")
-		if len(eps) == 2 {
-			urlMap[eps[0]] = eps[1]
-		} else {
-			return urlMap, fmt.Errorf("invalid extensionClientURLs %s", items[i])
+// ExtensionClientURL maps the client url of a member to the url accessible
+// by kstone, the client url is returned if it is not mapped
+func ExtensionClientURL(clientURL string, extensionClientURLs map[string]string) string {
+	for _, scheme := range []string{"https://", "http://"} {
+		if !strings.HasPrefix(clientURL, scheme) {
+			continue
+		}
+		if ep, ok := extensionClientURLs[strings.TrimPrefix(clientURL, scheme)]; ok {
+			return scheme + ep
 		}
 	}
-	return urlMap, nil
+	return clientURL
 }
 
 // GetRuntimeEtcdMembers get members of etcd
@@ -91,7 +86,7 @@ func GetRuntimeEtcdMembers(
 		return etcdMembers, err
 	}
 
-	extensionClientURLMap, err := populateExtensionCientURLMap(extensionClientURLs)
+	extensionClientURLMap, err := kstonev1alpha2.ParseExtClientURLs(extensionClientURLs)
 	if err != nil {
 		klog.Errorf("failed to populate extension clientURL,err is %v", err)
 		return etcdMembers, err
@@ -105,21 +100,7 @@ func GetRuntimeEtcdMembers(
 		items := strings.Split(m.ClientURLs[0], ":")
 		endPoint := strings.TrimPrefix(items[1], "//")
 
-		extensionClientURL := m.ClientURLs[0]
-		if extensionClientURLs != "" {
-			var ep string
-			if strings.HasPrefix(m.ClientURLs[0], "https://") {
-				ep = strings.TrimPrefix(m.ClientURLs[0], "https://")
-				if _, ok := extensionClientURLMap[ep]; ok {
-					extensionClientURL = "https://" + extensionClientURLMap[ep]
-				}
-			} else {
-				ep = strings.TrimPrefix(m.ClientURLs[0], "http://")
-				if _, ok := extensionClientURLMap[ep]; ok {
-					extensionClientURL = "http://" + extensionClientURLMap[ep]
-				}
-			}
-		}
+		extensionClientURL := ExtensionClientURL(m.ClientURLs[0], extensionClientURLMap)
 
 		// default info
 		memberVersion, memberStatus, memberRole := "", kstonev1alpha2.MemberPhaseUnStarted, kstonev1alpha2.EtcdMemberUnKnown
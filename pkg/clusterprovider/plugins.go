@@ -62,3 +62,11 @@ func GetEtcdClusterProvider(
 	}
 	return f(ctx)
 }
+
+// IsEtcdClusterProviderRegistered checks whether the specified cluster provider is registered
+func IsEtcdClusterProviderRegistered(name kstonev1alpha2.EtcdClusterType) bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	_, found := providers[name]
+	return found
+}
@@ -20,6 +20,7 @@ package imported
 
 import (
 	"sync"
+	"time"
 
 	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
 	"tkestack.io/kstone/pkg/clusterprovider"
@@ -28,7 +29,7 @@ import (
 )
 
 const (
-	AnnoImportedURI = "importedAddr"
+	AnnoImportedURI = kstonev1alpha2.AnnoImportedAddr
 )
 
 var (
@@ -140,3 +141,16 @@ func (c *EtcdClusterImported) Status(config *etcd.ClientConfig, cluster *kstonev
 	status.Members, status.Phase = clusterprovider.GetEtcdClusterMemberStatus(members, config)
 	return status, err
 }
+
+// MemberStartTime is not supported, the members of imported cluster are not managed by kstone
+func (c *EtcdClusterImported) MemberStartTime(
+	cluster *kstonev1alpha2.EtcdCluster,
+	member *kstonev1alpha2.MemberStatus,
+) (time.Time, error) {
+	return time.Time{}, clusterprovider.ErrRestartNotSupported
+}
+
+// RestartMember is not supported, the members of imported cluster are not managed by kstone
+func (c *EtcdClusterImported) RestartMember(cluster *kstonev1alpha2.EtcdCluster, member *kstonev1alpha2.MemberStatus) error {
+	return clusterprovider.ErrRestartNotSupported
+}
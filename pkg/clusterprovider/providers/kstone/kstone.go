@@ -26,6 +26,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -44,7 +45,7 @@ import (
 
 const (
 	providerName              = kstonev1alpha2.EtcdClusterKstone
-	AnnoImportedURI           = "importedAddr"
+	AnnoImportedURI           = kstonev1alpha2.AnnoImportedAddr
 	defaultEmptyResourceValue = "0"
 )
 
@@ -122,17 +123,17 @@ func (c *EtcdClusterKstone) Create(cluster *kstonev1alpha2.EtcdCluster) error {
 // AfterCreate handles etcdcluster after created
 func (c *EtcdClusterKstone) AfterCreate(cluster *kstonev1alpha2.EtcdCluster) error {
 	if cluster.Annotations["scheme"] == "https" {
-		cluster.Annotations["certName"] = fmt.Sprintf("%s/%s-etcd-client-cert", cluster.Namespace, cluster.Name)
+		cluster.Annotations[kstonev1alpha2.AnnoClientCertSecret] = fmt.Sprintf("%s/%s-etcd-client-cert", cluster.Namespace, cluster.Name)
 	}
 
-	cluster.Annotations["importedAddr"] = fmt.Sprintf(
+	cluster.Annotations[kstonev1alpha2.AnnoImportedAddr] = fmt.Sprintf(
 		"%s://%s-etcd.%s.svc.cluster.local:2379",
 		cluster.Annotations["scheme"],
 		cluster.Name,
 		cluster.Namespace,
 	)
 	// update extClientURL
-	extClientURL := ""
+	extClientURL := make(map[string]string, cluster.Spec.Size)
 	for i := 0; i < int(cluster.Spec.Size); i++ {
 		key := fmt.Sprintf("%s-etcd-%d:2379", cluster.Name, i)
 		value := fmt.Sprintf(
@@ -142,13 +143,9 @@ func (c *EtcdClusterKstone) AfterCreate(cluster *kstonev1alpha2.EtcdCluster) err
 			cluster.Name,
 			cluster.Namespace,
 		)
-		if i < int(cluster.Spec.Size)-1 {
-			extClientURL += fmt.Sprintf("%s->%s,", key, value)
-		} else {
-			extClientURL += fmt.Sprintf("%s->%s", key, value)
-		}
+		extClientURL[key] = value
 	}
-	cluster.Annotations["extClientURL"] = extClientURL
+	cluster.Annotations[kstonev1alpha2.AnnoExtClientURL] = kstonev1alpha2.FormatExtClientURLs(extClientURL)
 	return nil
 }
 
@@ -308,6 +305,39 @@ func (c *EtcdClusterKstone) Status(config *etcd.ClientConfig, cluster *kstonev1a
 	return status, err
 }
 
+// MemberStartTime returns the creation time of the pod of the member, the pods
+// of kstone-etcd-operator are named after the members
+func (c *EtcdClusterKstone) MemberStartTime(
+	cluster *kstonev1alpha2.EtcdCluster,
+	member *kstonev1alpha2.MemberStatus,
+) (time.Time, error) {
+	pod, err := c.ctx.Clientbuilder.ClientOrDie().CoreV1().Pods(cluster.Namespace).
+		Get(context.TODO(), member.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		klog.Errorf("failed to get pod of member %s, cluster is %s, err is %v", member.Name, cluster.Name, err)
+		return time.Time{}, err
+	}
+	if pod.DeletionTimestamp != nil {
+		return time.Time{}, nil
+	}
+	return pod.CreationTimestamp.Time, nil
+}
+
+// RestartMember deletes the pod of the member, kstone-etcd-operator recreates
+// it with the same name and volume
+func (c *EtcdClusterKstone) RestartMember(cluster *kstonev1alpha2.EtcdCluster, member *kstonev1alpha2.MemberStatus) error {
+	err := c.ctx.Clientbuilder.ClientOrDie().CoreV1().Pods(cluster.Namespace).
+		Delete(context.TODO(), member.Name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		klog.Errorf("failed to delete pod of member %s, cluster is %s, err is %v", member.Name, cluster.Name, err)
+		return err
+	}
+	return nil
+}
+
 // updateEtcdSpec update spec
 func (c *EtcdClusterKstone) updateEtcdSpec(etcd *unstructured.Unstructured, cluster *kstonev1alpha2.EtcdCluster) error {
 	newSpec := c.generateEtcdSpec(cluster)
@@ -419,7 +449,9 @@ func (c *EtcdClusterKstone) generateEtcdSpec(cluster *kstonev1alpha2.EtcdCluster
 		}
 	}
 
-	if cluster.Annotations["repository"] != "" {
+	if cluster.Spec.Repository != "" {
+		spec["repository"] = cluster.Spec.Repository
+	} else if cluster.Annotations["repository"] != "" {
 		spec["repository"] = cluster.Annotations["repository"]
 	}
 
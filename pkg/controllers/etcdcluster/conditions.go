@@ -0,0 +1,197 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package etcdcluster
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+)
+
+// maxHistory is the max number of the operations kept in the history
+const maxHistory = 30
+
+// operationTypes are the types of the operations recorded in the history
+var operationTypes = map[kstonev1alpha2.EtcdClusterConditionType]bool{
+	kstonev1alpha2.EtcdClusterConditionCreate:  true,
+	kstonev1alpha2.EtcdClusterConditionImport:  true,
+	kstonev1alpha2.EtcdClusterConditionUpdate:  true,
+	kstonev1alpha2.EtcdClusterConditionDelete:  true,
+	kstonev1alpha2.EtcdClusterConditionRestore: true,
+	kstonev1alpha2.EtcdClusterConditionBackup:  true,
+	kstonev1alpha2.EtcdClusterConditionRestart: true,
+}
+
+// migrateHistory moves the operations recorded in the conditions by the
+// previous versions of kstone to the history
+func migrateHistory(cluster *kstonev1alpha2.EtcdCluster) {
+	var conditions []kstonev1alpha2.EtcdClusterCondition
+	for _, condition := range cluster.Status.Conditions {
+		if operationTypes[condition.Type] {
+			cluster.Status.History = append(cluster.Status.History, condition)
+		} else {
+			conditions = append(conditions, condition)
+		}
+	}
+	cluster.Status.Conditions = conditions
+}
+
+// appendHistory appends the operation to the history, the oldest operations
+// are dropped if the history is too long
+func appendHistory(
+	history []kstonev1alpha2.EtcdClusterCondition,
+	operation kstonev1alpha2.EtcdClusterCondition,
+) []kstonev1alpha2.EtcdClusterCondition {
+	history = append(history, operation)
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+	return history
+}
+
+// lastOperation returns the latest operation of the history
+func lastOperation(history []kstonev1alpha2.EtcdClusterCondition) *kstonev1alpha2.EtcdClusterCondition {
+	if len(history) == 0 {
+		return nil
+	}
+	return &history[len(history)-1]
+}
+
+// setClusterConditions computes the Ready, Available, Progressing and Degraded
+// conditions from the phase, the members and the history of the cluster
+func setClusterConditions(cluster *kstonev1alpha2.EtcdCluster) {
+	status := &cluster.Status
+
+	running := 0
+	for _, member := range status.Members {
+		if member.Status == kstonev1alpha2.MemberPhaseRunning {
+			running++
+		}
+	}
+	size := int(cluster.Spec.Size)
+	if size == 0 {
+		size = len(status.Members)
+	}
+
+	last := lastOperation(status.History)
+	failed := last != nil && last.Status != corev1.ConditionTrue && last.Reason != ""
+
+	progressing := newCondition(kstonev1alpha2.EtcdClusterConditionProgressing, corev1.ConditionFalse, "Reconciled", "")
+	switch {
+	case status.Phase == kstonev1alpha2.EtcdCluterCreating ||
+		status.Phase == kstonev1alpha2.EtcdClusterUpdating ||
+		status.Phase == kstonev1alpha2.EtcdClusterRestoring:
+		progressing.Status = corev1.ConditionTrue
+		progressing.Reason = string(status.Phase)
+		progressing.Message = fmt.Sprintf("cluster is %s", status.Phase)
+	case last != nil && last.Status != corev1.ConditionTrue && last.EndTime.IsZero():
+		progressing.Status = corev1.ConditionTrue
+		progressing.Reason = string(last.Type) + "InProgress"
+		progressing.Message = fmt.Sprintf("operation %s is in progress", last.Type)
+		if last.Message != "" {
+			progressing.Message = last.Message
+		}
+	case status.ObservedGeneration < cluster.Generation:
+		progressing.Status = corev1.ConditionTrue
+		progressing.Reason = "SpecChanged"
+		progressing.Message = fmt.Sprintf("generation %d is not reconciled yet", cluster.Generation)
+	}
+
+	membersMessage := fmt.Sprintf("%d/%d members are running", running, size)
+	available := newCondition(kstonev1alpha2.EtcdClusterConditionAvailable, corev1.ConditionTrue, "QuorumAvailable", membersMessage)
+	if size == 0 || running < size/2+1 {
+		available.Status = corev1.ConditionFalse
+		available.Reason = "QuorumLost"
+	}
+
+	degraded := newCondition(kstonev1alpha2.EtcdClusterConditionDegraded, corev1.ConditionFalse, "AsExpected", "")
+	switch {
+	case failed:
+		degraded.Status = corev1.ConditionTrue
+		degraded.Reason = string(last.Type) + "Failed"
+		degraded.Message = last.Reason
+	case running < size:
+		degraded.Status = corev1.ConditionTrue
+		degraded.Reason = "MembersNotRunning"
+		degraded.Message = membersMessage
+	case status.Phase == kstonev1alpha2.EtcdClusterUnhealthy || status.Phase == kstonev1alpha2.EtcdClusterUnknown:
+		degraded.Status = corev1.ConditionTrue
+		degraded.Reason = "Cluster" + string(status.Phase)
+		degraded.Message = fmt.Sprintf("cluster phase is %s", status.Phase)
+	}
+
+	ready := newCondition(kstonev1alpha2.EtcdClusterConditionReady, corev1.ConditionTrue, "Ready", membersMessage)
+	switch {
+	case status.Phase != kstonev1alpha2.EtcdClusterRunning:
+		ready.Status = corev1.ConditionFalse
+		ready.Reason = "NotRunning"
+		ready.Message = fmt.Sprintf("cluster phase is %s", status.Phase)
+	case progressing.Status == corev1.ConditionTrue:
+		ready.Status = corev1.ConditionFalse
+		ready.Reason = progressing.Reason
+		ready.Message = progressing.Message
+	case size == 0 || running < size:
+		ready.Status = corev1.ConditionFalse
+		ready.Reason = "MembersNotRunning"
+	}
+
+	for _, condition := range []kstonev1alpha2.EtcdClusterCondition{ready, available, progressing, degraded} {
+		condition.ObservedGeneration = cluster.Generation
+		status.Conditions = setCondition(status.Conditions, condition)
+	}
+}
+
+// newCondition generates the condition
+func newCondition(
+	conditionType kstonev1alpha2.EtcdClusterConditionType,
+	status corev1.ConditionStatus,
+	reason string,
+	message string,
+) kstonev1alpha2.EtcdClusterCondition {
+	return kstonev1alpha2.EtcdClusterCondition{
+		Type:    conditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+}
+
+// setCondition adds or updates the condition, the LastTransitionTime is only
+// updated if the status of the condition changed
+func setCondition(
+	conditions []kstonev1alpha2.EtcdClusterCondition,
+	condition kstonev1alpha2.EtcdClusterCondition,
+) []kstonev1alpha2.EtcdClusterCondition {
+	for i := range conditions {
+		if conditions[i].Type != condition.Type {
+			continue
+		}
+		condition.LastTransitionTime = conditions[i].LastTransitionTime
+		if conditions[i].Status != condition.Status || condition.LastTransitionTime.IsZero() {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		conditions[i] = condition
+		return conditions
+	}
+	condition.LastTransitionTime = metav1.Now()
+	return append(conditions, condition)
+}
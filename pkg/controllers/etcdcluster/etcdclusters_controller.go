@@ -40,6 +40,8 @@ import (
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 
+	"tkestack.io/kstone/pkg/backup"
+	_ "tkestack.io/kstone/pkg/backup/providers" // register backup storage provider
 	"tkestack.io/kstone/pkg/clusterprovider"
 	_ "tkestack.io/kstone/pkg/clusterprovider/providers" // register cluster provider
 	"tkestack.io/kstone/pkg/controllers/util"
@@ -54,8 +56,12 @@ import (
 	platformscheme "tkestack.io/kstone/pkg/generated/clientset/versioned/scheme"
 	informers "tkestack.io/kstone/pkg/generated/informers/externalversions/kstone/v1alpha2"
 	listers "tkestack.io/kstone/pkg/generated/listers/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/notifier"
 )
 
+// featureSweepInterval is the interval to sweep the resources of the deleted clusters
+const featureSweepInterval = 10 * time.Minute
+
 // ClusterController is the controller implementation for EtcdCluster resources
 type ClusterController struct {
 	// kubeclientset is a standard kubernetes clientset
@@ -69,6 +75,9 @@ type ClusterController struct {
 	etcdclusterLister listers.EtcdClusterLister
 	etcdclusterSynced cache.InformerSynced
 
+	etcdrestoreLister listers.EtcdRestoreLister
+	etcdrestoreSynced cache.InformerSynced
+
 	// To allow injection of syncEtcdCluster for testing.
 	syncHandler func(eKey string) error
 
@@ -85,6 +94,9 @@ type ClusterController struct {
 	clientbuilder util.ClientBuilder
 
 	clientConfigGetter etcd.ClientConfigGetter
+
+	// backupSvr records the results of on-demand backups
+	backupSvr *backup.Server
 }
 
 // NewEtcdclusterController returns a new etcdcluster controller
@@ -93,7 +105,8 @@ func NewEtcdclusterController(
 	kubeclientset kubernetes.Interface,
 	platformclientset clientset.Interface,
 	secretInformer informerscorev1.SecretInformer,
-	etcdclusterInformer informers.EtcdClusterInformer) *ClusterController {
+	etcdclusterInformer informers.EtcdClusterInformer,
+	etcdrestoreInformer informers.EtcdRestoreInformer) *ClusterController {
 
 	// Create event broadcaster
 	// Add kstone types to the default Kubernetes Scheme so Events can be
@@ -116,6 +129,8 @@ func NewEtcdclusterController(
 		secretSynced:      secretInformer.Informer().HasSynced,
 		etcdclusterLister: etcdclusterInformer.Lister(),
 		etcdclusterSynced: etcdclusterInformer.Informer().HasSynced,
+		etcdrestoreLister: etcdrestoreInformer.Lister(),
+		etcdrestoreSynced: etcdrestoreInformer.Informer().HasSynced,
 		workqueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "EtcdClusters"),
 		recorder:          recorder,
 	}
@@ -123,13 +138,28 @@ func NewEtcdclusterController(
 	controller.syncHandler = controller.syncEtcdCluster
 	controller.clientConfigGetter = etcd.NewClientConfigSecretCacheGetter(controller.secretLister)
 
+	backupSvr, err := backup.NewBackupServer(clientbuilder)
+	if err != nil {
+		klog.Errorf("failed to init backup server, the results of on-demand backups are not recorded, err is %v", err)
+	}
+	controller.backupSvr = backupSvr
+
 	klog.Info("Setting up event handlers")
 	// Set up an event handler for when EtcdCluster resources change
 	etcdclusterInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: controller.enqueueEtcdcluster,
 		UpdateFunc: func(old, new interface{}) {
+			notifyPhaseChange(old, new)
 			controller.enqueueEtcdcluster(new)
 		},
+		DeleteFunc: controller.handleEtcdclusterDelete,
+	})
+	// Record the progress of the EtcdRestores on their target clusters
+	etcdrestoreInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueRestoreTarget,
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueRestoreTarget(new)
+		},
 	})
 
 	return controller
@@ -148,7 +178,7 @@ func (c *ClusterController) Run(threadiness int, stopCh <-chan struct{}) error {
 
 	// Wait for the caches to be synced before starting workers
 	klog.Info("Waiting for informer caches to sync")
-	if ok := cache.WaitForCacheSync(stopCh, c.secretSynced, c.etcdclusterSynced); !ok {
+	if ok := cache.WaitForCacheSync(stopCh, c.secretSynced, c.etcdclusterSynced, c.etcdrestoreSynced); !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
 
@@ -157,6 +187,7 @@ func (c *ClusterController) Run(threadiness int, stopCh <-chan struct{}) error {
 	for i := 0; i < threadiness; i++ {
 		go wait.Until(c.runWorker, time.Second, stopCh)
 	}
+	go wait.Until(c.sweepFeatureResources, featureSweepInterval, stopCh)
 
 	klog.Info("Started workers")
 	<-stopCh
@@ -218,7 +249,9 @@ func (c *ClusterController) syncEtcdCluster(key string) error {
 	return c.reconcileEtcdCluster(etcdcluster.DeepCopy())
 }
 
-// updateEtcdClusterStatus
+// updateEtcdClusterStatus updates the Status block of the EtcdCluster resource
+// through the status subresource, the standard conditions are computed before
+// updated, the changes of the metadata and the spec are ignored by apiserver.
 func (c *ClusterController) updateEtcdClusterStatus(cluster *kstonev1alpha2.EtcdCluster) (
 	*kstonev1alpha2.EtcdCluster,
 	error,
@@ -227,10 +260,21 @@ func (c *ClusterController) updateEtcdClusterStatus(cluster *kstonev1alpha2.Etcd
 	// You can use DeepCopy() to make a deep copy of original object and modify this copy
 	// Or create a copy manually for better performance
 	// clusterCopy := cluster.DeepCopy()
-	// If the CustomResourceSubresources feature gate is not enabled,
-	// we must use Update instead of UpdateStatus to update the Status block of the EtcdCluster resource.
-	// UpdateStatus will not allow changes to the Spec of the resource,
-	// which is ideal for ensuring nothing other than resource status has been updated.
+	setClusterConditions(cluster)
+	etcdcluster, err := c.platformclientset.KstoneV1alpha2().EtcdClusters(cluster.Namespace).
+		UpdateStatus(context.TODO(), cluster, metav1.UpdateOptions{})
+	if err != nil {
+		return cluster, err
+	}
+	return etcdcluster, nil
+}
+
+// updateEtcdCluster updates the metadata and the spec of the EtcdCluster
+// resource, the changes of the status are ignored by apiserver.
+func (c *ClusterController) updateEtcdCluster(cluster *kstonev1alpha2.EtcdCluster) (
+	*kstonev1alpha2.EtcdCluster,
+	error,
+) {
 	etcdcluster, err := c.platformclientset.KstoneV1alpha2().EtcdClusters(cluster.Namespace).
 		Update(context.TODO(), cluster, metav1.UpdateOptions{})
 	if err != nil {
@@ -239,6 +283,25 @@ func (c *ClusterController) updateEtcdClusterStatus(cluster *kstonev1alpha2.Etcd
 	return etcdcluster, nil
 }
 
+// saveClusterMetadata saves the annotations and the labels of the cluster if
+// they are changed from origin, the status of the cluster is kept for the
+// following status update
+func (c *ClusterController) saveClusterMetadata(
+	cluster *kstonev1alpha2.EtcdCluster,
+	origin *metav1.ObjectMeta,
+) (*kstonev1alpha2.EtcdCluster, error) {
+	if reflect.DeepEqual(cluster.Annotations, origin.Annotations) && reflect.DeepEqual(cluster.Labels, origin.Labels) {
+		return cluster, nil
+	}
+	status := cluster.Status.DeepCopy()
+	saved, err := c.updateEtcdCluster(cluster)
+	if err != nil {
+		return cluster, err
+	}
+	saved.Status = *status
+	return saved, nil
+}
+
 // enqueueEtcdcluster takes a EtcdCluster resource and converts it into a namespace/name
 // string which is then put onto the work queue. This method should *not* be
 // passed resources of any type other than EtcdCluster.
@@ -252,10 +315,111 @@ func (c *ClusterController) enqueueEtcdcluster(obj interface{}) {
 	c.workqueue.Add(key)
 }
 
+// notifyPhaseChange notifies the transition of the phase of the EtcdCluster
+func notifyPhaseChange(old, new interface{}) {
+	oldCluster, ok := old.(*kstonev1alpha2.EtcdCluster)
+	if !ok {
+		return
+	}
+	newCluster, ok := new.(*kstonev1alpha2.EtcdCluster)
+	if !ok {
+		return
+	}
+	// the members are unhealthy in turn during a rolling restart, the phase
+	// changes are not notified until the restart finished, then the phase is
+	// compared with the running phase the restart began with
+	if isClusterRestarting(newCluster) {
+		return
+	}
+	oldPhase, newPhase := oldCluster.Status.Phase, newCluster.Status.Phase
+	if isClusterRestarting(oldCluster) {
+		oldPhase = kstonev1alpha2.EtcdClusterRunning
+	}
+	if oldPhase == newPhase || oldPhase == "" {
+		return
+	}
+
+	severity := notifier.SeverityInfo
+	switch newPhase {
+	case kstonev1alpha2.EtcdClusterUnhealthy, kstonev1alpha2.EtcdClusterUnknown:
+		severity = notifier.SeverityCritical
+	}
+	notifier.Notify(&notifier.Event{
+		Namespace: newCluster.Namespace,
+		Cluster:   newCluster.Name,
+		Reason:    notifier.ReasonPhaseChanged,
+		Severity:  severity,
+		Subject:   fmt.Sprintf("%s->%s", oldPhase, newPhase),
+		Message:   fmt.Sprintf("phase changed from %s to %s", oldPhase, newPhase),
+	})
+}
+
+// handleEtcdclusterDelete cleans the resources of the deleted EtcdCluster
+// which are not garbage collected by the owner references.
+func (c *ClusterController) handleEtcdclusterDelete(obj interface{}) {
+	cluster, ok := obj.(*kstonev1alpha2.EtcdCluster)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("couldn't get object from tombstone %#v", obj))
+			return
+		}
+		cluster, ok = tombstone.Obj.(*kstonev1alpha2.EtcdCluster)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("tombstone contained object that is not a EtcdCluster %#v", obj))
+			return
+		}
+	}
+
+	for name := range featureprovider.EtcdFeatureProviders {
+		feature, err := c.GetFeatureProvider(name)
+		if err != nil {
+			klog.Errorf("failed to get feature %s provider,err is %v", name, err)
+			continue
+		}
+		cleaner, ok := feature.(featureprovider.FeatureCleaner)
+		if !ok {
+			continue
+		}
+		if err = cleaner.Clean(cluster); err != nil {
+			klog.Errorf("failed to clean %s feature, err is %v, cluster is %s", name, err, cluster.Name)
+		}
+	}
+}
+
+// sweepFeatureResources cleans the resources of the clusters whose deletion is
+// missed by handleEtcdclusterDelete, e.g. the controller was not running.
+func (c *ClusterController) sweepFeatureResources() {
+	exists := func(namespace, name string) (bool, error) {
+		_, err := c.etcdclusterLister.EtcdClusters(namespace).Get(name)
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	for name := range featureprovider.EtcdFeatureProviders {
+		feature, err := c.GetFeatureProvider(name)
+		if err != nil {
+			klog.Errorf("failed to get feature %s provider,err is %v", name, err)
+			continue
+		}
+		cleaner, ok := feature.(featureprovider.FeatureCleaner)
+		if !ok {
+			continue
+		}
+		if err = cleaner.Sweep(exists); err != nil {
+			klog.Errorf("failed to sweep %s feature, err is %v", name, err)
+		}
+	}
+}
+
 func (c *ClusterController) handleClusterManagement(cluster *kstonev1alpha2.EtcdCluster) (
 	*kstonev1alpha2.EtcdCluster,
 	error,
 ) {
+	migrateHistory(cluster)
+
 	// Get cluster provider
 	provider, err := c.GetEtcdClusterProvider(cluster.Spec.ClusterType)
 	if err != nil {
@@ -267,6 +431,9 @@ func (c *ClusterController) handleClusterManagement(cluster *kstonev1alpha2.Etcd
 	if err != nil {
 		return cluster, err
 	}
+	// the provider hooks may set the annotations and the labels, e.g. the
+	// address of the created cluster, which are not saved by the status update
+	origin := cluster.ObjectMeta.DeepCopy()
 	switch nextAction {
 	case kstonev1alpha2.EtcdCluterCreating:
 		cluster, err = c.handleClusterCreate(cluster, provider)
@@ -275,7 +442,22 @@ func (c *ClusterController) handleClusterManagement(cluster *kstonev1alpha2.Etcd
 	default:
 		cluster, err = c.handleClusterStatus(cluster, provider)
 	}
-	_, _ = c.updateEtcdClusterStatus(cluster)
+	if err == nil {
+		cluster.Status.ObservedGeneration = cluster.Generation
+	}
+	if saved, sErr := c.saveClusterMetadata(cluster, origin); sErr == nil {
+		cluster = saved
+	} else {
+		klog.Errorf("failed to save cluster metadata, err is %v, cluster is %s", sErr, cluster.Name)
+		if err == nil {
+			err = sErr
+		}
+	}
+	if updated, uErr := c.updateEtcdClusterStatus(cluster); uErr == nil {
+		cluster = updated
+	} else {
+		klog.Errorf("failed to update cluster status, err is %v, cluster is %s", uErr, cluster.Name)
+	}
 	if err != nil {
 		c.recorder.Eventf(
 			cluster,
@@ -315,7 +497,7 @@ func (c *ClusterController) handleClusterLabels(
 	labels["version"] = cluster.Spec.Version
 	if !reflect.DeepEqual(cluster.ObjectMeta.Labels, labels) {
 		cluster.ObjectMeta.Labels = labels
-		return c.updateEtcdClusterStatus(cluster)
+		return c.updateEtcdCluster(cluster)
 	}
 	return cluster, nil
 }
@@ -371,12 +553,55 @@ func (c *ClusterController) reconcileEtcdCluster(cluster *kstonev1alpha2.EtcdClu
 		return err
 	}
 
+	provider, err := c.GetEtcdClusterProvider(cluster.Spec.ClusterType)
+	if err != nil {
+		klog.Errorf("failed to get cluster provider, err is %v, cluster is %s", err, cluster.Name)
+		return err
+	}
+
 	// If cluster is not running, do not proceed to the next step
-	if cluster.Status.Phase != kstonev1alpha2.EtcdClusterRunning {
+	if cluster.Status.Phase != kstonev1alpha2.EtcdClusterRunning &&
+		cluster.Status.Phase != kstonev1alpha2.EtcdClusterRestoring {
+		// the restarted member is unhealthy, an in-progress rolling restart
+		// goes on until it finished or timed out
+		if isClusterRestarting(cluster) {
+			_, err = c.handleClusterRestart(cluster, provider)
+			if err != nil {
+				klog.Errorf("failed to handle cluster restart, err is %v, cluster is %s", err, cluster.Name)
+			}
+			return err
+		}
 		klog.Warningf("cluster %s is not ready", cluster.Name)
 		return nil
 	}
 
+	// Handle cluster restore
+	cluster, err = c.handleClusterRestore(cluster)
+	if err != nil {
+		klog.Errorf("failed to handle cluster restore, err is %v, cluster is %s", err, cluster.Name)
+		return err
+	}
+
+	// A restoring cluster only records the progress of the restore
+	if cluster.Status.Phase == kstonev1alpha2.EtcdClusterRestoring {
+		klog.V(3).Infof("cluster %s is restoring", cluster.Name)
+		return nil
+	}
+
+	// Handle rolling restart
+	cluster, err = c.handleClusterRestart(cluster, provider)
+	if err != nil {
+		klog.Errorf("failed to handle cluster restart, err is %v, cluster is %s", err, cluster.Name)
+		return err
+	}
+
+	// Handle on-demand backups
+	cluster, err = c.handleClusterOnDemandBackup(cluster)
+	if err != nil {
+		klog.Errorf("failed to handle on-demand backup, err is %v, cluster is %s", err, cluster.Name)
+		return err
+	}
+
 	// Handle cluster labels
 	cluster, err = c.handleClusterLabels(cluster)
 	if err != nil {
@@ -421,13 +646,11 @@ func (c *ClusterController) getDesiredAction(
 	cluster *kstonev1alpha2.EtcdCluster,
 	provider clusterprovider.Cluster,
 ) (kstonev1alpha2.EtcdClusterPhase, error) {
-	if len(cluster.Status.Conditions) == 0 {
+	lastCondition := lastOperation(cluster.Status.History)
+	if lastCondition == nil {
 		return kstonev1alpha2.EtcdCluterCreating, nil
 	}
 
-	conditionIndex := len(cluster.Status.Conditions) - 1
-	lastCondition := cluster.Status.Conditions[conditionIndex]
-
 	switch lastCondition.Type {
 	case kstonev1alpha2.EtcdClusterConditionCreate:
 		if lastCondition.Status == corev1.ConditionFalse {
@@ -437,6 +660,11 @@ func (c *ClusterController) getDesiredAction(
 		if lastCondition.Status == corev1.ConditionFalse {
 			return kstonev1alpha2.EtcdClusterUpdating, nil
 		}
+	case kstonev1alpha2.EtcdClusterConditionRestart:
+		// the spec changes are applied after the rolling restart finished
+		if lastCondition.EndTime.IsZero() {
+			return kstonev1alpha2.EtcdClusterRunning, nil
+		}
 	}
 
 	equal, err := provider.Equal(cluster)
@@ -452,7 +680,8 @@ func (c *ClusterController) getDesiredAction(
 	return kstonev1alpha2.EtcdClusterRunning, nil
 }
 
-func (c *ClusterController) generateConditions(
+// generateHistory starts the next operation in the history
+func (c *ClusterController) generateHistory(
 	conditions []kstonev1alpha2.EtcdClusterCondition,
 	phase kstonev1alpha2.EtcdClusterPhase,
 	nextConditionType kstonev1alpha2.EtcdClusterConditionType,
@@ -460,7 +689,12 @@ func (c *ClusterController) generateConditions(
 	conditionIndex := len(conditions) - 1
 	if conditionIndex >= 0 {
 		lastCondition := conditions[conditionIndex]
-		if lastCondition.Status != corev1.ConditionTrue {
+		// a failed restore, backup or restart is finished and is not retried,
+		// so it does not block the following operations
+		if lastCondition.Status != corev1.ConditionTrue &&
+			lastCondition.Type != kstonev1alpha2.EtcdClusterConditionRestore &&
+			lastCondition.Type != kstonev1alpha2.EtcdClusterConditionBackup &&
+			!(lastCondition.Type == kstonev1alpha2.EtcdClusterConditionRestart && !lastCondition.EndTime.IsZero()) {
 			return conditions
 		}
 
@@ -469,52 +703,50 @@ func (c *ClusterController) generateConditions(
 		}
 	}
 
-	conditions = append(conditions, kstonev1alpha2.EtcdClusterCondition{
+	return appendHistory(conditions, kstonev1alpha2.EtcdClusterCondition{
 		Type:      nextConditionType,
 		Status:    corev1.ConditionFalse,
 		StartTime: metav1.Now(),
 	})
-
-	return conditions
 }
 
 func (c *ClusterController) handleClusterCreate(
 	cluster *kstonev1alpha2.EtcdCluster,
 	provider clusterprovider.Cluster,
 ) (*kstonev1alpha2.EtcdCluster, error) {
-	cluster.Status.Conditions = c.generateConditions(
-		cluster.Status.Conditions,
+	cluster.Status.History = c.generateHistory(
+		cluster.Status.History,
 		cluster.Status.Phase,
 		kstonev1alpha2.EtcdClusterConditionCreate,
 	)
 	cluster.Status.Phase = kstonev1alpha2.EtcdCluterCreating
 
-	conditionIndex := len(cluster.Status.Conditions) - 1
+	conditionIndex := len(cluster.Status.History) - 1
 
 	err := provider.BeforeCreate(cluster)
 	if err != nil {
 		klog.Errorf("failed to do something before create, err is %v, cluster is %s", err, cluster.Name)
-		cluster.Status.Conditions[conditionIndex].Reason = err.Error()
+		cluster.Status.History[conditionIndex].Reason = err.Error()
 		return cluster, err
 	}
 
 	err = provider.Create(cluster)
 	if err != nil {
 		klog.Errorf("failed to create, err is %v, cluster is %s", err, cluster.Name)
-		cluster.Status.Conditions[conditionIndex].Reason = err.Error()
+		cluster.Status.History[conditionIndex].Reason = err.Error()
 		return cluster, err
 	}
 
 	err = provider.AfterCreate(cluster)
 	if err != nil {
 		klog.Errorf("failed to do something after create, err is %v, cluster is %s", err, cluster.Name)
-		cluster.Status.Conditions[conditionIndex].Reason = err.Error()
+		cluster.Status.History[conditionIndex].Reason = err.Error()
 		return cluster, err
 	}
 
-	cluster.Status.Conditions[conditionIndex].Reason = ""
-	cluster.Status.Conditions[conditionIndex].EndTime = metav1.Now()
-	cluster.Status.Conditions[conditionIndex].Status = corev1.ConditionTrue
+	cluster.Status.History[conditionIndex].Reason = ""
+	cluster.Status.History[conditionIndex].EndTime = metav1.Now()
+	cluster.Status.History[conditionIndex].Status = corev1.ConditionTrue
 	return cluster, nil
 }
 
@@ -522,41 +754,117 @@ func (c *ClusterController) handleClusterUpdate(
 	cluster *kstonev1alpha2.EtcdCluster,
 	provider clusterprovider.Cluster,
 ) (*kstonev1alpha2.EtcdCluster, error) {
-	cluster.Status.Conditions = c.generateConditions(
-		cluster.Status.Conditions,
+	cluster.Status.History = c.generateHistory(
+		cluster.Status.History,
 		cluster.Status.Phase,
 		kstonev1alpha2.EtcdClusterConditionUpdate,
 	)
 	cluster.Status.Phase = kstonev1alpha2.EtcdClusterUpdating
-	conditionIndex := len(cluster.Status.Conditions) - 1
+	conditionIndex := len(cluster.Status.History) - 1
 
 	err := provider.BeforeUpdate(cluster)
 	if err != nil {
 		klog.Errorf("failed to do something before update, err is %v, cluster is %s", err, cluster.Name)
-		cluster.Status.Conditions[conditionIndex].Reason = err.Error()
+		cluster.Status.History[conditionIndex].Reason = err.Error()
 		return cluster, err
 	}
 
 	err = provider.Update(cluster)
 	if err != nil {
 		klog.Errorf("failed to update, err is %v, cluster is %s", err, cluster.Name)
-		cluster.Status.Conditions[conditionIndex].Reason = err.Error()
+		cluster.Status.History[conditionIndex].Reason = err.Error()
 		return cluster, err
 	}
 
 	err = provider.AfterUpdate(cluster)
 	if err != nil {
 		klog.Errorf("failed to do something after update, err is %v, cluster is %s", err, cluster.Name)
-		cluster.Status.Conditions[conditionIndex].Reason = err.Error()
+		cluster.Status.History[conditionIndex].Reason = err.Error()
 		return cluster, err
 	}
 
-	cluster.Status.Conditions[conditionIndex].Reason = ""
-	cluster.Status.Conditions[conditionIndex].EndTime = metav1.Now()
-	cluster.Status.Conditions[conditionIndex].Status = corev1.ConditionTrue
+	cluster.Status.History[conditionIndex].Reason = ""
+	cluster.Status.History[conditionIndex].EndTime = metav1.Now()
+	cluster.Status.History[conditionIndex].Status = corev1.ConditionTrue
 	return cluster, nil
 }
 
+// handleClusterOnDemandBackup records the results of the finished on-demand
+// backups as Backup conditions of the cluster.
+func (c *ClusterController) handleClusterOnDemandBackup(cluster *kstonev1alpha2.EtcdCluster) (
+	*kstonev1alpha2.EtcdCluster,
+	error,
+) {
+	if c.backupSvr == nil {
+		return cluster, nil
+	}
+	if _, found := cluster.Annotations[backup.AnnoBackupConfig]; !found {
+		return cluster, nil
+	}
+
+	backups, err := c.backupSvr.ListOnDemandBackups(cluster)
+	if err != nil {
+		return cluster, err
+	}
+	for _, eb := range backups {
+		if backup.IsOnDemandBackupRecorded(eb) {
+			continue
+		}
+		status := backup.NewOnDemandBackupStatus(eb)
+		if status.Phase == backup.OnDemandBackupRunning {
+			continue
+		}
+
+		condition := kstonev1alpha2.EtcdClusterCondition{
+			Type:      kstonev1alpha2.EtcdClusterConditionBackup,
+			Status:    corev1.ConditionTrue,
+			StartTime: status.CreatedTime,
+			EndTime:   metav1.Now(),
+		}
+		if status.Phase == backup.OnDemandBackupSucceeded {
+			condition.EndTime = *status.FinishedTime
+			condition.Message = fmt.Sprintf(
+				"on-demand backup %s saved revision %d to %s",
+				status.Name,
+				status.Revision,
+				status.Path,
+			)
+			c.recorder.Event(cluster, corev1.EventTypeNormal, string(kstonev1alpha2.EtcdClusterConditionBackup), condition.Message)
+		} else {
+			condition.Status = corev1.ConditionFalse
+			condition.Reason = status.Reason
+			condition.Message = fmt.Sprintf("on-demand backup %s failed", status.Name)
+			c.recorder.Eventf(
+				cluster,
+				corev1.EventTypeWarning,
+				string(kstonev1alpha2.EtcdClusterConditionBackup),
+				"on-demand backup %s failed, err is %s",
+				status.Name,
+				status.Reason,
+			)
+		}
+
+		// consecutive backup operations are merged to keep the history short
+		history := cluster.Status.History
+		if n := len(history); n > 0 && history[n-1].Type == kstonev1alpha2.EtcdClusterConditionBackup {
+			history = history[:n-1]
+		}
+		cluster.Status.History = appendHistory(history, condition)
+		cluster, err = c.updateEtcdClusterStatus(cluster)
+		if err != nil {
+			klog.Errorf("failed to update cluster status, err is %v, cluster is %s", err, cluster.Name)
+			return cluster, err
+		}
+
+		err = c.backupSvr.MarkOnDemandBackupRecorded(eb)
+		if err != nil {
+			klog.Errorf("failed to mark on-demand backup %s recorded, err is %v", eb.Name, err)
+			return cluster, err
+		}
+	}
+	return cluster, c.backupSvr.CleanOnDemandBackups(backups)
+}
+
 // handleClusterStatus checks the status, if equal, updates status
 // if not equal, updates etcdclusters.etcd.tkestack.io
 func (c *ClusterController) handleClusterStatus(
@@ -577,6 +885,10 @@ func (c *ClusterController) handleClusterStatus(
 		return cluster, err
 	}
 
+	// the providers do not know the restoring phase, it is running for them
+	if cluster.Status.Phase == kstonev1alpha2.EtcdClusterRestoring {
+		cluster.Status.Phase = kstonev1alpha2.EtcdClusterRunning
+	}
 	status, err := provider.Status(clientConfig, cluster)
 	if err != nil {
 		c.recorder.Eventf(
@@ -588,6 +900,9 @@ func (c *ClusterController) handleClusterStatus(
 		)
 	}
 	cluster.Status = status
+	if cluster.Status.Phase == kstonev1alpha2.EtcdClusterRunning && c.isClusterRestoring(cluster) {
+		cluster.Status.Phase = kstonev1alpha2.EtcdClusterRestoring
+	}
 
 	return cluster, nil
 }
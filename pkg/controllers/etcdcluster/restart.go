@@ -0,0 +1,323 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package etcdcluster
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/clusterprovider"
+	"tkestack.io/kstone/pkg/controllers/util"
+	"tkestack.io/kstone/pkg/etcd"
+	featureutil "tkestack.io/kstone/pkg/featureprovider/util"
+)
+
+const (
+	// restartCheckInterval is the interval of checking the progress of the rolling restart
+	restartCheckInterval = 5 * time.Second
+	// restartTimeout is the max duration of the rolling restart
+	restartTimeout = 30 * time.Minute
+	// restartMaxAppliedIndexLag is the max lag of the applied index of a member
+	// behind the leader, the member is caught up if its lag is less than it
+	restartMaxAppliedIndexLag = 100
+)
+
+// handleClusterRestart restarts the members one by one when the restart
+// annotation is set. The followers are restarted first, the leadership is
+// moved away from the leader before it is restarted. The next member is only
+// restarted when all the members are healthy and caught up with the leader.
+// One step is performed in each reconcile, the members restarted are the ones
+// started after the restart operation began.
+func (c *ClusterController) handleClusterRestart(
+	cluster *kstonev1alpha2.EtcdCluster,
+	provider clusterprovider.Cluster,
+) (*kstonev1alpha2.EtcdCluster, error) {
+	last := lastOperation(cluster.Status.History)
+	inProgress := isClusterRestarting(cluster)
+	if _, found := cluster.Annotations[kstonev1alpha2.AnnoRestart]; !found && !inProgress {
+		return cluster, nil
+	}
+
+	if !inProgress {
+		return c.startClusterRestart(cluster)
+	}
+
+	defer c.enqueueEtcdclusterAfter(cluster, restartCheckInterval)
+	conditionIndex := len(cluster.Status.History) - 1
+	message, done, err := c.restartNextMember(cluster, provider, last.StartTime.Time)
+	if err == nil && !done && time.Since(last.StartTime.Time) > restartTimeout {
+		err = fmt.Errorf("rolling restart timed out after %s, %s", restartTimeout, message)
+	}
+	switch {
+	case err != nil:
+		klog.Errorf("failed to restart, err is %v, cluster is %s", err, cluster.Name)
+		cluster.Status.History[conditionIndex].Reason = err.Error()
+		cluster.Status.History[conditionIndex].EndTime = metav1.Now()
+		c.recorder.Eventf(
+			cluster,
+			corev1.EventTypeWarning,
+			string(kstonev1alpha2.EtcdClusterConditionRestart),
+			"failed to restart cluster, err is %v",
+			err,
+		)
+	case done:
+		cluster.Status.History[conditionIndex].Status = corev1.ConditionTrue
+		cluster.Status.History[conditionIndex].Message = message
+		cluster.Status.History[conditionIndex].EndTime = metav1.Now()
+		c.recorder.Event(cluster, corev1.EventTypeNormal, string(kstonev1alpha2.EtcdClusterConditionRestart), message)
+	case message != cluster.Status.History[conditionIndex].Message:
+		cluster.Status.History[conditionIndex].Message = message
+		c.recorder.Event(cluster, corev1.EventTypeNormal, string(kstonev1alpha2.EtcdClusterConditionRestart), message)
+	default:
+		return cluster, nil
+	}
+	return c.updateEtcdClusterStatus(cluster)
+}
+
+// isClusterRestarting checks if a rolling restart of the cluster is in progress
+func isClusterRestarting(cluster *kstonev1alpha2.EtcdCluster) bool {
+	last := lastOperation(cluster.Status.History)
+	return last != nil && last.Type == kstonev1alpha2.EtcdClusterConditionRestart && last.EndTime.IsZero()
+}
+
+// startClusterRestart records the restart operation in the history and removes
+// the restart annotation, the restart is never repeated even if the status
+// failed to be updated
+func (c *ClusterController) startClusterRestart(cluster *kstonev1alpha2.EtcdCluster) (
+	*kstonev1alpha2.EtcdCluster,
+	error,
+) {
+	cluster.Status.History = c.generateHistory(
+		cluster.Status.History,
+		cluster.Status.Phase,
+		kstonev1alpha2.EtcdClusterConditionRestart,
+	)
+	last := lastOperation(cluster.Status.History)
+	if last.Type != kstonev1alpha2.EtcdClusterConditionRestart {
+		// another operation is in progress, the restart starts after it finished
+		return cluster, nil
+	}
+	last.Message = "rolling restart is requested"
+
+	status := cluster.Status.DeepCopy()
+	delete(cluster.Annotations, kstonev1alpha2.AnnoRestart)
+	cluster, err := c.updateEtcdCluster(cluster)
+	if err != nil {
+		klog.Errorf("failed to remove restart annotation, err is %v, cluster is %s", err, cluster.Name)
+		return cluster, err
+	}
+	cluster.Status = *status
+	c.enqueueEtcdclusterAfter(cluster, restartCheckInterval)
+	return c.updateEtcdClusterStatus(cluster)
+}
+
+// restartNextMember restarts the next member if all the members are ready,
+// returns the progress of the rolling restart, done is true if all the
+// members have been restarted after startTime
+func (c *ClusterController) restartNextMember(
+	cluster *kstonev1alpha2.EtcdCluster,
+	provider clusterprovider.Cluster,
+	startTime time.Time,
+) (message string, done bool, err error) {
+	members := cluster.Status.Members
+	total := len(members)
+	if total == 0 {
+		return "", false, fmt.Errorf("no member found")
+	}
+
+	for _, m := range members {
+		if m.Status != kstonev1alpha2.MemberPhaseRunning {
+			return fmt.Sprintf("waiting for member %s to be healthy", m.Name), false, nil
+		}
+	}
+
+	// the followers are restarted before the leader
+	var next, leader *kstonev1alpha2.MemberStatus
+	restarted := 0
+	for i := range members {
+		m := &members[i]
+		if m.Role == kstonev1alpha2.EtcdMemberLeader {
+			leader = m
+		}
+		started, err := provider.MemberStartTime(cluster, m)
+		if err != nil {
+			return "", false, err
+		}
+		if started.IsZero() {
+			return fmt.Sprintf("waiting for member %s to restart, %d/%d members restarted", m.Name, restarted, total), false, nil
+		}
+		// the pods are created in seconds, a pod created in the same second
+		// as the restart began is regarded as not restarted
+		if started.After(startTime) {
+			restarted++
+			continue
+		}
+		if next == nil || next.Role == kstonev1alpha2.EtcdMemberLeader {
+			next = m
+		}
+	}
+
+	appliedIndex, err := c.getMembersAppliedIndex(cluster)
+	if err != nil {
+		return fmt.Sprintf("waiting for members to be reachable, %d/%d members restarted", restarted, total), false, nil
+	}
+	var leaderIndex uint64
+	if leader != nil {
+		leaderIndex = appliedIndex[leader.Name]
+	}
+	for _, m := range members {
+		if leaderIndex > appliedIndex[m.Name]+restartMaxAppliedIndexLag {
+			return fmt.Sprintf("waiting for member %s to catch up with the leader", m.Name), false, nil
+		}
+	}
+
+	if next == nil {
+		return fmt.Sprintf("%d/%d members restarted", restarted, total), true, nil
+	}
+	// wait a second before the first restart, so that the restarted pods
+	// are always created in a later second than the restart began
+	if time.Since(startTime) < time.Second {
+		return fmt.Sprintf("%d/%d members restarted", restarted, total), false, nil
+	}
+
+	if next.Role == kstonev1alpha2.EtcdMemberLeader && total > 1 {
+		transferee := pickTransferee(members, next, appliedIndex)
+		if transferee == nil {
+			return fmt.Sprintf("waiting for a follower to take over leader %s", next.Name), false, nil
+		}
+		err = c.moveLeader(cluster, next, transferee)
+		if err != nil {
+			klog.Errorf("failed to move leader, err is %v, cluster is %s", err, cluster.Name)
+			return fmt.Sprintf("moving leadership from %s to %s", next.Name, transferee.Name), false, nil
+		}
+		return fmt.Sprintf("moved leadership from %s to %s", next.Name, transferee.Name), false, nil
+	}
+
+	err = provider.RestartMember(cluster, next)
+	if err != nil {
+		return "", false, err
+	}
+	return fmt.Sprintf("restarting member %s, %d/%d members restarted", next.Name, restarted, total), false, nil
+}
+
+// pickTransferee picks the follower with the highest applied index
+func pickTransferee(
+	members []kstonev1alpha2.MemberStatus,
+	leader *kstonev1alpha2.MemberStatus,
+	appliedIndex map[string]uint64,
+) *kstonev1alpha2.MemberStatus {
+	var transferee *kstonev1alpha2.MemberStatus
+	for i := range members {
+		m := &members[i]
+		if m.Name == leader.Name || m.Role != kstonev1alpha2.EtcdMemberFollower {
+			continue
+		}
+		if transferee == nil || appliedIndex[m.Name] > appliedIndex[transferee.Name] {
+			transferee = m
+		}
+	}
+	return transferee
+}
+
+// getMembersAppliedIndex gets the raft applied index of the members, the
+// applied index of v2 members is always 0
+func (c *ClusterController) getMembersAppliedIndex(cluster *kstonev1alpha2.EtcdCluster) (map[string]uint64, error) {
+	appliedIndex := make(map[string]uint64, len(cluster.Status.Members))
+	for _, m := range cluster.Status.Members {
+		backendStorage := etcd.EtcdV3Backend
+		if strings.HasPrefix(m.Version, "2") {
+			backendStorage = etcd.EtcdV2Backend
+		}
+		backend, err := etcd.NewEtcdStatBackend(backendStorage)
+		if err != nil {
+			return nil, err
+		}
+
+		clientConfig, err := c.newClientConfig(cluster)
+		if err != nil {
+			return nil, err
+		}
+		clientConfig.Endpoints = []string{m.ExtensionClientUrl}
+		err = backend.Init(clientConfig)
+		if err != nil {
+			klog.Errorf("failed to init etcd client, endpoint is %s, err is %v", m.ExtensionClientUrl, err)
+			return nil, err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), etcd.DefaultDialTimeout)
+		metadata, err := backend.GetIndex(ctx, m.ExtensionClientUrl)
+		cancel()
+		_ = backend.Close()
+		if err != nil {
+			return nil, err
+		}
+		appliedIndex[m.Name] = metadata[featureutil.ConsistencyRaftRaftAppliedIndex]
+	}
+	return appliedIndex, nil
+}
+
+// moveLeader transfers the leadership from leader to transferee
+func (c *ClusterController) moveLeader(
+	cluster *kstonev1alpha2.EtcdCluster,
+	leader *kstonev1alpha2.MemberStatus,
+	transferee *kstonev1alpha2.MemberStatus,
+) error {
+	id, err := strconv.ParseUint(transferee.MemberId, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid member id %s, err is %v", transferee.MemberId, err)
+	}
+
+	clientConfig, err := c.newClientConfig(cluster)
+	if err != nil {
+		return err
+	}
+	clientConfig.Endpoints = []string{leader.ExtensionClientUrl}
+	cli, err := etcd.NewClientv3(clientConfig)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	return etcd.MoveLeader(cli, id)
+}
+
+// newClientConfig generates the etcd client config of the cluster
+func (c *ClusterController) newClientConfig(cluster *kstonev1alpha2.EtcdCluster) (*etcd.ClientConfig, error) {
+	path := fmt.Sprintf("%s/%s", cluster.Namespace, cluster.Name)
+	return c.clientConfigGetter.New(path, cluster.Annotations[util.ClusterTLSSecretName])
+}
+
+// enqueueEtcdclusterAfter requeues the cluster after the duration
+func (c *ClusterController) enqueueEtcdclusterAfter(cluster *kstonev1alpha2.EtcdCluster, duration time.Duration) {
+	key, err := cache.MetaNamespaceKeyFunc(cluster)
+	if err != nil {
+		klog.Errorf("failed to get key of cluster %s, err is %v", cluster.Name, err)
+		return
+	}
+	c.workqueue.AddAfter(key, duration)
+}
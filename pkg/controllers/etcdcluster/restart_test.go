@@ -0,0 +1,74 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package etcdcluster
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+)
+
+func TestIsClusterRestarting(t *testing.T) {
+	operation := func(conditionType kstonev1alpha2.EtcdClusterConditionType, ended bool) kstonev1alpha2.EtcdClusterCondition {
+		condition := kstonev1alpha2.EtcdClusterCondition{Type: conditionType, StartTime: metav1.Now()}
+		if ended {
+			condition.EndTime = metav1.Now()
+		}
+		return condition
+	}
+	tests := []struct {
+		name    string
+		history []kstonev1alpha2.EtcdClusterCondition
+		want    bool
+	}{
+		{
+			name:    "no history",
+			history: nil,
+			want:    false,
+		},
+		{
+			name:    "restart in progress",
+			history: []kstonev1alpha2.EtcdClusterCondition{operation(kstonev1alpha2.EtcdClusterConditionRestart, false)},
+			want:    true,
+		},
+		{
+			name:    "restart finished or timed out",
+			history: []kstonev1alpha2.EtcdClusterCondition{operation(kstonev1alpha2.EtcdClusterConditionRestart, true)},
+			want:    false,
+		},
+		{
+			name: "another operation after the restart",
+			history: []kstonev1alpha2.EtcdClusterCondition{
+				operation(kstonev1alpha2.EtcdClusterConditionRestart, true),
+				operation(kstonev1alpha2.EtcdClusterConditionUpdate, false),
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &kstonev1alpha2.EtcdCluster{Status: kstonev1alpha2.EtcdClusterStatus{History: tt.history}}
+			if got := isClusterRestarting(cluster); got != tt.want {
+				t.Errorf("isClusterRestarting() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package etcdcluster
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/backup"
+)
+
+// handleClusterRestore creates the EtcdRestore requested by the restore
+// annotation, and records the progress and the results of the EtcdRestores
+// targeting the cluster in the history. The restores are run by the
+// etcdrestore controller.
+func (c *ClusterController) handleClusterRestore(cluster *kstonev1alpha2.EtcdCluster) (
+	*kstonev1alpha2.EtcdCluster,
+	error,
+) {
+	cluster, err := c.createRequestedRestore(cluster)
+	if err != nil {
+		return cluster, err
+	}
+
+	restores, err := c.etcdrestoreLister.EtcdRestores(cluster.Namespace).List(labels.Everything())
+	if err != nil {
+		return cluster, err
+	}
+	sort.Slice(restores, func(i, j int) bool {
+		return restores[i].CreationTimestamp.Before(&restores[j].CreationTimestamp)
+	})
+	for _, restore := range restores {
+		if backup.RestoreTarget(restore) != cluster.Name || backup.IsRestoreRecorded(restore) {
+			continue
+		}
+		// the restores waiting for the cluster have not touched it yet
+		if restore.Status.Attempts == 0 && !backup.IsRestoreFinished(restore) {
+			continue
+		}
+		cluster, err = c.recordRestore(cluster, restore)
+		if err != nil {
+			return cluster, err
+		}
+	}
+	return cluster, nil
+}
+
+// createRequestedRestore creates the EtcdRestore requested by the restore
+// annotation and removes the annotation. The name of the EtcdRestore is
+// derived from the annotation, so it is created only once.
+func (c *ClusterController) createRequestedRestore(cluster *kstonev1alpha2.EtcdCluster) (
+	*kstonev1alpha2.EtcdCluster,
+	error,
+) {
+	value, found := cluster.Annotations[backup.AnnoRestoreConfig]
+	if !found {
+		return cluster, nil
+	}
+	origin := cluster.ObjectMeta.DeepCopy()
+
+	spec, err := backup.GetRestoreConfig(cluster)
+	if err != nil {
+		c.recorder.Eventf(cluster, corev1.EventTypeWarning, string(kstonev1alpha2.EtcdClusterConditionRestore), "invalid restore annotation, err is %v", err)
+	}
+	if spec != nil {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(value))
+		restore := &kstonev1alpha2.EtcdRestore{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%08x", cluster.Name, h.Sum32()),
+				Namespace: cluster.Namespace,
+			},
+			Spec: *spec,
+		}
+		_, err = c.platformclientset.KstoneV1alpha2().EtcdRestores(restore.Namespace).
+			Create(context.TODO(), restore, metav1.CreateOptions{})
+		if err != nil && !errors.IsAlreadyExists(err) {
+			klog.Errorf("failed to create restore %s, err is %v, cluster is %s", restore.Name, err, cluster.Name)
+			return cluster, err
+		}
+		klog.Infof("restore %s is created by the restore annotation, cluster is %s", restore.Name, cluster.Name)
+	}
+
+	delete(cluster.Annotations, backup.AnnoRestoreConfig)
+	return c.saveClusterMetadata(cluster, origin)
+}
+
+// recordRestore records the restore as the Restore operation of the history,
+// the operation is finished when the restore succeeded or failed.
+func (c *ClusterController) recordRestore(
+	cluster *kstonev1alpha2.EtcdCluster,
+	restore *kstonev1alpha2.EtcdRestore,
+) (*kstonev1alpha2.EtcdCluster, error) {
+	last := lastOperation(cluster.Status.History)
+	if last == nil || last.Type != kstonev1alpha2.EtcdClusterConditionRestore || !last.EndTime.IsZero() {
+		startTime := restore.CreationTimestamp
+		if restore.Status.StartTime != nil {
+			startTime = *restore.Status.StartTime
+		}
+		cluster.Status.History = appendHistory(cluster.Status.History, kstonev1alpha2.EtcdClusterCondition{
+			Type:      kstonev1alpha2.EtcdClusterConditionRestore,
+			Status:    corev1.ConditionFalse,
+			StartTime: startTime,
+		})
+	} else if !backup.IsRestoreFinished(restore) && last.Message == restore.Status.Message {
+		return cluster, nil
+	}
+
+	conditionIndex := len(cluster.Status.History) - 1
+	cluster.Status.History[conditionIndex].Message = restore.Status.Message
+	if !backup.IsRestoreFinished(restore) {
+		return c.updateEtcdClusterStatus(cluster)
+	}
+
+	endTime := metav1.Now()
+	if restore.Status.CompletionTime != nil {
+		endTime = *restore.Status.CompletionTime
+	}
+	cluster.Status.History[conditionIndex].EndTime = endTime
+	if restore.Status.Phase == kstonev1alpha2.EtcdRestoreSucceeded {
+		cluster.Status.History[conditionIndex].Status = corev1.ConditionTrue
+		cluster.Status.History[conditionIndex].Reason = ""
+		c.recorder.Event(cluster, corev1.EventTypeNormal, string(kstonev1alpha2.EtcdClusterConditionRestore), restore.Status.Message)
+	} else {
+		cluster.Status.History[conditionIndex].Reason = restore.Status.Reason
+		c.recorder.Eventf(
+			cluster,
+			corev1.EventTypeWarning,
+			string(kstonev1alpha2.EtcdClusterConditionRestore),
+			"restore %s failed, err is %s",
+			restore.Name,
+			restore.Status.Reason,
+		)
+	}
+	cluster, err := c.updateEtcdClusterStatus(cluster)
+	if err != nil {
+		klog.Errorf("failed to update cluster status, err is %v, cluster is %s", err, cluster.Name)
+		return cluster, err
+	}
+
+	restore = restore.DeepCopy()
+	if restore.Annotations == nil {
+		restore.Annotations = make(map[string]string)
+	}
+	restore.Annotations[backup.AnnoRestoreRecorded] = metav1.Now().Format(time.RFC3339)
+	_, err = c.platformclientset.KstoneV1alpha2().EtcdRestores(restore.Namespace).
+		Update(context.TODO(), restore, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf("failed to mark restore %s recorded, err is %v", restore.Name, err)
+		return cluster, err
+	}
+	return cluster, nil
+}
+
+// isClusterRestoring checks whether a running EtcdRestore writes the cluster,
+// the uid of the target cluster is recorded by the restore before it is written.
+func (c *ClusterController) isClusterRestoring(cluster *kstonev1alpha2.EtcdCluster) bool {
+	restores, err := c.etcdrestoreLister.EtcdRestores(cluster.Namespace).List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list restores, err is %v, cluster is %s", err, cluster.Name)
+		return false
+	}
+	for _, restore := range restores {
+		if backup.RestoreTarget(restore) == cluster.Name &&
+			restore.Status.Phase == kstonev1alpha2.EtcdRestoreRunning &&
+			restore.Status.TargetUID == string(cluster.UID) {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueueRestoreTarget puts the target cluster of the EtcdRestore onto the work queue.
+func (c *ClusterController) enqueueRestoreTarget(obj interface{}) {
+	restore, ok := obj.(*kstonev1alpha2.EtcdRestore)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("expected EtcdRestore but got %#v", obj))
+		return
+	}
+	c.workqueue.Add(restore.Namespace + "/" + backup.RestoreTarget(restore))
+}
@@ -0,0 +1,97 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package etcdcluster
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	listers "tkestack.io/kstone/pkg/generated/listers/kstone/v1alpha2"
+)
+
+func TestIsClusterRestoring(t *testing.T) {
+	cluster := &kstonev1alpha2.EtcdCluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kstone", Name: "etcd", UID: "uid-1"},
+	}
+	restore := func(target string, phase kstonev1alpha2.EtcdRestorePhase, uid string) *kstonev1alpha2.EtcdRestore {
+		return &kstonev1alpha2.EtcdRestore{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kstone", Name: "restore"},
+			Spec:       kstonev1alpha2.EtcdRestoreSpec{SourceCluster: "source", TargetCluster: target},
+			Status:     kstonev1alpha2.EtcdRestoreStatus{Phase: phase, TargetUID: uid},
+		}
+	}
+	tests := []struct {
+		name    string
+		restore *kstonev1alpha2.EtcdRestore
+		want    bool
+	}{
+		{
+			name:    "no restore",
+			restore: nil,
+			want:    false,
+		},
+		{
+			name:    "running restore writing the cluster",
+			restore: restore("etcd", kstonev1alpha2.EtcdRestoreRunning, "uid-1"),
+			want:    true,
+		},
+		{
+			name:    "running restore staging the backup file",
+			restore: restore("etcd", kstonev1alpha2.EtcdRestoreRunning, ""),
+			want:    false,
+		},
+		{
+			name:    "running restore of the deleted cluster of the same name",
+			restore: restore("etcd", kstonev1alpha2.EtcdRestoreRunning, "uid-0"),
+			want:    false,
+		},
+		{
+			name:    "pending retry",
+			restore: restore("etcd", kstonev1alpha2.EtcdRestorePending, "uid-1"),
+			want:    false,
+		},
+		{
+			name:    "succeeded restore",
+			restore: restore("etcd", kstonev1alpha2.EtcdRestoreSucceeded, "uid-1"),
+			want:    false,
+		},
+		{
+			name:    "running restore of another cluster",
+			restore: restore("other", kstonev1alpha2.EtcdRestoreRunning, "uid-1"),
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			if tt.restore != nil {
+				if err := indexer.Add(tt.restore); err != nil {
+					t.Fatalf("err is %v", err)
+				}
+			}
+			c := &ClusterController{etcdrestoreLister: listers.NewEtcdRestoreLister(indexer)}
+			if got := c.isClusterRestoring(cluster); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -51,6 +51,7 @@ import (
 	platformscheme "tkestack.io/kstone/pkg/generated/clientset/versioned/scheme"
 	informers "tkestack.io/kstone/pkg/generated/informers/externalversions/kstone/v1alpha2"
 	listers "tkestack.io/kstone/pkg/generated/listers/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/notifier"
 )
 
 // InspectionController is the controller implementation for etcdinspection resources
@@ -81,6 +82,9 @@ type InspectionController struct {
 	clientbuilder util.ClientBuilder
 
 	clientConfigGetter etcd.ClientConfigGetter
+
+	// scheduler runs each etcdinspection every its own interval
+	scheduler *scheduler
 }
 
 func NewInspectionControllerMetric() http.Handler {
@@ -128,7 +132,8 @@ func NewEtcdInspectionController(
 			workqueue.DefaultControllerRateLimiter(),
 			"etcdinspections",
 		),
-		recorder: recorder,
+		recorder:  recorder,
+		scheduler: newScheduler(),
 	}
 	controller.syncHandler = controller.doClusterInspection
 	controller.clientConfigGetter = etcd.NewClientConfigSecretCacheGetter(controller.secretLister)
@@ -140,6 +145,7 @@ func NewEtcdInspectionController(
 		UpdateFunc: func(old, new interface{}) {
 			controller.enqueueEtcdInspection(new)
 		},
+		DeleteFunc: controller.forgetEtcdInspection,
 	})
 
 	return controller
@@ -224,12 +230,70 @@ func (c *InspectionController) doClusterInspection(key string) error {
 		// processing.
 		if errors.IsNotFound(err) {
 			utilruntime.HandleError(fmt.Errorf("etcdinspection '%s' in work queue no longer exists", key))
+			c.scheduler.forget(key)
 			return nil
 		}
 		return err
 	}
 
-	return c.doInspectionTask(etcdinspection)
+	// the informer enqueues the etcdinspection on every change and resync,
+	// it is requeued until the next run is due
+	now := time.Now()
+	if delay := c.scheduler.due(key, etcdinspection, now); delay > 0 {
+		c.workqueue.AddAfter(key, delay)
+		return nil
+	}
+
+	delay := c.scheduler.start(key, now)
+	result, err := c.doInspectionTask(etcdinspection)
+	if err != nil {
+		klog.Errorf("failed to do inspection %s, err is %v", key, err)
+	}
+	if rErr := c.recordInspection(etcdinspection, now, result, err); rErr != nil && !errors.IsNotFound(rErr) {
+		klog.Errorf("failed to record inspection %s, err is %v", key, rErr)
+	}
+	// a failed run is retried at the next interval, instead of the rate
+	// limited requeue, which would be skipped as it is not due
+	c.workqueue.AddAfter(key, delay)
+	return nil
+}
+
+// forgetEtcdInspection forgets the schedule of the deleted etcdinspection,
+// and stops its feature if the feature keeps running state for it
+func (c *InspectionController) forgetEtcdInspection(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.scheduler.forget(key)
+
+	inspection, ok := obj.(*kstonev1alpha2.EtcdInspection)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("couldn't get object from tombstone %#v", obj))
+			return
+		}
+		inspection, ok = tombstone.Obj.(*kstonev1alpha2.EtcdInspection)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("tombstone contained object that is not a EtcdInspection %#v", obj))
+			return
+		}
+	}
+
+	feature, err := c.GetInspectionFeatureProvider(inspection.Spec.InspectionType)
+	if err != nil {
+		klog.Errorf("failed to get feature %s provider,err is %v", inspection.Spec.InspectionType, err)
+		return
+	}
+	stopper, ok := feature.(featureprovider.FeatureStopper)
+	if !ok {
+		return
+	}
+	if err = stopper.Stop(inspection); err != nil {
+		klog.Errorf("failed to stop %s feature, err is %v, inspection is %s", inspection.Spec.InspectionType, err, key)
+	}
 }
 
 // enqueueEtcdInspection takes a etcdinspection resource and converts it into a namespace/name
@@ -257,11 +321,24 @@ func (c *InspectionController) GetInspectionFeatureProvider(name string) (featur
 	return feature, nil
 }
 
-func (c *InspectionController) doInspectionTask(etcdinspection *kstonev1alpha2.EtcdInspection) error {
+func (c *InspectionController) doInspectionTask(
+	etcdinspection *kstonev1alpha2.EtcdInspection,
+) (*kstonev1alpha2.EtcdInspectionResult, error) {
 	inspectionType := etcdinspection.Spec.InspectionType
 	feature, err := c.GetInspectionFeatureProvider(inspectionType)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	result, err := feature.Do(etcdinspection)
+	if err != nil {
+		notifier.Notify(&notifier.Event{
+			Namespace: etcdinspection.Namespace,
+			Cluster:   etcdinspection.Spec.ClusterName,
+			Reason:    notifier.ReasonInspectionFailed,
+			Severity:  notifier.SeverityWarning,
+			Subject:   inspectionType,
+			Message:   fmt.Sprintf("%s inspection failed, err is %v", inspectionType, err),
+		})
 	}
-	return feature.Do(etcdinspection)
+	return result, err
 }
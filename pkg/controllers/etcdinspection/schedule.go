@@ -0,0 +1,180 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package etcdinspection
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	klog "k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/inspection"
+	"tkestack.io/kstone/pkg/inspection/metrics"
+)
+
+const (
+	// ScheduleJitterFactor delays each run by up to 10% of the interval, so
+	// the inspections created at the same time do not run at the same time
+	ScheduleJitterFactor = 0.1
+
+	InspectionSucceeded = "InspectionSucceeded"
+	InspectionFailed    = "InspectionFailed"
+)
+
+// schedule is the schedule of an etcdinspection
+type schedule struct {
+	interval time.Duration
+	lastRun  time.Time
+	nextRun  time.Time
+}
+
+// scheduler tracks when each etcdinspection runs next, the same key is never
+// processed by two workers at the same time, so the runs never overlap
+type scheduler struct {
+	mutex     sync.Mutex
+	schedules map[string]*schedule
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		schedules: make(map[string]*schedule),
+	}
+}
+
+// due returns how long to wait before the next run of the etcdinspection,
+// it is due if the returned duration is not positive
+func (s *scheduler) due(key string, task *kstonev1alpha2.EtcdInspection, now time.Time) time.Duration {
+	interval := inspection.GetInspectionInterval(task)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sched, found := s.schedules[key]
+	if !found {
+		// resume from the last record after the controller restarts
+		sched = &schedule{interval: interval}
+		if n := len(task.Status.Records); n > 0 {
+			sched.lastRun = task.Status.Records[n-1].StartTime.Time
+			sched.nextRun = sched.lastRun.Add(interval)
+		}
+		s.schedules[key] = sched
+	}
+	if sched.interval != interval {
+		sched.interval = interval
+		if !sched.lastRun.IsZero() {
+			sched.nextRun = sched.lastRun.Add(wait.Jitter(interval, ScheduleJitterFactor))
+		}
+	}
+	return sched.nextRun.Sub(now)
+}
+
+// start records the start of a run and returns the delay of the next run
+func (s *scheduler) start(key string, now time.Time) time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sched := s.schedules[key]
+	delay := wait.Jitter(sched.interval, ScheduleJitterFactor)
+	sched.lastRun = now
+	sched.nextRun = now.Add(delay)
+	return delay
+}
+
+// forget forgets the schedule of the deleted etcdinspection
+func (s *scheduler) forget(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.schedules, key)
+}
+
+// recordInspection records the start and end time and the result of a run in
+// the status of etcdinspection, the latest MaxInspectionRecords records are kept.
+// If the feature has recorded the run with its own reason, only the end time
+// and the result are filled. The status is written from the cached task, it is
+// only read again if the task has been changed since then.
+func (c *InspectionController) recordInspection(
+	task *kstonev1alpha2.EtcdInspection,
+	start time.Time,
+	result *kstonev1alpha2.EtcdInspectionResult,
+	runErr error,
+) error {
+	result = inspection.FinalizeResult(result, runErr)
+	if result != nil {
+		metrics.EtcdInspectionResult.With(map[string]string{
+			"clusterName":    task.Spec.ClusterName,
+			"inspectionType": task.Spec.InspectionType,
+		}).Set(inspection.ResultStatusValue(result.Status))
+	}
+
+	latest := task.DeepCopy()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if latest == nil {
+			var err error
+			latest, err = c.platformclientset.KstoneV1alpha2().EtcdInspections(task.Namespace).
+				Get(context.TODO(), task.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+		}
+
+		now := metav1.Now()
+		records := latest.Status.Records
+		// metav1.Time is serialized in seconds
+		if n := len(records); n > 0 && !records[n-1].StartTime.Time.Before(start.Truncate(time.Second)) {
+			if !records[n-1].EndTime.IsZero() && result == nil {
+				return nil
+			}
+			if records[n-1].EndTime.IsZero() {
+				records[n-1].EndTime = now
+			}
+			records[n-1].Result = result
+		} else {
+			record := kstonev1alpha2.EtcdInspectionRecord{
+				StartTime: metav1.NewTime(start),
+				EndTime:   now,
+				Reason:    InspectionSucceeded,
+				Result:    result,
+			}
+			if runErr != nil {
+				record.Reason = InspectionFailed
+				record.Message = runErr.Error()
+			}
+			records = append(records, record)
+			if len(records) > inspection.MaxInspectionRecords {
+				records = records[len(records)-inspection.MaxInspectionRecords:]
+			}
+			latest.Status.Reason = record.Reason
+			latest.Status.Message = record.Message
+		}
+		latest.Status.Records = records
+		latest.Status.Result = result
+		latest.Status.LastUpdatedTime = now
+
+		_, err := c.platformclientset.KstoneV1alpha2().EtcdInspections(latest.Namespace).
+			UpdateStatus(context.TODO(), latest, metav1.UpdateOptions{})
+		if err != nil {
+			klog.V(2).Infof("failed to record etcdinspection %s/%s, err is %v", latest.Namespace, latest.Name, err)
+			latest = nil
+		}
+		return err
+	})
+}
@@ -0,0 +1,122 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package etcdinspection
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/generated/clientset/versioned/fake"
+)
+
+func newTestInspection(records ...kstonev1alpha2.EtcdInspectionRecord) *kstonev1alpha2.EtcdInspection {
+	return &kstonev1alpha2.EtcdInspection{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kstone", Name: "etcd-healthy"},
+		Spec:       kstonev1alpha2.EtcdInspectionSpec{ClusterName: "etcd", InspectionType: "healthy"},
+		Status:     kstonev1alpha2.EtcdInspectionStatus{Records: records},
+	}
+}
+
+// verbs returns the verbs of the actions, the subresource is appended
+func verbs(actions []k8stesting.Action) []string {
+	result := make([]string, 0, len(actions))
+	for _, action := range actions {
+		verb := action.GetVerb()
+		if action.GetSubresource() != "" {
+			verb += "/" + action.GetSubresource()
+		}
+		result = append(result, verb)
+	}
+	return result
+}
+
+func TestRecordInspection(t *testing.T) {
+	task := newTestInspection()
+	client := fake.NewSimpleClientset(task)
+	c := &InspectionController{platformclientset: client}
+
+	start := time.Now()
+	if err := c.recordInspection(task, start, nil, errors.New("unhealthy")); err != nil {
+		t.Fatalf("failed to record inspection, err is %v", err)
+	}
+	// the cached task is up to date, it is not read again
+	if got := verbs(client.Actions()); !reflect.DeepEqual(got, []string{"update/status"}) {
+		t.Fatalf("actions = %v, want [update/status]", got)
+	}
+
+	updated := client.Actions()[0].(k8stesting.UpdateAction).GetObject().(*kstonev1alpha2.EtcdInspection)
+	records := updated.Status.Records
+	if len(records) != 1 || records[0].Reason != InspectionFailed || records[0].Message != "unhealthy" {
+		t.Errorf("records = %+v, want a failed record", records)
+	}
+	if updated.Status.Reason != InspectionFailed || updated.Status.LastUpdatedTime.IsZero() {
+		t.Errorf("status = %+v, want the reason and the last updated time", updated.Status)
+	}
+}
+
+func TestRecordInspectionRecordedByFeature(t *testing.T) {
+	start := time.Now()
+	task := newTestInspection(kstonev1alpha2.EtcdInspectionRecord{
+		StartTime: metav1.NewTime(start),
+		EndTime:   metav1.NewTime(start),
+		Reason:    "BackupVerified",
+	})
+	client := fake.NewSimpleClientset(task)
+	c := &InspectionController{platformclientset: client}
+
+	// nothing is written if the feature has recorded the run without a result
+	if err := c.recordInspection(task, start, nil, nil); err != nil {
+		t.Fatalf("failed to record inspection, err is %v", err)
+	}
+	if got := verbs(client.Actions()); len(got) != 0 {
+		t.Errorf("actions = %v, want none", got)
+	}
+}
+
+func TestRecordInspectionConflict(t *testing.T) {
+	task := newTestInspection()
+	client := fake.NewSimpleClientset(task)
+	conflicted := false
+	client.PrependReactor("update", "etcdinspections", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicted {
+			return false, nil, nil
+		}
+		conflicted = true
+		return true, nil, apierrors.NewConflict(schema.GroupResource{Resource: "etcdinspections"}, task.Name, errors.New("stale"))
+	})
+	c := &InspectionController{platformclientset: client}
+
+	if err := c.recordInspection(task, time.Now(), nil, nil); err != nil {
+		t.Fatalf("failed to record inspection, err is %v", err)
+	}
+	// the task is read again after the conflict
+	want := []string{"update/status", "get", "update/status"}
+	if got := verbs(client.Actions()); !reflect.DeepEqual(got, want) {
+		t.Errorf("actions = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,471 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package etcdrestore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	informerscorev1 "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/backup"
+	_ "tkestack.io/kstone/pkg/backup/providers" // register backup storage provider
+	"tkestack.io/kstone/pkg/clusterprovider"
+	"tkestack.io/kstone/pkg/controllers/util"
+	"tkestack.io/kstone/pkg/etcd"
+	clientset "tkestack.io/kstone/pkg/generated/clientset/versioned"
+	platformscheme "tkestack.io/kstone/pkg/generated/clientset/versioned/scheme"
+	informers "tkestack.io/kstone/pkg/generated/informers/externalversions/kstone/v1alpha2"
+	listers "tkestack.io/kstone/pkg/generated/listers/kstone/v1alpha2"
+)
+
+const (
+	// maxRestoreAttempts is the number of the attempts before the restore fails
+	maxRestoreAttempts = 10
+	// restoreWaitInterval is the interval of checking the clusters the restore waits for
+	restoreWaitInterval = 10 * time.Second
+	// restoreBaseBackoff and restoreMaxBackoff bound the delay of the retry after a failed attempt
+	restoreBaseBackoff = 30 * time.Second
+	restoreMaxBackoff  = 30 * time.Minute
+)
+
+// RestoreController is the controller implementation for EtcdRestore resources.
+// The restores run in its own workers, so a long restore never blocks the
+// reconciliation of the clusters.
+type RestoreController struct {
+	// kubeclientset is a standard kubernetes clientset
+	kubeclientset kubernetes.Interface
+	// platformclientset is a clientset for our own API group
+	platformclientset clientset.Interface
+
+	secretLister listerscorev1.SecretLister
+	secretSynced cache.InformerSynced
+
+	etcdclusterLister listers.EtcdClusterLister
+	etcdclusterSynced cache.InformerSynced
+
+	etcdrestoreLister listers.EtcdRestoreLister
+	etcdrestoreSynced cache.InformerSynced
+
+	// To allow injection of syncEtcdRestore for testing.
+	syncHandler func(eKey string) error
+
+	// workqueue is a rate limited work queue, the retries of the failed
+	// attempts are scheduled by the backoff of the restore instead.
+	workqueue workqueue.RateLimitingInterface
+	// recorder is an event recorder for recording Event resources to the
+	// Kubernetes API.
+	recorder record.EventRecorder
+
+	clientConfigGetter etcd.ClientConfigGetter
+}
+
+// NewEtcdRestoreController returns a new etcdrestore controller
+func NewEtcdRestoreController(
+	kubeclientset kubernetes.Interface,
+	platformclientset clientset.Interface,
+	secretInformer informerscorev1.SecretInformer,
+	etcdclusterInformer informers.EtcdClusterInformer,
+	etcdrestoreInformer informers.EtcdRestoreInformer) *RestoreController {
+
+	utilruntime.Must(platformscheme.AddToScheme(scheme.Scheme))
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartStructuredLogging(0)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeclientset.CoreV1().Events("")})
+	recorder := eventBroadcaster.NewRecorder(
+		scheme.Scheme,
+		corev1.EventSource{Component: util.ComponentEtcdRestoreController},
+	)
+
+	controller := &RestoreController{
+		kubeclientset:     kubeclientset,
+		platformclientset: platformclientset,
+		secretLister:      secretInformer.Lister(),
+		secretSynced:      secretInformer.Informer().HasSynced,
+		etcdclusterLister: etcdclusterInformer.Lister(),
+		etcdclusterSynced: etcdclusterInformer.Informer().HasSynced,
+		etcdrestoreLister: etcdrestoreInformer.Lister(),
+		etcdrestoreSynced: etcdrestoreInformer.Informer().HasSynced,
+		workqueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "EtcdRestores"),
+		recorder:          recorder,
+	}
+	controller.syncHandler = controller.syncEtcdRestore
+	controller.clientConfigGetter = etcd.NewClientConfigSecretCacheGetter(controller.secretLister)
+
+	klog.Info("Setting up event handlers")
+	etcdrestoreInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueEtcdRestore,
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueEtcdRestore(new)
+		},
+	})
+	return controller
+}
+
+// Run starts the workers, it blocks until stopCh is closed.
+func (c *RestoreController) Run(threadiness int, stopCh <-chan struct{}) error {
+	defer utilruntime.HandleCrash()
+	defer c.workqueue.ShutDown()
+
+	klog.Info("Starting EtcdRestore controller")
+
+	klog.Info("Waiting for informer caches to sync")
+	if ok := cache.WaitForCacheSync(stopCh, c.secretSynced, c.etcdclusterSynced, c.etcdrestoreSynced); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+
+	klog.Info("Starting workers")
+	for i := 0; i < threadiness; i++ {
+		go wait.Until(c.runWorker, time.Second, stopCh)
+	}
+
+	klog.Info("Started workers")
+	<-stopCh
+	klog.Info("Shutting down workers")
+
+	return nil
+}
+
+// runWorker processes the work items until the workqueue is shut down.
+func (c *RestoreController) runWorker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+// processNextWorkItem will read a single work item off the workqueue and
+// attempt to process it, by calling the syncHandler.
+func (c *RestoreController) processNextWorkItem() bool {
+	obj, shutdown := c.workqueue.Get()
+	if shutdown {
+		return false
+	}
+
+	err := util.ProcessWorkQueue(c.workqueue, c.syncHandler, obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+	}
+	return true
+}
+
+// syncEtcdRestore runs the restore if it is not finished.
+func (c *RestoreController) syncEtcdRestore(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	restore, err := c.etcdrestoreLister.EtcdRestores(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			utilruntime.HandleError(fmt.Errorf("EtcdRestore '%s' in work queue no longer exists", key))
+			return nil
+		}
+		return err
+	}
+	if backup.IsRestoreFinished(restore) {
+		return nil
+	}
+
+	return c.reconcileEtcdRestore(restore.DeepCopy())
+}
+
+// reconcileEtcdRestore waits for the source and the target clusters, then
+// starts an attempt of the restore. A failed attempt keeps the restore
+// pending, and it is retried after a backoff until maxRestoreAttempts.
+func (c *RestoreController) reconcileEtcdRestore(restore *kstonev1alpha2.EtcdRestore) error {
+	spec := restore.Spec.DeepCopy()
+	spec.TargetCluster = backup.RestoreTarget(restore)
+	if err := backup.ValidateRestoreSpec(spec); err != nil {
+		return c.finishEtcdRestore(restore, nil, err)
+	}
+
+	if delay := c.retryDelay(restore); delay > 0 {
+		c.enqueueEtcdRestoreAfter(restore, delay)
+		return nil
+	}
+
+	source, err := c.etcdclusterLister.EtcdClusters(restore.Namespace).Get(spec.SourceCluster)
+	if errors.IsNotFound(err) {
+		return c.waitEtcdRestore(restore, fmt.Sprintf("waiting for source cluster %s to be created", spec.SourceCluster))
+	}
+	if err != nil {
+		return err
+	}
+
+	target, err := c.etcdclusterLister.EtcdClusters(restore.Namespace).Get(spec.TargetCluster)
+	if errors.IsNotFound(err) {
+		return c.createTargetCluster(restore, source, spec.TargetCluster)
+	}
+	if err != nil {
+		return err
+	}
+	// the target cluster is still restoring if the last attempt wrote it
+	if (target.Status.Phase != kstonev1alpha2.EtcdClusterRunning &&
+		target.Status.Phase != kstonev1alpha2.EtcdClusterRestoring) || len(target.Status.Members) == 0 {
+		return c.waitEtcdRestore(restore, fmt.Sprintf("waiting for target cluster %s to be running", target.Name))
+	}
+
+	now := metav1.Now()
+	restore.Status.Phase = kstonev1alpha2.EtcdRestoreRunning
+	restore.Status.Attempts++
+	restore.Status.LastAttemptTime = &now
+	if restore.Status.StartTime == nil {
+		restore.Status.StartTime = &now
+	}
+	restore.Status.Message = fmt.Sprintf("attempt %d is restoring %s into %s", restore.Status.Attempts, source.Name, target.Name)
+	restore, err = c.updateEtcdRestoreStatus(restore)
+	if err != nil {
+		return err
+	}
+
+	restore, result, err := c.restoreEtcdCluster(restore, spec, source, target)
+	if err != nil && restore.Status.Attempts < maxRestoreAttempts {
+		return c.retryEtcdRestore(restore, err)
+	}
+	return c.finishEtcdRestore(restore, result, err)
+}
+
+// restoreEtcdCluster stages the backup file in an ephemeral member, then
+// copies it into the target cluster. The target cluster must be empty unless
+// the restore asks to overwrite it. The uid of the target cluster is recorded
+// before it is written, the retries of the restore may overwrite it.
+func (c *RestoreController) restoreEtcdCluster(
+	restore *kstonev1alpha2.EtcdRestore,
+	spec *kstonev1alpha2.EtcdRestoreSpec,
+	source *kstonev1alpha2.EtcdCluster,
+	target *kstonev1alpha2.EtcdCluster,
+) (*kstonev1alpha2.EtcdRestore, *backup.RestoreResult, error) {
+	backupConfig, err := backup.GetBackupConfig(source)
+	if err != nil {
+		return restore, nil, err
+	}
+	storage, err := backup.GetBackupStorageProvider(string(backupConfig.StorageType), &backup.StorageConfig{
+		KubeCli: c.kubeclientset,
+	})
+	if err != nil {
+		klog.Errorf("failed to get backup provider, cluster %s, err is %v", source.Name, err)
+		return restore, nil, err
+	}
+
+	staged, err := backup.StageRestore(storage, source, spec)
+	if err != nil {
+		return restore, nil, err
+	}
+	defer staged.Close()
+
+	path := fmt.Sprintf("%s/%s", target.Namespace, target.Name)
+	clientConfig, err := c.clientConfigGetter.New(path, target.Annotations[util.ClusterTLSSecretName])
+	if err != nil {
+		return restore, nil, err
+	}
+	clientConfig.Endpoints = clusterprovider.GetStorageMemberEndpoints(target)
+	cli, err := etcd.NewClientv3(clientConfig)
+	if err != nil {
+		klog.Errorf("failed to get etcd client, cluster %s, err is %v", target.Name, err)
+		return restore, nil, err
+	}
+	defer cli.Close()
+
+	written := restore.Status.TargetUID == string(target.UID)
+	if !written {
+		if !spec.Overwrite {
+			if err = backup.CheckTargetEmpty(cli); err != nil {
+				return restore, nil, err
+			}
+		}
+		restore.Status.TargetUID = string(target.UID)
+		restore, err = c.updateEtcdRestoreStatus(restore)
+		if err != nil {
+			return restore, nil, err
+		}
+	}
+	result, err := staged.CopyTo(cli, spec.Overwrite || written)
+	return restore, result, err
+}
+
+// createTargetCluster creates the target cluster from the spec of the source
+// cluster, only the clusters created by kstone-etcd-operator can be created.
+func (c *RestoreController) createTargetCluster(
+	restore *kstonev1alpha2.EtcdRestore,
+	source *kstonev1alpha2.EtcdCluster,
+	name string,
+) error {
+	if source.Spec.ClusterType != kstonev1alpha2.EtcdClusterKstone {
+		return c.waitEtcdRestore(restore, fmt.Sprintf("waiting for target cluster %s to be created", name))
+	}
+
+	target := &kstonev1alpha2.EtcdCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: source.Namespace,
+		},
+		Spec: *source.Spec.DeepCopy(),
+	}
+	target.Spec.Name = name
+	target.Spec.Description = fmt.Sprintf("restored from %s by %s", source.Name, restore.Name)
+	_, err := c.platformclientset.KstoneV1alpha2().EtcdClusters(target.Namespace).
+		Create(context.TODO(), target, metav1.CreateOptions{})
+	switch {
+	case err == nil:
+		c.recorder.Eventf(restore, corev1.EventTypeNormal, "TargetCreated", "created target cluster %s from %s", name, source.Name)
+	case !errors.IsAlreadyExists(err):
+		klog.Errorf("failed to create target cluster %s, err is %v", name, err)
+		return err
+	}
+	return c.waitEtcdRestore(restore, fmt.Sprintf("waiting for target cluster %s to be running", name))
+}
+
+// waitEtcdRestore keeps the restore pending and checks it again later.
+func (c *RestoreController) waitEtcdRestore(restore *kstonev1alpha2.EtcdRestore, message string) error {
+	defer c.enqueueEtcdRestoreAfter(restore, restoreWaitInterval)
+	if restore.Status.Phase == kstonev1alpha2.EtcdRestorePending && restore.Status.Message == message {
+		return nil
+	}
+	restore.Status.Phase = kstonev1alpha2.EtcdRestorePending
+	restore.Status.Message = message
+	_, err := c.updateEtcdRestoreStatus(restore)
+	return err
+}
+
+// retryEtcdRestore keeps the restore pending after a failed attempt, the next
+// attempt is scheduled after the backoff.
+func (c *RestoreController) retryEtcdRestore(restore *kstonev1alpha2.EtcdRestore, err error) error {
+	klog.Errorf("restore attempt %d failed, err is %v, restore is %s", restore.Status.Attempts, err, restore.Name)
+	c.recorder.Eventf(restore, corev1.EventTypeWarning, "AttemptFailed", "attempt %d failed, err is %v", restore.Status.Attempts, err)
+
+	restore.Status.Phase = kstonev1alpha2.EtcdRestorePending
+	restore.Status.Reason = err.Error()
+	restore.Status.Message = fmt.Sprintf("attempt %d failed, retrying in %s", restore.Status.Attempts, backoff(restore.Status.Attempts))
+	restore, err = c.updateEtcdRestoreStatus(restore)
+	if err != nil {
+		return err
+	}
+	c.enqueueEtcdRestoreAfter(restore, backoff(restore.Status.Attempts))
+	return nil
+}
+
+// finishEtcdRestore records the result of the restore, err is the error of
+// the last attempt.
+func (c *RestoreController) finishEtcdRestore(
+	restore *kstonev1alpha2.EtcdRestore,
+	result *backup.RestoreResult,
+	err error,
+) error {
+	now := metav1.Now()
+	restore.Status.CompletionTime = &now
+	if err != nil {
+		klog.Errorf("restore failed, err is %v, restore is %s", err, restore.Name)
+		restore.Status.Phase = kstonev1alpha2.EtcdRestoreFailed
+		restore.Status.Reason = err.Error()
+		restore.Status.Message = fmt.Sprintf("restore failed after %d attempts", restore.Status.Attempts)
+		if restore.Status.Attempts == 0 {
+			restore.Status.Message = "restore is invalid"
+		}
+		c.recorder.Eventf(restore, corev1.EventTypeWarning, string(restore.Status.Phase), "restore failed, err is %v", err)
+	} else {
+		restore.Status.Phase = kstonev1alpha2.EtcdRestoreSucceeded
+		restore.Status.Reason = ""
+		restore.Status.BackupKey = result.BackupKey
+		restore.Status.Revision = result.Revision
+		restore.Status.Keys = result.Keys
+		restore.Status.Leases = result.Leases
+		restore.Status.Message = fmt.Sprintf(
+			"restored %d keys and %d leases of revision %d from %s/%s",
+			result.Keys,
+			result.Leases,
+			result.Revision,
+			restore.Spec.SourceCluster,
+			result.BackupKey,
+		)
+		c.recorder.Event(restore, corev1.EventTypeNormal, string(restore.Status.Phase), restore.Status.Message)
+	}
+	_, err = c.updateEtcdRestoreStatus(restore)
+	return err
+}
+
+// retryDelay returns the remaining backoff of the restore after a failed attempt.
+func (c *RestoreController) retryDelay(restore *kstonev1alpha2.EtcdRestore) time.Duration {
+	if restore.Status.Phase != kstonev1alpha2.EtcdRestorePending || restore.Status.LastAttemptTime == nil {
+		return 0
+	}
+	return time.Until(restore.Status.LastAttemptTime.Add(backoff(restore.Status.Attempts)))
+}
+
+// backoff returns the delay of the next attempt after the failed attempts.
+func backoff(attempts int32) time.Duration {
+	delay := restoreBaseBackoff
+	for i := int32(1); i < attempts && delay < restoreMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > restoreMaxBackoff {
+		delay = restoreMaxBackoff
+	}
+	return delay
+}
+
+// updateEtcdRestoreStatus updates the Status block of the EtcdRestore resource
+// through the status subresource.
+func (c *RestoreController) updateEtcdRestoreStatus(restore *kstonev1alpha2.EtcdRestore) (
+	*kstonev1alpha2.EtcdRestore,
+	error,
+) {
+	updated, err := c.platformclientset.KstoneV1alpha2().EtcdRestores(restore.Namespace).
+		UpdateStatus(context.TODO(), restore, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf("failed to update restore status, err is %v, restore is %s", err, restore.Name)
+		return restore, err
+	}
+	return updated, nil
+}
+
+// enqueueEtcdRestore takes a EtcdRestore resource and puts its key onto the work queue.
+func (c *RestoreController) enqueueEtcdRestore(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.workqueue.Add(key)
+}
+
+// enqueueEtcdRestoreAfter puts the key of the restore onto the work queue after duration.
+func (c *RestoreController) enqueueEtcdRestoreAfter(restore *kstonev1alpha2.EtcdRestore, duration time.Duration) {
+	key, err := cache.MetaNamespaceKeyFunc(restore)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.workqueue.AddAfter(key, duration)
+}
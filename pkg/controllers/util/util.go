@@ -34,12 +34,15 @@ import (
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
 	"tkestack.io/kstone/pkg/k8s"
 )
 
 const (
 	ComponentEtcdClusterController    = "etcdcluster-controller"
 	ComponentEtcdInspectionController = "etcdinspection-controller"
+	ComponentEtcdRestoreController    = "etcdrestore-controller"
+	ComponentKstoneAPI                = "kstone-api"
 )
 
 type EtcdClusterPhase string
@@ -51,8 +54,8 @@ const (
 )
 
 const (
-	ClusterTLSSecretName      = "certName"
-	ClusterExtensionClientURL = "extClientURL"
+	ClusterTLSSecretName      = kstonev1alpha2.AnnoClientCertSecret
+	ClusterExtensionClientURL = kstonev1alpha2.AnnoExtClientURL
 )
 
 type ClientBuilder interface {
@@ -19,6 +19,7 @@
 package etcd
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/ioutil"
@@ -29,6 +30,8 @@ import (
 	"strings"
 	"time"
 
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
 	"go.etcd.io/etcd/client/pkg/v3/transport"
 	clientv2 "go.etcd.io/etcd/client/v2"
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -40,6 +43,8 @@ const (
 	DefaultCommandTimeOut   = 10 * time.Second
 	DefaultKeepAliveTime    = 10 * time.Second
 	DefaultKeepAliveTimeOut = 30 * time.Second
+	DefaultDefragTimeout    = 5 * time.Minute
+	DefaultHashKVTimeout    = 1 * time.Minute
 
 	CliCertFile = "client.pem"
 	CliKeyFile  = "client-key.pem"
@@ -141,6 +146,41 @@ func Status(endpoint string, cli *clientv3.Client) (*clientv3.StatusResponse, er
 	return cli.Status(ctx, endpoint)
 }
 
+// HashKV returns the hash of the keys and the values of the member endpoint
+// at the revision rev, the hash covers the revisions since the last compaction
+func HashKV(endpoint string, rev int64, cli *clientv3.Client) (*clientv3.HashKVResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultHashKVTimeout)
+	defer cancel()
+
+	return cli.HashKV(ctx, endpoint, rev)
+}
+
+// MoveLeader transfers the leadership to the member transferee, cli must
+// connect to the leader
+func MoveLeader(cli *clientv3.Client, transferee uint64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCommandTimeOut)
+	defer cancel()
+
+	_, err := cli.MoveLeader(ctx, transferee)
+	if err != nil {
+		klog.Errorf("failed to move leader to %x, err is %v", transferee, err)
+	}
+	return err
+}
+
+// Defragment defragments the backend database of the member endpoint, the
+// member does not serve requests until it is finished
+func Defragment(endpoint string, cli *clientv3.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultDefragTimeout)
+	defer cancel()
+
+	_, err := cli.Defragment(ctx, endpoint)
+	if err != nil {
+		klog.Errorf("failed to defragment member %s, err is %v", endpoint, err)
+	}
+	return err
+}
+
 // writeFile writes []bytes to file
 func writeFile(dir, file string, data []byte) (string, error) {
 	p := filepath.Join(dir, file)
@@ -225,6 +265,29 @@ func MemberHealthy(endpoint string, cli *ClientConfig) (bool, error) {
 	return true, nil
 }
 
+// MemberMetrics scrapes the prometheus metrics of member
+func MemberMetrics(endpoint string, cli *ClientConfig) (map[string]*dto.MetricFamily, error) {
+	backend := &HealthCheckHTTPClient{method: HealthCheckHTTP}
+	err := backend.Init(cli.CaCert, cli.Cert, cli.Key, endpoint)
+	if err != nil {
+		klog.Errorf("failed to init metrics client,endpoint is %s,err is %v", endpoint, err)
+		return nil, err
+	}
+	defer backend.Close()
+
+	body, err := backend.GetByAPI("metrics")
+	if err != nil {
+		return nil, err
+	}
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(body))
+	if err != nil {
+		klog.Errorf("failed to parse metrics,endpoint is %s,err is %v", endpoint, err)
+		return nil, err
+	}
+	return families, nil
+}
+
 func NewShortConnectionClientv2(config *ClientConfig) (*clientv2.Client, error) {
 	setDefaultConfig(config)
 	cfg, err := newClientv2Config(config)
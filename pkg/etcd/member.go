@@ -0,0 +1,259 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"k8s.io/klog/v2"
+)
+
+// MemberHealth is the membership and the health of an etcd member
+type MemberHealth struct {
+	ID         string   `json:"memberId"`
+	Name       string   `json:"name"`
+	PeerURLs   []string `json:"peerURLs"`
+	ClientURLs []string `json:"clientURLs"`
+	IsLearner  bool     `json:"isLearner"`
+	IsLeader   bool     `json:"isLeader"`
+	// Healthy is true if the member responds to the status request without errors,
+	// a member which has not been started is never healthy
+	Healthy          bool   `json:"healthy"`
+	RaftAppliedIndex uint64 `json:"raftAppliedIndex"`
+	Error            string `json:"error,omitempty"`
+}
+
+// GetMembersHealth lists the members and checks the health of each of them,
+// clientURL maps the client url of a member to the url accessible by kstone
+func GetMembersHealth(cli *clientv3.Client, clientURL func(string) string) ([]MemberHealth, error) {
+	rsp, err := MemberList(cli)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]MemberHealth, 0, len(rsp.Members))
+	for _, m := range rsp.Members {
+		member := MemberHealth{
+			ID:         strconv.FormatUint(m.ID, 10),
+			Name:       m.Name,
+			PeerURLs:   m.PeerURLs,
+			ClientURLs: m.ClientURLs,
+			IsLearner:  m.IsLearner,
+		}
+		if len(m.ClientURLs) == 0 {
+			member.Error = "member is not started"
+			members = append(members, member)
+			continue
+		}
+
+		status, err := Status(clientURL(m.ClientURLs[0]), cli)
+		switch {
+		case err != nil:
+			member.Error = err.Error()
+		case len(status.Errors) > 0:
+			member.Error = status.Errors[0]
+		default:
+			member.Healthy = true
+			member.IsLeader = status.Leader == m.ID
+			member.RaftAppliedIndex = status.RaftAppliedIndex
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// MemberAdd adds a member with peerURLs, a learner does not vote until it is promoted
+func MemberAdd(cli *clientv3.Client, peerURLs []string, isLearner bool) (*clientv3.MemberAddResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCommandTimeOut)
+	defer cancel()
+
+	var rsp *clientv3.MemberAddResponse
+	var err error
+	if isLearner {
+		rsp, err = cli.MemberAddAsLearner(ctx, peerURLs)
+	} else {
+		rsp, err = cli.MemberAdd(ctx, peerURLs)
+	}
+	if err != nil {
+		klog.Errorf("failed to add member %v, err is %v", peerURLs, err)
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// MemberPromote promotes the learner id to a voting member
+func MemberPromote(cli *clientv3.Client, id uint64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCommandTimeOut)
+	defer cancel()
+
+	_, err := cli.MemberPromote(ctx, id)
+	if err != nil {
+		klog.Errorf("failed to promote member %x, err is %v", id, err)
+	}
+	return err
+}
+
+// MemberRemove removes the member id
+func MemberRemove(cli *clientv3.Client, id uint64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCommandTimeOut)
+	defer cancel()
+
+	_, err := cli.MemberRemove(ctx, id)
+	if err != nil {
+		klog.Errorf("failed to remove member %x, err is %v", id, err)
+	}
+	return err
+}
+
+// Quorum returns the number of the voting members required to commit
+func Quorum(voters int) int {
+	return voters/2 + 1
+}
+
+// countVoters counts the voting members and the healthy ones, except the member exclude
+func countVoters(members []MemberHealth, exclude string) (voters int, healthy int) {
+	for _, m := range members {
+		if m.IsLearner || m.ID == exclude {
+			continue
+		}
+		voters++
+		if m.Healthy {
+			healthy++
+		}
+	}
+	return voters, healthy
+}
+
+// findMember finds the member by id
+func findMember(members []MemberHealth, id string) (*MemberHealth, error) {
+	for i := range members {
+		if members[i].ID == id {
+			return &members[i], nil
+		}
+	}
+	return nil, fmt.Errorf("member %s not found", id)
+}
+
+// CheckMemberAdd checks that the quorum is kept after the member is added,
+// a new voting member is not healthy until it is started
+func CheckMemberAdd(members []MemberHealth, isLearner bool) error {
+	if isLearner {
+		for _, m := range members {
+			if m.IsLearner {
+				return fmt.Errorf("learner %s already exists, promote or remove it first", m.ID)
+			}
+		}
+		return nil
+	}
+
+	voters, healthy := countVoters(members, "")
+	if healthy < Quorum(voters+1) {
+		return fmt.Errorf(
+			"%d of %d voting members are healthy, less than the quorum %d after adding a voting member, add a learner instead",
+			healthy,
+			voters,
+			Quorum(voters+1),
+		)
+	}
+	return nil
+}
+
+// CheckMemberPromote checks that the member is a healthy learner, and the
+// quorum is kept after it is promoted
+func CheckMemberPromote(members []MemberHealth, id string) error {
+	target, err := findMember(members, id)
+	if err != nil {
+		return err
+	}
+	if !target.IsLearner {
+		return fmt.Errorf("member %s is not a learner", id)
+	}
+	if !target.Healthy {
+		return fmt.Errorf("learner %s is not healthy", id)
+	}
+
+	voters, healthy := countVoters(members, "")
+	if healthy+1 < Quorum(voters+1) {
+		return fmt.Errorf(
+			"%d of %d voting members are healthy, less than the quorum %d after promoting the learner",
+			healthy,
+			voters,
+			Quorum(voters+1),
+		)
+	}
+	return nil
+}
+
+// CheckMemberRemove checks that the member is not the leader, and the quorum
+// is kept after it is removed
+func CheckMemberRemove(members []MemberHealth, id string) error {
+	target, err := findMember(members, id)
+	if err != nil {
+		return err
+	}
+	if target.IsLearner {
+		return nil
+	}
+	if target.IsLeader {
+		return fmt.Errorf("member %s is the leader, move the leadership first", id)
+	}
+
+	voters, healthy := countVoters(members, id)
+	if voters == 0 {
+		return fmt.Errorf("member %s is the last voting member", id)
+	}
+	if healthy < Quorum(voters) {
+		return fmt.Errorf(
+			"%d of %d voting members are healthy after removing member %s, less than the quorum %d",
+			healthy,
+			voters,
+			id,
+			Quorum(voters),
+		)
+	}
+	return nil
+}
+
+// CheckMoveLeader checks that the member is a healthy voting member, and the
+// cluster has a healthy leader to transfer the leadership, the leader is returned
+func CheckMoveLeader(members []MemberHealth, id string) (*MemberHealth, error) {
+	target, err := findMember(members, id)
+	if err != nil {
+		return nil, err
+	}
+	if target.IsLearner {
+		return nil, fmt.Errorf("member %s is a learner", id)
+	}
+	if !target.Healthy {
+		return nil, fmt.Errorf("member %s is not healthy", id)
+	}
+	if target.IsLeader {
+		return nil, fmt.Errorf("member %s is already the leader", id)
+	}
+
+	for i := range members {
+		if members[i].IsLeader && members[i].Healthy {
+			return &members[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no healthy leader found")
+}
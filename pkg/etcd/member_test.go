@@ -0,0 +1,162 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package etcd
+
+import (
+	"testing"
+)
+
+// members returns voting members 1..n, the first healthy of them are healthy
+// and member 1 is the leader if it is healthy
+func members(n, healthy int) []MemberHealth {
+	result := make([]MemberHealth, 0, n)
+	for i := 1; i <= n; i++ {
+		result = append(result, MemberHealth{
+			ID:       string(rune('0' + i)),
+			Healthy:  i <= healthy,
+			IsLeader: i == 1 && healthy > 0,
+		})
+	}
+	return result
+}
+
+// withLearner appends the learner 9
+func withLearner(members []MemberHealth, healthy bool) []MemberHealth {
+	return append(members, MemberHealth{ID: "9", IsLearner: true, Healthy: healthy})
+}
+
+func TestQuorum(t *testing.T) {
+	for voters, want := range map[int]int{1: 1, 2: 2, 3: 2, 4: 3, 5: 3} {
+		if got := Quorum(voters); got != want {
+			t.Errorf("Quorum(%d) = %d, want %d", voters, got, want)
+		}
+	}
+}
+
+func TestCheckMemberAdd(t *testing.T) {
+	tests := []struct {
+		name      string
+		members   []MemberHealth
+		isLearner bool
+		wantErr   bool
+	}{
+		{name: "voter to healthy cluster", members: members(3, 3)},
+		// 2 of 4 voters are healthy after the new voter is added
+		{name: "voter with one unhealthy", members: members(3, 2), wantErr: true},
+		{name: "voter to 4 member cluster with one unhealthy", members: members(4, 3)},
+		{name: "voter with unhealthy majority", members: members(3, 1), wantErr: true},
+		// 2 of 3 voters are healthy after the new voter is added, not started yet
+		{name: "voter to 2 member cluster", members: members(2, 2)},
+		{name: "voter with one unhealthy of 2", members: members(2, 1), wantErr: true},
+		{name: "learner with unhealthy majority", members: members(3, 1), isLearner: true},
+		{name: "learner already present", members: withLearner(members(3, 3), true), isLearner: true, wantErr: true},
+		{name: "voter with learner present", members: withLearner(members(3, 3), false)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckMemberAdd(tt.members, tt.isLearner)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckMemberAdd() err is %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckMemberPromote(t *testing.T) {
+	tests := []struct {
+		name    string
+		members []MemberHealth
+		id      string
+		wantErr bool
+	}{
+		{name: "healthy learner", members: withLearner(members(3, 3), true), id: "9"},
+		// healthy+1 = 3 is just at the quorum 3 of 4 voters
+		{name: "healthy+1 at quorum", members: withLearner(members(3, 2), true), id: "9"},
+		// healthy+1 = 2 is less than the quorum 3 of 4 voters
+		{name: "healthy+1 below quorum", members: withLearner(members(3, 1), true), id: "9", wantErr: true},
+		// healthy+1 = 2 is just at the quorum 2 of 2 voters
+		{name: "single voter", members: withLearner(members(1, 1), true), id: "9"},
+		{name: "unhealthy learner", members: withLearner(members(3, 3), false), id: "9", wantErr: true},
+		{name: "voting member", members: withLearner(members(3, 3), true), id: "2", wantErr: true},
+		{name: "unknown member", members: members(3, 3), id: "9", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckMemberPromote(tt.members, tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckMemberPromote() err is %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckMemberRemove(t *testing.T) {
+	tests := []struct {
+		name    string
+		members []MemberHealth
+		id      string
+		wantErr bool
+	}{
+		{name: "healthy follower", members: members(3, 3), id: "3"},
+		{name: "unhealthy follower", members: members(3, 2), id: "3"},
+		// the other 2 voters are healthy after member 2 is removed
+		{name: "healthy follower with one unhealthy", members: members(3, 2), id: "2", wantErr: true},
+		{name: "follower with unhealthy majority", members: members(5, 2), id: "5", wantErr: true},
+		{name: "leader", members: members(3, 3), id: "1", wantErr: true},
+		{name: "last voter", members: withLearner(members(1, 0), true), id: "1", wantErr: true},
+		{name: "learner", members: withLearner(members(3, 1), false), id: "9"},
+		{name: "unknown member", members: members(3, 3), id: "9", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckMemberRemove(tt.members, tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckMemberRemove() err is %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckMoveLeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		members    []MemberHealth
+		id         string
+		wantLeader string
+		wantErr    bool
+	}{
+		{name: "healthy follower", members: members(3, 3), id: "2", wantLeader: "1"},
+		{name: "unhealthy follower", members: members(3, 2), id: "3", wantErr: true},
+		{name: "already the leader", members: members(3, 3), id: "1", wantErr: true},
+		{name: "learner", members: withLearner(members(3, 3), true), id: "9", wantErr: true},
+		{name: "no leader", members: members(3, 0), id: "2", wantErr: true},
+		{name: "unknown member", members: members(3, 3), id: "9", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leader, err := CheckMoveLeader(tt.members, tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckMoveLeader() err is %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && leader.ID != tt.wantLeader {
+				t.Errorf("CheckMoveLeader() = %s, want %s", leader.ID, tt.wantLeader)
+			}
+		})
+	}
+}
@@ -32,9 +32,29 @@ type Feature interface {
 	// Sync synchronizes the latest feature configuration
 	Sync(cluster *v1alpha2.EtcdCluster) error
 
-	// Do executes inspection tasks.
-	Do(task *v1alpha2.EtcdInspection) error
+	// Do executes inspection tasks, and returns the result of the run,
+	// nil if the feature reports nothing.
+	Do(task *v1alpha2.EtcdInspection) (*v1alpha2.EtcdInspectionResult, error)
 }
+
+// FeatureCleaner is implemented by the features which create resources that
+// are not garbage collected with the cluster, e.g. the resources in other namespaces.
+type FeatureCleaner interface {
+	// Clean deletes the resources of the deleted cluster
+	Clean(cluster *v1alpha2.EtcdCluster) error
+
+	// Sweep deletes the resources of the clusters which no longer exist, in
+	// case the deletion of a cluster is missed, exists checks the cluster
+	Sweep(exists func(namespace, name string) (bool, error)) error
+}
+
+// FeatureStopper is implemented by the features which keep running state for
+// the inspection task between the runs, e.g. a long-running watch.
+type FeatureStopper interface {
+	// Stop releases the state of the deleted inspection task
+	Stop(task *v1alpha2.EtcdInspection) error
+}
+
 type FeatureContext struct {
 	ClientBuilder      util.ClientBuilder
 	ClientConfigGetter etcd.ClientConfigGetter
@@ -70,6 +70,6 @@ func (c *FeatureAlarm) Sync(cluster *kstonev1alpha2.EtcdCluster) error {
 	return c.inspection.Sync(cluster, kstonev1alpha2.KStoneFeatureAlarm)
 }
 
-func (c *FeatureAlarm) Do(inspection *kstonev1alpha2.EtcdInspection) error {
+func (c *FeatureAlarm) Do(inspection *kstonev1alpha2.EtcdInspection) (*kstonev1alpha2.EtcdInspectionResult, error) {
 	return c.inspection.CollectAlarmList(inspection)
 }
@@ -25,6 +25,7 @@ import (
 	"tkestack.io/kstone/pkg/backup"
 	"tkestack.io/kstone/pkg/featureprovider"
 	featureutil "tkestack.io/kstone/pkg/featureprovider/util"
+	"tkestack.io/kstone/pkg/inspection"
 )
 
 const (
@@ -37,9 +38,10 @@ var (
 )
 
 type FeatureBackup struct {
-	name      string
-	backupSvr *backup.Server
-	ctx       *featureprovider.FeatureContext
+	name       string
+	backupSvr  *backup.Server
+	inspection *inspection.Server
+	ctx        *featureprovider.FeatureContext
 }
 
 func init() {
@@ -59,6 +61,10 @@ func initFeatureBackupInstance(ctx *featureprovider.FeatureContext) (featureprov
 			ctx:  ctx,
 		}
 		instance.backupSvr, err = backup.NewBackupServer(ctx.ClientBuilder)
+		if err != nil {
+			return
+		}
+		instance.inspection, err = inspection.NewInspectionServer(ctx)
 	})
 	return instance, err
 }
@@ -66,20 +72,24 @@ func initFeatureBackupInstance(ctx *featureprovider.FeatureContext) (featureprov
 func (bak *FeatureBackup) Equal(cluster *kstonev1alpha2.EtcdCluster) bool {
 	if !featureutil.IsFeatureGateEnabled(cluster.ObjectMeta.Annotations, kstonev1alpha2.KStoneFeatureBackup) {
 		if cluster.Status.FeatureGatesStatus[kstonev1alpha2.KStoneFeatureBackup] != featureutil.FeatureStatusDisabled {
-			return bak.backupSvr.CheckEqualIfDisabled(cluster)
+			return bak.backupSvr.CheckEqualIfDisabled(cluster) && bak.inspection.Equal(cluster, kstonev1alpha2.KStoneFeatureBackup)
 		}
 		return true
 	}
-	return bak.backupSvr.CheckEqualIfEnabled(cluster)
+	return bak.backupSvr.CheckEqualIfEnabled(cluster) && bak.inspection.Equal(cluster, kstonev1alpha2.KStoneFeatureBackup)
 }
 
 func (bak *FeatureBackup) Sync(cluster *kstonev1alpha2.EtcdCluster) error {
+	// the inspection task enforces the retention policy
+	if err := bak.inspection.Sync(cluster, kstonev1alpha2.KStoneFeatureBackup); err != nil {
+		return err
+	}
 	if !featureutil.IsFeatureGateEnabled(cluster.ObjectMeta.Annotations, kstonev1alpha2.KStoneFeatureBackup) {
 		return bak.backupSvr.CleanBackup(cluster)
 	}
 	return bak.backupSvr.SyncBackup(cluster)
 }
 
-func (bak *FeatureBackup) Do(inspection *kstonev1alpha2.EtcdInspection) error {
-	return nil
+func (bak *FeatureBackup) Do(inspection *kstonev1alpha2.EtcdInspection) (*kstonev1alpha2.EtcdInspectionResult, error) {
+	return bak.inspection.EnforceBackupRetention(inspection)
 }
@@ -70,6 +70,6 @@ func (c *FeatureBackupCheck) Sync(cluster *kstonev1alpha2.EtcdCluster) error {
 	return c.inspection.Sync(cluster, kstonev1alpha2.KStoneFeatureBackupCheck)
 }
 
-func (c *FeatureBackupCheck) Do(inspection *kstonev1alpha2.EtcdInspection) error {
+func (c *FeatureBackupCheck) Do(inspection *kstonev1alpha2.EtcdInspection) (*kstonev1alpha2.EtcdInspectionResult, error) {
 	return c.inspection.StatBackupFiles(inspection)
 }
@@ -70,6 +70,6 @@ func (c *FeatureConsistency) Sync(cluster *kstonev1alpha2.EtcdCluster) error {
 	return c.inspection.Sync(cluster, kstonev1alpha2.KStoneFeatureConsistency)
 }
 
-func (c *FeatureConsistency) Do(inspection *kstonev1alpha2.EtcdInspection) error {
+func (c *FeatureConsistency) Do(inspection *kstonev1alpha2.EtcdInspection) (*kstonev1alpha2.EtcdInspectionResult, error) {
 	return c.inspection.CollectClusterConsistentData(inspection)
 }
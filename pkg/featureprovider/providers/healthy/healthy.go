@@ -70,6 +70,6 @@ func (c *FeatureHealthy) Sync(cluster *kstonev1alpha2.EtcdCluster) error {
 	return c.inspection.Sync(cluster, kstonev1alpha2.KStoneFeatureHealthy)
 }
 
-func (c *FeatureHealthy) Do(inspection *kstonev1alpha2.EtcdInspection) error {
+func (c *FeatureHealthy) Do(inspection *kstonev1alpha2.EtcdInspection) (*kstonev1alpha2.EtcdInspectionResult, error) {
 	return c.inspection.CollectMemberHealthy(inspection)
 }
@@ -80,6 +80,14 @@ func (p *FeaturePrometheus) Sync(cluster *kstonev1alpha2.EtcdCluster) error {
 	return p.prom.SyncPrometheusMonitor(cluster)
 }
 
-func (p *FeaturePrometheus) Do(inspection *kstonev1alpha2.EtcdInspection) error {
-	return nil
+func (p *FeaturePrometheus) Clean(cluster *kstonev1alpha2.EtcdCluster) error {
+	return p.prom.CleanGrafanaDashboard(cluster)
+}
+
+func (p *FeaturePrometheus) Sweep(exists func(namespace, name string) (bool, error)) error {
+	return p.prom.SweepGrafanaDashboards(exists)
+}
+
+func (p *FeaturePrometheus) Do(inspection *kstonev1alpha2.EtcdInspection) (*kstonev1alpha2.EtcdInspectionResult, error) {
+	return nil, nil
 }
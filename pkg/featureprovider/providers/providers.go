@@ -33,4 +33,14 @@ import (
 	_ "tkestack.io/kstone/pkg/featureprovider/providers/alarm"
 	// register backupcheck inspection feature
 	_ "tkestack.io/kstone/pkg/featureprovider/providers/backupcheck"
+	// register backupverify inspection feature
+	_ "tkestack.io/kstone/pkg/featureprovider/providers/backupverify"
+	// register defrag inspection feature
+	_ "tkestack.io/kstone/pkg/featureprovider/providers/defrag"
+	// register dbsize inspection feature
+	_ "tkestack.io/kstone/pkg/featureprovider/providers/dbsize"
+	// register keyspace inspection feature
+	_ "tkestack.io/kstone/pkg/featureprovider/providers/keyspace"
+	// register scraper inspection feature
+	_ "tkestack.io/kstone/pkg/featureprovider/providers/scraper"
 )
@@ -70,6 +70,10 @@ func (c *FeatureRequest) Sync(cluster *kstonev1alpha2.EtcdCluster) error {
 	return c.inspection.Sync(cluster, kstonev1alpha2.KStoneFeatureRequest)
 }
 
-func (c *FeatureRequest) Do(inspection *kstonev1alpha2.EtcdInspection) error {
+func (c *FeatureRequest) Do(inspection *kstonev1alpha2.EtcdInspection) (*kstonev1alpha2.EtcdInspectionResult, error) {
 	return c.inspection.CollectEtcdClusterRequest(inspection)
 }
+
+func (c *FeatureRequest) Stop(inspection *kstonev1alpha2.EtcdInspection) error {
+	return c.inspection.StopEtcdClusterRequest(inspection)
+}
@@ -19,9 +19,6 @@
 package util
 
 import (
-	"strconv"
-	"strings"
-
 	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
 	"tkestack.io/kstone/pkg/inspection/metrics"
 )
@@ -46,21 +43,9 @@ const (
 )
 
 func IsFeatureGateEnabled(annotations map[string]string, name kstonev1alpha2.KStoneFeature) bool {
-	if gates, found := annotations[kstonev1alpha2.KStoneFeatureAnno]; found && gates != "" {
-		featurelist := strings.Split(gates, ",")
-		for _, item := range featurelist {
-			features := strings.Split(item, "=")
-			if len(features) != 2 {
-				continue
-			}
-
-			enabled, _ := strconv.ParseBool(features[1])
-			if kstonev1alpha2.KStoneFeature(features[0]) == name && enabled {
-				return true
-			}
-		}
-	}
-	return false
+	// the invalid items are ignored, the valid ones still take effect
+	features, _ := kstonev1alpha2.ParseFeatureGates(annotations[kstonev1alpha2.KStoneFeatureAnno])
+	return features[name]
 }
 
 func IncrFailedInspectionCounter(clusterName string, featureName kstonev1alpha2.KStoneFeature) {
@@ -28,12 +28,14 @@ import (
 	flowcontrol "k8s.io/client-go/util/flowcontrol"
 	kstonev1alpha1 "tkestack.io/kstone/pkg/generated/clientset/versioned/typed/kstone/v1alpha1"
 	kstonev1alpha2 "tkestack.io/kstone/pkg/generated/clientset/versioned/typed/kstone/v1alpha2"
+	kstonev1alpha3 "tkestack.io/kstone/pkg/generated/clientset/versioned/typed/kstone/v1alpha3"
 )
 
 type Interface interface {
 	Discovery() discovery.DiscoveryInterface
 	KstoneV1alpha1() kstonev1alpha1.KstoneV1alpha1Interface
 	KstoneV1alpha2() kstonev1alpha2.KstoneV1alpha2Interface
+	KstoneV1alpha3() kstonev1alpha3.KstoneV1alpha3Interface
 }
 
 // Clientset contains the clients for groups. Each group has exactly one
@@ -42,6 +44,7 @@ type Clientset struct {
 	*discovery.DiscoveryClient
 	kstoneV1alpha1 *kstonev1alpha1.KstoneV1alpha1Client
 	kstoneV1alpha2 *kstonev1alpha2.KstoneV1alpha2Client
+	kstoneV1alpha3 *kstonev1alpha3.KstoneV1alpha3Client
 }
 
 // KstoneV1alpha1 retrieves the KstoneV1alpha1Client
@@ -54,6 +57,11 @@ func (c *Clientset) KstoneV1alpha2() kstonev1alpha2.KstoneV1alpha2Interface {
 	return c.kstoneV1alpha2
 }
 
+// KstoneV1alpha3 retrieves the KstoneV1alpha3Client
+func (c *Clientset) KstoneV1alpha3() kstonev1alpha3.KstoneV1alpha3Interface {
+	return c.kstoneV1alpha3
+}
+
 // Discovery retrieves the DiscoveryClient
 func (c *Clientset) Discovery() discovery.DiscoveryInterface {
 	if c == nil {
@@ -83,6 +91,10 @@ func NewForConfig(c *rest.Config) (*Clientset, error) {
 	if err != nil {
 		return nil, err
 	}
+	cs.kstoneV1alpha3, err = kstonev1alpha3.NewForConfig(&configShallowCopy)
+	if err != nil {
+		return nil, err
+	}
 
 	cs.DiscoveryClient, err = discovery.NewDiscoveryClientForConfig(&configShallowCopy)
 	if err != nil {
@@ -97,6 +109,7 @@ func NewForConfigOrDie(c *rest.Config) *Clientset {
 	var cs Clientset
 	cs.kstoneV1alpha1 = kstonev1alpha1.NewForConfigOrDie(c)
 	cs.kstoneV1alpha2 = kstonev1alpha2.NewForConfigOrDie(c)
+	cs.kstoneV1alpha3 = kstonev1alpha3.NewForConfigOrDie(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClientForConfigOrDie(c)
 	return &cs
@@ -107,6 +120,7 @@ func New(c rest.Interface) *Clientset {
 	var cs Clientset
 	cs.kstoneV1alpha1 = kstonev1alpha1.New(c)
 	cs.kstoneV1alpha2 = kstonev1alpha2.New(c)
+	cs.kstoneV1alpha3 = kstonev1alpha3.New(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClient(c)
 	return &cs
@@ -31,6 +31,8 @@ import (
 	fakekstonev1alpha1 "tkestack.io/kstone/pkg/generated/clientset/versioned/typed/kstone/v1alpha1/fake"
 	kstonev1alpha2 "tkestack.io/kstone/pkg/generated/clientset/versioned/typed/kstone/v1alpha2"
 	fakekstonev1alpha2 "tkestack.io/kstone/pkg/generated/clientset/versioned/typed/kstone/v1alpha2/fake"
+	kstonev1alpha3 "tkestack.io/kstone/pkg/generated/clientset/versioned/typed/kstone/v1alpha3"
+	fakekstonev1alpha3 "tkestack.io/kstone/pkg/generated/clientset/versioned/typed/kstone/v1alpha3/fake"
 )
 
 // NewSimpleClientset returns a clientset that will respond with the provided objects.
@@ -89,3 +91,8 @@ func (c *Clientset) KstoneV1alpha1() kstonev1alpha1.KstoneV1alpha1Interface {
 func (c *Clientset) KstoneV1alpha2() kstonev1alpha2.KstoneV1alpha2Interface {
 	return &fakekstonev1alpha2.FakeKstoneV1alpha2{Fake: &c.Fake}
 }
+
+// KstoneV1alpha3 retrieves the KstoneV1alpha3Client
+func (c *Clientset) KstoneV1alpha3() kstonev1alpha3.KstoneV1alpha3Interface {
+	return &fakekstonev1alpha3.FakeKstoneV1alpha3{Fake: &c.Fake}
+}
@@ -28,6 +28,7 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	kstonev1alpha1 "tkestack.io/kstone/pkg/apis/kstone/v1alpha1"
 	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	kstonev1alpha3 "tkestack.io/kstone/pkg/apis/kstone/v1alpha3"
 )
 
 var scheme = runtime.NewScheme()
@@ -36,6 +37,7 @@ var codecs = serializer.NewCodecFactory(scheme)
 var localSchemeBuilder = runtime.SchemeBuilder{
 	kstonev1alpha1.AddToScheme,
 	kstonev1alpha2.AddToScheme,
+	kstonev1alpha3.AddToScheme,
 }
 
 // AddToScheme adds all types of this clientset into the given scheme. This allows composition
@@ -38,6 +38,10 @@ func (c *FakeKstoneV1alpha2) EtcdInspections(namespace string) v1alpha2.EtcdInsp
 	return &FakeEtcdInspections{c, namespace}
 }
 
+func (c *FakeKstoneV1alpha2) EtcdRestores(namespace string) v1alpha2.EtcdRestoreInterface {
+	return &FakeEtcdRestores{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeKstoneV1alpha2) RESTClient() rest.Interface {
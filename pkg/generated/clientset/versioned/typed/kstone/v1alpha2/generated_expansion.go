@@ -23,3 +23,5 @@ package v1alpha2
 type EtcdClusterExpansion interface{}
 
 type EtcdInspectionExpansion interface{}
+
+type EtcdRestoreExpansion interface{}
@@ -30,6 +30,7 @@ type KstoneV1alpha2Interface interface {
 	RESTClient() rest.Interface
 	EtcdClustersGetter
 	EtcdInspectionsGetter
+	EtcdRestoresGetter
 }
 
 // KstoneV1alpha2Client is used to interact with features provided by the kstone.tkestack.io group.
@@ -45,6 +46,10 @@ func (c *KstoneV1alpha2Client) EtcdInspections(namespace string) EtcdInspectionI
 	return newEtcdInspections(c, namespace)
 }
 
+func (c *KstoneV1alpha2Client) EtcdRestores(namespace string) EtcdRestoreInterface {
+	return newEtcdRestores(c, namespace)
+}
+
 // NewForConfig creates a new KstoneV1alpha2Client for the given config.
 func NewForConfig(c *rest.Config) (*KstoneV1alpha2Client, error) {
 	config := *c
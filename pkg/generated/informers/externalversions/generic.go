@@ -27,6 +27,7 @@ import (
 	cache "k8s.io/client-go/tools/cache"
 	v1alpha1 "tkestack.io/kstone/pkg/apis/kstone/v1alpha1"
 	v1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	v1alpha3 "tkestack.io/kstone/pkg/apis/kstone/v1alpha3"
 )
 
 // GenericInformer is type of SharedIndexInformer which will locate and delegate to other
@@ -66,6 +67,12 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Kstone().V1alpha2().EtcdClusters().Informer()}, nil
 	case v1alpha2.SchemeGroupVersion.WithResource("etcdinspections"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Kstone().V1alpha2().EtcdInspections().Informer()}, nil
+	case v1alpha2.SchemeGroupVersion.WithResource("etcdrestores"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Kstone().V1alpha2().EtcdRestores().Informer()}, nil
+
+		// Group=kstone.tkestack.io, Version=v1alpha3
+	case v1alpha3.SchemeGroupVersion.WithResource("etcdclusters"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Kstone().V1alpha3().EtcdClusters().Informer()}, nil
 
 	}
 
@@ -24,6 +24,7 @@ import (
 	internalinterfaces "tkestack.io/kstone/pkg/generated/informers/externalversions/internalinterfaces"
 	v1alpha1 "tkestack.io/kstone/pkg/generated/informers/externalversions/kstone/v1alpha1"
 	v1alpha2 "tkestack.io/kstone/pkg/generated/informers/externalversions/kstone/v1alpha2"
+	v1alpha3 "tkestack.io/kstone/pkg/generated/informers/externalversions/kstone/v1alpha3"
 )
 
 // Interface provides access to each of this group's versions.
@@ -32,6 +33,8 @@ type Interface interface {
 	V1alpha1() v1alpha1.Interface
 	// V1alpha2 provides access to shared informers for resources in V1alpha2.
 	V1alpha2() v1alpha2.Interface
+	// V1alpha3 provides access to shared informers for resources in V1alpha3.
+	V1alpha3() v1alpha3.Interface
 }
 
 type group struct {
@@ -54,3 +57,8 @@ func (g *group) V1alpha1() v1alpha1.Interface {
 func (g *group) V1alpha2() v1alpha2.Interface {
 	return v1alpha2.New(g.factory, g.namespace, g.tweakListOptions)
 }
+
+// V1alpha3 returns a new v1alpha3.Interface.
+func (g *group) V1alpha3() v1alpha3.Interface {
+	return v1alpha3.New(g.factory, g.namespace, g.tweakListOptions)
+}
@@ -30,6 +30,8 @@ type Interface interface {
 	EtcdClusters() EtcdClusterInformer
 	// EtcdInspections returns a EtcdInspectionInformer.
 	EtcdInspections() EtcdInspectionInformer
+	// EtcdRestores returns a EtcdRestoreInformer.
+	EtcdRestores() EtcdRestoreInformer
 }
 
 type version struct {
@@ -52,3 +54,8 @@ func (v *version) EtcdClusters() EtcdClusterInformer {
 func (v *version) EtcdInspections() EtcdInspectionInformer {
 	return &etcdInspectionInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// EtcdRestores returns a EtcdRestoreInformer.
+func (v *version) EtcdRestores() EtcdRestoreInformer {
+	return &etcdRestoreInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
@@ -35,3 +35,11 @@ type EtcdInspectionListerExpansion interface{}
 // EtcdInspectionNamespaceListerExpansion allows custom methods to be added to
 // EtcdInspectionNamespaceLister.
 type EtcdInspectionNamespaceListerExpansion interface{}
+
+// EtcdRestoreListerExpansion allows custom methods to be added to
+// EtcdRestoreLister.
+type EtcdRestoreListerExpansion interface{}
+
+// EtcdRestoreNamespaceListerExpansion allows custom methods to be added to
+// EtcdRestoreNamespaceLister.
+type EtcdRestoreNamespaceListerExpansion interface{}
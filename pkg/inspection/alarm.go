@@ -19,7 +19,10 @@
 package inspection
 
 import (
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"k8s.io/klog/v2"
 
@@ -27,13 +30,14 @@ import (
 	"tkestack.io/kstone/pkg/clusterprovider"
 	featureutil "tkestack.io/kstone/pkg/featureprovider/util"
 	"tkestack.io/kstone/pkg/inspection/metrics"
+	"tkestack.io/kstone/pkg/notifier"
 )
 
 var alarmTypeList = []string{"NOSPACE", "CORRUPT"}
 
 // CollectAlarmList collects the alarms of etcd, and
 // transfer them to prometheus metrics
-func (c *Server) CollectAlarmList(inspection *kstonev1alpha2.EtcdInspection) error {
+func (c *Server) CollectAlarmList(inspection *kstonev1alpha2.EtcdInspection) (*kstonev1alpha2.EtcdInspectionResult, error) {
 	namespace, name := inspection.Namespace, inspection.Spec.ClusterName
 	cluster, clientConfig, err := c.GetEtcdClusterInfo(namespace, name)
 	defer func() {
@@ -43,39 +47,91 @@ func (c *Server) CollectAlarmList(inspection *kstonev1alpha2.EtcdInspection) err
 	}()
 	if err != nil {
 		klog.Errorf("load tlsConfig failed, namespace is %s, name is %s, err is %v", namespace, name, err)
-		return err
+		return nil, err
 	}
 
 	alarms, err := clusterprovider.GetEtcdAlarms([]string{cluster.Status.ServiceName}, clientConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	// the members whose hash differs found by the deep consistency check
+	// are reported as the CORRUPT alarm, even if etcd has not raised it
+	revision, mismatched := deepChecks.getMismatched(namespace+"/"+name, time.Now())
+	corrupted := make(map[string]bool)
+	for _, endpoint := range mismatched {
+		corrupted[endpoint] = true
+	}
+
+	result := newResultBuilder()
 	for _, m := range cluster.Status.Members {
-		if len(alarms) == 0 {
-			cleanAllAlarmMetrics(cluster.Name, m.Endpoint)
-		}
+		raised := make([]string, 0)
 		for _, a := range alarms {
 			if m.MemberId == strconv.FormatUint(a.MemberID, 10) {
-				labels := map[string]string{
-					"clusterName": cluster.Name,
-					"endpoint":    m.Endpoint,
-					"alarmType":   a.AlarmType,
-				}
-				metrics.EtcdEndpointAlarm.With(labels).Set(1)
+				raised = append(raised, a.AlarmType)
+				result.addFinding(
+					kstonev1alpha2.EtcdInspectionFail,
+					"member %s raised the %s alarm",
+					m.Endpoint,
+					a.AlarmType,
+				)
+				notifier.Notify(&notifier.Event{
+					Namespace: namespace,
+					Cluster:   name,
+					Reason:    notifier.ReasonAlarm,
+					Severity:  notifier.SeverityCritical,
+					Subject:   m.Endpoint + "/" + a.AlarmType,
+					Message:   fmt.Sprintf("member %s raised the %s alarm", m.Endpoint, a.AlarmType),
+				})
 			}
 		}
+		if corrupted[m.Endpoint] && !containsString(raised, AlarmCorrupt) {
+			// notified by the consistency inspection with the same subject
+			raised = append(raised, AlarmCorrupt)
+			result.addFinding(
+				kstonev1alpha2.EtcdInspectionFail,
+				"member %s is %s, its hash differs from the others at revision %d",
+				m.Endpoint,
+				AlarmCorrupt,
+				revision,
+			)
+		}
+		setAlarmMetrics(cluster.Name, m.Endpoint, raised)
+
+		if len(raised) > 0 {
+			result.addMember(m.Endpoint, kstonev1alpha2.EtcdInspectionFail, "alarms: "+strings.Join(raised, ","))
+		} else {
+			result.addMember(m.Endpoint, kstonev1alpha2.EtcdInspectionPass, "no alarm")
+		}
 	}
-	return nil
+	return result.build("no alarm is raised"), nil
 }
 
-// cleanAllAlarmMetrics clear all alarm metrics by cluster
-func cleanAllAlarmMetrics(clusterName, endpoint string) {
+// setAlarmMetrics sets the alarm metrics of the member, the alarms not raised are cleared
+func setAlarmMetrics(clusterName, endpoint string, raised []string) {
 	for _, t := range alarmTypeList {
+		if !containsString(raised, t) {
+			metrics.EtcdEndpointAlarm.With(map[string]string{
+				"clusterName": clusterName,
+				"endpoint":    endpoint,
+				"alarmType":   t,
+			}).Set(0)
+		}
+	}
+	for _, t := range raised {
 		metrics.EtcdEndpointAlarm.With(map[string]string{
 			"clusterName": clusterName,
 			"endpoint":    endpoint,
 			"alarmType":   t,
-		}).Set(0)
+		}).Set(1)
+	}
+}
+
+func containsString(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
 	}
+	return false
 }
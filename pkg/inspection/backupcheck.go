@@ -20,6 +20,8 @@ package inspection
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -28,6 +30,7 @@ import (
 	"tkestack.io/kstone/pkg/backup"
 	_ "tkestack.io/kstone/pkg/backup/providers" // import backup provider
 	"tkestack.io/kstone/pkg/inspection/metrics"
+	"tkestack.io/kstone/pkg/notifier"
 
 	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
 	featureutil "tkestack.io/kstone/pkg/featureprovider/util"
@@ -35,7 +38,7 @@ import (
 
 // StatBackupFiles counts the number of backup files in the last day and
 // transfer it to prometheus metrics
-func (c *Server) StatBackupFiles(inspection *kstonev1alpha2.EtcdInspection) error {
+func (c *Server) StatBackupFiles(inspection *kstonev1alpha2.EtcdInspection) (*kstonev1alpha2.EtcdInspectionResult, error) {
 	namespace, name := inspection.Namespace, inspection.Spec.ClusterName
 	labels := map[string]string{
 		"clusterName": name,
@@ -49,16 +52,16 @@ func (c *Server) StatBackupFiles(inspection *kstonev1alpha2.EtcdInspection) erro
 	}()
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			return nil
+			return nil, nil
 		}
-		return err
+		return nil, err
 	}
 
 	// get backup config
 	backupConfig, err := backup.GetBackupConfig(cluster)
 	if err != nil {
 		klog.Errorf("failed to get backup config,cluster %s,err is %v", cluster.Name, err)
-		return err
+		return nil, err
 	}
 
 	// get specified backup storage provider
@@ -67,19 +70,15 @@ func (c *Server) StatBackupFiles(inspection *kstonev1alpha2.EtcdInspection) erro
 	})
 	if err != nil {
 		klog.Errorf("failed to get backup provider,cluster %s,err is %v", inspection.ClusterName, err)
-		return err
+		return nil, err
 	}
 	objects, err := storage.List(cluster)
 	if err != nil {
 		klog.Errorf("failed to list backup files,cluster %s,err is %v", inspection.ClusterName, err)
-		return err
+		return nil, err
 	}
 
-	actualFiles, err := storage.Stat(objects)
-	if err != nil {
-		klog.Errorf("failed to stat backup files,cluster %s,err is %v", inspection.ClusterName, err)
-		return err
-	}
+	actualFiles := backup.CountRecentBackups(objects, featureutil.OneDaySeconds*time.Second)
 	DesiredFiles := int(featureutil.OneDaySeconds / backupConfig.StoragePolicy.BackupIntervalInSecond)
 	if DesiredFiles > backupConfig.StoragePolicy.MaxBackups {
 		DesiredFiles = backupConfig.StoragePolicy.MaxBackups
@@ -91,5 +90,33 @@ func (c *Server) StatBackupFiles(inspection *kstonev1alpha2.EtcdInspection) erro
 
 	metrics.EtcdBackupFiles.With(labels).Set(float64(actualFiles))
 	metrics.EtcdFailedBackupFiles.With(labels).Set(float64(failedFiles))
-	return nil
+
+	result := newResultBuilder()
+	if failedFiles > 0 {
+		result.addFinding(
+			kstonev1alpha2.EtcdInspectionWarn,
+			"%d of %d backup files in the last day",
+			actualFiles,
+			DesiredFiles,
+		)
+	}
+
+	// a backup is missed if there is no backup file in the last two intervals
+	interval := time.Duration(backupConfig.StoragePolicy.BackupIntervalInSecond) * time.Second
+	if backup.CountRecentBackups(objects, 2*interval) == 0 {
+		result.addFinding(kstonev1alpha2.EtcdInspectionFail, "no backup file in the last %s", 2*interval)
+		notifier.Notify(&notifier.Event{
+			Namespace: namespace,
+			Cluster:   name,
+			Reason:    notifier.ReasonBackupMissing,
+			Severity:  notifier.SeverityWarning,
+			Message: fmt.Sprintf(
+				"no backup file in the last %s, %d of %d backup files in the last day",
+				2*interval,
+				actualFiles,
+				DesiredFiles,
+			),
+		})
+	}
+	return result.build(fmt.Sprintf("%d of %d backup files in the last day", actualFiles, DesiredFiles)), nil
 }
@@ -0,0 +1,116 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package inspection
+
+import (
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/backup"
+	featureutil "tkestack.io/kstone/pkg/featureprovider/util"
+	"tkestack.io/kstone/pkg/inspection/metrics"
+)
+
+const (
+	// DefaultBackupRetentionInterval is the interval of enforcing the retention
+	// policy if IntervalInSecond of etcdinspection is not specified
+	DefaultBackupRetentionInterval = 10 * time.Minute
+)
+
+// EnforceBackupRetention deletes the backup files which are not kept by the
+// retention policy of the backup config, and transfers the result to
+// prometheus metrics and the records of etcdinspection
+func (c *Server) EnforceBackupRetention(inspection *kstonev1alpha2.EtcdInspection) (*kstonev1alpha2.EtcdInspectionResult, error) {
+	namespace, name := inspection.Namespace, inspection.Spec.ClusterName
+	labels := map[string]string{
+		"clusterName": name,
+	}
+	start := time.Now()
+
+	cluster, _, err := c.GetEtcdClusterInfo(namespace, name)
+	defer func() {
+		if err != nil {
+			featureutil.IncrFailedInspectionCounter(name, kstonev1alpha2.KStoneFeatureBackup)
+		}
+	}()
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	backupConfig, err := backup.GetBackupConfig(cluster)
+	if err != nil {
+		klog.Errorf("failed to get backup config,cluster %s,err is %v", name, err)
+		return nil, err
+	}
+	storage, err := backup.GetBackupStorageProvider(string(backupConfig.StorageType), &backup.StorageConfig{
+		KubeCli: c.kubeCli,
+	})
+	if err != nil {
+		klog.Errorf("failed to get backup provider,cluster %s,err is %v", name, err)
+		return nil, err
+	}
+	// MaxBackups of etcd-operator rotates the backup files if retention is not
+	// set, only the event segments older than the backup files are deleted
+	if backupConfig.Retention == nil {
+		var deleted []string
+		deleted, err = backup.PruneEventSegments(storage, cluster)
+		if err != nil {
+			klog.Errorf("failed to prune event segments, cluster %s, err is %v", name, err)
+			return nil, err
+		}
+		metrics.EtcdBackupRetentionDeletedEventSegments.With(labels).Add(float64(len(deleted)))
+		return nil, nil
+	}
+
+	plan, err := backup.EnforceRetention(storage, cluster, backupConfig.Retention, false)
+	record := kstonev1alpha2.EtcdInspectionRecord{
+		StartTime: metav1.NewTime(start),
+	}
+	if plan != nil {
+		metrics.EtcdBackupRetentionDeletedFiles.With(labels).Add(float64(len(plan.Delete)))
+		metrics.EtcdBackupRetentionKeptFiles.With(labels).Set(float64(len(plan.Keep)))
+		metrics.EtcdBackupRetentionDeletedEventSegments.With(labels).Add(float64(len(plan.DeleteEventSegments)))
+		record.Message = fmt.Sprintf("%d backup files are kept, %d are deleted, %d event segments are deleted",
+			len(plan.Keep), len(plan.Delete), len(plan.DeleteEventSegments))
+	}
+	if err != nil {
+		klog.Errorf("failed to enforce backup retention, cluster %s, err is %v", name, err)
+		record.Reason = "RetentionFailed"
+		record.Message = err.Error()
+	} else {
+		record.Reason = "RetentionSucceeded"
+	}
+
+	record.EndTime = metav1.Now()
+	if rErr := c.AddEtcdInspectionRecord(inspection, record); rErr != nil && err == nil {
+		err = rErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newResultBuilder().build(record.Message), nil
+}
@@ -0,0 +1,176 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package inspection
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/backup"
+	"tkestack.io/kstone/pkg/clusterprovider"
+	"tkestack.io/kstone/pkg/etcd"
+	featureutil "tkestack.io/kstone/pkg/featureprovider/util"
+	"tkestack.io/kstone/pkg/inspection/metrics"
+)
+
+const (
+	// DefaultBackupVerifyInterval is the interval of restore drills if
+	// IntervalInSecond of etcdinspection is not specified
+	DefaultBackupVerifyInterval = time.Hour
+)
+
+// VerifyBackup restores the newest backup file into an ephemeral etcd, compares
+// it with the live cluster, and transfers the result to prometheus metrics and
+// the records of etcdinspection
+func (c *Server) VerifyBackup(inspection *kstonev1alpha2.EtcdInspection) (*kstonev1alpha2.EtcdInspectionResult, error) {
+	namespace, name := inspection.Namespace, inspection.Spec.ClusterName
+	labels := map[string]string{
+		"clusterName": name,
+	}
+	start := time.Now()
+
+	cluster, clientConfig, err := c.GetEtcdClusterInfo(namespace, name)
+	defer func() {
+		if err != nil {
+			featureutil.IncrFailedInspectionCounter(name, kstonev1alpha2.KStoneFeatureBackupVerify)
+		}
+	}()
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	result, err := c.verifyBackup(cluster)
+	record := kstonev1alpha2.EtcdInspectionRecord{
+		StartTime: metav1.NewTime(start),
+	}
+	if err == nil {
+		var revision, keys int64
+		revision, keys, err = c.getLiveRevisionAndKeys(cluster, clientConfig)
+		if err == nil {
+			metrics.EtcdBackupVerifyRevisionLag.With(labels).Set(float64(revision - result.Revision))
+			metrics.EtcdBackupVerifyKeyDiff.With(labels).Set(float64(keys - result.Keys))
+			err = checkVerifyResult(result, revision, keys, getAlertThresholds(cluster).BackupKeyDiff)
+			record.Message = fmt.Sprintf(
+				"backup file %s is restored, revision %d (live %d), keys %d (live %d)",
+				result.BackupKey,
+				result.Revision,
+				revision,
+				result.Keys,
+				keys,
+			)
+		}
+	}
+	if result != nil {
+		metrics.EtcdBackupVerifySize.With(labels).Set(float64(result.Size))
+	}
+	if err != nil {
+		klog.Errorf("failed to verify backup, cluster %s, err is %v", name, err)
+		metrics.EtcdBackupVerifySuccess.With(labels).Set(0)
+		record.Reason = "VerifyFailed"
+		if record.Message != "" {
+			record.Message += ": " + err.Error()
+		} else {
+			record.Message = err.Error()
+		}
+	} else {
+		metrics.EtcdBackupVerifySuccess.With(labels).Set(1)
+		record.Reason = "VerifySucceeded"
+	}
+	metrics.EtcdBackupVerifyDuration.With(labels).Set(time.Since(start).Seconds())
+
+	record.EndTime = metav1.Now()
+	if rErr := c.AddEtcdInspectionRecord(inspection, record); rErr != nil && err == nil {
+		err = rErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newResultBuilder().build(record.Message), nil
+}
+
+// checkVerifyResult checks the backup file restored against the revision and
+// the number of keys of the live cluster, the revision of a backup file is
+// never higher than the live one unless the backup file belongs to another
+// cluster or the cluster lost its data, and the number of keys must not differ
+// by more than maxKeyDiff
+func checkVerifyResult(result *backup.VerifyResult, revision, keys, maxKeyDiff int64) error {
+	if result.Revision > revision {
+		return fmt.Errorf("the revision %d of the backup file is higher than the live revision %d", result.Revision, revision)
+	}
+	diff := keys - result.Keys
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > maxKeyDiff {
+		return fmt.Errorf("the number of keys differs from the live cluster by %d, more than %d", diff, maxKeyDiff)
+	}
+	return nil
+}
+
+// verifyBackup restores the newest backup file of cluster into an ephemeral etcd
+func (c *Server) verifyBackup(cluster *kstonev1alpha2.EtcdCluster) (*backup.VerifyResult, error) {
+	backupConfig, err := backup.GetBackupConfig(cluster)
+	if err != nil {
+		klog.Errorf("failed to get backup config,cluster %s,err is %v", cluster.Name, err)
+		return nil, err
+	}
+
+	storage, err := backup.GetBackupStorageProvider(string(backupConfig.StorageType), &backup.StorageConfig{
+		KubeCli: c.kubeCli,
+	})
+	if err != nil {
+		klog.Errorf("failed to get backup provider,cluster %s,err is %v", cluster.Name, err)
+		return nil, err
+	}
+
+	return backup.VerifyBackup(storage, cluster)
+}
+
+// getLiveRevisionAndKeys gets the revision and the number of keys of the live cluster
+func (c *Server) getLiveRevisionAndKeys(
+	cluster *kstonev1alpha2.EtcdCluster,
+	clientConfig *etcd.ClientConfig,
+) (int64, int64, error) {
+	clientConfig.Endpoints = clusterprovider.GetStorageMemberEndpoints(cluster)
+	client, err := etcd.NewClientv3(clientConfig)
+	if err != nil {
+		klog.Errorf("failed to get new etcd clientv3,err is %v", err)
+		return 0, 0, err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), etcd.DefaultDialTimeout)
+	defer cancel()
+	resp, err := client.Get(ctx, "\x00", clientv3.WithFromKey(), clientv3.WithCountOnly())
+	if err != nil {
+		klog.Errorf("failed to count keys, cluster %s, err is %v", cluster.Name, err)
+		return 0, 0, err
+	}
+	return resp.Header.Revision, resp.Count, nil
+}
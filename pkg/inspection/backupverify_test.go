@@ -0,0 +1,52 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package inspection
+
+import (
+	"testing"
+
+	"tkestack.io/kstone/pkg/backup"
+)
+
+func TestCheckVerifyResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		revision int64
+		keys     int64
+		live     int64
+		liveKeys int64
+		wantErr  bool
+	}{
+		{name: "behind the live cluster", revision: 90, keys: 1000, live: 100, liveKeys: 1010},
+		{name: "same revision", revision: 100, keys: 1000, live: 100, liveKeys: 1000},
+		{name: "revision higher than the live one", revision: 101, keys: 1000, live: 100, liveKeys: 1000, wantErr: true},
+		{name: "key diff at the bound", revision: 90, keys: 1000, live: 100, liveKeys: 1100},
+		{name: "more keys than the bound", revision: 90, keys: 1000, live: 100, liveKeys: 1101, wantErr: true},
+		{name: "fewer keys than the bound", revision: 90, keys: 1101, live: 100, liveKeys: 1000, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &backup.VerifyResult{BackupKey: "etcdbackup", Revision: tt.revision, Keys: tt.keys}
+			err := checkVerifyResult(result, tt.live, tt.liveKeys, 100)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkVerifyResult() err is %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -20,9 +20,12 @@ package inspection
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 	"k8s.io/klog/v2"
@@ -33,20 +36,65 @@ import (
 	"tkestack.io/kstone/pkg/inspection/metrics"
 )
 
+const (
+	// DefaultDeepConsistencyInterval is the default min interval of the deep consistency checks
+	DefaultDeepConsistencyInterval = 10 * time.Minute
+	// MinDeepConsistencyIntervalInSecond is the min value of DeepIntervalInSecond,
+	// a deep check reads the whole keyspace of every member
+	MinDeepConsistencyIntervalInSecond = 60
+)
+
+// ConsistencyInfo is the config of the consistency inspection
 type ConsistencyInfo struct {
+	// Path is the prefix of the keys counted.
 	Path     string `json:"path,omitempty"`
 	Interval int    `json:"interval,omitempty"`
+	// Deep compares the hashes of the keyspace of the members at the same
+	// revision, besides the counters.
+	Deep bool `json:"deep,omitempty"`
+	// DeepIntervalInSecond is the min interval of the deep checks.
+	DeepIntervalInSecond int `json:"deepIntervalInSecond,omitempty"`
+}
+
+// Validate validates the consistency config
+func (info *ConsistencyInfo) Validate() error {
+	if info.DeepIntervalInSecond != 0 && info.DeepIntervalInSecond < MinDeepConsistencyIntervalInSecond {
+		return fmt.Errorf("deepIntervalInSecond must not be less than %d", MinDeepConsistencyIntervalInSecond)
+	}
+	return nil
+}
+
+// GetConsistencyInfo gets the consistency config of cluster
+func GetConsistencyInfo(cluster *kstonev1alpha2.EtcdCluster) (*ConsistencyInfo, error) {
+	info := &ConsistencyInfo{}
+	if cfg, found := cluster.Annotations[kstonev1alpha2.AnnoConsistency]; found {
+		if err := json.Unmarshal([]byte(cfg), info); err != nil {
+			return nil, err
+		}
+		if err := info.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// GetDeepConsistencyInterval gets the min interval of the deep consistency checks
+func GetDeepConsistencyInterval(info *ConsistencyInfo) time.Duration {
+	if info.DeepIntervalInSecond > 0 {
+		return time.Duration(info.DeepIntervalInSecond) * time.Second
+	}
+	return DefaultDeepConsistencyInterval
 }
 
-// getEtcdConsistentMetadata gets the etcd consistent metadata.
+// getEtcdConsistentMetadata gets the etcd consistent metadata of each member, by the endpoint of the member.
 func (c *Server) getEtcdConsistentMetadata(
 	cluster *kstonev1alpha2.EtcdCluster,
 	keyPrefix string,
 	cli *etcd.ClientConfig,
-) (map[featureutil.ConsistencyType][]uint64, error) {
+) (map[string]map[featureutil.ConsistencyType]uint64, error) {
 
 	var mu sync.Mutex
-	endpointMetadata := make(map[featureutil.ConsistencyType][]uint64)
+	endpointMetadata := make(map[string]map[featureutil.ConsistencyType]uint64)
 
 	ctx, cancel := context.WithTimeout(context.Background(), etcd.DefaultDialTimeout)
 	g, ctx := errgroup.WithContext(ctx)
@@ -89,9 +137,7 @@ func (c *Server) getEtcdConsistentMetadata(
 			metadata[featureutil.ConsistencyKeyTotal] = totalKey
 
 			mu.Lock()
-			for t, v := range metadata {
-				endpointMetadata[t] = append(endpointMetadata[t], v)
-			}
+			endpointMetadata[member.Endpoint] = metadata
 			mu.Unlock()
 			return nil
 		})
@@ -104,7 +150,7 @@ func (c *Server) getEtcdConsistentMetadata(
 
 // CollectClusterConsistentData collects the etcd metadata info, calculate the difference, and
 // transfer them to prometheus metrics
-func (c *Server) CollectClusterConsistentData(inspection *kstonev1alpha2.EtcdInspection) error {
+func (c *Server) CollectClusterConsistentData(inspection *kstonev1alpha2.EtcdInspection) (*kstonev1alpha2.EtcdInspectionResult, error) {
 	namespace, name := inspection.Namespace, inspection.Spec.ClusterName
 	cluster, clientConfig, err := c.GetEtcdClusterInfo(namespace, name)
 	defer func() {
@@ -114,18 +160,88 @@ func (c *Server) CollectClusterConsistentData(inspection *kstonev1alpha2.EtcdIns
 	}()
 	if err != nil {
 		klog.Errorf("failed to load tls config, namespace is %s, name is %s, err is %v", namespace, name, err)
-		return err
+		return nil, err
+	}
+	info, err := GetConsistencyInfo(cluster)
+	if err != nil {
+		klog.Errorf("failed to get consistency config, cluster %s, err is %v", cluster.Name, err)
+		return nil, err
 	}
+
+	result := newResultBuilder()
+	var hashKV *hashKVResult
+	key := cluster.Namespace + "/" + cluster.Name
+	if interval := GetDeepConsistencyInterval(info); info.Deep && deepChecks.due(key, interval, time.Now()) {
+		hashKV = c.checkHashKV(cluster, clientConfig, result)
+		if hashKV != nil {
+			reportHashKV(cluster, hashKV, interval, result)
+		}
+	}
+	// the mismatched members of the last deep check are reported until they match
+	if revision, mismatched := deepChecks.getMismatched(key, time.Now()); hashKV == nil && len(mismatched) > 0 {
+		result.addFinding(
+			kstonev1alpha2.EtcdInspectionFail,
+			"the hashes of members %s differ from the others at revision %d",
+			strings.Join(mismatched, ","),
+			revision,
+		)
+	}
+
 	endpointMetadataDiff := make(map[featureutil.ConsistencyType]uint64)
-	endpointMetadata, err := c.getEtcdConsistentMetadata(cluster, DefaultInspectionPath, clientConfig)
+	endpointMetadata, err := c.getEtcdConsistentMetadata(cluster, info.Path, clientConfig)
 	if err != nil {
 		klog.Errorf("failed to getEtcdConsistentMetadata, etcd cluster %s, err is %v", cluster.Name, err)
+		result.addFinding(kstonev1alpha2.EtcdInspectionFail, "failed to get the consistent metadata: %v", err)
 	} else {
-		for t, values := range endpointMetadata {
+		typeValues := make(map[featureutil.ConsistencyType][]uint64)
+		for _, metadata := range endpointMetadata {
+			for t, v := range metadata {
+				typeValues[t] = append(typeValues[t], v)
+			}
+		}
+		for t, values := range typeValues {
 			sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
 			endpointMetadataDiff[t] = values[len(values)-1] - values[0]
 		}
 	}
+	for _, m := range cluster.Status.Members {
+		status, messages := kstonev1alpha2.EtcdInspectionPass, make([]string, 0)
+		if metadata, found := endpointMetadata[m.Endpoint]; found {
+			messages = append(messages, fmt.Sprintf(
+				"%d keys, revision %d, raft applied index %d",
+				metadata[featureutil.ConsistencyKeyTotal],
+				metadata[featureutil.ConsistencyRevision],
+				metadata[featureutil.ConsistencyRaftRaftAppliedIndex],
+			))
+		}
+		if hashKV != nil {
+			messages = append(messages, fmt.Sprintf("hash %d at revision %d", hashKV.hashes[m.Endpoint], hashKV.revision))
+			if hashKV.mismatched[m.Endpoint] {
+				status = kstonev1alpha2.EtcdInspectionFail
+			}
+		}
+		if len(messages) > 0 {
+			result.addMember(m.Endpoint, status, strings.Join(messages, ", "))
+		}
+	}
+
+	thresholds := getAlertThresholds(cluster)
+	if diff := endpointMetadataDiff[featureutil.ConsistencyKeyTotal]; diff > uint64(thresholds.KeyDiff) {
+		result.addFinding(
+			kstonev1alpha2.EtcdInspectionWarn,
+			"the key totals of the members differ by %d, more than %d",
+			diff,
+			thresholds.KeyDiff,
+		)
+	}
+	if diff := endpointMetadataDiff[featureutil.ConsistencyRevision]; diff > uint64(thresholds.RevisionDiff) {
+		result.addFinding(
+			kstonev1alpha2.EtcdInspectionWarn,
+			"the revisions of the members differ by %d, more than %d",
+			diff,
+			thresholds.RevisionDiff,
+		)
+	}
 	labels := map[string]string{
 		"clusterName": cluster.Name,
 	}
@@ -143,5 +259,5 @@ func (c *Server) CollectClusterConsistentData(inspection *kstonev1alpha2.EtcdIns
 			metrics.EtcdNodeRaftIndexDiff.With(labels).Set(float64(v))
 		}
 	}
-	return nil
+	return result.build("the members are consistent"), nil
 }
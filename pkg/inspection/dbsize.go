@@ -0,0 +1,260 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package inspection
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/clusterprovider"
+	"tkestack.io/kstone/pkg/etcd"
+	featureutil "tkestack.io/kstone/pkg/featureprovider/util"
+	"tkestack.io/kstone/pkg/inspection/metrics"
+)
+
+const (
+	// DefaultQuotaBackendBytes is the quota-backend-bytes of etcd if it is not specified
+	DefaultQuotaBackendBytes = 2 * 1024 * 1024 * 1024
+	// DBSizeGrowthWindow is the duration of the db size samples used to
+	// calculate the growth rate
+	DBSizeGrowthWindow = time.Hour
+	// DBSizeGrowthMinSpan is the minimum duration of the samples required to
+	// calculate the growth rate
+	DBSizeGrowthMinSpan = 10 * time.Minute
+
+	quotaBackendBytesFlag = "quota-backend-bytes"
+)
+
+// dbSizeSample is the db size of an etcd member at a time
+type dbSizeSample struct {
+	time   time.Time
+	dbSize int64
+}
+
+// dbSizeSamples keeps the recent db size samples of etcd members, by the
+// <namespace>/<name> of the cluster and the endpoint of the member
+type dbSizeSamples struct {
+	mux     sync.Mutex
+	samples map[string]map[string][]dbSizeSample
+}
+
+func newDBSizeSamples() *dbSizeSamples {
+	return &dbSizeSamples{
+		samples: make(map[string]map[string][]dbSizeSample),
+	}
+}
+
+// add adds the sample of the member of cluster, and returns the growth rate
+// in bytes per second, ok is false if the samples do not span DBSizeGrowthMinSpan
+func (s *dbSizeSamples) add(cluster, endpoint string, sample dbSizeSample) (rate float64, ok bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	members, found := s.samples[cluster]
+	if !found {
+		members = make(map[string][]dbSizeSample)
+		s.samples[cluster] = members
+	}
+	samples := append(members[endpoint], sample)
+	for len(samples) > 1 && sample.time.Sub(samples[0].time) > DBSizeGrowthWindow {
+		samples = samples[1:]
+	}
+	members[endpoint] = samples
+
+	span := sample.time.Sub(samples[0].time)
+	if span < DBSizeGrowthMinSpan {
+		return 0, false
+	}
+	return float64(sample.dbSize-samples[0].dbSize) / span.Seconds(), true
+}
+
+// prune removes the samples of the members of cluster which are not in
+// endpoints, and returns the removed endpoints
+func (s *dbSizeSamples) prune(cluster string, endpoints []string) []string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	current := make(map[string]bool, len(endpoints))
+	for _, endpoint := range endpoints {
+		current[endpoint] = true
+	}
+	removed := make([]string, 0)
+	for endpoint := range s.samples[cluster] {
+		if !current[endpoint] {
+			delete(s.samples[cluster], endpoint)
+			removed = append(removed, endpoint)
+		}
+	}
+	return removed
+}
+
+// remove removes the samples of all the members of cluster
+func (s *dbSizeSamples) remove(cluster string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.samples, cluster)
+}
+
+// CollectDBSize collects the db size, the db size in use and the quota of etcd
+// members, and transfers them to prometheus metrics with the percentage of the
+// quota used and the projected time to reach the quota
+func (c *Server) CollectDBSize(inspection *kstonev1alpha2.EtcdInspection) (*kstonev1alpha2.EtcdInspectionResult, error) {
+	namespace, name := inspection.Namespace, inspection.Spec.ClusterName
+	cluster, clientConfig, err := c.GetEtcdClusterInfo(namespace, name)
+	defer func() {
+		if err != nil {
+			featureutil.IncrFailedInspectionCounter(name, kstonev1alpha2.KStoneFeatureDBSize)
+		}
+	}()
+	if err != nil {
+		klog.Errorf("load tlsConfig failed, namespace is %s, name is %s, err is %v", namespace, name, err)
+		return nil, err
+	}
+
+	quota := GetQuotaBackendBytes(cluster)
+	metrics.EtcdDBQuota.With(map[string]string{"clusterName": cluster.Name}).Set(float64(quota))
+
+	clientConfig.Endpoints = clusterprovider.GetStorageMemberEndpoints(cluster)
+	client, err := etcd.NewClientv3(clientConfig)
+	if err != nil {
+		klog.Errorf("failed to get new etcd clientv3,err is %v", err)
+		return nil, err
+	}
+	defer client.Close()
+
+	// the samples and the metrics of the removed members are never updated again
+	samplesKey := cluster.Namespace + "/" + cluster.Name
+	endpoints := make([]string, 0, len(cluster.Status.Members))
+	for _, m := range cluster.Status.Members {
+		endpoints = append(endpoints, m.Endpoint)
+	}
+	for _, endpoint := range c.dbSizeSamples.prune(samplesKey, endpoints) {
+		deleteDBSizeMetrics(cluster.Name, endpoint)
+	}
+
+	result := newResultBuilder()
+	thresholds := getAlertThresholds(cluster)
+	now := time.Now()
+	for _, m := range cluster.Status.Members {
+		status, sErr := etcd.Status(m.ExtensionClientUrl, client)
+		if sErr != nil {
+			klog.Errorf("failed to get status of member %s, cluster %s, err is %v", m.ExtensionClientUrl, cluster.Name, sErr)
+			result.addMember(m.Endpoint, kstonev1alpha2.EtcdInspectionFail, sErr.Error())
+			err = sErr
+			continue
+		}
+
+		labels := map[string]string{
+			"clusterName": cluster.Name,
+			"endpoint":    m.Endpoint,
+		}
+		metrics.EtcdDBSize.With(labels).Set(float64(status.DbSize))
+		metrics.EtcdDBSizeInUse.With(labels).Set(float64(status.DbSizeInUse))
+		usedPercent := float64(status.DbSize) / float64(quota) * 100
+		metrics.EtcdDBSizeUsedPercent.With(labels).Set(usedPercent)
+		message := fmt.Sprintf("db size %d, in use %d, %.1f%% of the quota", status.DbSize, status.DbSizeInUse, usedPercent)
+		if usedPercent >= float64(thresholds.DBUsedPercent) {
+			result.addMember(m.Endpoint, kstonev1alpha2.EtcdInspectionWarn, message)
+			result.addFinding(
+				kstonev1alpha2.EtcdInspectionWarn,
+				"member %s uses %.1f%% of the quota, more than %d%%",
+				m.Endpoint,
+				usedPercent,
+				thresholds.DBUsedPercent,
+			)
+		} else {
+			result.addMember(m.Endpoint, kstonev1alpha2.EtcdInspectionPass, message)
+		}
+
+		rate, ok := c.dbSizeSamples.add(samplesKey, m.Endpoint, dbSizeSample{time: now, dbSize: status.DbSize})
+		if !ok || rate <= 0 {
+			metrics.EtcdDBTimeToQuota.Delete(labels)
+			continue
+		}
+		left := float64(quota - status.DbSize)
+		if left < 0 {
+			left = 0
+		}
+		metrics.EtcdDBTimeToQuota.With(labels).Set(left / rate)
+	}
+	return result.build(fmt.Sprintf("the quota is %d bytes", quota)), err
+}
+
+// StopDBSize removes the db size samples of the cluster of the deleted inspection
+func (c *Server) StopDBSize(inspection *kstonev1alpha2.EtcdInspection) error {
+	c.dbSizeSamples.remove(inspection.Namespace + "/" + inspection.Spec.ClusterName)
+	return nil
+}
+
+// deleteDBSizeMetrics deletes the db size metrics of the member
+func deleteDBSizeMetrics(clusterName, endpoint string) {
+	labels := map[string]string{
+		"clusterName": clusterName,
+		"endpoint":    endpoint,
+	}
+	metrics.EtcdDBSize.Delete(labels)
+	metrics.EtcdDBSizeInUse.Delete(labels)
+	metrics.EtcdDBSizeUsedPercent.Delete(labels)
+	metrics.EtcdDBTimeToQuota.Delete(labels)
+}
+
+// GetQuotaBackendBytes returns the quota-backend-bytes of cluster, the
+// quotaBackendBytes annotation takes precedence over the args of cluster
+func GetQuotaBackendBytes(cluster *kstonev1alpha2.EtcdCluster) int64 {
+	if value, found := cluster.Annotations[kstonev1alpha2.AnnoQuotaBackendBytes]; found {
+		quota, err := strconv.ParseInt(value, 10, 64)
+		if err == nil && quota > 0 {
+			return quota
+		}
+		klog.Warningf("invalid quotaBackendBytes annotation %s, cluster %s", value, cluster.Name)
+	}
+	if quota := parseQuotaBackendBytes(cluster.Spec.Args); quota > 0 {
+		return quota
+	}
+	return DefaultQuotaBackendBytes
+}
+
+// parseQuotaBackendBytes parses the quota-backend-bytes flag of etcd args, in
+// the form of --quota-backend-bytes=<bytes> or --quota-backend-bytes <bytes>,
+// 0 is returned if it is not found
+func parseQuotaBackendBytes(args []string) int64 {
+	for i, arg := range args {
+		arg = strings.TrimLeft(strings.TrimSpace(arg), "-")
+		var value string
+		switch {
+		case strings.HasPrefix(arg, quotaBackendBytesFlag+"="):
+			value = strings.TrimPrefix(arg, quotaBackendBytesFlag+"=")
+		case arg == quotaBackendBytesFlag && i+1 < len(args):
+			value = args[i+1]
+		default:
+			continue
+		}
+		quota, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err == nil && quota > 0 {
+			return quota
+		}
+	}
+	return 0
+}
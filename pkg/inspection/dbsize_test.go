@@ -0,0 +1,92 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package inspection
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseQuotaBackendBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want int64
+	}{
+		{name: "no args", args: nil, want: 0},
+		{name: "not set", args: []string{"--snapshot-count=10000"}, want: 0},
+		{name: "equal sign", args: []string{"--quota-backend-bytes=8589934592"}, want: 8589934592},
+		{name: "single dash", args: []string{"-quota-backend-bytes=1024"}, want: 1024},
+		{name: "separate value", args: []string{"--quota-backend-bytes", "2048"}, want: 2048},
+		{name: "spaces", args: []string{" --quota-backend-bytes= 4096 "}, want: 4096},
+		{name: "among other args", args: []string{"--auto-compaction-retention=1", "--quota-backend-bytes=1024", "--snapshot-count=10000"}, want: 1024},
+		{name: "missing value", args: []string{"--quota-backend-bytes"}, want: 0},
+		{name: "empty value", args: []string{"--quota-backend-bytes="}, want: 0},
+		{name: "invalid value", args: []string{"--quota-backend-bytes=8G"}, want: 0},
+		{name: "zero", args: []string{"--quota-backend-bytes=0"}, want: 0},
+		{name: "negative", args: []string{"--quota-backend-bytes=-1"}, want: 0},
+		{name: "first valid value", args: []string{"--quota-backend-bytes=8G", "--quota-backend-bytes=1024"}, want: 1024},
+		{name: "similar flag", args: []string{"--quota-backend-bytes-limit=1024"}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseQuotaBackendBytes(tt.args); got != tt.want {
+				t.Errorf("parseQuotaBackendBytes(%q) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDBSizeSamples(t *testing.T) {
+	samples := newDBSizeSamples()
+	now := time.Now()
+	if _, ok := samples.add("kstone/a", "m1", dbSizeSample{time: now, dbSize: 100}); ok {
+		t.Errorf("add() of the first sample ok = true, want false")
+	}
+	rate, ok := samples.add("kstone/a", "m1", dbSizeSample{time: now.Add(DBSizeGrowthMinSpan), dbSize: 700})
+	if want := 600 / DBSizeGrowthMinSpan.Seconds(); !ok || rate != want {
+		t.Errorf("add() = %v, %v, want %v, true", rate, ok, want)
+	}
+	// the samples out of the window are dropped
+	rate, ok = samples.add("kstone/a", "m1", dbSizeSample{time: now.Add(DBSizeGrowthWindow + DBSizeGrowthMinSpan), dbSize: 1300})
+	if want := 600 / (DBSizeGrowthWindow).Seconds(); !ok || rate != want {
+		t.Errorf("add() = %v, %v, want %v, true", rate, ok, want)
+	}
+
+	samples.add("kstone/a", "m2", dbSizeSample{time: now, dbSize: 100})
+	samples.add("kstone/b", "m1", dbSizeSample{time: now, dbSize: 100})
+	if removed := samples.prune("kstone/a", []string{"m1", "m3"}); !reflect.DeepEqual(removed, []string{"m2"}) {
+		t.Errorf("prune() = %v, want [m2]", removed)
+	}
+	if _, found := samples.samples["kstone/a"]["m2"]; found {
+		t.Errorf("samples of the removed member are kept")
+	}
+	if removed := samples.prune("kstone/c", nil); len(removed) != 0 {
+		t.Errorf("prune() of an unknown cluster = %v, want empty", removed)
+	}
+
+	samples.remove("kstone/a")
+	if _, found := samples.samples["kstone/a"]; found {
+		t.Errorf("samples of the removed cluster are kept")
+	}
+	if len(samples.samples["kstone/b"]) != 1 {
+		t.Errorf("samples of the other cluster are removed")
+	}
+}
@@ -0,0 +1,306 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package inspection
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/clusterprovider"
+	"tkestack.io/kstone/pkg/etcd"
+	featureutil "tkestack.io/kstone/pkg/featureprovider/util"
+	"tkestack.io/kstone/pkg/inspection/metrics"
+)
+
+const (
+	// DefaultDefragInterval is the interval of defragmentation runs if
+	// IntervalInSecond of etcdinspection is not specified
+	DefaultDefragInterval = time.Hour
+	// DefaultDefragFragmentationPercent is the percentage of the db size not
+	// in use, a member is defragmented if its fragmentation reaches it
+	DefaultDefragFragmentationPercent = 50
+	// DefaultDefragMinDBSize is the db size a member must reach to be
+	// defragmented, defragmenting a small db reclaims little space
+	DefaultDefragMinDBSize = 100 * 1024 * 1024
+)
+
+// DefragInfo is the config of the thresholds of the defrag feature
+type DefragInfo struct {
+	// FragmentationPercent is the percentage of the db size not in use, a
+	// member is defragmented if its fragmentation reaches it.
+	FragmentationPercent int `json:"fragmentationPercent,omitempty"`
+	// MinDBSize is the db size in bytes a member must reach to be defragmented.
+	MinDBSize int64 `json:"minDBSize,omitempty"`
+}
+
+// Validate validates the defrag config
+func (info *DefragInfo) Validate() error {
+	if info.FragmentationPercent < 0 || info.FragmentationPercent > 100 {
+		return fmt.Errorf("fragmentationPercent must be between 0 and 100")
+	}
+	if info.MinDBSize < 0 {
+		return fmt.Errorf("minDBSize must not be negative")
+	}
+	return nil
+}
+
+// GetDefragInfo gets the defrag config of cluster, the unset fields are defaulted
+func GetDefragInfo(cluster *kstonev1alpha2.EtcdCluster) (*DefragInfo, error) {
+	info := &DefragInfo{}
+	if cfg, found := cluster.Annotations[kstonev1alpha2.AnnoDefrag]; found {
+		if err := json.Unmarshal([]byte(cfg), info); err != nil {
+			return nil, err
+		}
+		if err := info.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if info.FragmentationPercent == 0 {
+		info.FragmentationPercent = DefaultDefragFragmentationPercent
+	}
+	if info.MinDBSize == 0 {
+		info.MinDBSize = DefaultDefragMinDBSize
+	}
+	return info, nil
+}
+
+const (
+	DefragSucceeded = "DefragSucceeded"
+	DefragFailed    = "DefragFailed"
+	DefragSkipped   = "DefragSkipped"
+)
+
+// memberDBStatus is the db size of an etcd member
+type memberDBStatus struct {
+	endpoint    string
+	isLeader    bool
+	dbSize      int64
+	dbSizeInUse int64
+}
+
+// fragmentationRatio returns the ratio of the db size not in use to the db size
+func (m *memberDBStatus) fragmentationRatio() float64 {
+	if m.dbSize <= 0 {
+		return 0
+	}
+	return 1 - float64(m.dbSizeInUse)/float64(m.dbSize)
+}
+
+// Defragment defragments the members whose fragmentation ratio crosses the
+// threshold one at a time, followers before the leader, the run is skipped
+// if the cluster is unhealthy, and the result is transferred to prometheus
+// metrics and the records of etcdinspection
+func (c *Server) Defragment(inspection *kstonev1alpha2.EtcdInspection) (*kstonev1alpha2.EtcdInspectionResult, error) {
+	namespace, name := inspection.Namespace, inspection.Spec.ClusterName
+	start := time.Now()
+
+	cluster, clientConfig, err := c.GetEtcdClusterInfo(namespace, name)
+	defer func() {
+		if err != nil {
+			featureutil.IncrFailedInspectionCounter(name, kstonev1alpha2.KStoneFeatureDefrag)
+		}
+	}()
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	record := kstonev1alpha2.EtcdInspectionRecord{
+		StartTime: metav1.NewTime(start),
+	}
+	record.Reason, record.Message, err = c.defragment(cluster, clientConfig)
+	if err != nil {
+		klog.Errorf("failed to defragment, cluster %s, err is %v", name, err)
+	}
+	if record.Reason == DefragSkipped {
+		metrics.EtcdDefragSkippedTotal.With(map[string]string{"clusterName": name}).Inc()
+	}
+
+	record.EndTime = metav1.Now()
+	if rErr := c.AddEtcdInspectionRecord(inspection, record); rErr != nil && err == nil {
+		err = rErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newResultBuilder().build(record.Message), nil
+}
+
+// defragment defragments the fragmented members of cluster, and returns the
+// reason and the message of the inspection record
+func (c *Server) defragment(
+	cluster *kstonev1alpha2.EtcdCluster,
+	clientConfig *etcd.ClientConfig,
+) (string, string, error) {
+	if cluster.Status.Phase != kstonev1alpha2.EtcdClusterRunning {
+		return DefragSkipped, fmt.Sprintf("cluster phase is %s", cluster.Status.Phase), nil
+	}
+	info, err := GetDefragInfo(cluster)
+	if err != nil {
+		klog.Errorf("failed to get defrag config, cluster %s, err is %v", cluster.Name, err)
+		return DefragFailed, err.Error(), err
+	}
+
+	clientConfig.Endpoints = clusterprovider.GetStorageMemberEndpoints(cluster)
+	client, err := etcd.NewClientv3(clientConfig)
+	if err != nil {
+		klog.Errorf("failed to get new etcd clientv3,err is %v", err)
+		return DefragFailed, err.Error(), err
+	}
+	defer client.Close()
+
+	members, err := getMembersDBStatus(cluster, client)
+	if err != nil {
+		return DefragSkipped, err.Error(), nil
+	}
+	for _, m := range members {
+		metrics.EtcdDefragFragmentationRatio.With(map[string]string{
+			"clusterName": cluster.Name,
+			"endpoint":    m.endpoint,
+		}).Set(m.fragmentationRatio())
+	}
+
+	candidates := pickDefragMembers(members, info)
+	if len(candidates) == 0 {
+		return DefragSucceeded, "no member needs to be defragmented", nil
+	}
+
+	results := make([]string, 0, len(candidates))
+	for i, m := range candidates {
+		// the previous member may not recover from the defragmentation,
+		// check the cluster again before defragmenting the next one
+		if i > 0 {
+			if _, err = getMembersDBStatus(cluster, client); err != nil {
+				results = append(results, fmt.Sprintf("stopped: %v", err))
+				return DefragSkipped, strings.Join(results, "; "), nil
+			}
+		}
+
+		var reclaimed int64
+		reclaimed, err = defragmentMember(cluster.Name, m, client)
+		if err != nil {
+			results = append(results, fmt.Sprintf("%s failed: %v", m.endpoint, err))
+			return DefragFailed, strings.Join(results, "; "), err
+		}
+		results = append(results, fmt.Sprintf("%s reclaimed %d bytes", m.endpoint, reclaimed))
+	}
+	return DefragSucceeded, strings.Join(results, "; "), nil
+}
+
+// getMembersDBStatus gets the db size of the members of cluster, an error is
+// returned if any member is unhealthy
+func getMembersDBStatus(cluster *kstonev1alpha2.EtcdCluster, client *clientv3.Client) ([]*memberDBStatus, error) {
+	if len(cluster.Status.Members) == 0 {
+		return nil, fmt.Errorf("no member found")
+	}
+
+	members := make([]*memberDBStatus, 0, len(cluster.Status.Members))
+	for _, m := range cluster.Status.Members {
+		status, err := etcd.Status(m.ExtensionClientUrl, client)
+		if err != nil {
+			return nil, fmt.Errorf("member %s is unhealthy: %v", m.ExtensionClientUrl, err)
+		}
+		if len(status.Errors) > 0 {
+			return nil, fmt.Errorf("member %s is unhealthy: %s", m.ExtensionClientUrl, status.Errors[0])
+		}
+		if status.Leader == 0 {
+			return nil, fmt.Errorf("member %s has no leader", m.ExtensionClientUrl)
+		}
+		members = append(members, &memberDBStatus{
+			endpoint:    m.ExtensionClientUrl,
+			isLeader:    status.Leader == status.Header.MemberId,
+			dbSize:      status.DbSize,
+			dbSizeInUse: status.DbSizeInUse,
+		})
+	}
+	return members, nil
+}
+
+// pickDefragMembers picks the members crossing the thresholds of info to be
+// defragmented, followers are placed before the leader
+func pickDefragMembers(members []*memberDBStatus, info *DefragInfo) []*memberDBStatus {
+	ratio := float64(info.FragmentationPercent) / 100
+	candidates := make([]*memberDBStatus, 0)
+	for _, m := range members {
+		if m.dbSize >= info.MinDBSize && m.fragmentationRatio() >= ratio {
+			candidates = append(candidates, m)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return !candidates[i].isLeader && candidates[j].isLeader
+	})
+	return candidates
+}
+
+// defragmentMember defragments the member, and returns the db size reclaimed
+func defragmentMember(clusterName string, member *memberDBStatus, client *clientv3.Client) (int64, error) {
+	labels := map[string]string{
+		"clusterName": clusterName,
+		"endpoint":    member.endpoint,
+	}
+
+	klog.Infof("defragment member %s of cluster %s, db size %d, in use %d",
+		member.endpoint, clusterName, member.dbSize, member.dbSizeInUse)
+	start := time.Now()
+	err := etcd.Defragment(member.endpoint, client)
+	metrics.EtcdDefragDuration.With(labels).Set(time.Since(start).Seconds())
+	if err != nil {
+		metrics.EtcdDefragTotal.With(map[string]string{
+			"clusterName": clusterName,
+			"endpoint":    member.endpoint,
+			"result":      "failed",
+		}).Inc()
+		return 0, err
+	}
+	metrics.EtcdDefragTotal.With(map[string]string{
+		"clusterName": clusterName,
+		"endpoint":    member.endpoint,
+		"result":      "succeeded",
+	}).Inc()
+
+	status, err := etcd.Status(member.endpoint, client)
+	if err != nil {
+		klog.Errorf("failed to get status of member %s, err is %v", member.endpoint, err)
+		return 0, nil
+	}
+	after := &memberDBStatus{
+		endpoint:    member.endpoint,
+		dbSize:      status.DbSize,
+		dbSizeInUse: status.DbSizeInUse,
+	}
+	metrics.EtcdDefragFragmentationRatio.With(labels).Set(after.fragmentationRatio())
+
+	reclaimed := member.dbSize - after.dbSize
+	if reclaimed < 0 {
+		reclaimed = 0
+	}
+	metrics.EtcdDefragReclaimedBytes.With(labels).Add(float64(reclaimed))
+	return reclaimed, nil
+}
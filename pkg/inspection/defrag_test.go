@@ -0,0 +1,187 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package inspection
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+)
+
+func TestFragmentationRatio(t *testing.T) {
+	tests := []struct {
+		member memberDBStatus
+		want   float64
+	}{
+		{member: memberDBStatus{dbSize: 200, dbSizeInUse: 50}, want: 0.75},
+		{member: memberDBStatus{dbSize: 200, dbSizeInUse: 200}, want: 0},
+		{member: memberDBStatus{dbSize: 0, dbSizeInUse: 0}, want: 0},
+	}
+	for _, tt := range tests {
+		if got := tt.member.fragmentationRatio(); got != tt.want {
+			t.Errorf("fragmentationRatio() of %+v = %v, want %v", tt.member, got, tt.want)
+		}
+	}
+}
+
+func TestPickDefragMembers(t *testing.T) {
+	const size = DefaultDefragMinDBSize
+	defaults := &DefragInfo{FragmentationPercent: DefaultDefragFragmentationPercent, MinDBSize: DefaultDefragMinDBSize}
+	// member returns a member whose ratio of the db size not in use is ratio
+	member := func(endpoint string, isLeader bool, dbSize int64, ratio float64) *memberDBStatus {
+		return &memberDBStatus{
+			endpoint:    endpoint,
+			isLeader:    isLeader,
+			dbSize:      dbSize,
+			dbSizeInUse: dbSize - int64(float64(dbSize)*ratio),
+		}
+	}
+	tests := []struct {
+		name    string
+		members []*memberDBStatus
+		info    *DefragInfo
+		want    []string
+	}{
+		{
+			name: "followers before the leader",
+			members: []*memberDBStatus{
+				member("a", true, size, 0.8),
+				member("b", false, size, 0.6),
+				member("c", false, 2*size, 0.7),
+			},
+			want: []string{"b", "c", "a"},
+		},
+		{
+			name: "at the thresholds",
+			members: []*memberDBStatus{
+				member("a", false, size, 0.5),
+				member("b", false, size-1, 0.9),
+				member("c", false, 4*size, 0.49),
+			},
+			want: []string{"a"},
+		},
+		{
+			name: "only the leader",
+			members: []*memberDBStatus{
+				member("a", false, size, 0.1),
+				member("b", true, size, 0.9),
+			},
+			want: []string{"b"},
+		},
+		{
+			name: "no fragmented members",
+			members: []*memberDBStatus{
+				member("a", true, size, 0.1),
+				member("b", false, 10, 0.9),
+				member("c", false, 0, 0),
+			},
+			want: []string{},
+		},
+		{
+			name:    "no members",
+			members: nil,
+			want:    []string{},
+		},
+		{
+			name: "configured thresholds",
+			members: []*memberDBStatus{
+				member("a", false, 1024, 0.25),
+				member("b", false, 1023, 0.9),
+				member("c", false, size, 0.1),
+			},
+			info: &DefragInfo{FragmentationPercent: 20, MinDBSize: 1024},
+			want: []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := tt.info
+			if info == nil {
+				info = defaults
+			}
+			got := make([]string, 0)
+			for _, m := range pickDefragMembers(tt.members, info) {
+				got = append(got, m.endpoint)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pickDefragMembers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetDefragInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		anno    map[string]string
+		want    *DefragInfo
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			anno: nil,
+			want: &DefragInfo{FragmentationPercent: DefaultDefragFragmentationPercent, MinDBSize: DefaultDefragMinDBSize},
+		},
+		{
+			name: "configured",
+			anno: map[string]string{kstonev1alpha2.AnnoDefrag: `{"fragmentationPercent":30,"minDBSize":1048576}`},
+			want: &DefragInfo{FragmentationPercent: 30, MinDBSize: 1048576},
+		},
+		{
+			name: "partially configured",
+			anno: map[string]string{kstonev1alpha2.AnnoDefrag: `{"minDBSize":1048576}`},
+			want: &DefragInfo{FragmentationPercent: DefaultDefragFragmentationPercent, MinDBSize: 1048576},
+		},
+		{
+			name:    "invalid json",
+			anno:    map[string]string{kstonev1alpha2.AnnoDefrag: `{"fragmentationPercent":"30"}`},
+			wantErr: true,
+		},
+		{
+			name:    "percent too large",
+			anno:    map[string]string{kstonev1alpha2.AnnoDefrag: `{"fragmentationPercent":101}`},
+			wantErr: true,
+		},
+		{
+			name:    "negative percent",
+			anno:    map[string]string{kstonev1alpha2.AnnoDefrag: `{"fragmentationPercent":-1}`},
+			wantErr: true,
+		},
+		{
+			name:    "negative db size",
+			anno:    map[string]string{kstonev1alpha2.AnnoDefrag: `{"minDBSize":-1}`},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &kstonev1alpha2.EtcdCluster{ObjectMeta: metav1.ObjectMeta{Annotations: tt.anno}}
+			got, err := GetDefragInfo(cluster)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetDefragInfo() err is %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetDefragInfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package inspection
+
+import (
+	"bytes"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/backup"
+)
+
+const (
+	defaultSegmentInterval = 300 * time.Second
+	maxSegmentEvents       = 10000
+	// maxPendingEvents limits the memory used by events that failed to be persisted.
+	maxPendingEvents = 10 * maxSegmentEvents
+)
+
+// eventRecorder persists the watched events to the backup storage in segments,
+// it is only accessed by the watch goroutine of the cluster.
+type eventRecorder struct {
+	cluster  *kstonev1alpha2.EtcdCluster
+	storage  backup.Storage
+	interval time.Duration
+	events   []*backup.Event
+	// from and to are the revisions covered by the pending events
+	from      int64
+	to        int64
+	lastFlush time.Time
+}
+
+// newEventRecorder generates the recorder of events starting from revision from
+func newEventRecorder(
+	cluster *kstonev1alpha2.EtcdCluster,
+	storage backup.Storage,
+	intervalInSecond int,
+	from int64,
+) *eventRecorder {
+	interval := defaultSegmentInterval
+	if intervalInSecond > 0 {
+		interval = time.Duration(intervalInSecond) * time.Second
+	}
+	return &eventRecorder{
+		cluster:   cluster,
+		storage:   storage,
+		interval:  interval,
+		from:      from,
+		to:        from - 1,
+		lastFlush: time.Now(),
+	}
+}
+
+// record appends the event to the pending segment
+func (r *eventRecorder) record(ev *clientv3.Event) {
+	rev := ev.Kv.ModRevision
+	// events of a transaction share the revision, never split them
+	if rev != r.to && len(r.events) >= maxSegmentEvents {
+		_ = r.flush()
+		if len(r.events) >= maxPendingEvents {
+			klog.Errorf("too many pending events, drop events before revision %d, cluster is %s", rev, r.cluster.Name)
+			r.skip(rev)
+		}
+	}
+
+	event := &backup.Event{
+		Type:     ev.Type,
+		Key:      ev.Kv.Key,
+		Revision: rev,
+		Time:     time.Now(),
+	}
+	if ev.Type == clientv3.EventTypePut {
+		event.Value = ev.Kv.Value
+		event.Lease = ev.Kv.Lease
+	}
+	r.events = append(r.events, event)
+	r.to = rev
+}
+
+// flushIfDue uploads the pending segment if the segment interval elapsed
+func (r *eventRecorder) flushIfDue() {
+	if time.Since(r.lastFlush) >= r.interval {
+		_ = r.flush()
+	}
+}
+
+// flush uploads the pending segment, the events are kept and retried later if failed
+func (r *eventRecorder) flush() error {
+	r.lastFlush = time.Now()
+	if len(r.events) == 0 {
+		return nil
+	}
+
+	data, err := backup.EncodeEvents(r.events)
+	if err != nil {
+		klog.Errorf("failed to encode events, cluster is %s, err is %v", r.cluster.Name, err)
+		return err
+	}
+	name := backup.EventSegmentName(r.from, r.to)
+	err = r.storage.Put(r.cluster, name, bytes.NewReader(data))
+	if err != nil {
+		klog.Errorf("failed to persist event segment %s, cluster is %s, err is %v", name, r.cluster.Name, err)
+		return err
+	}
+	klog.V(2).Infof("persist event segment %s, events %d, cluster is %s", name, len(r.events), r.cluster.Name)
+
+	r.events = nil
+	r.from = r.to + 1
+	return nil
+}
+
+// skip drops the pending events and starts a new segment from rev, the
+// revisions before rev are missing in the history.
+func (r *eventRecorder) skip(rev int64) {
+	r.events = nil
+	r.from = rev
+	r.to = rev - 1
+}
@@ -0,0 +1,236 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package inspection
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/clusterprovider"
+	"tkestack.io/kstone/pkg/etcd"
+	"tkestack.io/kstone/pkg/notifier"
+)
+
+const (
+	// AlarmCorrupt is the alarm type raised for the members whose hash differs
+	AlarmCorrupt = "CORRUPT"
+)
+
+// hashCheck is the last deep consistency check of a cluster
+type hashCheck struct {
+	time       time.Time
+	interval   time.Duration
+	revision   int64
+	mismatched []string
+}
+
+// hashChecks keeps the last deep consistency check of each cluster, it is
+// shared by the consistency and the alarm inspections, which are served by
+// different servers
+type hashChecks struct {
+	mux    sync.Mutex
+	checks map[string]*hashCheck
+}
+
+var deepChecks = &hashChecks{
+	checks: make(map[string]*hashCheck),
+}
+
+// due checks whether the last deep check of the cluster key is older than interval
+func (s *hashChecks) due(key string, interval time.Duration, now time.Time) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	check, found := s.checks[key]
+	return !found || now.Sub(check.time) >= interval
+}
+
+// record records the deep check of the cluster key
+func (s *hashChecks) record(key string, check *hashCheck) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.checks[key] = check
+}
+
+// getMismatched returns the mismatched members found by the last deep check
+// of the cluster key, the check expires after two intervals, e.g. the deep
+// check is disabled
+func (s *hashChecks) getMismatched(key string, now time.Time) (int64, []string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	check, found := s.checks[key]
+	if !found {
+		return 0, nil
+	}
+	if now.Sub(check.time) > 2*check.interval {
+		delete(s.checks, key)
+		return 0, nil
+	}
+	return check.revision, check.mismatched
+}
+
+// hashKVResult is the result of a deep consistency check
+type hashKVResult struct {
+	revision   int64
+	hashes     map[string]uint32
+	mismatched map[string]bool
+}
+
+// checkHashKV compares the hashes of the keyspace of the members at the same
+// revision, which is the smallest current revision of the members, so that
+// every member has applied it. The members whose hash differs from the
+// majority are mismatched, all members are mismatched if there is no majority.
+// nil is returned if the check is skipped, the reason is added to the result.
+func (c *Server) checkHashKV(
+	cluster *kstonev1alpha2.EtcdCluster,
+	clientConfig *etcd.ClientConfig,
+	result *resultBuilder,
+) *hashKVResult {
+	for _, m := range cluster.Status.Members {
+		if strings.HasPrefix(m.Version, "2") {
+			result.addFinding(kstonev1alpha2.EtcdInspectionWarn, "the deep check is not supported by etcd v2")
+			return nil
+		}
+	}
+
+	clientConfig.Endpoints = clusterprovider.GetStorageMemberEndpoints(cluster)
+	client, err := etcd.NewClientv3(clientConfig)
+	if err != nil {
+		klog.Errorf("failed to get new etcd clientv3,err is %v", err)
+		result.addFinding(kstonev1alpha2.EtcdInspectionWarn, "the deep check is skipped: %v", err)
+		return nil
+	}
+	defer client.Close()
+
+	hashKV := &hashKVResult{
+		hashes:     make(map[string]uint32),
+		mismatched: make(map[string]bool),
+	}
+	for _, m := range cluster.Status.Members {
+		status, sErr := etcd.Status(m.ExtensionClientUrl, client)
+		if sErr != nil {
+			result.addFinding(
+				kstonev1alpha2.EtcdInspectionWarn,
+				"the deep check is skipped, failed to get the status of member %s: %v",
+				m.Endpoint,
+				sErr,
+			)
+			return nil
+		}
+		if hashKV.revision == 0 || status.Header.Revision < hashKV.revision {
+			hashKV.revision = status.Header.Revision
+		}
+	}
+
+	compactRevisions := make(map[int64]bool)
+	counts := make(map[uint32]int)
+	for _, m := range cluster.Status.Members {
+		rsp, hErr := etcd.HashKV(m.ExtensionClientUrl, hashKV.revision, client)
+		if hErr != nil {
+			// the revision may be compacted after the status is got, it is retried at the next check
+			result.addFinding(
+				kstonev1alpha2.EtcdInspectionWarn,
+				"the deep check is skipped, failed to hash member %s at revision %d: %v",
+				m.Endpoint,
+				hashKV.revision,
+				hErr,
+			)
+			return nil
+		}
+		hashKV.hashes[m.Endpoint] = rsp.Hash
+		compactRevisions[rsp.CompactRevision] = true
+		counts[rsp.Hash]++
+	}
+	// the hashes only cover the revisions since the last compaction
+	if len(compactRevisions) > 1 {
+		result.addFinding(
+			kstonev1alpha2.EtcdInspectionWarn,
+			"the deep check is skipped, the members are compacted at different revisions",
+		)
+		return nil
+	}
+
+	if len(counts) > 1 {
+		var majority uint32
+		found := false
+		for hash, count := range counts {
+			if count > len(hashKV.hashes)/2 {
+				majority, found = hash, true
+			}
+		}
+		for endpoint, hash := range hashKV.hashes {
+			if !found || hash != majority {
+				hashKV.mismatched[endpoint] = true
+			}
+		}
+	}
+	return hashKV
+}
+
+// mismatchedEndpoints returns the sorted endpoints of the mismatched members
+func (r *hashKVResult) mismatchedEndpoints() []string {
+	endpoints := make([]string, 0, len(r.mismatched))
+	for endpoint := range r.mismatched {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	return endpoints
+}
+
+// reportHashKV records the deep check of the cluster, so that the mismatched
+// members are reported as the CORRUPT alarm by the alarm inspection, and
+// notifies the mismatched members
+func reportHashKV(cluster *kstonev1alpha2.EtcdCluster, hashKV *hashKVResult, interval time.Duration, result *resultBuilder) {
+	endpoints := hashKV.mismatchedEndpoints()
+	deepChecks.record(cluster.Namespace+"/"+cluster.Name, &hashCheck{
+		time:       time.Now(),
+		interval:   interval,
+		revision:   hashKV.revision,
+		mismatched: endpoints,
+	})
+	if len(endpoints) == 0 {
+		return
+	}
+
+	result.addFinding(
+		kstonev1alpha2.EtcdInspectionFail,
+		"the hashes of members %s differ from the others at revision %d",
+		strings.Join(endpoints, ","),
+		hashKV.revision,
+	)
+	for _, endpoint := range endpoints {
+		notifier.Notify(&notifier.Event{
+			Namespace: cluster.Namespace,
+			Cluster:   cluster.Name,
+			Reason:    notifier.ReasonAlarm,
+			Severity:  notifier.SeverityCritical,
+			Subject:   endpoint + "/" + AlarmCorrupt,
+			Message: fmt.Sprintf(
+				"the hash of member %s differs from the others at revision %d, its data may be corrupted",
+				endpoint,
+				hashKV.revision,
+			),
+		})
+	}
+}
@@ -19,6 +19,8 @@
 package inspection
 
 import (
+	"fmt"
+
 	"k8s.io/klog/v2"
 
 	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
@@ -29,7 +31,7 @@ import (
 
 // CollectMemberHealthy collects the health of etcd, and
 // transfer them to prometheus metrics
-func (c *Server) CollectMemberHealthy(inspection *kstonev1alpha2.EtcdInspection) error {
+func (c *Server) CollectMemberHealthy(inspection *kstonev1alpha2.EtcdInspection) (*kstonev1alpha2.EtcdInspectionResult, error) {
 	namespace, name := inspection.Namespace, inspection.Spec.ClusterName
 	cluster, clientConfig, err := c.GetEtcdClusterInfo(namespace, name)
 	defer func() {
@@ -39,9 +41,11 @@ func (c *Server) CollectMemberHealthy(inspection *kstonev1alpha2.EtcdInspection)
 	}()
 	if err != nil {
 		klog.Errorf("load tlsConfig failed, namespace is %s, name is %s, err is %v", namespace, name, err)
-		return err
+		return nil, err
 	}
 
+	result := newResultBuilder()
+	unhealthy := 0
 	for _, m := range cluster.Status.Members {
 		healthy, hErr := etcd.MemberHealthy(m.ExtensionClientUrl, clientConfig)
 		labels := map[string]string{
@@ -50,9 +54,24 @@ func (c *Server) CollectMemberHealthy(inspection *kstonev1alpha2.EtcdInspection)
 		}
 		if hErr != nil || !healthy {
 			metrics.EtcdEndpointHealthy.With(labels).Set(0)
+			message := "member is not healthy"
+			if hErr != nil {
+				message = hErr.Error()
+			}
+			result.addMember(m.Endpoint, kstonev1alpha2.EtcdInspectionFail, message)
+			unhealthy++
 		} else {
 			metrics.EtcdEndpointHealthy.With(labels).Set(1)
+			result.addMember(m.Endpoint, kstonev1alpha2.EtcdInspectionPass, "member is healthy")
 		}
 	}
-	return nil
+	if unhealthy > 0 {
+		result.addFinding(
+			kstonev1alpha2.EtcdInspectionFail,
+			"%d of %d members are not healthy",
+			unhealthy,
+			len(cluster.Status.Members),
+		)
+	}
+	return result.build(fmt.Sprintf("%d members are healthy", len(cluster.Status.Members))), nil
 }
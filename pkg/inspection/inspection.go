@@ -24,7 +24,6 @@ import (
 	"sync"
 	"time"
 
-	clientv3 "go.etcd.io/etcd/client/v3"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -43,17 +42,17 @@ import (
 const (
 	DefaultInspectionInterval = 300 * time.Second
 	DefaultInspectionPath     = ""
+	// MaxInspectionRecords is the number of records kept in the status of etcdinspection
+	MaxInspectionRecords = 10
 )
 
 type Server struct {
 	cli                *clientset.Clientset
 	kubeCli            kubernetes.Interface
-	client             map[string]*clientv3.Client
-	wchan              map[string]clientv3.WatchChan
-	watcher            map[string]clientv3.Watcher
-	eventCh            map[string]chan *clientv3.Event
+	watchers           map[string]*requestWatcher
 	mux                sync.Mutex
 	clientConfigGetter etcd.ClientConfigGetter
+	dbSizeSamples      *dbSizeSamples
 }
 
 // NewInspectionServer generates the server of inspection
@@ -66,11 +65,9 @@ func NewInspectionServer(ctx *featureprovider.FeatureContext) (*Server, error) {
 	return &Server{
 		kubeCli:            ctx.ClientBuilder.ClientOrDie(),
 		cli:                cli,
-		client:             make(map[string]*clientv3.Client),
-		wchan:              make(map[string]clientv3.WatchChan),
-		watcher:            make(map[string]clientv3.Watcher),
-		eventCh:            make(map[string]chan *clientv3.Event),
+		watchers:           make(map[string]*requestWatcher),
 		clientConfigGetter: ctx.ClientConfigGetter,
+		dbSizeSamples:      newDBSizeSamples(),
 	}, nil
 }
 
@@ -123,6 +120,52 @@ func (c *Server) CreateEtcdInspection(inspection *kstonev1alpha2.EtcdInspection)
 	return newinspectionTask, nil
 }
 
+// AddEtcdInspectionRecord appends the record to the status of etcdinspection,
+// only the latest MaxInspectionRecords records are kept
+func (c *Server) AddEtcdInspectionRecord(
+	inspection *kstonev1alpha2.EtcdInspection,
+	record kstonev1alpha2.EtcdInspectionRecord,
+) error {
+	return c.addEtcdInspectionRecord(inspection, record, nil)
+}
+
+// addEtcdInspectionRecord appends the record to the status of etcdinspection,
+// and applies update to the status if it is not nil
+func (c *Server) addEtcdInspectionRecord(
+	inspection *kstonev1alpha2.EtcdInspection,
+	record kstonev1alpha2.EtcdInspectionRecord,
+	update func(status *kstonev1alpha2.EtcdInspectionStatus),
+) error {
+	task, err := c.GetEtcdInspection(inspection.Namespace, inspection.Name)
+	if err != nil {
+		return err
+	}
+
+	records := append(task.Status.Records, record)
+	if len(records) > MaxInspectionRecords {
+		records = records[len(records)-MaxInspectionRecords:]
+	}
+	task.Status.Records = records
+	task.Status.Reason = record.Reason
+	task.Status.Message = record.Message
+	task.Status.LastUpdatedTime = metav1.Now()
+	if update != nil {
+		update(&task.Status)
+	}
+
+	_, err = c.cli.KstoneV1alpha2().EtcdInspections(task.Namespace).
+		UpdateStatus(context.TODO(), task, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf(
+			"failed to update etcdinspection, namespace is %s, name is %s, err is %v",
+			task.Namespace,
+			task.Name,
+			err,
+		)
+	}
+	return err
+}
+
 func (c *Server) initInspectionTask(
 	cluster *kstonev1alpha2.EtcdCluster,
 	inspectionFeatureName kstonev1alpha2.KStoneFeature,
@@ -138,11 +181,6 @@ func (c *Server) initInspectionTask(
 		InspectionType: string(inspectionFeatureName),
 		ClusterName:    cluster.Name,
 	}
-	inspectionTask.Status = kstonev1alpha2.EtcdInspectionStatus{
-		LastUpdatedTime: metav1.Time{
-			Time: time.Now(),
-		},
-	}
 
 	err := controllerutil.SetOwnerReference(cluster, inspectionTask, platformscheme.Scheme)
 	if err != nil {
@@ -0,0 +1,326 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package inspection
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/clusterprovider"
+	"tkestack.io/kstone/pkg/etcd"
+	featureutil "tkestack.io/kstone/pkg/featureprovider/util"
+)
+
+const (
+	// DefaultKeyspaceInterval is the interval of keyspace analysis if
+	// IntervalInSecond of etcdinspection is not specified
+	DefaultKeyspaceInterval = time.Hour
+	// DefaultKeyspaceDepth aggregates the keys by the first two path segments,
+	// such as /registry/pods/
+	DefaultKeyspaceDepth = 2
+	// DefaultKeyspaceTopN is the number of the largest keys and prefixes reported
+	DefaultKeyspaceTopN = 10
+	MaxKeyspaceDepth    = 10
+	MaxKeyspaceTopN     = 100
+
+	// keyspacePageSize is the number of keys read by a range request
+	keyspacePageSize = 500
+)
+
+// KeyspaceInfo is the config of the keyspace inspection
+type KeyspaceInfo struct {
+	Prefix string `json:"prefix,omitempty"`
+	// Depth is the number of the path segments of the aggregated prefixes.
+	Depth int `json:"depth,omitempty"`
+	// TopN is the number of the largest keys and prefixes reported.
+	TopN int `json:"topN,omitempty"`
+}
+
+// Validate validates the keyspace config
+func (info *KeyspaceInfo) Validate() error {
+	if info.Depth < 0 || info.Depth > MaxKeyspaceDepth {
+		return fmt.Errorf("depth must be between 0 and %d", MaxKeyspaceDepth)
+	}
+	if info.TopN < 0 || info.TopN > MaxKeyspaceTopN {
+		return fmt.Errorf("topN must be between 0 and %d", MaxKeyspaceTopN)
+	}
+	return nil
+}
+
+// GetKeyspaceInfo gets the keyspace config of cluster, the unset fields are defaulted
+func GetKeyspaceInfo(cluster *kstonev1alpha2.EtcdCluster) (*KeyspaceInfo, error) {
+	info := &KeyspaceInfo{}
+	if cfg, found := cluster.Annotations[kstonev1alpha2.AnnoKeyspace]; found {
+		if err := json.Unmarshal([]byte(cfg), info); err != nil {
+			return nil, err
+		}
+		if err := info.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if info.Depth == 0 {
+		info.Depth = DefaultKeyspaceDepth
+	}
+	if info.TopN == 0 {
+		info.TopN = DefaultKeyspaceTopN
+	}
+	return info, nil
+}
+
+// AnalyzeKeyspace walks the keyspace of etcd with paginated range requests,
+// and saves the largest keys and prefixes to the status of etcdinspection
+func (c *Server) AnalyzeKeyspace(inspection *kstonev1alpha2.EtcdInspection) (*kstonev1alpha2.EtcdInspectionResult, error) {
+	namespace, name := inspection.Namespace, inspection.Spec.ClusterName
+	start := time.Now()
+
+	cluster, clientConfig, err := c.GetEtcdClusterInfo(namespace, name)
+	defer func() {
+		if err != nil {
+			featureutil.IncrFailedInspectionCounter(name, kstonev1alpha2.KStoneFeatureKeyspace)
+		}
+	}()
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	result, err := c.analyzeKeyspace(cluster, clientConfig)
+	record := kstonev1alpha2.EtcdInspectionRecord{
+		StartTime: metav1.NewTime(start),
+	}
+	if err != nil {
+		klog.Errorf("failed to analyze keyspace, cluster %s, err is %v", name, err)
+		record.Reason = "AnalyzeFailed"
+		record.Message = err.Error()
+	} else {
+		record.Reason = "AnalyzeSucceeded"
+		record.Message = fmt.Sprintf(
+			"%d keys, %d bytes at revision %d",
+			result.TotalKeys,
+			result.TotalBytes,
+			result.Revision,
+		)
+	}
+
+	record.EndTime = metav1.Now()
+	rErr := c.addEtcdInspectionRecord(inspection, record, func(status *kstonev1alpha2.EtcdInspectionStatus) {
+		if result != nil {
+			status.Keyspace = result
+		}
+	})
+	if rErr != nil && err == nil {
+		err = rErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newResultBuilder().build(record.Message), nil
+}
+
+// analyzeKeyspace analyzes the keyspace of cluster at the current revision
+func (c *Server) analyzeKeyspace(
+	cluster *kstonev1alpha2.EtcdCluster,
+	clientConfig *etcd.ClientConfig,
+) (*kstonev1alpha2.EtcdKeyspaceStatus, error) {
+	if cluster.Spec.StorageBackend == string(kstonev1alpha2.EtcdStorageV2) {
+		return nil, fmt.Errorf("keyspace analysis is not supported by storage backend v2")
+	}
+	info, err := GetKeyspaceInfo(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	clientConfig.Endpoints = clusterprovider.GetStorageMemberEndpoints(cluster)
+	client, err := etcd.NewClientv3(clientConfig)
+	if err != nil {
+		klog.Errorf("failed to get new etcd clientv3,err is %v", err)
+		return nil, err
+	}
+	defer client.Close()
+
+	analyzer := newKeyspaceAnalyzer(info)
+	key := info.Prefix
+	if key == "" {
+		key = "\x00"
+	}
+	end := clientv3.GetPrefixRangeEnd(info.Prefix)
+	// the following pages are read at the revision of the first page, so
+	// that the result is a consistent snapshot of the keyspace
+	var revision int64
+	for {
+		opts := []clientv3.OpOption{clientv3.WithRange(end), clientv3.WithLimit(keyspacePageSize)}
+		if revision > 0 {
+			opts = append(opts, clientv3.WithRev(revision))
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), etcd.DefaultCommandTimeOut)
+		resp, err := client.Get(ctx, key, opts...)
+		cancel()
+		if err != nil {
+			klog.Errorf("failed to range keys from %s, cluster %s, err is %v", key, cluster.Name, err)
+			return nil, err
+		}
+		if revision == 0 {
+			revision = resp.Header.Revision
+		}
+
+		for _, kv := range resp.Kvs {
+			analyzer.add(string(kv.Key), int64(len(kv.Value)))
+		}
+		if !resp.More || len(resp.Kvs) == 0 {
+			break
+		}
+		key = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+
+	result := analyzer.result()
+	result.Revision = revision
+	result.Prefix = info.Prefix
+	return result, nil
+}
+
+// keyspaceAnalyzer aggregates the sizes of keys
+type keyspaceAnalyzer struct {
+	depth      int
+	topN       int
+	totalKeys  int64
+	totalBytes int64
+	topKeys    keyUsageHeap
+	prefixes   map[string]*kstonev1alpha2.EtcdPrefixUsage
+}
+
+func newKeyspaceAnalyzer(info *KeyspaceInfo) *keyspaceAnalyzer {
+	return &keyspaceAnalyzer{
+		depth:    info.Depth,
+		topN:     info.TopN,
+		prefixes: make(map[string]*kstonev1alpha2.EtcdPrefixUsage),
+	}
+}
+
+// add adds the key with the size of its value
+func (a *keyspaceAnalyzer) add(key string, valueSize int64) {
+	bytes := int64(len(key)) + valueSize
+	a.totalKeys++
+	a.totalBytes += bytes
+
+	if a.topKeys.Len() < a.topN {
+		heap.Push(&a.topKeys, kstonev1alpha2.EtcdKeyUsage{Key: key, ValueSize: valueSize})
+	} else if a.topKeys[0].ValueSize < valueSize {
+		a.topKeys[0] = kstonev1alpha2.EtcdKeyUsage{Key: key, ValueSize: valueSize}
+		heap.Fix(&a.topKeys, 0)
+	}
+
+	prefix := keyPrefix(key, a.depth)
+	usage, found := a.prefixes[prefix]
+	if !found {
+		usage = &kstonev1alpha2.EtcdPrefixUsage{Prefix: prefix}
+		a.prefixes[prefix] = usage
+	}
+	usage.Keys++
+	usage.Bytes += bytes
+}
+
+// result returns the top N keys and prefixes in descending order
+func (a *keyspaceAnalyzer) result() *kstonev1alpha2.EtcdKeyspaceStatus {
+	topKeys := make([]kstonev1alpha2.EtcdKeyUsage, len(a.topKeys))
+	copy(topKeys, a.topKeys)
+	sort.Slice(topKeys, func(i, j int) bool {
+		return topKeys[i].ValueSize > topKeys[j].ValueSize
+	})
+
+	prefixes := make([]kstonev1alpha2.EtcdPrefixUsage, 0, len(a.prefixes))
+	for _, usage := range a.prefixes {
+		prefixes = append(prefixes, *usage)
+	}
+	topPrefixes := func(size func(usage kstonev1alpha2.EtcdPrefixUsage) int64) []kstonev1alpha2.EtcdPrefixUsage {
+		sort.Slice(prefixes, func(i, j int) bool {
+			if x, y := size(prefixes[i]), size(prefixes[j]); x != y {
+				return x > y
+			}
+			return prefixes[i].Prefix < prefixes[j].Prefix
+		})
+		n := a.topN
+		if n > len(prefixes) {
+			n = len(prefixes)
+		}
+		return append([]kstonev1alpha2.EtcdPrefixUsage(nil), prefixes[:n]...)
+	}
+
+	return &kstonev1alpha2.EtcdKeyspaceStatus{
+		Depth:      a.depth,
+		TotalKeys:  a.totalKeys,
+		TotalBytes: a.totalBytes,
+		TopKeys:    topKeys,
+		TopPrefixesByBytes: topPrefixes(func(usage kstonev1alpha2.EtcdPrefixUsage) int64 {
+			return usage.Bytes
+		}),
+		TopPrefixesByKeys: topPrefixes(func(usage kstonev1alpha2.EtcdPrefixUsage) int64 {
+			return usage.Keys
+		}),
+	}
+}
+
+// keyPrefix returns the prefix of key with depth path segments, such as
+// /registry/pods/ of /registry/pods/default/nginx with depth 2, the key
+// is returned if it has less segments
+func keyPrefix(key string, depth int) string {
+	idx := 0
+	for i := 0; i < depth; i++ {
+		if idx+1 >= len(key) {
+			return key
+		}
+		next := strings.IndexByte(key[idx+1:], '/')
+		if next < 0 {
+			return key
+		}
+		idx += next + 1
+	}
+	return key[:idx+1]
+}
+
+// keyUsageHeap is a min-heap of the value sizes of keys
+type keyUsageHeap []kstonev1alpha2.EtcdKeyUsage
+
+func (h keyUsageHeap) Len() int           { return len(h) }
+func (h keyUsageHeap) Less(i, j int) bool { return h[i].ValueSize < h[j].ValueSize }
+func (h keyUsageHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *keyUsageHeap) Push(x interface{}) {
+	*h = append(*h, x.(kstonev1alpha2.EtcdKeyUsage))
+}
+
+func (h *keyUsageHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,53 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package inspection
+
+import (
+	"testing"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	tests := []struct {
+		key   string
+		depth int
+		want  string
+	}{
+		{key: "/registry/pods/default/nginx", depth: 1, want: "/registry/"},
+		{key: "/registry/pods/default/nginx", depth: 2, want: "/registry/pods/"},
+		{key: "/registry/pods/default/nginx", depth: 3, want: "/registry/pods/default/"},
+		// the key has less segments
+		{key: "/registry/pods/default/nginx", depth: 4, want: "/registry/pods/default/nginx"},
+		{key: "/registry/pods/default/nginx", depth: 10, want: "/registry/pods/default/nginx"},
+		{key: "/registry/", depth: 2, want: "/registry/"},
+		{key: "/registry", depth: 1, want: "/registry"},
+		// keys without the leading slash
+		{key: "app/orders/1", depth: 1, want: "app/"},
+		{key: "app/orders/1", depth: 2, want: "app/orders/"},
+		{key: "app", depth: 1, want: "app"},
+		// empty segments
+		{key: "/a//b", depth: 2, want: "/a//"},
+		{key: "//", depth: 1, want: "//"},
+		{key: "", depth: 1, want: ""},
+	}
+	for _, tt := range tests {
+		if got := keyPrefix(tt.key, tt.depth); got != tt.want {
+			t.Errorf("keyPrefix(%q, %d) = %q, want %q", tt.key, tt.depth, got, tt.want)
+		}
+	}
+}
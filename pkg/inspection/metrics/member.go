@@ -0,0 +1,166 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/klog/v2"
+)
+
+// MemberMetricsTTL is the duration the scraped metrics of a member are exported,
+// the metrics of the members no longer scraped are dropped after it
+const MemberMetricsTTL = 5 * time.Minute
+
+// memberMetricNames maps the curated metrics of etcd members to the names
+// exported by kstone
+var memberMetricNames = map[string]string{
+	"etcd_disk_wal_fsync_duration_seconds":      "etcd_member_wal_fsync_duration_seconds",
+	"etcd_disk_backend_commit_duration_seconds": "etcd_member_backend_commit_duration_seconds",
+	"etcd_server_leader_changes_seen_total":     "etcd_member_leader_changes_seen_total",
+	"etcd_server_proposals_failed_total":        "etcd_member_proposals_failed_total",
+}
+
+// memberMetrics is the curated metrics scraped from an etcd member
+type memberMetrics struct {
+	families   []*dto.MetricFamily
+	scrapeTime time.Time
+}
+
+// MemberMetricsCollector mirrors the curated metrics scraped from etcd members,
+// with the labels clusterName and member
+type MemberMetricsCollector struct {
+	mux sync.Mutex
+	// members maps clusterName and member to the scraped metrics
+	members map[string]map[string]*memberMetrics
+}
+
+// NewMemberMetricsCollector generates the collector of the metrics of etcd members
+func NewMemberMetricsCollector() *MemberMetricsCollector {
+	return &MemberMetricsCollector{
+		members: make(map[string]map[string]*memberMetrics),
+	}
+}
+
+// Set sets the metrics scraped from the member of cluster, the metrics not
+// curated are ignored
+func (c *MemberMetricsCollector) Set(clusterName, member string, families map[string]*dto.MetricFamily) {
+	curated := make([]*dto.MetricFamily, 0, len(memberMetricNames))
+	for name := range memberMetricNames {
+		if family, found := families[name]; found {
+			curated = append(curated, family)
+		}
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.members[clusterName] == nil {
+		c.members[clusterName] = make(map[string]*memberMetrics)
+	}
+	c.members[clusterName][member] = &memberMetrics{
+		families:   curated,
+		scrapeTime: time.Now(),
+	}
+}
+
+// Delete deletes the metrics of the member of cluster
+func (c *MemberMetricsCollector) Delete(clusterName, member string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	delete(c.members[clusterName], member)
+	if len(c.members[clusterName]) == 0 {
+		delete(c.members, clusterName)
+	}
+}
+
+// Describe sends no descriptors, the collector is unchecked since the labels
+// of the mirrored metrics are only known after scraping
+func (c *MemberMetricsCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect sends the mirrored metrics, and drops the expired ones
+func (c *MemberMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	now := time.Now()
+	for clusterName, members := range c.members {
+		for member, m := range members {
+			if now.Sub(m.scrapeTime) > MemberMetricsTTL {
+				delete(members, member)
+				continue
+			}
+			for _, family := range m.families {
+				collectMemberMetricFamily(ch, clusterName, member, family)
+			}
+		}
+		if len(members) == 0 {
+			delete(c.members, clusterName)
+		}
+	}
+}
+
+// collectMemberMetricFamily sends the metrics of family renamed under the
+// kstone namespace, counters, gauges and histograms are supported
+func collectMemberMetricFamily(ch chan<- prometheus.Metric, clusterName, member string, family *dto.MetricFamily) {
+	name := prometheus.BuildFQName("kstone", "inspection", memberMetricNames[family.GetName()])
+	for _, metric := range family.GetMetric() {
+		labelNames := []string{"clusterName", "member"}
+		labelValues := []string{clusterName, member}
+		pairs := metric.GetLabel()
+		sort.Slice(pairs, func(i, j int) bool { return pairs[i].GetName() < pairs[j].GetName() })
+		for _, pair := range pairs {
+			labelNames = append(labelNames, pair.GetName())
+			labelValues = append(labelValues, pair.GetValue())
+		}
+		desc := prometheus.NewDesc(name, family.GetHelp(), labelNames, nil)
+
+		var m prometheus.Metric
+		var err error
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			m, err = prometheus.NewConstMetric(desc, prometheus.CounterValue, metric.GetCounter().GetValue(), labelValues...)
+		case dto.MetricType_GAUGE:
+			m, err = prometheus.NewConstMetric(desc, prometheus.GaugeValue, metric.GetGauge().GetValue(), labelValues...)
+		case dto.MetricType_HISTOGRAM:
+			histogram := metric.GetHistogram()
+			buckets := make(map[float64]uint64, len(histogram.GetBucket()))
+			for _, b := range histogram.GetBucket() {
+				buckets[b.GetUpperBound()] = b.GetCumulativeCount()
+			}
+			m, err = prometheus.NewConstHistogram(
+				desc,
+				histogram.GetSampleCount(),
+				histogram.GetSampleSum(),
+				buckets,
+				labelValues...,
+			)
+		default:
+			continue
+		}
+		if err != nil {
+			klog.Errorf("failed to mirror metric %s of member %s, err is %v", family.GetName(), member, err)
+			continue
+		}
+		ch <- m
+	}
+}
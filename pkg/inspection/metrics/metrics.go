@@ -51,6 +51,34 @@ var (
 		Help:      "The total number of etcd key",
 	}, []string{"clusterName", "etcdPrefix", "resourceName"})
 
+	EtcdRequestWatchUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_request_watch_up",
+		Help:      "Whether the requests are being watched, 1 is watching and 0 is retrying",
+	}, []string{"clusterName"})
+
+	EtcdRequestWatchRevision = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_request_watch_revision",
+		Help:      "The next revision of the requests to watch",
+	}, []string{"clusterName"})
+
+	EtcdRequestWatchRestarts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_request_watch_restarts_total",
+		Help:      "The total number of the restarts of the request watch, by reason",
+	}, []string{"clusterName", "reason"})
+
+	EtcdRequestWatchQueueLength = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_request_watch_queue_length",
+		Help:      "The number of the watched events waiting to be processed",
+	}, []string{"clusterName"})
+
 	EtcdEndpointAlarm = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: "kstone",
 		Subsystem: "inspection",
@@ -100,12 +128,147 @@ var (
 		Help:      "The Number of failed backup files in the last day",
 	}, []string{"clusterName"})
 
+	EtcdBackupVerifySuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_backup_verify_success",
+		Help:      "Whether the newest backup file is restored successfully",
+	}, []string{"clusterName"})
+
+	EtcdBackupVerifyDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_backup_verify_duration_seconds",
+		Help:      "The duration of the last backup restore verification",
+	}, []string{"clusterName"})
+
+	EtcdBackupVerifySize = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_backup_verify_size_bytes",
+		Help:      "The size of the verified backup file",
+	}, []string{"clusterName"})
+
+	EtcdBackupVerifyRevisionLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_backup_verify_revision_lag",
+		Help:      "The revision difference between the live cluster and the verified backup file",
+	}, []string{"clusterName"})
+
+	EtcdBackupVerifyKeyDiff = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_backup_verify_key_diff",
+		Help:      "The key number difference between the live cluster and the verified backup file",
+	}, []string{"clusterName"})
+
+	EtcdBackupRetentionDeletedFiles = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_backup_retention_deleted_files",
+		Help:      "The number of backup files deleted by the retention policy",
+	}, []string{"clusterName"})
+
+	EtcdBackupRetentionDeletedEventSegments = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_backup_retention_deleted_event_segments",
+		Help:      "The number of event segments deleted as they are older than the backup files",
+	}, []string{"clusterName"})
+
+	EtcdBackupRetentionKeptFiles = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_backup_retention_kept_files",
+		Help:      "The number of backup files kept by the retention policy",
+	}, []string{"clusterName"})
+
+	EtcdDefragFragmentationRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_defrag_fragmentation_ratio",
+		Help:      "The ratio of the db size not in use to the db size of etcd member",
+	}, []string{"clusterName", "endpoint"})
+
+	EtcdDefragDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_defrag_duration_seconds",
+		Help:      "The duration of the last defragmentation of etcd member",
+	}, []string{"clusterName", "endpoint"})
+
+	EtcdDefragReclaimedBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_defrag_reclaimed_bytes",
+		Help:      "The db size reclaimed by the defragmentation of etcd member",
+	}, []string{"clusterName", "endpoint"})
+
+	EtcdDefragTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_defrag_total",
+		Help:      "The total number of defragmentations of etcd member",
+	}, []string{"clusterName", "endpoint", "result"})
+
+	EtcdDefragSkippedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_defrag_skipped_total",
+		Help:      "The total number of defragmentation runs skipped because the cluster is unhealthy",
+	}, []string{"clusterName"})
+
+	EtcdDBSize = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_db_size_bytes",
+		Help:      "The db size of etcd member",
+	}, []string{"clusterName", "endpoint"})
+
+	EtcdDBSizeInUse = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_db_size_in_use_bytes",
+		Help:      "The db size in use of etcd member",
+	}, []string{"clusterName", "endpoint"})
+
+	EtcdDBQuota = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_db_quota_bytes",
+		Help:      "The quota-backend-bytes of etcd cluster",
+	}, []string{"clusterName"})
+
+	EtcdDBSizeUsedPercent = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_db_size_used_percent",
+		Help:      "The percentage of the quota used by the db of etcd member",
+	}, []string{"clusterName", "endpoint"})
+
+	EtcdDBTimeToQuota = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "etcd_db_time_to_quota_seconds",
+		Help:      "The projected time until the db of etcd member reaches the quota at the recent growth rate",
+	}, []string{"clusterName", "endpoint"})
+
+	EtcdMemberMetrics = NewMemberMetricsCollector()
+
 	EtcdInspectionFailedNum = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Namespace: "kstone",
 		Subsystem: "inspection",
 		Name:      "failed_num",
 		Help:      "The total Number of failed inspection",
 	}, []string{"clusterName", "inspectionType"})
+
+	EtcdInspectionResult = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kstone",
+		Subsystem: "inspection",
+		Name:      "result",
+		Help:      "The result of the last inspection, 0 is pass, 1 is warn and 2 is fail",
+	}, []string{"clusterName", "inspectionType"})
 )
 
 func init() {
@@ -113,6 +276,10 @@ func init() {
 	prometheus.MustRegister(EtcdEndpointHealthy)
 	prometheus.MustRegister(EtcdRequestTotal)
 	prometheus.MustRegister(EtcdKeyTotal)
+	prometheus.MustRegister(EtcdRequestWatchUp)
+	prometheus.MustRegister(EtcdRequestWatchRevision)
+	prometheus.MustRegister(EtcdRequestWatchRestarts)
+	prometheus.MustRegister(EtcdRequestWatchQueueLength)
 	prometheus.MustRegister(EtcdEndpointAlarm)
 	prometheus.MustRegister(EtcdNodeRevisionDiff)
 	prometheus.MustRegister(EtcdNodeIndexDiff)
@@ -120,5 +287,25 @@ func init() {
 	prometheus.MustRegister(EtcdNodeRaftIndexDiff)
 	prometheus.MustRegister(EtcdBackupFiles)
 	prometheus.MustRegister(EtcdFailedBackupFiles)
+	prometheus.MustRegister(EtcdBackupVerifySuccess)
+	prometheus.MustRegister(EtcdBackupVerifyDuration)
+	prometheus.MustRegister(EtcdBackupVerifySize)
+	prometheus.MustRegister(EtcdBackupVerifyRevisionLag)
+	prometheus.MustRegister(EtcdBackupVerifyKeyDiff)
+	prometheus.MustRegister(EtcdBackupRetentionDeletedFiles)
+	prometheus.MustRegister(EtcdBackupRetentionDeletedEventSegments)
+	prometheus.MustRegister(EtcdBackupRetentionKeptFiles)
+	prometheus.MustRegister(EtcdDefragFragmentationRatio)
+	prometheus.MustRegister(EtcdDefragDuration)
+	prometheus.MustRegister(EtcdDefragReclaimedBytes)
+	prometheus.MustRegister(EtcdDefragTotal)
+	prometheus.MustRegister(EtcdDefragSkippedTotal)
+	prometheus.MustRegister(EtcdDBSize)
+	prometheus.MustRegister(EtcdDBSizeInUse)
+	prometheus.MustRegister(EtcdDBQuota)
+	prometheus.MustRegister(EtcdDBSizeUsedPercent)
+	prometheus.MustRegister(EtcdDBTimeToQuota)
+	prometheus.MustRegister(EtcdMemberMetrics)
 	prometheus.MustRegister(EtcdInspectionFailedNum)
+	prometheus.MustRegister(EtcdInspectionResult)
 }
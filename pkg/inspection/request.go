@@ -21,34 +21,153 @@ package inspection
 import (
 	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
+	"regexp"
 	"strings"
-	"time"
 
-	"go.etcd.io/etcd/api/v3/mvccpb"
-	clientv3 "go.etcd.io/etcd/client/v3"
 	"k8s.io/klog/v2"
 
 	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/backup"
 	"tkestack.io/kstone/pkg/clusterprovider"
 	"tkestack.io/kstone/pkg/etcd"
 	featureutil "tkestack.io/kstone/pkg/featureprovider/util"
-	"tkestack.io/kstone/pkg/inspection/metrics"
 )
 
 const (
-	inspectionRequestAnno = "request"
-	eventBuffer           = 40960
+	inspectionRequestAnno = kstonev1alpha2.AnnoRequest
+	// DefaultRequestMaxLabels is the default max number of the label values
+	// of the key and request metrics of a cluster
+	DefaultRequestMaxLabels = 100
+	MaxRequestMaxLabels     = 1000
+	// DefaultRequestLabelDepth is the default number of the key segments in the
+	// resourceName label, such as pods in /registry/pods/
+	DefaultRequestLabelDepth = 1
+	MaxRequestLabelDepth     = 10
 )
 
 type RequestInfo struct {
 	Path     string `json:"path,omitempty"`
 	Interval int    `json:"interval,omitempty"`
 	Prefix   bool   `json:"prefix,omitempty"`
+	// PersistEvents persists the watched events to the backup storage,
+	// which is required by the point in time restore.
+	PersistEvents bool `json:"persistEvents,omitempty"`
+	// SegmentIntervalInSecond is the max duration of a persisted event segment.
+	SegmentIntervalInSecond int `json:"segmentIntervalInSecond,omitempty"`
+	// Prefixes are the watched prefixes with their label rules, Path must not
+	// be set with them.
+	Prefixes []RequestPrefix `json:"prefixes,omitempty"`
+	// MaxLabels is the max number of the label values, the keys over the
+	// limit are counted with the other labels.
+	MaxLabels int `json:"maxLabels,omitempty"`
 }
 
-// CollectEtcdClusterRequest collects request of etcd
-func (c *Server) CollectEtcdClusterRequest(inspection *kstonev1alpha2.EtcdInspection) error {
+// RequestPrefix is a watched prefix and the rule extracting the labels from its keys
+type RequestPrefix struct {
+	Path string `json:"path,omitempty"`
+	// Regex extracts the labels from the key, the first capture group is the
+	// etcdPrefix label, and the second one is the resourceName label.
+	Regex string `json:"regex,omitempty"`
+	// Depth is the number of the key segments after the etcdPrefix label
+	// joined as the resourceName label.
+	Depth int `json:"depth,omitempty"`
+}
+
+// Validate validates the request config
+func (info *RequestInfo) Validate() error {
+	if info.Interval < 0 || info.SegmentIntervalInSecond < 0 {
+		return fmt.Errorf("intervals must not be negative")
+	}
+	if info.MaxLabels < 0 || info.MaxLabels > MaxRequestMaxLabels {
+		return fmt.Errorf("maxLabels must be between 0 and %d", MaxRequestMaxLabels)
+	}
+	// the restore replays the persisted events on top of the whole snapshot,
+	// the keys not watched would be left at the revision of the snapshot
+	if info.PersistEvents && !info.watchesWholeKeyspace() {
+		return fmt.Errorf("persistEvents requires watching the whole keyspace, path and prefixes must not be set")
+	}
+	if len(info.Prefixes) == 0 {
+		return nil
+	}
+	if info.Path != "" {
+		return fmt.Errorf("path and prefixes can not be set together")
+	}
+	for i, prefix := range info.Prefixes {
+		if err := prefix.Validate(); err != nil {
+			return fmt.Errorf("prefix %q: %v", prefix.Path, err)
+		}
+		for _, other := range info.Prefixes[i+1:] {
+			if strings.HasPrefix(prefix.Path, other.Path) || strings.HasPrefix(other.Path, prefix.Path) {
+				return fmt.Errorf("prefix %q overlaps prefix %q", prefix.Path, other.Path)
+			}
+		}
+	}
+	return nil
+}
+
+// watchesWholeKeyspace checks whether all the keys are watched
+func (info *RequestInfo) watchesWholeKeyspace() bool {
+	if info.Path != "" || len(info.Prefixes) > 1 {
+		return false
+	}
+	return len(info.Prefixes) == 0 || info.Prefixes[0].Path == ""
+}
+
+// Validate validates the label rule of the prefix
+func (prefix *RequestPrefix) Validate() error {
+	if prefix.Depth < 0 || prefix.Depth > MaxRequestLabelDepth {
+		return fmt.Errorf("depth must be between 0 and %d", MaxRequestLabelDepth)
+	}
+	if prefix.Regex == "" {
+		return nil
+	}
+	if prefix.Depth != 0 {
+		return fmt.Errorf("regex and depth can not be set together")
+	}
+	re, err := regexp.Compile(prefix.Regex)
+	if err != nil {
+		return err
+	}
+	if re.NumSubexp() == 0 {
+		return fmt.Errorf("regex must have a capture group")
+	}
+	return nil
+}
+
+// GetRequestInfo gets the request info of cluster, the unset fields are defaulted
+func GetRequestInfo(cluster *kstonev1alpha2.EtcdCluster) (*RequestInfo, error) {
+	info := &RequestInfo{Path: DefaultInspectionPath}
+	if infoStr, found := cluster.Annotations[inspectionRequestAnno]; found {
+		if err := json.Unmarshal([]byte(infoStr), info); err != nil {
+			return nil, err
+		}
+		if err := info.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if len(info.Prefixes) == 0 {
+		info.Prefixes = []RequestPrefix{{Path: info.Path}}
+	}
+	for i := range info.Prefixes {
+		if info.Prefixes[i].Regex == "" && info.Prefixes[i].Depth == 0 {
+			info.Prefixes[i].Depth = DefaultRequestLabelDepth
+		}
+	}
+	if info.MaxLabels == 0 {
+		info.MaxLabels = DefaultRequestMaxLabels
+	}
+	return info, nil
+}
+
+// requestWatcherKey returns the key of the request watcher of the cluster
+func requestWatcherKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// CollectEtcdClusterRequest collects request of etcd, it starts the request
+// watcher of the cluster, and restarts it if the config of the cluster changes
+func (c *Server) CollectEtcdClusterRequest(inspection *kstonev1alpha2.EtcdInspection) (*kstonev1alpha2.EtcdInspectionResult, error) {
 	namespace, name := inspection.Namespace, inspection.Spec.ClusterName
 	cluster, config, err := c.GetEtcdClusterInfo(namespace, name)
 	defer func() {
@@ -59,170 +178,108 @@ func (c *Server) CollectEtcdClusterRequest(inspection *kstonev1alpha2.EtcdInspec
 
 	if err != nil {
 		klog.Errorf("failed to get cluster info, namespace is %s, name is %s, err is %v", namespace, name, err)
-		return err
-	}
-
-	_, ok := c.watcher[cluster.Name]
-	if ok {
-		return nil
+		return nil, err
 	}
 
-	annotations := cluster.ObjectMeta.Annotations
-	watchKey := DefaultInspectionPath
-	if annotations != nil {
-		if infoStr, found := annotations[inspectionRequestAnno]; found {
-			info := &RequestInfo{}
-			err = json.Unmarshal([]byte(infoStr), info)
-			if err == nil {
-				watchKey = info.Path
-			}
-		}
+	info, err := GetRequestInfo(cluster)
+	if err != nil {
+		klog.Errorf("failed to parse request info, cluster is %s, err is %v", cluster.Name, err)
+		return nil, err
 	}
 
 	config.Endpoints = clusterprovider.GetStorageMemberEndpoints(cluster)
-
-	client, err := etcd.NewClientv3(config)
+	rules, err := newLabelRules(info.Prefixes)
 	if err != nil {
-		klog.Errorf("failed to get new etcd clientv3,err is %v", err)
-		return err
+		klog.Errorf("failed to parse request label rules, cluster is %s, err is %v", cluster.Name, err)
+		return nil, err
 	}
-
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	rsp, rErr := client.Get(
-		timeoutCtx,
-		watchKey,
-		clientv3.WithPrefix(),
-		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend),
-		clientv3.WithKeysOnly(),
+	fingerprint := fmt.Sprintf(
+		"%+v,%d,%t,%d,%s",
+		info.Prefixes,
+		info.MaxLabels,
+		info.PersistEvents,
+		info.SegmentIntervalInSecond,
+		strings.Join(config.Endpoints, ","),
 	)
-	if rErr != nil {
-		klog.Errorf("failed to get all etcd cluster keys,err is %v", rErr)
-		return rErr
+
+	key := requestWatcherKey(namespace, name)
+	c.mux.Lock()
+	w, found := c.watchers[key]
+	c.mux.Unlock()
+	if found {
+		if w.fingerprint == fingerprint {
+			return w.result(), nil
+		}
+		klog.Infof("the request config of cluster %s is changed, restart the request watcher", cluster.Name)
+		c.stopRequestWatcher(key)
 	}
 
-	c.populateClusterTotalKeyMetrics(cluster, rsp.Kvs)
-	c.client[cluster.Name] = client
-	eventCh := make(chan *clientv3.Event, eventBuffer)
-	c.setEventCh(eventCh, cluster.Name)
-	err = c.Watch(cluster, client, watchKey)
+	client, err := etcd.NewClientv3(config)
 	if err != nil {
-		klog.Errorf("failed to get watch etcdcluster,err is %v", err)
-		return err
+		klog.Errorf("failed to get new etcd clientv3,err is %v", err)
+		return nil, err
 	}
-	go c.processWatchEvent(cluster)
-	return err
-}
 
-// populateClusterTotalKeyMetrics generates prometheus metrics of the etcd key
-func (c *Server) populateClusterTotalKeyMetrics(cluster *kstonev1alpha2.EtcdCluster, nodes []*mvccpb.KeyValue) {
-	klog.V(2).Infof("cluster name %s,total node:%d", cluster.Name, len(nodes))
-	labels := map[string]string{
-		"clusterName": cluster.Name,
-	}
-	for i := 0; i < len(nodes); i++ {
-		c.setEtcdPrefixAndResourceName(labels, string(nodes[i].Key))
-		metrics.EtcdKeyTotal.With(labels).Inc()
+	w = newRequestWatcher(cluster.Name, rules, info.MaxLabels, fingerprint, client)
+	rev, err := w.listKeys(context.Background())
+	if err != nil {
+		klog.Errorf("failed to get all etcd cluster keys,err is %v", err)
+		w.stop()
+		return nil, err
 	}
-}
 
-// setEtcdPrefixAndResourceName sets prefix and resource name of metric
-func (c *Server) setEtcdPrefixAndResourceName(labels map[string]string, key string) {
-	labels["etcdPrefix"] = ""
-	labels["resourceName"] = ""
-	keys := strings.Split(key, "/")
-	if len(keys) < 2 {
-		return
-	}
-	labels["etcdPrefix"] = keys[1]
-	if len(keys) > 2 {
-		labels["resourceName"] = keys[2]
+	if info.PersistEvents {
+		w.recorder, err = c.newEventRecorder(cluster, info, rev+1)
+		if err != nil {
+			klog.Errorf("failed to init event recorder, cluster is %s, err is %v", cluster.Name, err)
+			w.stop()
+			return nil, err
+		}
 	}
-}
 
-// setEventCh sets event chan
-func (c *Server) setEventCh(ch chan *clientv3.Event, clusterName string) {
+	w.start(rev + 1)
 	c.mux.Lock()
-	defer c.mux.Unlock()
-	c.eventCh[clusterName] = ch
+	c.watchers[key] = w
+	c.mux.Unlock()
+	return newResultBuilder().build(
+		fmt.Sprintf("start to watch the requests of %s from revision %d", w.paths(), rev+1),
+	), nil
 }
 
-// getEventCh gets event chan
-func (c *Server) getEventCh(clusterName string) chan *clientv3.Event {
-	c.mux.Lock()
-	defer c.mux.Unlock()
-	ch, ok := c.eventCh[clusterName]
-	if !ok {
-		klog.Fatalf("failed to get event ch,cluster name is %s", clusterName)
-	}
-	return ch
-}
-
-// Watch watches etcd event
-func (c *Server) Watch(cluster *kstonev1alpha2.EtcdCluster, client *clientv3.Client, keyPrefix string) error {
-	watcher := clientv3.NewWatcher(client)
-	c.watcher[cluster.Name] = watcher
-	go func() {
-		for {
-			klog.V(2).Infof("cluster name:%s,prefix:%s,start to watch key change", cluster.Name, keyPrefix)
-			ch := watcher.Watch(context.Background(), keyPrefix, clientv3.WithPrefix())
-			err := c.watch(cluster, ch)
-			if err == nil {
-				return
-			}
-			//if failed to watch,just retry
-			time.Sleep(time.Second)
-		}
-	}()
+// StopEtcdClusterRequest stops the request watcher of the cluster
+func (c *Server) StopEtcdClusterRequest(inspection *kstonev1alpha2.EtcdInspection) error {
+	c.stopRequestWatcher(requestWatcherKey(inspection.Namespace, inspection.Spec.ClusterName))
 	return nil
 }
 
-func (c *Server) watch(cluster *kstonev1alpha2.EtcdCluster, wchan clientv3.WatchChan) error {
-	ch := c.getEventCh(cluster.Name)
-	for wresp := range wchan {
-		if wresp.Canceled {
-			klog.V(3).Infof("cluster:%s,watcher is closed", cluster.Name)
-			return errors.New("watch failure")
-		}
-		for _, ev := range wresp.Events {
-			switch ev.Type {
-			case mvccpb.PUT:
-				klog.V(3).Infof("type: put,key:%s,lease:%d,mod version:%d", ev.Kv.Key, ev.Kv.Lease, ev.Kv.ModRevision)
-				ch <- ev
-			case mvccpb.DELETE:
-				klog.V(3).Infof("type: delete,key:%s", ev.Kv.Key)
-				ch <- ev
-			}
-		}
+// stopRequestWatcher stops the request watcher by key and removes it
+func (c *Server) stopRequestWatcher(key string) {
+	c.mux.Lock()
+	w, found := c.watchers[key]
+	delete(c.watchers, key)
+	c.mux.Unlock()
+	if !found {
+		return
 	}
-	return nil
+	klog.Infof("stop the request watcher of cluster %s", key)
+	w.stop()
 }
 
-// processWatchEvent prcoesses the event watched
-func (c *Server) processWatchEvent(cluster *kstonev1alpha2.EtcdCluster) {
-	ch := c.getEventCh(cluster.Name)
-	labels := map[string]string{
-		"clusterName": cluster.Name,
-	}
-	for ev := range ch {
-		//fix inconsistent label cardinality,etcdKeyTotal metrics does not have label grpcMethod
-		delete(labels, "grpcMethod")
-		switch ev.Type {
-		case mvccpb.PUT:
-			c.setEtcdPrefixAndResourceName(labels, string(ev.Kv.Key))
-			if ev.IsCreate() {
-				metrics.EtcdKeyTotal.With(labels).Inc()
-			}
-			labels["grpcMethod"] = "PUT"
-			klog.V(3).Infof("cluster:%s,type: PUT,key:%s,lease:%d", cluster.Name, ev.Kv.Key, ev.Kv.Lease)
-			metrics.EtcdRequestTotal.With(labels).Inc()
-		case mvccpb.DELETE:
-			c.setEtcdPrefixAndResourceName(labels, string(ev.Kv.Key))
-			metrics.EtcdKeyTotal.With(labels).Dec()
-			labels["grpcMethod"] = "Delete"
-			metrics.EtcdRequestTotal.With(labels).Inc()
-			klog.V(3).Infof("cluster:%s,type: delete,key:%s,lease:%d", cluster.Name, ev.Kv.Key, ev.Kv.Lease)
-		}
+// newEventRecorder generates the event recorder with the backup storage of cluster
+func (c *Server) newEventRecorder(
+	cluster *kstonev1alpha2.EtcdCluster,
+	info *RequestInfo,
+	from int64,
+) (*eventRecorder, error) {
+	backupConfig, err := backup.GetBackupConfig(cluster)
+	if err != nil {
+		return nil, err
+	}
+	storage, err := backup.GetBackupStorageProvider(string(backupConfig.StorageType), &backup.StorageConfig{
+		KubeCli: c.kubeCli,
+	})
+	if err != nil {
+		return nil, err
 	}
+	return newEventRecorder(cluster, storage, info.SegmentIntervalInSecond, from), nil
 }
@@ -0,0 +1,91 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package inspection
+
+import (
+	"regexp"
+	"strings"
+)
+
+// otherLabel is the label value of the keys not matched by the regex or over
+// the limit of the label values
+const otherLabel = "other"
+
+// keyLabel is the etcdPrefix and resourceName labels of a key
+type keyLabel struct {
+	prefix   string
+	resource string
+}
+
+// otherKeyLabel is the labels of the keys not matched by the regex or over the limit
+var otherKeyLabel = keyLabel{prefix: otherLabel, resource: otherLabel}
+
+// labelRule extracts the labels from the keys of a watched prefix
+type labelRule struct {
+	path  string
+	regex *regexp.Regexp
+	depth int
+}
+
+// newLabelRules compiles the label rules of the prefixes
+func newLabelRules(prefixes []RequestPrefix) ([]*labelRule, error) {
+	rules := make([]*labelRule, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		rule := &labelRule{path: prefix.Path, depth: prefix.Depth}
+		if prefix.Regex != "" {
+			re, err := regexp.Compile(prefix.Regex)
+			if err != nil {
+				return nil, err
+			}
+			rule.regex = re
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// parse parses the labels of key, the regex captures them if it is set,
+// otherwise the key is split as /<etcdPrefix>/<resourceName of depth segments>/...
+func (r *labelRule) parse(key string) keyLabel {
+	if r.regex != nil {
+		matches := r.regex.FindStringSubmatch(key)
+		if matches == nil {
+			return otherKeyLabel
+		}
+		label := keyLabel{prefix: matches[1]}
+		if len(matches) > 2 {
+			label.resource = matches[2]
+		}
+		return label
+	}
+
+	keys := strings.Split(key, "/")
+	if len(keys) < 2 {
+		return keyLabel{}
+	}
+	label := keyLabel{prefix: keys[1]}
+	end := 2 + r.depth
+	if end > len(keys) {
+		end = len(keys)
+	}
+	if end > 2 {
+		label.resource = strings.Join(keys[2:end], "/")
+	}
+	return label
+}
@@ -0,0 +1,158 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package inspection
+
+import (
+	"testing"
+)
+
+func TestLabelRuleParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix RequestPrefix
+		key    string
+		want   keyLabel
+	}{
+		{
+			name:   "default depth",
+			prefix: RequestPrefix{Path: "/registry/", Depth: DefaultRequestLabelDepth},
+			key:    "/registry/pods/default/nginx",
+			want:   keyLabel{prefix: "registry", resource: "pods"},
+		},
+		{
+			name:   "depth 2",
+			prefix: RequestPrefix{Path: "/registry/apiregistration.k8s.io/", Depth: 2},
+			key:    "/registry/apiregistration.k8s.io/apiservices/v1.apps",
+			want:   keyLabel{prefix: "registry", resource: "apiregistration.k8s.io/apiservices"},
+		},
+		{
+			name:   "depth over the segments",
+			prefix: RequestPrefix{Path: "/registry/", Depth: 5},
+			key:    "/registry/ranges/serviceips",
+			want:   keyLabel{prefix: "registry", resource: "ranges/serviceips"},
+		},
+		{
+			name:   "depth 0",
+			prefix: RequestPrefix{Path: "/registry/"},
+			key:    "/registry/pods/default/nginx",
+			want:   keyLabel{prefix: "registry"},
+		},
+		{
+			name:   "key of a single segment",
+			prefix: RequestPrefix{Path: "/", Depth: 1},
+			key:    "/registry",
+			want:   keyLabel{prefix: "registry"},
+		},
+		{
+			name:   "key without slash",
+			prefix: RequestPrefix{Path: "", Depth: 1},
+			key:    "registry",
+			want:   keyLabel{},
+		},
+		{
+			name:   "regex",
+			prefix: RequestPrefix{Path: "/app/", Regex: "^/app/([^/]+)/([^/]+)/"},
+			key:    "/app/tenant-a/orders/1",
+			want:   keyLabel{prefix: "tenant-a", resource: "orders"},
+		},
+		{
+			name:   "regex not matched",
+			prefix: RequestPrefix{Path: "/app/", Regex: "^/app/([^/]+)/([^/]+)/"},
+			key:    "/app/tenant-a",
+			want:   otherKeyLabel,
+		},
+		{
+			name:   "regex with a single group",
+			prefix: RequestPrefix{Path: "/app/", Regex: "^/app/([^/]+)/"},
+			key:    "/app/tenant-a/orders/1",
+			want:   keyLabel{prefix: "tenant-a"},
+		},
+		{
+			name:   "regex with an unmatched optional group",
+			prefix: RequestPrefix{Path: "/app/", Regex: "^/app/([^/]+)(?:/([^/]+)/)?"},
+			key:    "/app/tenant-a",
+			want:   keyLabel{prefix: "tenant-a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.prefix.Validate(); err != nil {
+				t.Fatalf("invalid prefix, err is %v", err)
+			}
+			rules, err := newLabelRules([]RequestPrefix{tt.prefix})
+			if err != nil {
+				t.Fatalf("failed to create label rules, err is %v", err)
+			}
+			if got := rules[0].parse(tt.key); got != tt.want {
+				t.Errorf("parse(%q) = %+v, want %+v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestPrefixValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  RequestPrefix
+		wantErr bool
+	}{
+		{name: "depth", prefix: RequestPrefix{Path: "/registry/", Depth: 2}},
+		{name: "max depth", prefix: RequestPrefix{Path: "/registry/", Depth: MaxRequestLabelDepth}},
+		{name: "depth over max", prefix: RequestPrefix{Path: "/registry/", Depth: MaxRequestLabelDepth + 1}, wantErr: true},
+		{name: "negative depth", prefix: RequestPrefix{Path: "/registry/", Depth: -1}, wantErr: true},
+		{name: "regex", prefix: RequestPrefix{Path: "/app/", Regex: "^/app/([^/]+)/"}},
+		{name: "regex and depth", prefix: RequestPrefix{Path: "/app/", Regex: "^/app/([^/]+)/", Depth: 1}, wantErr: true},
+		{name: "regex without group", prefix: RequestPrefix{Path: "/app/", Regex: "^/app/"}, wantErr: true},
+		{name: "invalid regex", prefix: RequestPrefix{Path: "/app/", Regex: "^/app/(["}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.prefix.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() err is %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRequestInfoValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    RequestInfo
+		wantErr bool
+	}{
+		{name: "path", info: RequestInfo{Path: "/registry/"}},
+		{name: "prefixes", info: RequestInfo{Prefixes: []RequestPrefix{{Path: "/registry/"}, {Path: "/app/"}}}},
+		{name: "path and prefixes", info: RequestInfo{Path: "/registry/", Prefixes: []RequestPrefix{{Path: "/app/"}}}, wantErr: true},
+		{name: "overlapped prefixes", info: RequestInfo{Prefixes: []RequestPrefix{{Path: "/registry/"}, {Path: "/registry/pods/"}}}, wantErr: true},
+		{name: "persist whole keyspace", info: RequestInfo{PersistEvents: true}},
+		{name: "persist root prefix", info: RequestInfo{PersistEvents: true, Prefixes: []RequestPrefix{{Path: "", Depth: 2}}}},
+		{name: "persist path", info: RequestInfo{PersistEvents: true, Path: "/registry/"}, wantErr: true},
+		{name: "persist single prefix", info: RequestInfo{PersistEvents: true, Prefixes: []RequestPrefix{{Path: "/registry/"}}}, wantErr: true},
+		{name: "persist prefixes", info: RequestInfo{PersistEvents: true, Prefixes: []RequestPrefix{{Path: "/registry/"}, {Path: "/app/"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.info.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() err is %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
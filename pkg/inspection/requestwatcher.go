@@ -0,0 +1,442 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package inspection
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/etcd"
+	"tkestack.io/kstone/pkg/inspection/metrics"
+)
+
+const (
+	eventBuffer = 40960
+	// requestWatcherStopTimeout is the max time waiting for the pipeline to exit
+	requestWatcherStopTimeout = 10 * time.Second
+	// requestWatchRetryInterval is the interval to restart a failed watch
+	requestWatchRetryInterval = time.Second
+
+	watchReasonCompacted = "compacted"
+	watchReasonCanceled  = "canceled"
+	watchReasonClosed    = "closed"
+)
+
+// watchStatus is the status of the watch of a prefix
+type watchStatus struct {
+	watching bool
+	nextRev  int64
+	lastErr  error
+}
+
+// requestWatcher is the pipeline watching the requests of a cluster, a watch
+// goroutine of each prefix forwards the watched events to the process
+// goroutine, which transfers them to prometheus metrics
+type requestWatcher struct {
+	clusterName string
+	rules       []*labelRule
+	maxLabels   int
+	// fingerprint is the config of the pipeline, the pipeline is restarted if it changes
+	fingerprint string
+	client      *clientv3.Client
+	recorder    *eventRecorder
+	eventCh     chan *clientv3.Event
+	cancel      context.CancelFunc
+	done        chan struct{}
+
+	mux sync.Mutex
+	// totalsRev is the revision of the key totals, the events up to it are
+	// already counted by the key totals
+	totalsRev int64
+	// labels are the labels of the metrics set by the pipeline, at most
+	// maxLabels of them besides the other labels
+	labels map[keyLabel]bool
+	// overflowed is true if some keys are counted with the other labels for the limit
+	overflowed bool
+	status     map[string]*watchStatus
+	restarts   int
+}
+
+func newRequestWatcher(
+	clusterName string,
+	rules []*labelRule,
+	maxLabels int,
+	fingerprint string,
+	client *clientv3.Client,
+) *requestWatcher {
+	status := make(map[string]*watchStatus, len(rules))
+	for _, rule := range rules {
+		status[rule.path] = &watchStatus{}
+	}
+	return &requestWatcher{
+		clusterName: clusterName,
+		rules:       rules,
+		maxLabels:   maxLabels,
+		fingerprint: fingerprint,
+		client:      client,
+		eventCh:     make(chan *clientv3.Event, eventBuffer),
+		done:        make(chan struct{}),
+		labels:      make(map[keyLabel]bool),
+		status:      status,
+	}
+}
+
+// paths returns the watched prefixes
+func (w *requestWatcher) paths() string {
+	paths := make([]string, 0, len(w.rules))
+	for _, rule := range w.rules {
+		paths = append(paths, fmt.Sprintf("%q", rule.path))
+	}
+	return strings.Join(paths, ",")
+}
+
+// labelOf returns the labels of key, the new labels over the limit are folded
+// into the other labels, w.mux must be held
+func (w *requestWatcher) labelOf(key string) keyLabel {
+	label := otherKeyLabel
+	for _, rule := range w.rules {
+		if strings.HasPrefix(key, rule.path) {
+			label = rule.parse(key)
+			break
+		}
+	}
+	if w.labels[label] {
+		return label
+	}
+	if label != otherKeyLabel && len(w.labels) >= w.maxLabels {
+		if !w.overflowed {
+			klog.Warningf("cluster %s reaches the max labels %d, count the new labels as %s", w.clusterName, w.maxLabels, otherLabel)
+			w.overflowed = true
+		}
+		label = otherKeyLabel
+	}
+	w.labels[label] = true
+	return label
+}
+
+// listKeys counts the keys of the prefixes by their labels at the latest
+// revision, the revision is returned
+func (w *requestWatcher) listKeys(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, etcd.DefaultCommandTimeOut)
+	defer cancel()
+
+	var rev int64
+	var keys []string
+	for _, rule := range w.rules {
+		opts := []clientv3.OpOption{clientv3.WithPrefix(), clientv3.WithKeysOnly()}
+		if rev != 0 {
+			// count all prefixes at the same revision
+			opts = append(opts, clientv3.WithRev(rev))
+		}
+		rsp, err := w.client.Get(ctx, rule.path, opts...)
+		if err != nil {
+			return 0, err
+		}
+		rev = rsp.Header.Revision
+		for _, kv := range rsp.Kvs {
+			keys = append(keys, string(kv.Key))
+		}
+	}
+	klog.V(2).Infof("cluster name %s,total node:%d", w.clusterName, len(keys))
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	staleLabels := w.labels
+	w.labels = make(map[keyLabel]bool)
+	w.overflowed = false
+	totals := make(map[keyLabel]float64)
+	for _, key := range keys {
+		totals[w.labelOf(key)]++
+	}
+	for label := range staleLabels {
+		if !w.labels[label] {
+			w.deleteLabelMetrics(label)
+		}
+	}
+	for label, total := range totals {
+		metrics.EtcdKeyTotal.With(w.keyTotalLabels(label)).Set(total)
+	}
+	w.totalsRev = rev
+	return rev, nil
+}
+
+// start starts the pipeline from revision rev
+func (w *requestWatcher) start(rev int64) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	var wg sync.WaitGroup
+	for _, rule := range w.rules {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			w.run(ctx, path, rev)
+		}(rule.path)
+	}
+	go func() {
+		wg.Wait()
+		if w.recorder != nil {
+			_ = w.recorder.flush()
+		}
+		close(w.eventCh)
+	}()
+	go w.process()
+}
+
+// stop stops the pipeline if it is started, closes the client and deletes the metrics of the pipeline
+func (w *requestWatcher) stop() {
+	if w.cancel != nil {
+		w.cancel()
+		select {
+		case <-w.done:
+		case <-time.After(requestWatcherStopTimeout):
+			klog.Warningf("request watcher of cluster %s does not exit in %s", w.clusterName, requestWatcherStopTimeout)
+		}
+	}
+	if err := w.client.Close(); err != nil {
+		klog.Warningf("failed to close etcd client of cluster %s, err is %v", w.clusterName, err)
+	}
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	for label := range w.labels {
+		w.deleteLabelMetrics(label)
+	}
+	clusterLabels := map[string]string{"clusterName": w.clusterName}
+	metrics.EtcdRequestWatchUp.Delete(clusterLabels)
+	metrics.EtcdRequestWatchRevision.Delete(clusterLabels)
+	metrics.EtcdRequestWatchQueueLength.Delete(clusterLabels)
+	for _, reason := range []string{watchReasonCompacted, watchReasonCanceled, watchReasonClosed} {
+		metrics.EtcdRequestWatchRestarts.Delete(map[string]string{"clusterName": w.clusterName, "reason": reason})
+	}
+}
+
+// run watches the requests of the prefix path from revision rev until the
+// pipeline is stopped, a failed watch is restarted from the next revision of
+// the last watched event
+func (w *requestWatcher) run(ctx context.Context, path string, rev int64) {
+	for {
+		klog.V(2).Infof("cluster name:%s,prefix:%s,start to watch key change from revision %d", w.clusterName, path, rev)
+		w.setStatus(path, true, rev, nil)
+
+		wctx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+		wchan := w.client.Watch(wctx, path, clientv3.WithPrefix(), clientv3.WithRev(rev))
+		nextRev, reason, err := w.watch(ctx, path, wchan)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		klog.Warningf("cluster:%s,prefix:%s,watch is %s, err is %v, restart from revision %d", w.clusterName, path, reason, err, nextRev)
+		if nextRev > rev {
+			rev = nextRev
+		}
+		if reason == watchReasonCompacted {
+			// the events before the compacted revision are lost, count the keys again
+			if _, lErr := w.listKeys(ctx); lErr != nil {
+				klog.Errorf("failed to count keys, cluster is %s, err is %v", w.clusterName, lErr)
+			}
+		}
+		w.setStatus(path, false, rev, err)
+		metrics.EtcdRequestWatchRestarts.With(map[string]string{"clusterName": w.clusterName, "reason": reason}).Inc()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(requestWatchRetryInterval):
+		}
+	}
+}
+
+// watch forwards the watched events of the prefix path to the event chan, it
+// returns the revision to resume the watch from and the reason if the watch fails
+func (w *requestWatcher) watch(ctx context.Context, path string, wchan clientv3.WatchChan) (int64, string, error) {
+	clusterLabels := map[string]string{"clusterName": w.clusterName}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var nextRev int64
+	for {
+		select {
+		case <-ticker.C:
+			if w.recorder != nil {
+				w.recorder.flushIfDue()
+			}
+			metrics.EtcdRequestWatchQueueLength.With(clusterLabels).Set(float64(len(w.eventCh)))
+		case wresp, ok := <-wchan:
+			if !ok {
+				return nextRev, watchReasonClosed, fmt.Errorf("watch channel is closed")
+			}
+			if wresp.CompactRevision != 0 {
+				nextRev = wresp.CompactRevision
+				if w.recorder != nil {
+					_ = w.recorder.flush()
+					w.recorder.skip(nextRev)
+				}
+				return nextRev, watchReasonCompacted, fmt.Errorf("watch is compacted at revision %d", nextRev)
+			}
+			if wresp.Canceled {
+				return nextRev, watchReasonCanceled, wresp.Err()
+			}
+			for _, ev := range wresp.Events {
+				switch ev.Type {
+				case mvccpb.PUT:
+					klog.V(3).Infof("type: put,key:%s,lease:%d,mod version:%d", ev.Kv.Key, ev.Kv.Lease, ev.Kv.ModRevision)
+				case mvccpb.DELETE:
+					klog.V(3).Infof("type: delete,key:%s", ev.Kv.Key)
+				}
+				if w.recorder != nil {
+					w.recorder.record(ev)
+				}
+				nextRev = ev.Kv.ModRevision + 1
+				select {
+				case w.eventCh <- ev:
+				case <-ctx.Done():
+					return nextRev, "", ctx.Err()
+				}
+			}
+			if nextRev > 0 {
+				w.setStatus(path, true, nextRev, nil)
+			}
+		}
+	}
+}
+
+// process transfers the watched events to prometheus metrics until the event chan is closed
+func (w *requestWatcher) process() {
+	defer close(w.done)
+	for ev := range w.eventCh {
+		w.mux.Lock()
+		label := w.labelOf(string(ev.Kv.Key))
+		counted := ev.Kv.ModRevision <= w.totalsRev
+		switch {
+		case counted:
+		case ev.Type == mvccpb.PUT && ev.IsCreate():
+			metrics.EtcdKeyTotal.With(w.keyTotalLabels(label)).Inc()
+		case ev.Type == mvccpb.DELETE:
+			metrics.EtcdKeyTotal.With(w.keyTotalLabels(label)).Dec()
+		}
+		w.mux.Unlock()
+
+		switch ev.Type {
+		case mvccpb.PUT:
+			klog.V(3).Infof("cluster:%s,type: PUT,key:%s,lease:%d", w.clusterName, ev.Kv.Key, ev.Kv.Lease)
+			metrics.EtcdRequestTotal.With(w.requestTotalLabels(label, "PUT")).Inc()
+		case mvccpb.DELETE:
+			klog.V(3).Infof("cluster:%s,type: delete,key:%s,lease:%d", w.clusterName, ev.Kv.Key, ev.Kv.Lease)
+			metrics.EtcdRequestTotal.With(w.requestTotalLabels(label, "Delete")).Inc()
+		}
+	}
+}
+
+func (w *requestWatcher) keyTotalLabels(label keyLabel) map[string]string {
+	return map[string]string{
+		"clusterName":  w.clusterName,
+		"etcdPrefix":   label.prefix,
+		"resourceName": label.resource,
+	}
+}
+
+func (w *requestWatcher) requestTotalLabels(label keyLabel, method string) map[string]string {
+	labels := w.keyTotalLabels(label)
+	labels["grpcMethod"] = method
+	return labels
+}
+
+// deleteLabelMetrics deletes the key and request metrics of the labels
+func (w *requestWatcher) deleteLabelMetrics(label keyLabel) {
+	metrics.EtcdKeyTotal.Delete(w.keyTotalLabels(label))
+	for _, method := range []string{"PUT", "Delete"} {
+		metrics.EtcdRequestTotal.Delete(w.requestTotalLabels(label, method))
+	}
+}
+
+// setStatus sets the status of the watch of the prefix path, and the health
+// metrics of the pipeline, which is up if all prefixes are watched
+func (w *requestWatcher) setStatus(path string, watching bool, nextRev int64, err error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	status := w.status[path]
+	status.watching = watching
+	status.nextRev = nextRev
+	status.lastErr = err
+	if !watching {
+		w.restarts++
+	}
+
+	up, rev := 1.0, int64(0)
+	for _, s := range w.status {
+		if !s.watching {
+			up = 0
+		}
+		if rev == 0 || s.nextRev < rev {
+			rev = s.nextRev
+		}
+	}
+	clusterLabels := map[string]string{"clusterName": w.clusterName}
+	metrics.EtcdRequestWatchUp.With(clusterLabels).Set(up)
+	metrics.EtcdRequestWatchRevision.With(clusterLabels).Set(float64(rev))
+}
+
+// result returns the result of the pipeline, it warns if a watch is retrying
+// or the labels reach the limit
+func (w *requestWatcher) result() *kstonev1alpha2.EtcdInspectionResult {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	result := newResultBuilder()
+	var rev int64
+	for _, rule := range w.rules {
+		status := w.status[rule.path]
+		if !status.watching {
+			result.addFinding(
+				kstonev1alpha2.EtcdInspectionWarn,
+				"the watch of %q is retrying from revision %d: %v",
+				rule.path,
+				status.nextRev,
+				status.lastErr,
+			)
+		}
+		if rev == 0 || status.nextRev < rev {
+			rev = status.nextRev
+		}
+	}
+	if w.overflowed {
+		result.addFinding(
+			kstonev1alpha2.EtcdInspectionWarn,
+			"the labels reach the limit %d, the other keys are counted with the %q labels",
+			w.maxLabels,
+			otherLabel,
+		)
+	}
+	return result.build(fmt.Sprintf(
+		"watching %s at revision %d with %d labels, restarted %d times",
+		w.paths(),
+		rev,
+		len(w.labels),
+		w.restarts,
+	))
+}
@@ -0,0 +1,118 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package inspection
+
+import (
+	"fmt"
+
+	"k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/monitor"
+)
+
+var resultStatusSeverity = map[kstonev1alpha2.EtcdInspectionResultStatus]int{
+	kstonev1alpha2.EtcdInspectionPass: 0,
+	kstonev1alpha2.EtcdInspectionWarn: 1,
+	kstonev1alpha2.EtcdInspectionFail: 2,
+}
+
+// ResultStatusValue returns 0 for pass, 1 for warn and 2 for fail
+func ResultStatusValue(status kstonev1alpha2.EtcdInspectionResultStatus) float64 {
+	return float64(resultStatusSeverity[status])
+}
+
+// worseStatus returns the worse one of the two statuses
+func worseStatus(a, b kstonev1alpha2.EtcdInspectionResultStatus) kstonev1alpha2.EtcdInspectionResultStatus {
+	if resultStatusSeverity[b] > resultStatusSeverity[a] {
+		return b
+	}
+	return a
+}
+
+// FinalizeResult merges the error of a run into its result, a run that
+// returns an error always fails. nil is returned if the feature reports
+// nothing, e.g. the features which do not inspect the cluster.
+func FinalizeResult(result *kstonev1alpha2.EtcdInspectionResult, err error) *kstonev1alpha2.EtcdInspectionResult {
+	if err == nil {
+		return result
+	}
+	if result == nil {
+		result = &kstonev1alpha2.EtcdInspectionResult{}
+	} else {
+		result = result.DeepCopy()
+	}
+	result.Status = kstonev1alpha2.EtcdInspectionFail
+	result.Findings = append(result.Findings, err.Error())
+	return result
+}
+
+// resultBuilder collects the findings and the member results of a run
+type resultBuilder struct {
+	result kstonev1alpha2.EtcdInspectionResult
+}
+
+func newResultBuilder() *resultBuilder {
+	return &resultBuilder{
+		result: kstonev1alpha2.EtcdInspectionResult{
+			Status: kstonev1alpha2.EtcdInspectionPass,
+		},
+	}
+}
+
+// addFinding adds a problem found by the run
+func (b *resultBuilder) addFinding(status kstonev1alpha2.EtcdInspectionResultStatus, format string, args ...interface{}) {
+	b.result.Status = worseStatus(b.result.Status, status)
+	b.result.Findings = append(b.result.Findings, fmt.Sprintf(format, args...))
+}
+
+// addMember adds the result of a member
+func (b *resultBuilder) addMember(endpoint string, status kstonev1alpha2.EtcdInspectionResultStatus, message string) {
+	b.result.Status = worseStatus(b.result.Status, status)
+	b.result.Members = append(b.result.Members, kstonev1alpha2.EtcdInspectionMemberResult{
+		Endpoint: endpoint,
+		Status:   status,
+		Message:  message,
+	})
+}
+
+// build returns the result, summary is the only finding if nothing is wrong
+func (b *resultBuilder) build(summary string) *kstonev1alpha2.EtcdInspectionResult {
+	if len(b.result.Findings) == 0 && summary != "" {
+		b.result.Findings = []string{summary}
+	}
+	return &b.result
+}
+
+// getAlertThresholds gets the alert thresholds of the cluster, the results
+// warn at the same thresholds as the alerts of the monitor feature
+func getAlertThresholds(cluster *kstonev1alpha2.EtcdCluster) *monitor.AlertThresholds {
+	thresholds, err := monitor.GetAlertThresholds(cluster)
+	if err != nil {
+		klog.Warningf("invalid alert thresholds of cluster %s, use the defaults, err is %v", cluster.Name, err)
+		return &monitor.AlertThresholds{
+			KeyDiff:        monitor.DefaultAlertKeyDiff,
+			RevisionDiff:   monitor.DefaultAlertRevisionDiff,
+			MinBackupFiles: monitor.DefaultAlertMinBackupFiles,
+			DBUsedPercent:  monitor.DefaultAlertDBUsedPercent,
+			BackupKeyDiff:  monitor.DefaultAlertBackupKeyDiff,
+		}
+	}
+	return thresholds
+}
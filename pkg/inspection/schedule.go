@@ -0,0 +1,56 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package inspection
+
+import (
+	"time"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+)
+
+// DefaultCollectInterval is the interval of the inspections which only collect
+// metrics, they used to run on every resync of the informer
+const DefaultCollectInterval = 30 * time.Second
+
+// inspectionIntervals are the intervals of the inspections if IntervalInSecond
+// of etcdinspection is not specified, the other inspections run every
+// DefaultInspectionInterval
+var inspectionIntervals = map[kstonev1alpha2.KStoneFeature]time.Duration{
+	kstonev1alpha2.KStoneFeatureHealthy:      DefaultCollectInterval,
+	kstonev1alpha2.KStoneFeatureConsistency:  DefaultCollectInterval,
+	kstonev1alpha2.KStoneFeatureRequest:      DefaultCollectInterval,
+	kstonev1alpha2.KStoneFeatureAlarm:        DefaultCollectInterval,
+	kstonev1alpha2.KStoneFeatureDBSize:       DefaultCollectInterval,
+	kstonev1alpha2.KStoneFeatureScraper:      DefaultCollectInterval,
+	kstonev1alpha2.KStoneFeatureBackup:       DefaultBackupRetentionInterval,
+	kstonev1alpha2.KStoneFeatureBackupVerify: DefaultBackupVerifyInterval,
+	kstonev1alpha2.KStoneFeatureDefrag:       DefaultDefragInterval,
+	kstonev1alpha2.KStoneFeatureKeyspace:     DefaultKeyspaceInterval,
+}
+
+// GetInspectionInterval gets the interval between the runs of inspection
+func GetInspectionInterval(inspection *kstonev1alpha2.EtcdInspection) time.Duration {
+	if inspection.Spec.IntervalInSecond > 0 {
+		return time.Duration(inspection.Spec.IntervalInSecond) * time.Second
+	}
+	if interval, found := inspectionIntervals[kstonev1alpha2.KStoneFeature(inspection.Spec.InspectionType)]; found {
+		return interval
+	}
+	return DefaultInspectionInterval
+}
@@ -0,0 +1,61 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package inspection
+
+import (
+	"fmt"
+
+	"k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/etcd"
+	featureutil "tkestack.io/kstone/pkg/featureprovider/util"
+	"tkestack.io/kstone/pkg/inspection/metrics"
+)
+
+// ScrapeMemberMetrics scrapes the metrics of etcd members, and mirrors the
+// curated ones with the labels clusterName and member
+func (c *Server) ScrapeMemberMetrics(inspection *kstonev1alpha2.EtcdInspection) (*kstonev1alpha2.EtcdInspectionResult, error) {
+	namespace, name := inspection.Namespace, inspection.Spec.ClusterName
+	cluster, clientConfig, err := c.GetEtcdClusterInfo(namespace, name)
+	defer func() {
+		if err != nil {
+			featureutil.IncrFailedInspectionCounter(name, kstonev1alpha2.KStoneFeatureScraper)
+		}
+	}()
+	if err != nil {
+		klog.Errorf("load tlsConfig failed, namespace is %s, name is %s, err is %v", namespace, name, err)
+		return nil, err
+	}
+
+	result := newResultBuilder()
+	for _, m := range cluster.Status.Members {
+		families, sErr := etcd.MemberMetrics(m.ExtensionClientUrl, clientConfig)
+		if sErr != nil {
+			klog.Errorf("failed to scrape metrics of member %s, cluster %s, err is %v", m.Name, cluster.Name, sErr)
+			metrics.EtcdMemberMetrics.Delete(cluster.Name, m.Name)
+			result.addMember(m.Endpoint, kstonev1alpha2.EtcdInspectionFail, sErr.Error())
+			err = sErr
+			continue
+		}
+		metrics.EtcdMemberMetrics.Set(cluster.Name, m.Name, families)
+		result.addMember(m.Endpoint, kstonev1alpha2.EtcdInspectionPass, fmt.Sprintf("%d metric families", len(families)))
+	}
+	return result.build(fmt.Sprintf("the metrics of %d members are scraped", len(cluster.Status.Members))), err
+}
@@ -0,0 +1,351 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package monitor
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klog "k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	platformscheme "tkestack.io/kstone/pkg/generated/clientset/versioned/scheme"
+)
+
+const (
+	DefaultGrafanaNamespace = "kstone"
+	// GrafanaDashboardLabel selects the configmaps provisioned by the grafana sidecar
+	GrafanaDashboardLabel      = "grafana/dashboards"
+	GrafanaDashboardLabelValue = "default"
+	GrafanaDatasource          = "KSTONE-PROM"
+
+	labelEtcdName      = "etcdName"
+	labelEtcdNamespace = "etcdNamespace"
+)
+
+// GrafanaNamespace is the namespace watched by the grafana sidecar
+var GrafanaNamespace = os.Getenv("GRAFANA_NAMESPACE")
+
+func getGrafanaNamespace() string {
+	if GrafanaNamespace == "" {
+		return DefaultGrafanaNamespace
+	}
+	return GrafanaNamespace
+}
+
+// grafanaDashboardName returns the name of the dashboard configmap of cluster,
+// the configmaps of all the clusters are in the grafana namespace
+func grafanaDashboardName(cluster *kstonev1alpha2.EtcdCluster) string {
+	return fmt.Sprintf("grafana-etcd-%s-%s", cluster.Namespace, cluster.Name)
+}
+
+type grafanaTarget struct {
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat"`
+	RefID        string `json:"refId"`
+}
+
+type grafanaGridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type grafanaPanel struct {
+	ID          int                    `json:"id"`
+	Type        string                 `json:"type"`
+	Title       string                 `json:"title"`
+	Datasource  string                 `json:"datasource,omitempty"`
+	GridPos     grafanaGridPos         `json:"gridPos"`
+	Targets     []grafanaTarget        `json:"targets,omitempty"`
+	FieldConfig map[string]interface{} `json:"fieldConfig,omitempty"`
+}
+
+type grafanaDashboard struct {
+	UID           string            `json:"uid"`
+	Title         string            `json:"title"`
+	Tags          []string          `json:"tags"`
+	Editable      bool              `json:"editable"`
+	Refresh       string            `json:"refresh"`
+	SchemaVersion int               `json:"schemaVersion"`
+	Time          map[string]string `json:"time"`
+	Timezone      string            `json:"timezone"`
+	Panels        []grafanaPanel    `json:"panels"`
+}
+
+// dashboardBuilder lays out the panels in rows of two
+type dashboardBuilder struct {
+	panels []grafanaPanel
+	x, y   int
+}
+
+func (b *dashboardBuilder) row(title string) {
+	if b.x != 0 {
+		b.x, b.y = 0, b.y+8
+	}
+	b.panels = append(b.panels, grafanaPanel{
+		ID:      len(b.panels) + 1,
+		Type:    "row",
+		Title:   title,
+		GridPos: grafanaGridPos{H: 1, W: 24, X: 0, Y: b.y},
+	})
+	b.y++
+}
+
+func (b *dashboardBuilder) panel(title, unit, expr, legend string) {
+	b.panels = append(b.panels, grafanaPanel{
+		ID:         len(b.panels) + 1,
+		Type:       "timeseries",
+		Title:      title,
+		Datasource: GrafanaDatasource,
+		GridPos:    grafanaGridPos{H: 8, W: 12, X: b.x, Y: b.y},
+		Targets:    []grafanaTarget{{Expr: expr, LegendFormat: legend, RefID: "A"}},
+		FieldConfig: map[string]interface{}{
+			"defaults":  map[string]interface{}{"unit": unit},
+			"overrides": []interface{}{},
+		},
+	})
+	if b.x == 0 {
+		b.x = 12
+	} else {
+		b.x, b.y = 0, b.y+8
+	}
+}
+
+// initEtcdGrafanaDashboard generates the dashboard of cluster, which shows
+// the metrics of the members scraped by prometheus and the inspection metrics
+func initEtcdGrafanaDashboard(cluster *kstonev1alpha2.EtcdCluster) ([]byte, error) {
+	member := fmt.Sprintf(`etcdName="%s",namespace="%s"`, cluster.Name, cluster.Namespace)
+	inspection := fmt.Sprintf(`clusterName="%s"`, cluster.Name)
+
+	b := &dashboardBuilder{}
+	b.row("Members")
+	b.panel("Has Leader", "none",
+		fmt.Sprintf(`etcd_server_has_leader{%s}`, member), "{{endpoint}}")
+	b.panel("Leader Changes", "none",
+		fmt.Sprintf(`changes(etcd_server_leader_changes_seen_total{%s}[1h])`, member), "{{endpoint}}")
+	b.panel("gRPC Requests", "reqps",
+		fmt.Sprintf(`sum(rate(grpc_server_started_total{%s,grpc_type="unary"}[5m])) by (endpoint)`, member),
+		"{{endpoint}}")
+	b.panel("Failed Proposals", "none",
+		fmt.Sprintf(`rate(etcd_server_proposals_failed_total{%s}[5m])`, member), "{{endpoint}}")
+	b.panel("WAL Fsync Duration p99", "s",
+		fmt.Sprintf(`histogram_quantile(0.99, sum(rate(etcd_disk_wal_fsync_duration_seconds_bucket{%s}[5m])) by (endpoint, le))`,
+			member),
+		"{{endpoint}}")
+	b.panel("Backend Commit Duration p99", "s",
+		fmt.Sprintf(`histogram_quantile(0.99, sum(rate(etcd_disk_backend_commit_duration_seconds_bucket{%s}[5m])) by (endpoint, le))`,
+			member),
+		"{{endpoint}}")
+	b.panel("DB Size", "bytes",
+		fmt.Sprintf(`etcd_mvcc_db_total_size_in_bytes{%s}`, member), "{{endpoint}}")
+	b.panel("Memory", "bytes",
+		fmt.Sprintf(`process_resident_memory_bytes{%s}`, member), "{{endpoint}}")
+
+	b.row("Inspection")
+	// 0 is pass, 1 is warn and 2 is fail
+	b.panel("Inspection Result", "none",
+		fmt.Sprintf(`kstone_inspection_result{%s}`, inspection), "{{inspectionType}}")
+	b.panel("Endpoint Healthy", "none",
+		fmt.Sprintf(`kstone_inspection_etcd_endpoint_healthy{%s}`, inspection), "{{endpoint}}")
+	b.panel("Consistency Diff", "none",
+		fmt.Sprintf(`{__name__=~"kstone_inspection_etcd_node_(diff|revision_diff)_total",%s}`, inspection),
+		"{{__name__}}")
+	b.panel("DB Quota Used", "percent",
+		fmt.Sprintf(`kstone_inspection_etcd_db_size_used_percent{%s}`, inspection), "{{endpoint}}")
+	b.panel("Backup Files", "none",
+		fmt.Sprintf(`kstone_inspection_etcd_backup_files{%s}`, inspection), "backup files")
+	b.panel("Keys", "none",
+		fmt.Sprintf(`kstone_inspection_etcd_key_total{%s}`, inspection), "{{resourceName}}")
+	b.panel("Requests", "reqps",
+		fmt.Sprintf(`sum(rate(kstone_inspection_etcd_request_total{%s}[5m])) by (grpcMethod)`, inspection),
+		"{{grpcMethod}}")
+
+	dashboard := &grafanaDashboard{
+		// uid is limited to 40 characters
+		UID:           fmt.Sprintf("kstone-%x", sha1.Sum([]byte(cluster.Namespace+"/"+cluster.Name)))[:40],
+		Title:         fmt.Sprintf("etcd %s/%s", cluster.Namespace, cluster.Name),
+		Tags:          []string{"kstone", "etcd"},
+		Editable:      true,
+		Refresh:       "30s",
+		SchemaVersion: 30,
+		Time:          map[string]string{"from": "now-1h", "to": "now"},
+		Timezone:      "browser",
+		Panels:        b.panels,
+	}
+	return json.MarshalIndent(dashboard, "", "  ")
+}
+
+// initEtcdDashboardConfigMap inits the dashboard configmap of cluster
+func (prom *PrometheusMonitor) initEtcdDashboardConfigMap(cluster *kstonev1alpha2.EtcdCluster) (
+	*corev1.ConfigMap,
+	error) {
+	dashboard, err := initEtcdGrafanaDashboard(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	name := grafanaDashboardName(cluster)
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: getGrafanaNamespace(),
+			Labels: map[string]string{
+				GrafanaDashboardLabel: GrafanaDashboardLabelValue,
+				labelEtcdName:         cluster.Name,
+				labelEtcdNamespace:    cluster.Namespace,
+			},
+		},
+		Data: map[string]string{
+			name + ".json": string(dashboard),
+		},
+	}
+
+	// the owner reference can not cross namespaces, the configmaps of the
+	// clusters in the other namespaces are deleted by CleanGrafanaDashboard
+	if cm.Namespace == cluster.Namespace {
+		err = controllerutil.SetOwnerReference(cluster, cm, platformscheme.Scheme)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return cm, nil
+}
+
+// DashboardConfigMapIsEquivalent compares old dashboard configmap with new dashboard configmap
+func (prom *PrometheusMonitor) DashboardConfigMapIsEquivalent(old, new *corev1.ConfigMap) bool {
+	if !reflect.DeepEqual(old.Labels, new.Labels) {
+		return false
+	}
+	if !reflect.DeepEqual(old.Data, new.Data) {
+		return false
+	}
+	return true
+}
+
+// checkGrafanaDashboardEqual checks whether the dashboard of cluster is up to date
+func (prom *PrometheusMonitor) checkGrafanaDashboardEqual(cluster *kstonev1alpha2.EtcdCluster) bool {
+	newCM, err := prom.initEtcdDashboardConfigMap(cluster)
+	if err != nil {
+		return true
+	}
+	curCM, err := prom.kubeCli.CoreV1().ConfigMaps(newCM.Namespace).Get(context.TODO(), newCM.Name, metav1.GetOptions{})
+	if err != nil {
+		return false
+	}
+	return prom.DashboardConfigMapIsEquivalent(curCM, newCM)
+}
+
+// syncGrafanaDashboard creates or updates the dashboard configmap of cluster
+func (prom *PrometheusMonitor) syncGrafanaDashboard(cluster *kstonev1alpha2.EtcdCluster) error {
+	newCM, err := prom.initEtcdDashboardConfigMap(cluster)
+	if err != nil {
+		klog.Errorf("failed to init etcd %s dashboard, err is %v", cluster.Name, err)
+		return err
+	}
+	configMaps := prom.kubeCli.CoreV1().ConfigMaps(newCM.Namespace)
+	curCM, err := configMaps.Get(context.TODO(), newCM.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(context.TODO(), newCM, metav1.CreateOptions{})
+		if err != nil {
+			klog.Errorf("create etcd %s dashboard failed:%v", cluster.Name, err)
+		}
+		return err
+	} else if err != nil {
+		klog.Errorf("get etcd %s dashboard failed:%v", cluster.Name, err)
+		return err
+	} else if !prom.DashboardConfigMapIsEquivalent(curCM, newCM) {
+		newCM.ResourceVersion = curCM.ResourceVersion
+		_, err = configMaps.Update(context.TODO(), newCM, metav1.UpdateOptions{})
+		if err != nil {
+			klog.Errorf("failed to update etcd %s dashboard,err is %v", cluster.Name, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// getGrafanaDashboard gets the dashboard configmap of cluster
+func (prom *PrometheusMonitor) getGrafanaDashboard(cluster *kstonev1alpha2.EtcdCluster) (*corev1.ConfigMap, error) {
+	return prom.kubeCli.CoreV1().ConfigMaps(getGrafanaNamespace()).Get(
+		context.TODO(),
+		grafanaDashboardName(cluster),
+		metav1.GetOptions{},
+	)
+}
+
+// CleanGrafanaDashboard deletes the dashboard configmap of cluster
+func (prom *PrometheusMonitor) CleanGrafanaDashboard(cluster *kstonev1alpha2.EtcdCluster) error {
+	err := prom.kubeCli.CoreV1().ConfigMaps(getGrafanaNamespace()).Delete(
+		context.TODO(),
+		grafanaDashboardName(cluster),
+		metav1.DeleteOptions{},
+	)
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Errorf("delete etcd %s dashboard failed:%v", cluster.Name, err)
+		return err
+	}
+	return nil
+}
+
+// SweepGrafanaDashboards deletes the dashboard configmaps of the clusters
+// which no longer exist, they are left if the controller misses the deletion
+func (prom *PrometheusMonitor) SweepGrafanaDashboards(exists func(namespace, name string) (bool, error)) error {
+	configMaps := prom.kubeCli.CoreV1().ConfigMaps(getGrafanaNamespace())
+	list, err := configMaps.List(context.TODO(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,%s,%s",
+			GrafanaDashboardLabel, GrafanaDashboardLabelValue, labelEtcdName, labelEtcdNamespace),
+	})
+	if err != nil {
+		klog.Errorf("failed to list etcd dashboards, err is %v", err)
+		return err
+	}
+
+	var lastErr error
+	for i := range list.Items {
+		cm := &list.Items[i]
+		namespace, name := cm.Labels[labelEtcdNamespace], cm.Labels[labelEtcdName]
+		found, err := exists(namespace, name)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if found {
+			continue
+		}
+		klog.Infof("delete dashboard %s of the deleted etcd %s/%s", cm.Name, namespace, name)
+		err = configMaps.Delete(context.TODO(), cm.Name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &cm.UID},
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			klog.Errorf("failed to delete etcd dashboard %s, err is %v", cm.Name, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
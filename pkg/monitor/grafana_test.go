@@ -0,0 +1,72 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package monitor
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+)
+
+func TestSweepGrafanaDashboards(t *testing.T) {
+	prom := &PrometheusMonitor{kubeCli: fake.NewSimpleClientset()}
+	for _, name := range []string{"alive", "deleted"} {
+		cluster := &kstonev1alpha2.EtcdCluster{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+		if err := prom.syncGrafanaDashboard(cluster); err != nil {
+			t.Fatalf("failed to sync dashboard, err is %v", err)
+		}
+	}
+	// the configmaps without the etcd labels are not swept
+	_, err := prom.kubeCli.CoreV1().ConfigMaps(getGrafanaNamespace()).Create(context.TODO(), &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "grafana-etcd",
+			Labels: map[string]string{GrafanaDashboardLabel: GrafanaDashboardLabelValue},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = prom.SweepGrafanaDashboards(func(namespace, name string) (bool, error) {
+		return namespace == "default" && name == "alive", nil
+	})
+	if err != nil {
+		t.Fatalf("failed to sweep dashboards, err is %v", err)
+	}
+
+	list, err := prom.kubeCli.CoreV1().ConfigMaps(getGrafanaNamespace()).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, cm := range list.Items {
+		names = append(names, cm.Name)
+	}
+	sort.Strings(names)
+	if want := []string{"grafana-etcd", "grafana-etcd-default-alive"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("configmaps = %v, want %v", names, want)
+	}
+}
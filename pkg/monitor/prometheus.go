@@ -483,7 +483,13 @@ func (prom *PrometheusMonitor) CheckEqualIfDisabled(cluster *kstonev1alpha2.Etcd
 	if err != nil && apierrors.IsNotFound(err) {
 		_, err = prom.GetEtcdService(cluster.Namespace, cluster.Name)
 		if err != nil && apierrors.IsNotFound(err) {
-			return true
+			_, err = prom.GetPrometheusRule(cluster.Namespace, cluster.Name)
+			if err != nil && apierrors.IsNotFound(err) {
+				_, err = prom.getGrafanaDashboard(cluster)
+				if err != nil && apierrors.IsNotFound(err) {
+					return true
+				}
+			}
 		}
 	}
 	return false
@@ -514,7 +520,7 @@ func (prom *PrometheusMonitor) CheckEqualIfEnabled(cluster *kstonev1alpha2.EtcdC
 
 	if reflect.DeepEqual(epLabels, cluster.ObjectMeta.Labels) &&
 		reflect.DeepEqual(epAddrs, clusterEndpoints) {
-		return true
+		return prom.checkPrometheusRuleEqual(cluster) && prom.checkGrafanaDashboardEqual(cluster)
 	}
 	return false
 }
@@ -527,7 +533,10 @@ func (prom *PrometheusMonitor) CleanPrometheusMonitor(cluster *kstonev1alpha2.Et
 	if err := prom.DeleteEtcdService(cluster.Namespace, cluster.Name); err != nil && !apierrors.IsNotFound(err) {
 		return err
 	}
-	return nil
+	if err := prom.DeletePrometheusRule(cluster.Namespace, cluster.Name); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return prom.CleanGrafanaDashboard(cluster)
 }
 
 // SyncPrometheusMonitor syncs prometheus monitor for etcdcluster if it is enabled
@@ -610,6 +619,16 @@ func (prom *PrometheusMonitor) SyncPrometheusMonitor(cluster *kstonev1alpha2.Etc
 		}
 	}
 
+	// 4 init prometheusrule
+	if err = prom.syncPrometheusRule(cluster); err != nil {
+		return err
+	}
+
+	// 5 init grafana dashboard
+	if err = prom.syncGrafanaDashboard(cluster); err != nil {
+		return err
+	}
+
 	klog.V(2).Infof("add etcd task %s succ", taskName)
 	return err
 }
@@ -0,0 +1,302 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	promapiv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	klog "k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	platformscheme "tkestack.io/kstone/pkg/generated/clientset/versioned/scheme"
+)
+
+// The default thresholds of the alerts
+const (
+	DefaultAlertKeyDiff        = 10
+	DefaultAlertRevisionDiff   = 1000
+	DefaultAlertMinBackupFiles = 1
+	DefaultAlertDBUsedPercent  = 80
+	DefaultAlertBackupKeyDiff  = 10000
+)
+
+const (
+	severityCritical = "critical"
+	severityWarning  = "warning"
+)
+
+// AlertThresholds overrides the thresholds of the alerts of a cluster, the
+// zero values are replaced by the defaults
+type AlertThresholds struct {
+	// KeyDiff is the max difference of the number of keys between members.
+	KeyDiff int64 `json:"keyDiff,omitempty"`
+	// RevisionDiff is the max difference of the revisions between members.
+	RevisionDiff int64 `json:"revisionDiff,omitempty"`
+	// MinBackupFiles is the min number of backup files in the last day.
+	MinBackupFiles int64 `json:"minBackupFiles,omitempty"`
+	// DBUsedPercent is the max percentage of the quota used by the db of a member.
+	DBUsedPercent int64 `json:"dbUsedPercent,omitempty"`
+	// BackupKeyDiff is the max difference of the number of keys between the
+	// backup file restored by the backupverify feature and the live cluster.
+	BackupKeyDiff int64 `json:"backupKeyDiff,omitempty"`
+}
+
+// Validate validates the alert thresholds
+func (t *AlertThresholds) Validate() error {
+	if t.KeyDiff < 0 || t.RevisionDiff < 0 || t.MinBackupFiles < 0 || t.BackupKeyDiff < 0 {
+		return fmt.Errorf("thresholds must not be negative")
+	}
+	if t.DBUsedPercent < 0 || t.DBUsedPercent > 100 {
+		return fmt.Errorf("dbUsedPercent must be between 0 and 100")
+	}
+	return nil
+}
+
+// GetAlertThresholds gets the alert thresholds of cluster, the unset
+// thresholds are defaulted
+func GetAlertThresholds(cluster *kstonev1alpha2.EtcdCluster) (*AlertThresholds, error) {
+	t := &AlertThresholds{}
+	if cfg, found := cluster.Annotations[kstonev1alpha2.AnnoAlertThresholds]; found {
+		if err := json.Unmarshal([]byte(cfg), t); err != nil {
+			return nil, err
+		}
+		if err := t.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if t.KeyDiff == 0 {
+		t.KeyDiff = DefaultAlertKeyDiff
+	}
+	if t.RevisionDiff == 0 {
+		t.RevisionDiff = DefaultAlertRevisionDiff
+	}
+	if t.MinBackupFiles == 0 {
+		t.MinBackupFiles = DefaultAlertMinBackupFiles
+	}
+	if t.DBUsedPercent == 0 {
+		t.DBUsedPercent = DefaultAlertDBUsedPercent
+	}
+	if t.BackupKeyDiff == 0 {
+		t.BackupKeyDiff = DefaultAlertBackupKeyDiff
+	}
+	return t, nil
+}
+
+// GetPrometheusRule gets prometheus rule by namespace and name
+func (prom *PrometheusMonitor) GetPrometheusRule(namespace, name string) (*promapiv1.PrometheusRule, error) {
+	rule, err := prom.promCli.PrometheusRules(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("get prometheus rule,namespaces is %s,name is %s,error is %v", namespace, name, err)
+		return nil, err
+	}
+	return rule, err
+}
+
+// CreatePrometheusRule creates prometheus rule
+func (prom *PrometheusMonitor) CreatePrometheusRule(rule *promapiv1.PrometheusRule) (*promapiv1.PrometheusRule, error) {
+	newRule, err := prom.promCli.PrometheusRules(rule.Namespace).Create(context.TODO(), rule, metav1.CreateOptions{})
+	if err != nil {
+		klog.Errorf("create prometheus rule,namespaces is %s,name is %s,error is %v", rule.Namespace, rule.Name, err)
+		return nil, err
+	}
+	return newRule, err
+}
+
+// UpdatePrometheusRule updates prometheus rule
+func (prom *PrometheusMonitor) UpdatePrometheusRule(rule *promapiv1.PrometheusRule) (*promapiv1.PrometheusRule, error) {
+	newRule, err := prom.promCli.PrometheusRules(rule.Namespace).Update(context.TODO(), rule, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf("update prometheus rule,namespaces is %s,name is %s,error is %v", rule.Namespace, rule.Name, err)
+		return nil, err
+	}
+	return newRule, err
+}
+
+// DeletePrometheusRule deletes prometheus rule
+func (prom *PrometheusMonitor) DeletePrometheusRule(namespace, name string) error {
+	err := prom.promCli.PrometheusRules(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+	if err != nil {
+		klog.Errorf("delete prometheus rule,namespaces is %s,name is %s,error is %v", namespace, name, err)
+	}
+	return err
+}
+
+// PrometheusRuleIsEquivalent compares old prometheus rule with new prometheus rule
+func (prom *PrometheusMonitor) PrometheusRuleIsEquivalent(old, new *promapiv1.PrometheusRule) bool {
+	if !reflect.DeepEqual(old.Labels, new.Labels) {
+		return false
+	}
+	if !reflect.DeepEqual(old.Spec, new.Spec) {
+		return false
+	}
+	return true
+}
+
+// initEtcdPrometheusRule inits the alerting rules of cluster
+func (prom *PrometheusMonitor) initEtcdPrometheusRule(cluster *kstonev1alpha2.EtcdCluster) (
+	*promapiv1.PrometheusRule,
+	error) {
+	t, err := GetAlertThresholds(cluster)
+	if err != nil {
+		klog.Errorf("invalid alert thresholds, cluster is %s, err is %v", cluster.Name, err)
+		return nil, err
+	}
+
+	name := cluster.Name
+	alert := func(alert, severity, expr, duration, summary string) promapiv1.Rule {
+		return promapiv1.Rule{
+			Alert: alert,
+			Expr:  intstr.FromString(expr),
+			For:   duration,
+			Labels: map[string]string{
+				"severity":    severity,
+				"clusterName": name,
+			},
+			Annotations: map[string]string{
+				"summary": summary,
+			},
+		}
+	}
+	rules := []promapiv1.Rule{
+		alert(
+			"EtcdNoLeader",
+			severityCritical,
+			fmt.Sprintf(`etcd_server_has_leader{etcdName="%s"} == 0`, name),
+			"1m",
+			fmt.Sprintf("member {{ $labels.endpoint }} of etcd %s has no leader", name),
+		),
+		alert(
+			"EtcdMemberUnhealthy",
+			severityCritical,
+			fmt.Sprintf(`kstone_inspection_etcd_endpoint_healthy{clusterName="%s"} == 0`, name),
+			"5m",
+			fmt.Sprintf("member {{ $labels.endpoint }} of etcd %s is unhealthy", name),
+		),
+		alert(
+			"EtcdKeyDiff",
+			severityWarning,
+			fmt.Sprintf(`kstone_inspection_etcd_node_diff_total{clusterName="%s"} > %d`, name, t.KeyDiff),
+			"10m",
+			fmt.Sprintf("the numbers of keys of the members of etcd %s differ by {{ $value }}", name),
+		),
+		alert(
+			"EtcdRevisionDiff",
+			severityWarning,
+			fmt.Sprintf(`kstone_inspection_etcd_node_revision_diff_total{clusterName="%s"} > %d`, name, t.RevisionDiff),
+			"10m",
+			fmt.Sprintf("the revisions of the members of etcd %s differ by {{ $value }}", name),
+		),
+		alert(
+			"EtcdBackupMissing",
+			severityWarning,
+			fmt.Sprintf(`kstone_inspection_etcd_backup_files{clusterName="%s"} < %d`, name, t.MinBackupFiles),
+			"1h",
+			fmt.Sprintf("etcd %s has {{ $value }} backup files in the last day", name),
+		),
+		alert(
+			"EtcdDBNearQuota",
+			severityWarning,
+			fmt.Sprintf(`kstone_inspection_etcd_db_size_used_percent{clusterName="%s"} > %d`, name, t.DBUsedPercent),
+			"10m",
+			fmt.Sprintf("the db of member {{ $labels.endpoint }} of etcd %s uses {{ $value }}%% of the quota", name),
+		),
+		alert(
+			"EtcdBackupKeyDiff",
+			severityWarning,
+			fmt.Sprintf(`abs(kstone_inspection_etcd_backup_verify_key_diff{clusterName="%s"}) > %d`, name, t.BackupKeyDiff),
+			"1h",
+			fmt.Sprintf("the number of keys of the backup of etcd %s differs from the cluster by {{ $value }}", name),
+		),
+	}
+
+	labels := make(map[string]string, len(cluster.Labels)+1)
+	for k, v := range cluster.Labels {
+		labels[k] = v
+	}
+	labels["etcdName"] = cluster.Name
+	rule := &promapiv1.PrometheusRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cluster.Name,
+			Namespace: cluster.GetNamespace(),
+			Labels:    labels,
+		},
+		Spec: promapiv1.PrometheusRuleSpec{
+			Groups: []promapiv1.RuleGroup{
+				{
+					Name:  "kstone-" + cluster.Name,
+					Rules: rules,
+				},
+			},
+		},
+	}
+
+	err = controllerutil.SetOwnerReference(cluster, rule, platformscheme.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// checkPrometheusRuleEqual checks whether the prometheus rule of cluster is up to date
+func (prom *PrometheusMonitor) checkPrometheusRuleEqual(cluster *kstonev1alpha2.EtcdCluster) bool {
+	newRule, err := prom.initEtcdPrometheusRule(cluster)
+	if err != nil {
+		return true
+	}
+	curRule, err := prom.GetPrometheusRule(cluster.GetNamespace(), cluster.Name)
+	if err != nil {
+		return false
+	}
+	return prom.PrometheusRuleIsEquivalent(curRule, newRule)
+}
+
+// syncPrometheusRule creates or updates the prometheus rule of cluster
+func (prom *PrometheusMonitor) syncPrometheusRule(cluster *kstonev1alpha2.EtcdCluster) error {
+	newRule, err := prom.initEtcdPrometheusRule(cluster)
+	if err != nil {
+		return err
+	}
+	curRule, err := prom.GetPrometheusRule(cluster.GetNamespace(), cluster.Name)
+	if apierrors.IsNotFound(err) {
+		_, err = prom.CreatePrometheusRule(newRule)
+		if err != nil {
+			klog.Errorf("create etcd %s prometheus rule failed:%v", cluster.Name, err)
+		}
+		return err
+	} else if err != nil {
+		klog.Errorf("get etcd %s prometheus rule failed:%v", cluster.Name, err)
+		return err
+	} else if !prom.PrometheusRuleIsEquivalent(curRule, newRule) {
+		newRule.ResourceVersion = curRule.ResourceVersion
+		_, err = prom.UpdatePrometheusRule(newRule)
+		if err != nil {
+			klog.Errorf("failed to update etcd %s prometheus rule,err is %v", cluster.Name, err)
+		}
+		return err
+	}
+	return nil
+}
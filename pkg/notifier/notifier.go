@@ -0,0 +1,368 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	klog "k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	DefaultDedupInterval = time.Hour
+	DefaultSendTimeout   = 10 * time.Second
+)
+
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+type Reason string
+
+const (
+	ReasonPhaseChanged     Reason = "PhaseChanged"
+	ReasonInspectionFailed Reason = "InspectionFailed"
+	ReasonAlarm            Reason = "Alarm"
+	ReasonBackupMissing    Reason = "BackupMissing"
+)
+
+// Event is a notification about an EtcdCluster
+type Event struct {
+	Namespace string   `json:"namespace"`
+	Cluster   string   `json:"cluster"`
+	Reason    Reason   `json:"reason"`
+	Severity  Severity `json:"severity"`
+	// Subject distinguishes the events of the same reason, e.g. the new phase,
+	// the inspection type or the alarmed member, it is a part of the dedup key.
+	Subject string    `json:"subject,omitempty"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// Title returns the one line summary of the event
+func (e *Event) Title() string {
+	return fmt.Sprintf("[%s] etcd %s/%s: %s", e.Severity, e.Namespace, e.Cluster, e.Reason)
+}
+
+// Text returns the detail of the event
+func (e *Event) Text() string {
+	return fmt.Sprintf("%s\n%s\ntime: %s", e.Title(), e.Message, e.Time.Format(time.RFC3339))
+}
+
+func (e *Event) key() string {
+	return strings.Join([]string{e.Namespace, e.Cluster, string(e.Reason), e.Subject}, "/")
+}
+
+// Config is the configuration of the notifier
+type Config struct {
+	Sinks []SinkConfig `json:"sinks"`
+	// DedupIntervalInSecond suppresses the repeated events with the same
+	// cluster, reason and subject in the interval, default 1h.
+	DedupIntervalInSecond int64     `json:"dedupIntervalInSecond,omitempty"`
+	Silences              []Silence `json:"silences,omitempty"`
+}
+
+// SinkConfig is the configuration of a sink
+type SinkConfig struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	URL  string `json:"url,omitempty"`
+	// Headers are added to the requests of the webhook sink.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Secret signs the requests of the dingtalk sink.
+	Secret string `json:"secret,omitempty"`
+	// Severities filters the events sent to the sink, all events are sent if it is empty.
+	Severities []Severity  `json:"severities,omitempty"`
+	SMTP       *SMTPConfig `json:"smtp,omitempty"`
+}
+
+// SMTPConfig is the configuration of the email sink
+type SMTPConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// Silence suppresses the matched events between StartTime and EndTime
+type Silence struct {
+	// Namespace matches all namespaces if it is empty.
+	Namespace string `json:"namespace,omitempty"`
+	// Clusters matches all clusters if it is empty.
+	Clusters []string `json:"clusters,omitempty"`
+	// Reasons matches all reasons if it is empty.
+	Reasons   []Reason  `json:"reasons,omitempty"`
+	StartTime time.Time `json:"startTime,omitempty"`
+	EndTime   time.Time `json:"endTime"`
+}
+
+func (s *Silence) matches(event *Event) bool {
+	if !s.StartTime.IsZero() && event.Time.Before(s.StartTime) {
+		return false
+	}
+	if !event.Time.Before(s.EndTime) {
+		return false
+	}
+	if s.Namespace != "" && s.Namespace != event.Namespace {
+		return false
+	}
+	if len(s.Clusters) > 0 && !containsString(s.Clusters, event.Cluster) {
+		return false
+	}
+	if len(s.Reasons) > 0 {
+		found := false
+		for _, r := range s.Reasons {
+			if r == event.Reason {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate validates the configuration and builds the sinks
+func (cfg *Config) Validate() error {
+	_, err := cfg.buildSinks()
+	return err
+}
+
+type namedSink struct {
+	Sink
+	cfg *SinkConfig
+}
+
+func (s *namedSink) accepts(event *Event) bool {
+	if len(s.cfg.Severities) == 0 {
+		return true
+	}
+	for _, severity := range s.cfg.Severities {
+		if severity == event.Severity {
+			return true
+		}
+	}
+	return false
+}
+
+func (cfg *Config) buildSinks() ([]*namedSink, error) {
+	if cfg.DedupIntervalInSecond < 0 {
+		return nil, fmt.Errorf("dedupIntervalInSecond must not be negative")
+	}
+	for i, s := range cfg.Silences {
+		if s.EndTime.IsZero() {
+			return nil, fmt.Errorf("silence %d has no endTime", i)
+		}
+	}
+	sinks := make([]*namedSink, 0, len(cfg.Sinks))
+	names := make(map[string]bool)
+	for i := range cfg.Sinks {
+		sinkCfg := &cfg.Sinks[i]
+		if sinkCfg.Name == "" {
+			return nil, fmt.Errorf("sink %d has no name", i)
+		}
+		if names[sinkCfg.Name] {
+			return nil, fmt.Errorf("sink %s is duplicated", sinkCfg.Name)
+		}
+		names[sinkCfg.Name] = true
+		sink, err := GetSinkProvider(sinkCfg.Type, sinkCfg)
+		if err != nil {
+			return nil, fmt.Errorf("sink %s: %v", sinkCfg.Name, err)
+		}
+		sinks = append(sinks, &namedSink{Sink: sink, cfg: sinkCfg})
+	}
+	return sinks, nil
+}
+
+// Notifier sends the events to the configured sinks, the repeated events are
+// deduplicated and the silenced events are dropped
+type Notifier struct {
+	path    string
+	modTime time.Time
+
+	mutex sync.Mutex
+	cfg   *Config
+	sinks []*namedSink
+	// sent records the last time an event was sent by the dedup key
+	sent map[string]time.Time
+}
+
+var defaultNotifier *Notifier
+
+// Setup loads the configuration file of the default notifier, the
+// notifications are disabled if path is empty
+func Setup(path string) error {
+	if path == "" {
+		klog.Info("notifier config is not specified, notifications are disabled")
+		return nil
+	}
+	n := &Notifier{
+		path: path,
+		sent: make(map[string]time.Time),
+	}
+	if err := n.load(); err != nil {
+		return err
+	}
+	defaultNotifier = n
+	return nil
+}
+
+// Notify sends the event through the default notifier
+func Notify(event *Event) {
+	if defaultNotifier == nil {
+		return
+	}
+	defaultNotifier.Notify(event)
+}
+
+// load loads the configuration file if it is changed
+func (n *Notifier) load() error {
+	info, err := os.Stat(n.path)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(n.modTime) {
+		return nil
+	}
+	// a broken file is not retried until it is changed again
+	n.modTime = info.ModTime()
+	data, err := ioutil.ReadFile(n.path)
+	if err != nil {
+		return err
+	}
+	cfg := &Config{}
+	if err = yaml.Unmarshal(data, cfg); err != nil {
+		return err
+	}
+	sinks, err := cfg.buildSinks()
+	if err != nil {
+		return err
+	}
+
+	n.cfg, n.sinks = cfg, sinks
+	klog.Infof("load notifier config %s, %d sinks, %d silences", n.path, len(sinks), len(cfg.Silences))
+	return nil
+}
+
+// Notify sends the event to the sinks asynchronously
+func (n *Notifier) Notify(event *Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	// the configuration is mounted from a secret, reload it to pick up the
+	// changes of the silences, the last valid configuration is kept on errors
+	if err := n.load(); err != nil {
+		klog.Errorf("failed to reload notifier config %s, err is %v", n.path, err)
+	}
+
+	for i := range n.cfg.Silences {
+		if n.cfg.Silences[i].matches(event) {
+			klog.V(4).Infof("event %s is silenced", event.key())
+			return
+		}
+	}
+
+	interval := DefaultDedupInterval
+	if n.cfg.DedupIntervalInSecond > 0 {
+		interval = time.Duration(n.cfg.DedupIntervalInSecond) * time.Second
+	}
+	for key, t := range n.sent {
+		if event.Time.Sub(t) >= interval {
+			delete(n.sent, key)
+		}
+	}
+	key := event.key()
+	if _, found := n.sent[key]; found {
+		klog.V(4).Infof("event %s is deduplicated", key)
+		return
+	}
+
+	var sinks []*namedSink
+	for _, sink := range n.sinks {
+		if sink.accepts(event) {
+			sinks = append(sinks, sink)
+		}
+	}
+	if len(sinks) == 0 {
+		return
+	}
+	// the event is recorded before it is sent so that the repeated events are
+	// not sent concurrently, the record is dropped if no sink received it
+	n.sent[key] = event.Time
+	go n.send(key, event, sinks)
+}
+
+// send sends the event to the sinks concurrently, the event is sent again by
+// the next notification if it failed to be sent to all the sinks
+func (n *Notifier) send(key string, event *Event, sinks []*namedSink) {
+	var wg sync.WaitGroup
+	var sent int32
+	for _, sink := range sinks {
+		wg.Add(1)
+		go func(sink *namedSink) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), DefaultSendTimeout)
+			defer cancel()
+			if err := sink.Send(ctx, event); err != nil {
+				klog.Errorf("failed to send event %s to sink %s, err is %v", key, sink.cfg.Name, err)
+				return
+			}
+			atomic.AddInt32(&sent, 1)
+		}(sink)
+	}
+	wg.Wait()
+	if atomic.LoadInt32(&sent) > 0 {
+		return
+	}
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if t, found := n.sent[key]; found && t.Equal(event.Time) {
+		delete(n.sent, key)
+	}
+}
@@ -0,0 +1,131 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeSink reports each sent event on the channel
+type fakeSink struct {
+	err  error
+	sent chan *Event
+}
+
+func (s *fakeSink) Send(ctx context.Context, event *Event) error {
+	s.sent <- event
+	return s.err
+}
+
+func newTestNotifier(t *testing.T, errs ...error) (*Notifier, []*fakeSink) {
+	path := filepath.Join(t.TempDir(), "notifier.yaml")
+	if err := ioutil.WriteFile(path, []byte("sinks: []"), 0600); err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	n := &Notifier{path: path, sent: make(map[string]time.Time)}
+	if err := n.load(); err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	var sinks []*fakeSink
+	for i, err := range errs {
+		sink := &fakeSink{err: err, sent: make(chan *Event, 10)}
+		sinks = append(sinks, sink)
+		n.sinks = append(n.sinks, &namedSink{Sink: sink, cfg: &SinkConfig{Name: fmt.Sprintf("sink-%d", i)}})
+	}
+	return n, sinks
+}
+
+// waitSent waits until the event is sent to all the sinks and the result is recorded
+func waitSent(t *testing.T, n *Notifier, sinks []*fakeSink, key string, recorded bool) {
+	for _, sink := range sinks {
+		select {
+		case <-sink.sent:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected the event to be sent")
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		n.mutex.Lock()
+		_, found := n.sent[key]
+		n.mutex.Unlock()
+		if found == recorded {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the event to be recorded %v, got %v", recorded, found)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNotifyDedup(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name string
+		errs []error
+		// resent is true if the repeated event is sent again
+		resent bool
+	}{
+		{
+			name:   "sent to all the sinks",
+			errs:   []error{nil, nil},
+			resent: false,
+		},
+		{
+			name:   "sent to one of the sinks",
+			errs:   []error{failed, nil},
+			resent: false,
+		},
+		{
+			name:   "failed to be sent to all the sinks",
+			errs:   []error{failed, failed},
+			resent: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, sinks := newTestNotifier(t, tt.errs...)
+			event := func() *Event {
+				return &Event{Namespace: "kstone", Cluster: "etcd", Reason: ReasonPhaseChanged, Subject: "Running->UnHealthy"}
+			}
+			n.Notify(event())
+			waitSent(t, n, sinks, event().key(), !tt.resent)
+
+			n.Notify(event())
+			if !tt.resent {
+				for _, sink := range sinks {
+					select {
+					case <-sink.sent:
+						t.Errorf("expected the repeated event to be deduplicated")
+					case <-time.After(100 * time.Millisecond):
+					}
+				}
+				return
+			}
+			waitSent(t, n, sinks, event().key(), false)
+		})
+	}
+}
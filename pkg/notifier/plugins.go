@@ -0,0 +1,65 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	klog "k8s.io/klog/v2"
+)
+
+var (
+	mutex     sync.Mutex
+	Providers = make(map[string]Factory)
+)
+
+// Sink sends the notifications to a destination
+type Sink interface {
+	Send(ctx context.Context, event *Event) error
+}
+
+type Factory func(cfg *SinkConfig) (Sink, error)
+
+// RegisterSinkFactory registers the specified notification sink provider
+func RegisterSinkFactory(name string, factory Factory) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if _, found := Providers[name]; found {
+		klog.V(2).Infof("notification sink provider:%s was registered twice", name)
+	}
+
+	klog.V(2).Infof("register notification sink provider:%s", name)
+	Providers[name] = factory
+}
+
+// GetSinkProvider gets the specified notification sink provider
+func GetSinkProvider(name string, cfg *SinkConfig) (Sink, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	f, found := Providers[name]
+
+	klog.V(1).Infof("get provider name %s,status:%t", name, found)
+	if !found {
+		return nil, fmt.Errorf("notification sink provider %s not found", name)
+	}
+	return f(cfg)
+}
@@ -0,0 +1,91 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package dingtalk
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"tkestack.io/kstone/pkg/notifier"
+)
+
+const (
+	ProviderName = "dingtalk"
+)
+
+// SinkDingTalk posts the events to a DingTalk robot
+type SinkDingTalk struct {
+	url    string
+	secret string
+}
+
+type markdown struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+type message struct {
+	MsgType  string    `json:"msgtype"`
+	Markdown *markdown `json:"markdown"`
+}
+
+func init() {
+	notifier.RegisterSinkFactory(ProviderName, func(cfg *notifier.SinkConfig) (notifier.Sink, error) {
+		return NewDingTalkSink(cfg)
+	})
+}
+
+func NewDingTalkSink(cfg *notifier.SinkConfig) (notifier.Sink, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("url is required")
+	}
+	return &SinkDingTalk{
+		url:    cfg.URL,
+		secret: cfg.Secret,
+	}, nil
+}
+
+// signedURL signs the url with the secret of the robot
+func (s *SinkDingTalk) signedURL(now time.Time) string {
+	if s.secret == "" {
+		return s.url
+	}
+	timestamp := strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10)
+	h := hmac.New(sha256.New, []byte(s.secret))
+	h.Write([]byte(timestamp + "\n" + s.secret))
+	sign := url.QueryEscape(base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	return fmt.Sprintf("%s&timestamp=%s&sign=%s", s.url, timestamp, sign)
+}
+
+func (s *SinkDingTalk) Send(ctx context.Context, event *notifier.Event) error {
+	return notifier.PostJSON(ctx, s.signedURL(time.Now()), nil, &message{
+		MsgType: "markdown",
+		Markdown: &markdown{
+			Title: event.Title(),
+			Text:  fmt.Sprintf("### %s\n\n%s", event.Title(), event.Message),
+		},
+	})
+}
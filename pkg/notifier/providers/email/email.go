@@ -0,0 +1,149 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"tkestack.io/kstone/pkg/notifier"
+)
+
+const (
+	ProviderName = "email"
+)
+
+// SinkEmail sends the events by SMTP
+type SinkEmail struct {
+	cfg *notifier.SMTPConfig
+}
+
+func init() {
+	notifier.RegisterSinkFactory(ProviderName, func(cfg *notifier.SinkConfig) (notifier.Sink, error) {
+		return NewEmailSink(cfg)
+	})
+}
+
+func NewEmailSink(cfg *notifier.SinkConfig) (notifier.Sink, error) {
+	if cfg.SMTP == nil {
+		return nil, errors.New("smtp is required")
+	}
+	if cfg.SMTP.Host == "" || cfg.SMTP.Port == 0 || cfg.SMTP.From == "" || len(cfg.SMTP.To) == 0 {
+		return nil, errors.New("smtp host, port, from and to are required")
+	}
+	return &SinkEmail{cfg: cfg.SMTP}, nil
+}
+
+func (s *SinkEmail) Send(ctx context.Context, event *notifier.Event) error {
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+	msg := fmt.Sprintf(
+		"From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		s.cfg.From,
+		strings.Join(s.cfg.To, ", "),
+		event.Title(),
+		strings.ReplaceAll(event.Text(), "\n", "\r\n"),
+	)
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	return s.sendMail(ctx, addr, auth, []byte(msg))
+}
+
+// sendMail sends the message like smtp.SendMail, the connection is closed once
+// the context is done, so a stalled server never blocks the sender
+func (s *SinkEmail) sendMail(ctx context.Context, addr string, auth smtp.Auth, msg []byte) error {
+	dialer := &net.Dialer{Timeout: notifier.DefaultSendTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(notifier.DefaultSendTimeout)
+	}
+	if err = conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	c, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return withContextErr(ctx, err)
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: s.cfg.Host}); err != nil {
+			return withContextErr(ctx, err)
+		}
+	}
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp server doesn't support AUTH")
+		}
+		if err = c.Auth(auth); err != nil {
+			return withContextErr(ctx, err)
+		}
+	}
+	if err = c.Mail(s.cfg.From); err != nil {
+		return withContextErr(ctx, err)
+	}
+	for _, to := range s.cfg.To {
+		if err = c.Rcpt(to); err != nil {
+			return withContextErr(ctx, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return withContextErr(ctx, err)
+	}
+	if _, err = w.Write(msg); err != nil {
+		return withContextErr(ctx, err)
+	}
+	if err = w.Close(); err != nil {
+		return withContextErr(ctx, err)
+	}
+	return withContextErr(ctx, c.Quit())
+}
+
+// withContextErr returns the error of the context if it is done, which is
+// the cause of the error of the closed connection
+func withContextErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package email
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"tkestack.io/kstone/pkg/notifier"
+)
+
+// serveSMTP serves a single connection of the smtp client, and returns the
+// received message
+func serveSMTP(conn net.Conn) string {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return ""
+		}
+		switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+		case "EHLO", "HELO", "MAIL", "RCPT":
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return ""
+			}
+			tp.PrintfLine("250 OK")
+			if line, err = tp.ReadLine(); err == nil && strings.ToUpper(line) == "QUIT" {
+				tp.PrintfLine("221 bye")
+			}
+			return string(data)
+		default:
+			tp.PrintfLine("502 unsupported")
+		}
+	}
+}
+
+func newTestSink(t *testing.T, addr string) notifier.Sink {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &notifier.SMTPConfig{Host: host, Port: portNum, From: "kstone@example.com", To: []string{"ops@example.com"}}
+	sink, err := NewEmailSink(&notifier.SinkConfig{SMTP: cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sink
+}
+
+var testEvent = &notifier.Event{
+	Namespace: "kstone",
+	Cluster:   "etcd",
+	Reason:    "ClusterUnhealthy",
+	Severity:  "critical",
+	Message:   "member etcd-0 is unhealthy",
+	Time:      time.Unix(0, 0),
+}
+
+func TestSend(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- ""
+			return
+		}
+		received <- serveSMTP(conn)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = newTestSink(t, ln.Addr().String()).Send(ctx, testEvent); err != nil {
+		t.Fatalf("failed to send, err is %v", err)
+	}
+	msg := <-received
+	for _, want := range []string{"Subject: " + testEvent.Title(), "To: ops@example.com", testEvent.Message} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message %q does not contain %q", msg, want)
+		}
+	}
+}
+
+func TestSendStalledServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// the server accepts the connection but never greets
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		time.Sleep(5 * time.Second)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = newTestSink(t, ln.Addr().String()).Send(ctx, testEvent)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Send() err is %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Send() returned after %v, want the context deadline", elapsed)
+	}
+}
@@ -0,0 +1,32 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package providers
+
+import (
+	// import dingtalk provider
+	_ "tkestack.io/kstone/pkg/notifier/providers/dingtalk"
+	// import email provider
+	_ "tkestack.io/kstone/pkg/notifier/providers/email"
+	// import slack provider
+	_ "tkestack.io/kstone/pkg/notifier/providers/slack"
+	// import webhook provider
+	_ "tkestack.io/kstone/pkg/notifier/providers/webhook"
+	// import wecom provider
+	_ "tkestack.io/kstone/pkg/notifier/providers/wecom"
+)
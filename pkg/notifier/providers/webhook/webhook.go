@@ -0,0 +1,56 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package webhook
+
+import (
+	"context"
+	"errors"
+
+	"tkestack.io/kstone/pkg/notifier"
+)
+
+const (
+	ProviderName = "webhook"
+)
+
+// SinkWebhook posts the events as json to a generic webhook
+type SinkWebhook struct {
+	url     string
+	headers map[string]string
+}
+
+func init() {
+	notifier.RegisterSinkFactory(ProviderName, func(cfg *notifier.SinkConfig) (notifier.Sink, error) {
+		return NewWebhookSink(cfg)
+	})
+}
+
+func NewWebhookSink(cfg *notifier.SinkConfig) (notifier.Sink, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("url is required")
+	}
+	return &SinkWebhook{
+		url:     cfg.URL,
+		headers: cfg.Headers,
+	}, nil
+}
+
+func (s *SinkWebhook) Send(ctx context.Context, event *notifier.Event) error {
+	return notifier.PostJSON(ctx, s.url, s.headers, event)
+}
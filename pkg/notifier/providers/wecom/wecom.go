@@ -0,0 +1,67 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package wecom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"tkestack.io/kstone/pkg/notifier"
+)
+
+const (
+	ProviderName = "wecom"
+)
+
+// SinkWeCom posts the events to a WeCom group robot
+type SinkWeCom struct {
+	url string
+}
+
+type markdown struct {
+	Content string `json:"content"`
+}
+
+type message struct {
+	MsgType  string    `json:"msgtype"`
+	Markdown *markdown `json:"markdown"`
+}
+
+func init() {
+	notifier.RegisterSinkFactory(ProviderName, func(cfg *notifier.SinkConfig) (notifier.Sink, error) {
+		return NewWeComSink(cfg)
+	})
+}
+
+func NewWeComSink(cfg *notifier.SinkConfig) (notifier.Sink, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("url is required")
+	}
+	return &SinkWeCom{url: cfg.URL}, nil
+}
+
+func (s *SinkWeCom) Send(ctx context.Context, event *notifier.Event) error {
+	return notifier.PostJSON(ctx, s.url, nil, &message{
+		MsgType: "markdown",
+		Markdown: &markdown{
+			Content: fmt.Sprintf("**%s**\n%s", event.Title(), event.Message),
+		},
+	})
+}
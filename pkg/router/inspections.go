@@ -0,0 +1,97 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package router
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klog "k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/controllers/util"
+	clientset "tkestack.io/kstone/pkg/generated/clientset/versioned"
+)
+
+// InspectionResult is the latest result and the recent runs of an inspection
+type InspectionResult struct {
+	Type            string                                `json:"type"`
+	Reason          string                                `json:"reason,omitempty"`
+	Message         string                                `json:"message,omitempty"`
+	LastUpdatedTime metav1.Time                           `json:"lastUpdatedTime,omitempty"`
+	Result          *kstonev1alpha2.EtcdInspectionResult  `json:"result,omitempty"`
+	Records         []kstonev1alpha2.EtcdInspectionRecord `json:"records,omitempty"`
+}
+
+// InspectionList returns the results of the inspections of the cluster,
+// the inspections can be filtered by the type query
+func InspectionList(ctx *gin.Context) {
+	etcdName := ctx.Param("name")
+	inspectionType := ctx.Query("type")
+
+	clusterClient, err := clientset.NewForConfig(util.NewSimpleClientBuilder("").ConfigOrDie())
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+
+	cluster, err := clusterClient.KstoneV1alpha2().EtcdClusters(WorkNamespace).
+		Get(context.TODO(), etcdName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+
+	inspections, err := clusterClient.KstoneV1alpha2().EtcdInspections(WorkNamespace).
+		List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+
+	results := make([]InspectionResult, 0)
+	for _, inspection := range inspections.Items {
+		if inspection.Spec.ClusterName != cluster.Name {
+			continue
+		}
+		if inspectionType != "" && inspection.Spec.InspectionType != inspectionType {
+			continue
+		}
+		results = append(results, InspectionResult{
+			Type:            inspection.Spec.InspectionType,
+			Reason:          inspection.Status.Reason,
+			Message:         inspection.Status.Message,
+			LastUpdatedTime: inspection.Status.LastUpdatedTime,
+			Result:          inspection.Status.Result,
+			Records:         inspection.Status.Records,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Type < results[j].Type })
+
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"code": 0,
+		"data": results,
+	})
+}
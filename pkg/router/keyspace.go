@@ -0,0 +1,82 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package router
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klog "k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/controllers/util"
+	featureutil "tkestack.io/kstone/pkg/featureprovider/util"
+	clientset "tkestack.io/kstone/pkg/generated/clientset/versioned"
+)
+
+// KeyspaceAnalysis returns the largest keys and prefixes of the cluster
+// analyzed by the last keyspace inspection
+func KeyspaceAnalysis(ctx *gin.Context) {
+	etcdName := ctx.Param("name")
+
+	clusterClient, err := clientset.NewForConfig(util.NewSimpleClientBuilder("").ConfigOrDie())
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+
+	cluster, err := clusterClient.KstoneV1alpha2().EtcdClusters(WorkNamespace).
+		Get(context.TODO(), etcdName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+	if !featureutil.IsFeatureGateEnabled(cluster.Annotations, kstonev1alpha2.KStoneFeatureKeyspace) {
+		ctx.JSON(http.StatusBadRequest, map[string]interface{}{
+			"code": 1,
+			"err":  "keyspace feature is not enabled",
+		})
+		return
+	}
+
+	name := cluster.Name + "-" + string(kstonev1alpha2.KStoneFeatureKeyspace)
+	inspection, err := clusterClient.KstoneV1alpha2().EtcdInspections(WorkNamespace).
+		Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+	if err != nil || inspection.Status.Keyspace == nil {
+		ctx.JSON(http.StatusNotFound, map[string]interface{}{
+			"code": 1,
+			"err":  "keyspace is not analyzed yet",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"code": 0,
+		"data": inspection.Status.Keyspace,
+	})
+}
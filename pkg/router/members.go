@@ -0,0 +1,362 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	klog "k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/clusterprovider"
+	"tkestack.io/kstone/pkg/controllers/util"
+	"tkestack.io/kstone/pkg/etcd"
+	clientset "tkestack.io/kstone/pkg/generated/clientset/versioned"
+	platformscheme "tkestack.io/kstone/pkg/generated/clientset/versioned/scheme"
+)
+
+// The reasons of the events of the member operations
+const (
+	reasonMemberAdded    = "MemberAdded"
+	reasonMemberPromoted = "MemberPromoted"
+	reasonMemberRemoved  = "MemberRemoved"
+	reasonLeaderMoved    = "LeaderMoved"
+)
+
+var (
+	recorderOnce sync.Once
+	recorder     record.EventRecorder
+)
+
+// MemberAddRequest is the request to add a member
+type MemberAddRequest struct {
+	PeerURLs []string `json:"peerURLs"`
+	// IsLearner is true by default, a learner does not vote until it is promoted
+	IsLearner *bool `json:"isLearner,omitempty"`
+}
+
+// memberOperation is the context of an operation of the members of a cluster
+type memberOperation struct {
+	cluster  *kstonev1alpha2.EtcdCluster
+	config   *etcd.ClientConfig
+	cli      *clientv3.Client
+	members  []etcd.MemberHealth
+	recorder record.EventRecorder
+	// extClientURLs maps the client urls of the members to the urls accessible by kstone
+	extClientURLs map[string]string
+}
+
+// getEventRecorder returns the recorder of the events of etcdclusters
+func getEventRecorder(kubeCli kubernetes.Interface) record.EventRecorder {
+	recorderOnce.Do(func() {
+		utilruntime.Must(platformscheme.AddToScheme(scheme.Scheme))
+		eventBroadcaster := record.NewBroadcaster()
+		eventBroadcaster.StartStructuredLogging(0)
+		eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeCli.CoreV1().Events("")})
+		recorder = eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: util.ComponentKstoneAPI})
+	})
+	return recorder
+}
+
+// newMemberOperation connects to the cluster and checks the health of the
+// members, the error response is written if it failed
+func newMemberOperation(ctx *gin.Context, mutating bool) (*memberOperation, bool) {
+	etcdName := ctx.Param("name")
+
+	clientBuilder := util.NewSimpleClientBuilder("")
+	clusterClient, err := clientset.NewForConfig(clientBuilder.ConfigOrDie())
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	cluster, err := clusterClient.KstoneV1alpha2().EtcdClusters(WorkNamespace).
+		Get(context.TODO(), etcdName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if mutating && cluster.Spec.ClusterType != kstonev1alpha2.EtcdClusterImported {
+		ctx.JSON(http.StatusBadRequest, map[string]interface{}{
+			"code": 1,
+			"err":  fmt.Sprintf("the members of %s cluster are managed by its operator", cluster.Spec.ClusterType),
+		})
+		return nil, false
+	}
+
+	extClientURLs, err := kstonev1alpha2.ParseExtClientURLs(cluster.Annotations[kstonev1alpha2.AnnoExtClientURL])
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	clientConfigGetter := etcd.NewClientConfigSecretGetter(clientBuilder)
+	path := fmt.Sprintf("%s/%s", cluster.Namespace, cluster.Name)
+	config, err := clientConfigGetter.New(path, cluster.Annotations[kstonev1alpha2.AnnoClientCertSecret])
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return nil, false
+	}
+	config.Endpoints = clusterprovider.GetStorageMemberEndpoints(cluster)
+	if len(config.Endpoints) == 0 {
+		config.Endpoints = []string{cluster.Annotations[kstonev1alpha2.AnnoImportedAddr]}
+	}
+
+	cli, err := etcd.NewClientv3(config)
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	op := &memberOperation{
+		cluster:       cluster,
+		config:        config,
+		cli:           cli,
+		recorder:      getEventRecorder(clientBuilder.ClientOrDie()),
+		extClientURLs: extClientURLs,
+	}
+	op.members, err = etcd.GetMembersHealth(cli, op.clientURL)
+	if err != nil {
+		op.close()
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return nil, false
+	}
+	return op, true
+}
+
+// clientURL maps the client url of a member to the url accessible by kstone
+func (op *memberOperation) clientURL(clientURL string) string {
+	return clusterprovider.ExtensionClientURL(clientURL, op.extClientURLs)
+}
+
+// close closes the etcd client
+func (op *memberOperation) close() {
+	_ = op.cli.Close()
+}
+
+// reject responds that the operation is rejected by the safety check
+func (op *memberOperation) reject(ctx *gin.Context, reason string, err error) {
+	op.recorder.Eventf(op.cluster, corev1.EventTypeWarning, reason, "rejected by the safety check, %v", err)
+	ctx.JSON(http.StatusConflict, map[string]interface{}{
+		"code": 1,
+		"err":  err.Error(),
+	})
+}
+
+// fail responds that the operation failed
+func (op *memberOperation) fail(ctx *gin.Context, reason string, err error) {
+	op.recorder.Eventf(op.cluster, corev1.EventTypeWarning, reason, "failed, err is %v", err)
+	ctx.JSON(http.StatusInternalServerError, map[string]interface{}{
+		"code": 1,
+		"err":  err.Error(),
+	})
+}
+
+// succeed responds that the operation succeeded
+func (op *memberOperation) succeed(ctx *gin.Context, reason string, message string, data interface{}) {
+	op.recorder.Event(op.cluster, corev1.EventTypeNormal, reason, message)
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// parseMemberID parses the member id in the path, the error response is written if it is invalid
+func parseMemberID(ctx *gin.Context) (string, uint64, bool) {
+	memberID := ctx.Param("memberID")
+	id, err := strconv.ParseUint(memberID, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, map[string]interface{}{
+			"code": 1,
+			"err":  fmt.Sprintf("invalid member id %s", memberID),
+		})
+		return "", 0, false
+	}
+	return memberID, id, true
+}
+
+// MemberList returns the members of the cluster and their health
+func MemberList(ctx *gin.Context) {
+	op, ok := newMemberOperation(ctx, false)
+	if !ok {
+		return
+	}
+	defer op.close()
+
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"code": 0,
+		"data": op.members,
+	})
+}
+
+// MemberAdd adds a learner or a voting member to the imported cluster
+func MemberAdd(ctx *gin.Context) {
+	request := &MemberAddRequest{}
+	if err := ctx.BindJSON(request); err != nil {
+		klog.Errorf(err.Error())
+		return
+	}
+	if len(request.PeerURLs) == 0 {
+		ctx.JSON(http.StatusBadRequest, map[string]interface{}{
+			"code": 1,
+			"err":  "peerURLs is required",
+		})
+		return
+	}
+	for _, peerURL := range request.PeerURLs {
+		u, err := url.Parse(peerURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			ctx.JSON(http.StatusBadRequest, map[string]interface{}{
+				"code": 1,
+				"err":  fmt.Sprintf("invalid peer url %s", peerURL),
+			})
+			return
+		}
+	}
+	isLearner := request.IsLearner == nil || *request.IsLearner
+
+	op, ok := newMemberOperation(ctx, true)
+	if !ok {
+		return
+	}
+	defer op.close()
+
+	if err := etcd.CheckMemberAdd(op.members, isLearner); err != nil {
+		op.reject(ctx, reasonMemberAdded, err)
+		return
+	}
+	rsp, err := etcd.MemberAdd(op.cli, request.PeerURLs, isLearner)
+	if err != nil {
+		op.fail(ctx, reasonMemberAdded, err)
+		return
+	}
+
+	member := etcd.MemberHealth{
+		ID:        strconv.FormatUint(rsp.Member.ID, 10),
+		PeerURLs:  rsp.Member.PeerURLs,
+		IsLearner: rsp.Member.IsLearner,
+	}
+	op.succeed(
+		ctx,
+		reasonMemberAdded,
+		fmt.Sprintf("member %s is added with peer urls %v, learner %t", member.ID, member.PeerURLs, member.IsLearner),
+		member,
+	)
+}
+
+// MemberPromote promotes the learner of the imported cluster to a voting member
+func MemberPromote(ctx *gin.Context) {
+	memberID, id, ok := parseMemberID(ctx)
+	if !ok {
+		return
+	}
+	op, ok := newMemberOperation(ctx, true)
+	if !ok {
+		return
+	}
+	defer op.close()
+
+	if err := etcd.CheckMemberPromote(op.members, memberID); err != nil {
+		op.reject(ctx, reasonMemberPromoted, err)
+		return
+	}
+	if err := etcd.MemberPromote(op.cli, id); err != nil {
+		op.fail(ctx, reasonMemberPromoted, err)
+		return
+	}
+	op.succeed(ctx, reasonMemberPromoted, fmt.Sprintf("learner %s is promoted", memberID), nil)
+}
+
+// MemberRemove removes the member of the imported cluster
+func MemberRemove(ctx *gin.Context) {
+	memberID, id, ok := parseMemberID(ctx)
+	if !ok {
+		return
+	}
+	op, ok := newMemberOperation(ctx, true)
+	if !ok {
+		return
+	}
+	defer op.close()
+
+	if err := etcd.CheckMemberRemove(op.members, memberID); err != nil {
+		op.reject(ctx, reasonMemberRemoved, err)
+		return
+	}
+	if err := etcd.MemberRemove(op.cli, id); err != nil {
+		op.fail(ctx, reasonMemberRemoved, err)
+		return
+	}
+	op.succeed(ctx, reasonMemberRemoved, fmt.Sprintf("member %s is removed", memberID), nil)
+}
+
+// MemberMoveLeader transfers the leadership to the member
+func MemberMoveLeader(ctx *gin.Context) {
+	memberID, id, ok := parseMemberID(ctx)
+	if !ok {
+		return
+	}
+	op, ok := newMemberOperation(ctx, false)
+	if !ok {
+		return
+	}
+	defer op.close()
+
+	leader, err := etcd.CheckMoveLeader(op.members, memberID)
+	if err != nil {
+		op.reject(ctx, reasonLeaderMoved, err)
+		return
+	}
+
+	// the leadership can only be transferred by the leader
+	config := *op.config
+	config.Endpoints = []string{op.clientURL(leader.ClientURLs[0])}
+	cli, err := etcd.NewClientv3(&config)
+	if err != nil {
+		op.fail(ctx, reasonLeaderMoved, err)
+		return
+	}
+	defer cli.Close()
+
+	if err = etcd.MoveLeader(cli, id); err != nil {
+		op.fail(ctx, reasonLeaderMoved, err)
+		return
+	}
+	op.succeed(ctx, reasonLeaderMoved, fmt.Sprintf("leadership is moved from %s to %s", leader.ID, memberID), nil)
+}
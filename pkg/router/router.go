@@ -25,21 +25,25 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/clientcmd"
 	klog "k8s.io/klog/v2"
 
 	"tkestack.io/kstone/cmd/kstone-api/config"
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
 	"tkestack.io/kstone/pkg/authentication/request"
 	"tkestack.io/kstone/pkg/backup"
 	"tkestack.io/kstone/pkg/controllers/util"
 	"tkestack.io/kstone/pkg/etcd"
 	"tkestack.io/kstone/pkg/featureprovider"
+	featureutil "tkestack.io/kstone/pkg/featureprovider/util"
 	"tkestack.io/kstone/pkg/middlewares"
 
 	_ "tkestack.io/kstone/pkg/authentication/providers" // import token and authenticator provider
@@ -91,8 +95,28 @@ func NewRouter() *gin.Engine {
 	private.PATCH("/:resource/:name", ReverseProxy())
 	private.DELETE("/:resource/:name", ReverseProxy())
 
+	// the sub-routes of etcdclusters hide /:resource/:name of etcdclusters
+	private.GET("/etcdclusters/:name", ReverseProxyResource("etcdclusters"))
+	private.PUT("/etcdclusters/:name", ReverseProxyResource("etcdclusters"))
+	private.PATCH("/etcdclusters/:name", ReverseProxyResource("etcdclusters"))
+	private.DELETE("/etcdclusters/:name", ReverseProxyResource("etcdclusters"))
+
+	private.GET("/etcdclusters/:name/members", MemberList)
+	private.POST("/etcdclusters/:name/members", MemberAdd)
+	private.DELETE("/etcdclusters/:name/members/:memberID", MemberRemove)
+	private.POST("/etcdclusters/:name/members/:memberID/promote", MemberPromote)
+	private.POST("/etcdclusters/:name/members/:memberID/leader", MemberMoveLeader)
+	private.GET("/etcdclusters/:name/keyspace", KeyspaceAnalysis)
+	private.GET("/etcdclusters/:name/inspections", InspectionList)
+
 	private.GET("/etcd/:etcdName", EtcdKeyList)
 	private.GET("/backup/:etcdName", BackupList)
+	private.GET("/backup/:etcdName/retention", BackupRetention)
+	private.POST("/backup/:etcdName", BackupCreate)
+	private.GET("/backup/:etcdName/ondemand/:backupName", BackupStatus)
+	private.GET("/restore/:etcdName", RestoreList)
+	private.POST("/restore/:etcdName", BackupRestore)
+	private.POST("/restart/:etcdName", ClusterRestart)
 	private.GET("/features", FeatureList)
 
 	private.GET("/users", UserList)
@@ -162,6 +186,16 @@ func ReverseProxy() gin.HandlerFunc {
 	}
 }
 
+// ReverseProxyResource reverses proxy to the resource of kubernetes api, it is
+// used by the resources which have sub-routes
+func ReverseProxyResource(resource string) gin.HandlerFunc {
+	proxy := ReverseProxy()
+	return func(c *gin.Context) {
+		c.Params = append(c.Params, gin.Param{Key: "resource", Value: resource})
+		proxy(c)
+	}
+}
+
 // EtcdKeyList returns etcd key list
 func EtcdKeyList(ctx *gin.Context) {
 	etcdName := ctx.Param("etcdName")
@@ -193,8 +227,8 @@ func EtcdKeyList(ctx *gin.Context) {
 	annotations := cluster.Annotations
 	secretName := ""
 	if annotations != nil {
-		if _, found := annotations["certName"]; found {
-			secretName = annotations["certName"]
+		if _, found := annotations[kstonev1alpha2.AnnoClientCertSecret]; found {
+			secretName = annotations[kstonev1alpha2.AnnoClientCertSecret]
 		}
 	}
 	clientConfigGetter := etcd.NewClientConfigSecretGetter(util.NewSimpleClientBuilder(""))
@@ -291,18 +325,59 @@ func EtcdKeyList(ctx *gin.Context) {
 	}
 }
 
-// BackupList returns backup list
+// BackupList returns backup list, it supports the query parameters prefix,
+// limit, continue and order(asc or desc of the created time)
 func BackupList(ctx *gin.Context) {
 	etcdName := ctx.Param("etcdName")
 
+	listOptions := &backup.ListOptions{
+		Prefix:   ctx.Query("prefix"),
+		Continue: ctx.Query("continue"),
+		Order:    ctx.Query("order"),
+	}
+	if limit := ctx.Query("limit"); limit != "" {
+		var err error
+		if listOptions.Limit, err = strconv.Atoi(limit); err != nil {
+			listOptions.Limit = -1
+		}
+	}
+	if err := backup.ValidateListOptions(listOptions); err != nil {
+		ctx.JSON(http.StatusBadRequest, map[string]interface{}{
+			"code": 1,
+			"err":  err.Error(),
+		})
+		return
+	}
+
+	cluster, _, storage, err := getBackupStorage(etcdName)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+	objects, err := storage.List(cluster)
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+	list, err := backup.PageBackupObjects(objects, listOptions)
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, list)
+}
+
+// getBackupStorage gets the cluster, its backup config and backup storage provider
+func getBackupStorage(etcdName string) (*kstonev1alpha2.EtcdCluster, *backup.Config, backup.Storage, error) {
 	clientBuilder := util.NewSimpleClientBuilder("")
 
 	// generate k8s client
 	clusterClient, err := clientset.NewForConfig(clientBuilder.ConfigOrDie())
 	if err != nil {
 		klog.Errorf(err.Error())
-		ctx.JSON(http.StatusInternalServerError, err)
-		return
+		return nil, nil, nil, err
 	}
 
 	// get cluster
@@ -310,15 +385,14 @@ func BackupList(ctx *gin.Context) {
 		Get(context.TODO(), etcdName, metav1.GetOptions{})
 	if err != nil {
 		klog.Errorf(err.Error())
-		ctx.JSON(http.StatusInternalServerError, err)
-		return
+		return nil, nil, nil, err
 	}
 
 	// get backup config
 	backupConfig, err := backup.GetBackupConfig(cluster)
 	if err != nil {
 		klog.Errorf("failed to get backup config,cluster %s,err is %v", cluster.Name, err)
-		ctx.JSON(http.StatusInternalServerError, err)
+		return nil, nil, nil, err
 	}
 
 	// get specified backup storage provider
@@ -327,16 +401,303 @@ func BackupList(ctx *gin.Context) {
 	})
 	if err != nil {
 		klog.Errorf(err.Error())
+		return nil, nil, nil, err
+	}
+	return cluster, backupConfig, storage, nil
+}
+
+// BackupRetention previews the backup files deleted by the retention policy,
+// the policy of backup config is used unless it is specified by the query
+// parameters hourly, daily, weekly, monthly and minAgeInSecond
+func BackupRetention(ctx *gin.Context) {
+	etcdName := ctx.Param("etcdName")
+
+	cluster, backupConfig, storage, err := getBackupStorage(etcdName)
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, err)
 		return
 	}
-	resp, err := storage.List(cluster)
+
+	policy := backupConfig.Retention
+	if query := ctx.Request.URL.Query(); query.Get("hourly") != "" || query.Get("daily") != "" ||
+		query.Get("weekly") != "" || query.Get("monthly") != "" || query.Get("minAgeInSecond") != "" {
+		policy = &backup.RetentionPolicy{}
+		if err = ctx.BindQuery(policy); err != nil {
+			klog.Errorf(err.Error())
+			return
+		}
+	}
+	if policy == nil {
+		ctx.JSON(http.StatusBadRequest, map[string]interface{}{
+			"code": 1,
+			"err":  "retention policy is not configured",
+		})
+		return
+	}
+	if err = policy.Validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, map[string]interface{}{
+			"code": 1,
+			"err":  err.Error(),
+		})
+		return
+	}
+
+	plan, err := backup.EnforceRetention(storage, cluster, policy, true)
 	if err != nil {
 		klog.Errorf(err.Error())
 		ctx.JSON(http.StatusInternalServerError, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, resp)
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"code": 0,
+		"data": plan,
+	})
+}
+
+// BackupCreate creates an on-demand backup of the cluster, the returned name is
+// the handle to poll the status of the backup
+func BackupCreate(ctx *gin.Context) {
+	etcdName := ctx.Param("etcdName")
+
+	clientBuilder := util.NewSimpleClientBuilder("")
+	clusterClient, err := clientset.NewForConfig(clientBuilder.ConfigOrDie())
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+
+	cluster, err := clusterClient.KstoneV1alpha2().EtcdClusters(WorkNamespace).
+		Get(context.TODO(), etcdName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+	if !featureutil.IsFeatureGateEnabled(cluster.Annotations, kstonev1alpha2.KStoneFeatureBackup) {
+		ctx.JSON(http.StatusBadRequest, map[string]interface{}{
+			"code": 1,
+			"err":  "backup feature is not enabled",
+		})
+		return
+	}
+
+	backupSvr, err := backup.NewBackupServer(clientBuilder)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+	status, err := backupSvr.CreateOnDemandBackup(cluster)
+	if err == backup.ErrOnDemandBackupRunning {
+		ctx.JSON(http.StatusConflict, map[string]interface{}{
+			"code": 1,
+			"err":  err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"code": 1,
+			"err":  err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"code": 0,
+		"data": status,
+	})
+}
+
+// BackupStatus returns the status of the on-demand backup
+func BackupStatus(ctx *gin.Context) {
+	etcdName := ctx.Param("etcdName")
+	backupName := ctx.Param("backupName")
+
+	clientBuilder := util.NewSimpleClientBuilder("")
+	clusterClient, err := clientset.NewForConfig(clientBuilder.ConfigOrDie())
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+
+	cluster, err := clusterClient.KstoneV1alpha2().EtcdClusters(WorkNamespace).
+		Get(context.TODO(), etcdName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+
+	backupSvr, err := backup.NewBackupServer(clientBuilder)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+	eb, err := backupSvr.GetOnDemandBackup(cluster, backupName)
+	if apierrors.IsNotFound(err) {
+		ctx.JSON(http.StatusNotFound, map[string]interface{}{
+			"code": 1,
+			"err":  err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"code": 0,
+		"data": backup.NewOnDemandBackupStatus(eb),
+	})
+}
+
+// BackupRestore creates an EtcdRestore, which restores a backup file into the
+// cluster. The cluster must be empty unless overwrite is set, it is created
+// from the source cluster if it does not exist.
+func BackupRestore(ctx *gin.Context) {
+	etcdName := ctx.Param("etcdName")
+
+	spec := &kstonev1alpha2.EtcdRestoreSpec{}
+	if err := ctx.BindJSON(spec); err != nil {
+		klog.Errorf(err.Error())
+		return
+	}
+	if spec.SourceCluster == "" {
+		spec.SourceCluster = etcdName
+	}
+	spec.TargetCluster = etcdName
+	if err := backup.ValidateRestoreSpec(spec); err != nil {
+		ctx.JSON(http.StatusBadRequest, map[string]interface{}{
+			"code": 1,
+			"err":  err.Error(),
+		})
+		return
+	}
+
+	clientBuilder := util.NewSimpleClientBuilder("")
+	clusterClient, err := clientset.NewForConfig(clientBuilder.ConfigOrDie())
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+
+	restores, err := clusterClient.KstoneV1alpha2().EtcdRestores(WorkNamespace).
+		List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+	for i := range restores.Items {
+		restore := &restores.Items[i]
+		if backup.RestoreTarget(restore) == etcdName && !backup.IsRestoreFinished(restore) {
+			ctx.JSON(http.StatusConflict, map[string]interface{}{
+				"code": 1,
+				"err":  fmt.Sprintf("cluster is being restored by %s", restore.Name),
+			})
+			return
+		}
+	}
+
+	restore := &kstonev1alpha2.EtcdRestore{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: etcdName + "-",
+			Namespace:    WorkNamespace,
+		},
+		Spec: *spec,
+	}
+	restore, err = clusterClient.KstoneV1alpha2().EtcdRestores(WorkNamespace).
+		Create(context.TODO(), restore, metav1.CreateOptions{})
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"code": 0,
+		"data": restore,
+	})
+}
+
+// RestoreList lists the EtcdRestores targeting the cluster
+func RestoreList(ctx *gin.Context) {
+	etcdName := ctx.Param("etcdName")
+
+	clientBuilder := util.NewSimpleClientBuilder("")
+	clusterClient, err := clientset.NewForConfig(clientBuilder.ConfigOrDie())
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+
+	restores, err := clusterClient.KstoneV1alpha2().EtcdRestores(WorkNamespace).
+		List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+	items := make([]kstonev1alpha2.EtcdRestore, 0)
+	for i := range restores.Items {
+		if backup.RestoreTarget(&restores.Items[i]) == etcdName {
+			items = append(items, restores.Items[i])
+		}
+	}
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"code": 0,
+		"data": items,
+	})
+}
+
+// ClusterRestart requests a rolling restart of the members of the cluster
+func ClusterRestart(ctx *gin.Context) {
+	etcdName := ctx.Param("etcdName")
+
+	clientBuilder := util.NewSimpleClientBuilder("")
+	clusterClient, err := clientset.NewForConfig(clientBuilder.ConfigOrDie())
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+
+	cluster, err := clusterClient.KstoneV1alpha2().EtcdClusters(WorkNamespace).
+		Get(context.TODO(), etcdName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+	if cluster.Spec.ClusterType != kstonev1alpha2.EtcdClusterKstone {
+		ctx.JSON(http.StatusBadRequest, map[string]interface{}{
+			"code": 1,
+			"err":  fmt.Sprintf("restart is not supported by %s cluster", cluster.Spec.ClusterType),
+		})
+		return
+	}
+
+	requestedAt := time.Now().Format(time.RFC3339)
+	if cluster.Annotations == nil {
+		cluster.Annotations = make(map[string]string)
+	}
+	cluster.Annotations[kstonev1alpha2.AnnoRestart] = requestedAt
+	_, err = clusterClient.KstoneV1alpha2().EtcdClusters(WorkNamespace).
+		Update(context.TODO(), cluster, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf(err.Error())
+		ctx.JSON(http.StatusInternalServerError, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"code": 0,
+		"data": map[string]string{
+			"requestedAt": requestedAt,
+		},
+	})
 }
 
 // FeatureList returns all features
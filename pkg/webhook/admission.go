@@ -0,0 +1,140 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/klog/v2"
+)
+
+// admitFunc handles the admission request of a resource
+type admitFunc func(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse
+
+// patchOperation is an operation of JSON patch
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// serveValidate handles the AdmissionReview of the validating webhook
+func (s *Server) serveValidate(w http.ResponseWriter, r *http.Request) {
+	serveAdmission(w, r, map[string]admitFunc{
+		"etcdclusters":    validateEtcdCluster,
+		"etcdinspections": validateEtcdInspection,
+		"etcdrestores":    validateEtcdRestore,
+	})
+}
+
+// serveMutate handles the AdmissionReview of the mutating webhook
+func (s *Server) serveMutate(w http.ResponseWriter, r *http.Request) {
+	serveAdmission(w, r, map[string]admitFunc{
+		"etcdclusters": defaultEtcdCluster,
+	})
+}
+
+// serveAdmission decodes the AdmissionReview and dispatches it by resource
+func serveAdmission(w http.ResponseWriter, r *http.Request, admits map[string]admitFunc) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	review := &admissionv1.AdmissionReview{}
+	if err = json.Unmarshal(body, review); err != nil || review.Request == nil {
+		klog.Errorf("failed to decode admission review, err is %v", err)
+		http.Error(w, "invalid admission review", http.StatusBadRequest)
+		return
+	}
+
+	req := review.Request
+	admit, found := admits[req.Resource.Resource]
+	if found {
+		review.Response = admit(req)
+	} else {
+		review.Response = allowed()
+	}
+	review.Response.UID = req.UID
+	review.Request = nil
+
+	data, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// allowed returns the response which admits the request
+func allowed() *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
+
+// denied returns the response which rejects the request with errs
+func denied(req *admissionv1.AdmissionRequest, errs field.ErrorList) *admissionv1.AdmissionResponse {
+	message := fmt.Sprintf("%s %s is invalid: %v", req.Kind.Kind, req.Name, errs.ToAggregate())
+	klog.V(2).Infof("deny %s of %s/%s, %s", req.Operation, req.Namespace, req.Name, message)
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonInvalid,
+			Message: message,
+			Code:    http.StatusUnprocessableEntity,
+		},
+	}
+}
+
+// errored returns the response of the request failed to be handled
+func errored(err error) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonBadRequest,
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		},
+	}
+}
+
+// patched returns the response which admits the request with the JSON patch
+func patched(patches []patchOperation) *admissionv1.AdmissionResponse {
+	resp := allowed()
+	if len(patches) == 0 {
+		return resp
+	}
+	data, err := json.Marshal(patches)
+	if err != nil {
+		return errored(err)
+	}
+	patchType := admissionv1.PatchTypeJSONPatch
+	resp.Patch = data
+	resp.PatchType = &patchType
+	return resp
+}
@@ -0,0 +1,432 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// certValidity is the validity of the serving certificate
+	certValidity = 365 * 24 * time.Hour
+	// certRenewBefore is the time before expiration when the serving certificate is regenerated
+	certRenewBefore = 30 * 24 * time.Hour
+	// caRenewBefore is the time before expiration when the CA is regenerated, the
+	// new CA is published in the caBundle along with the old one long before it
+	// signs the first serving certificate
+	caRenewBefore = 2 * certValidity
+	// certCheckInterval is the interval to check whether the serving certificate needs to be renewed
+	certCheckInterval = time.Hour
+
+	// caCertKey and caPrivateKeyKey are the keys of the CA bundle and the key of the newest CA in the secret
+	caCertKey       = "ca.crt"
+	caPrivateKeyKey = "ca.key"
+)
+
+// ServingCert is the serving certificate of the webhook server and the caBundle to verify it
+type ServingCert struct {
+	CertPEM  []byte
+	KeyPEM   []byte
+	CABundle []byte
+}
+
+// EnsureServingCert gets the serving certificate of the webhook service from
+// the secret. The serving certificate is short-lived and signed by a
+// long-lived self-signed CA, both are generated and saved if they are not
+// found or about to expire. The caBundle contains all the CAs not expired,
+// so it is seldom changed and always trusts the serving certificate.
+func EnsureServingCert(kubeCli kubernetes.Interface, namespace, secretName, serviceName string) (*ServingCert, error) {
+	ctx := context.TODO()
+	secret, err := kubeCli.CoreV1().Secrets(namespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Errorf("failed to get webhook secret %s/%s, err is %v", namespace, secretName, err)
+		return nil, err
+	}
+	var data map[string][]byte
+	if err == nil {
+		data = secret.Data
+	}
+
+	host := fmt.Sprintf("%s.%s.svc", serviceName, namespace)
+	renewed, changed, genErr := renewCerts(data, host, []string{serviceName, fmt.Sprintf("%s.%s", serviceName, namespace)})
+	if genErr != nil {
+		return nil, genErr
+	}
+	if !changed {
+		return newServingCert(data), nil
+	}
+
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: namespace,
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: renewed,
+		}
+		_, err = kubeCli.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			// created by another replica, use its certificate
+			return EnsureServingCert(kubeCli, namespace, secretName, serviceName)
+		}
+	} else {
+		secret.Data = renewed
+		_, err = kubeCli.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{})
+		if apierrors.IsConflict(err) {
+			// renewed by another replica, use its certificate
+			return EnsureServingCert(kubeCli, namespace, secretName, serviceName)
+		}
+	}
+	if err != nil {
+		klog.Errorf("failed to save webhook secret %s/%s, err is %v", namespace, secretName, err)
+		return nil, err
+	}
+	klog.Infof("generate serving certificate for %s", host)
+	return newServingCert(renewed), nil
+}
+
+func newServingCert(data map[string][]byte) *ServingCert {
+	return &ServingCert{
+		CertPEM:  data[corev1.TLSCertKey],
+		KeyPEM:   data[corev1.TLSPrivateKeyKey],
+		CABundle: data[caCertKey],
+	}
+}
+
+// renewCerts regenerates the CA and the serving certificate in data if they
+// are about to expire, changed is false if nothing is regenerated.
+func renewCerts(data map[string][]byte, host string, alternateDNS []string) (map[string][]byte, bool, error) {
+	now := time.Now()
+
+	// the expired CAs are dropped from the bundle, the newest one is the first
+	var cas []*x509.Certificate
+	parsed, _ := certutil.ParseCertsPEM(data[caCertKey])
+	for _, cert := range parsed {
+		if now.Before(cert.NotAfter) {
+			cas = append(cas, cert)
+		}
+	}
+	changed := len(cas) != len(parsed)
+
+	var ca *x509.Certificate
+	var caKey crypto.Signer
+	if key, err := keyutil.ParsePrivateKeyPEM(data[caPrivateKeyKey]); err == nil && len(cas) > 0 {
+		if signer, ok := key.(crypto.Signer); ok && publicKeyEqual(cas[0].PublicKey, signer.Public()) {
+			ca, caKey = cas[0], signer
+		}
+	}
+	signer, signerKey := ca, caKey
+	if ca == nil || now.Add(caRenewBefore).After(ca.NotAfter) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, false, err
+		}
+		cert, err := certutil.NewSelfSignedCACert(certutil.Config{CommonName: fmt.Sprintf("%s-ca@%d", host, now.Unix())}, key)
+		if err != nil {
+			return nil, false, err
+		}
+		// the new CA is not trusted by the apiserver until the caBundle is
+		// published, the old CA keeps signing if it outlives the serving certificate
+		if ca == nil || !now.Add(certValidity).Before(ca.NotAfter) {
+			signer, signerKey = cert, key
+		}
+		ca, caKey = cert, key
+		cas = append([]*x509.Certificate{cert}, cas...)
+		changed = true
+	}
+
+	certPEM, keyPEM := data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey]
+	if !isCertValid(certPEM, cas) {
+		var err error
+		certPEM, keyPEM, err = newSignedCertKey(signer, signerKey, host, alternateDNS)
+		if err != nil {
+			return nil, false, err
+		}
+		changed = true
+	}
+	if !changed {
+		return data, false, nil
+	}
+
+	caPEM, err := certutil.EncodeCertificates(cas...)
+	if err != nil {
+		return nil, false, err
+	}
+	caKeyPEM, err := keyutil.MarshalPrivateKeyToPEM(caKey)
+	if err != nil {
+		return nil, false, err
+	}
+	return map[string][]byte{
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+		caCertKey:               caPEM,
+		caPrivateKeyKey:         caKeyPEM,
+	}, true, nil
+}
+
+// newSignedCertKey generates the serving certificate of the host signed by the CA
+func newSignedCertKey(ca *x509.Certificate, caKey crypto.Signer, host string, alternateDNS []string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     append([]string{host}, alternateDNS...),
+		NotBefore:    now.Add(-time.Hour).UTC(),
+		NotAfter:     now.Add(certValidity).UTC(),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, key.Public(), caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM, err := certutil.EncodeCertificates(cert)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := keyutil.MarshalPrivateKeyToPEM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return certPEM, keyPEM, nil
+}
+
+// publicKeyEqual checks whether the public keys are the same
+func publicKeyEqual(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(b)
+}
+
+// CertManager keeps the serving certificate of the webhook server valid. It
+// renews the certificate before expiration, serves the latest one through
+// GetCertificate and injects the caBundle into the crd and webhook configurations.
+type CertManager struct {
+	kubeCli       kubernetes.Interface
+	extCli        apiextensionsclientset.Interface
+	namespace     string
+	secretName    string
+	serviceName   string
+	crdName       string
+	webhookConfig string
+
+	mu      sync.RWMutex
+	certPEM []byte
+	cert    *tls.Certificate
+}
+
+// NewCertManager generates the certificate manager of the webhook server
+func NewCertManager(
+	kubeCli kubernetes.Interface,
+	extCli apiextensionsclientset.Interface,
+	namespace, secretName, serviceName, crdName, webhookConfig string,
+) *CertManager {
+	return &CertManager{
+		kubeCli:       kubeCli,
+		extCli:        extCli,
+		namespace:     namespace,
+		secretName:    secretName,
+		serviceName:   serviceName,
+		crdName:       crdName,
+		webhookConfig: webhookConfig,
+	}
+}
+
+// Sync ensures the serving certificate is valid, injects the caBundle into
+// the crd and webhook configurations and loads the certificate if it is
+// changed. The caBundle is published before the certificate is served, so the
+// apiserver always trusts the certificate being served.
+func (m *CertManager) Sync() error {
+	servingCert, err := EnsureServingCert(m.kubeCli, m.namespace, m.secretName, m.serviceName)
+	if err != nil {
+		return err
+	}
+
+	if err = InjectConversionCABundle(m.extCli, m.crdName, servingCert.CABundle); err != nil {
+		return err
+	}
+	if err = InjectAdmissionCABundle(m.kubeCli, m.webhookConfig, servingCert.CABundle); err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	changed := !bytes.Equal(m.certPEM, servingCert.CertPEM)
+	m.mu.RUnlock()
+	if !changed {
+		return nil
+	}
+	cert, err := tls.X509KeyPair(servingCert.CertPEM, servingCert.KeyPEM)
+	if err != nil {
+		return fmt.Errorf("failed to load serving certificate, err is %v", err)
+	}
+	m.mu.Lock()
+	m.certPEM = servingCert.CertPEM
+	m.cert = &cert
+	m.mu.Unlock()
+	klog.Infof("load serving certificate from secret %s/%s", m.namespace, m.secretName)
+	return nil
+}
+
+// Run checks the serving certificate periodically, it blocks until stopCh is closed
+func (m *CertManager) Run(stopCh <-chan struct{}) {
+	wait.Until(func() {
+		if err := m.Sync(); err != nil {
+			klog.Errorf("failed to sync serving certificate, err is %v", err)
+		}
+	}, certCheckInterval, stopCh)
+}
+
+// GetCertificate returns the latest serving certificate, it is used as
+// tls.Config.GetCertificate so that a renewed certificate is served without restart
+func (m *CertManager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.cert == nil {
+		return nil, fmt.Errorf("serving certificate is not loaded")
+	}
+	return m.cert, nil
+}
+
+// isCertValid checks whether the certificate is not about to expire and is signed by one of the CAs
+func isCertValid(certPEM []byte, cas []*x509.Certificate) bool {
+	certs, err := certutil.ParseCertsPEM(certPEM)
+	if err != nil || len(certs) == 0 {
+		return false
+	}
+	if !time.Now().Add(certRenewBefore).Before(certs[0].NotAfter) {
+		return false
+	}
+	roots := x509.NewCertPool()
+	for _, ca := range cas {
+		roots.AddCert(ca)
+	}
+	_, err = certs[0].Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}})
+	return err == nil
+}
+
+// InjectConversionCABundle sets the caBundle of the conversion webhook of the CRD
+func InjectConversionCABundle(cli apiextensionsclientset.Interface, crdName string, caBundle []byte) error {
+	ctx := context.TODO()
+	crd, err := cli.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, crdName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("failed to get crd %s, err is %v", crdName, err)
+		return err
+	}
+
+	conversion := crd.Spec.Conversion
+	if conversion == nil || conversion.Strategy != apiextensionsv1.WebhookConverter ||
+		conversion.Webhook == nil || conversion.Webhook.ClientConfig == nil {
+		return fmt.Errorf("conversion webhook of crd %s is not configured", crdName)
+	}
+	if bytes.Equal(conversion.Webhook.ClientConfig.CABundle, caBundle) {
+		return nil
+	}
+
+	conversion.Webhook.ClientConfig.CABundle = caBundle
+	_, err = cli.ApiextensionsV1().CustomResourceDefinitions().Update(ctx, crd, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf("failed to update caBundle of crd %s, err is %v", crdName, err)
+		return err
+	}
+	klog.Infof("update caBundle of crd %s", crdName)
+	return nil
+}
+
+// InjectAdmissionCABundle sets the caBundle of the webhooks of the validating
+// and mutating webhook configurations with the name
+func InjectAdmissionCABundle(kubeCli kubernetes.Interface, name string, caBundle []byte) error {
+	ctx := context.TODO()
+	validating, err := kubeCli.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("failed to get validating webhook configuration %s, err is %v", name, err)
+		return err
+	}
+	updated := false
+	for i := range validating.Webhooks {
+		if !bytes.Equal(validating.Webhooks[i].ClientConfig.CABundle, caBundle) {
+			validating.Webhooks[i].ClientConfig.CABundle = caBundle
+			updated = true
+		}
+	}
+	if updated {
+		_, err = kubeCli.AdmissionregistrationV1().ValidatingWebhookConfigurations().Update(ctx, validating, metav1.UpdateOptions{})
+		if err != nil {
+			klog.Errorf("failed to update caBundle of validating webhook configuration %s, err is %v", name, err)
+			return err
+		}
+		klog.Infof("update caBundle of validating webhook configuration %s", name)
+	}
+
+	mutating, err := kubeCli.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("failed to get mutating webhook configuration %s, err is %v", name, err)
+		return err
+	}
+	updated = false
+	for i := range mutating.Webhooks {
+		if !bytes.Equal(mutating.Webhooks[i].ClientConfig.CABundle, caBundle) {
+			mutating.Webhooks[i].ClientConfig.CABundle = caBundle
+			updated = true
+		}
+	}
+	if updated {
+		_, err = kubeCli.AdmissionregistrationV1().MutatingWebhookConfigurations().Update(ctx, mutating, metav1.UpdateOptions{})
+		if err != nil {
+			klog.Errorf("failed to update caBundle of mutating webhook configuration %s, err is %v", name, err)
+			return err
+		}
+		klog.Infof("update caBundle of mutating webhook configuration %s", name)
+	}
+	return nil
+}
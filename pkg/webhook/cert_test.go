@@ -0,0 +1,352 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	extfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+)
+
+const (
+	testNamespace     = "kstone"
+	testSecretName    = "kstone-webhook-certs"
+	testServiceName   = "kstone-webhook"
+	testCRDName       = "etcdclusters.kstone.tkestack.io"
+	testWebhookConfig = "kstone-webhook"
+)
+
+func newTestSecret(data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testSecretName,
+			Namespace: testNamespace,
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: data,
+	}
+}
+
+// newTestCerts generates the secret data with a CA expiring after caValidity
+func newTestCerts(t *testing.T, caValidity time.Duration) map[string][]byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	certPEM, keyPEM, err := newSignedCertKey(ca, key, "kstone-webhook.kstone.svc", nil)
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	caPEM, err := certutil.EncodeCertificates(ca)
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	caKeyPEM, err := keyutil.MarshalPrivateKeyToPEM(key)
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	return map[string][]byte{
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+		caCertKey:               caPEM,
+		caPrivateKeyKey:         caKeyPEM,
+	}
+}
+
+func parseCAs(t *testing.T, caBundle []byte) []*x509.Certificate {
+	cas, err := certutil.ParseCertsPEM(caBundle)
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	return cas
+}
+
+func TestEnsureServingCert(t *testing.T) {
+	valid := newTestCerts(t, 10*certValidity)
+	expiring := newTestCerts(t, 10*certValidity)
+	expiring[corev1.TLSCertKey] = []byte("expired")
+	// the CA is about to expire, the serving certificate is still valid
+	caExpiring := newTestCerts(t, caRenewBefore-time.Hour)
+	otherCA := newTestCerts(t, 10*certValidity)
+	untrusted := newTestCerts(t, 10*certValidity)
+	untrusted[caCertKey] = otherCA[caCertKey]
+	untrusted[caPrivateKeyKey] = otherCA[caPrivateKeyKey]
+
+	tests := []struct {
+		name      string
+		data      map[string][]byte
+		reuseCert bool
+		reuseCA   bool
+		cas       int
+	}{
+		{
+			name: "secret not found",
+			cas:  1,
+		},
+		{
+			name:      "valid certificate",
+			data:      valid,
+			reuseCert: true,
+			reuseCA:   true,
+			cas:       1,
+		},
+		{
+			name:    "serving certificate about to expire",
+			data:    expiring,
+			reuseCA: true,
+			cas:     1,
+		},
+		{
+			name:      "CA about to expire",
+			data:      caExpiring,
+			reuseCert: true,
+			cas:       2,
+		},
+		{
+			name:    "serving certificate not signed by the CA",
+			data:    untrusted,
+			reuseCA: true,
+			cas:     1,
+		},
+		{
+			name: "invalid certificate",
+			data: map[string][]byte{corev1.TLSCertKey: []byte("invalid"), caCertKey: []byte("invalid")},
+			cas:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objects []runtime.Object
+			if tt.data != nil {
+				objects = append(objects, newTestSecret(tt.data))
+			}
+			kubeCli := fake.NewSimpleClientset(objects...)
+			cert, err := EnsureServingCert(kubeCli, testNamespace, testSecretName, testServiceName)
+			if err != nil {
+				t.Fatalf("err is %v", err)
+			}
+			if reused := bytes.Equal(cert.CertPEM, tt.data[corev1.TLSCertKey]); reused != tt.reuseCert {
+				t.Errorf("expected reuse certificate %v, got %v", tt.reuseCert, reused)
+			}
+			cas := parseCAs(t, cert.CABundle)
+			if len(cas) != tt.cas {
+				t.Fatalf("expected %d CAs in the caBundle, got %d", tt.cas, len(cas))
+			}
+			if tt.data != nil && tt.data[caCertKey] != nil {
+				if reused := bytes.HasSuffix(cert.CABundle, tt.data[caCertKey]); reused != (tt.reuseCA || tt.cas > 1) {
+					t.Errorf("expected the old CA kept %v, got %v", tt.reuseCA || tt.cas > 1, reused)
+				}
+			}
+			if !isCertValid(cert.CertPEM, cas) {
+				t.Errorf("expected the certificate to be trusted by the caBundle")
+			}
+			secret, err := kubeCli.CoreV1().Secrets(testNamespace).Get(context.TODO(), testSecretName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("err is %v", err)
+			}
+			if !bytes.Equal(secret.Data[corev1.TLSCertKey], cert.CertPEM) ||
+				!bytes.Equal(secret.Data[corev1.TLSPrivateKeyKey], cert.KeyPEM) ||
+				!bytes.Equal(secret.Data[caCertKey], cert.CABundle) {
+				t.Errorf("expected the certificate to be saved in the secret")
+			}
+		})
+	}
+}
+
+func TestEnsureServingCertCARotation(t *testing.T) {
+	data := newTestCerts(t, caRenewBefore-time.Hour)
+	kubeCli := fake.NewSimpleClientset(newTestSecret(data))
+	rotated, err := EnsureServingCert(kubeCli, testNamespace, testSecretName, testServiceName)
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	cas := parseCAs(t, rotated.CABundle)
+	if len(cas) != 2 {
+		t.Fatalf("expected both CAs in the caBundle, got %d", len(cas))
+	}
+
+	// the serving certificate renewed later is signed by the new CA, the old CA is still published
+	secret, err := kubeCli.CoreV1().Secrets(testNamespace).Get(context.TODO(), testSecretName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	secret.Data[corev1.TLSCertKey] = []byte("expired")
+	if _, err = kubeCli.CoreV1().Secrets(testNamespace).Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	renewed, err := EnsureServingCert(kubeCli, testNamespace, testSecretName, testServiceName)
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	if !bytes.Equal(renewed.CABundle, rotated.CABundle) {
+		t.Errorf("expected the caBundle not changed by renewing the serving certificate")
+	}
+	if !isCertValid(renewed.CertPEM, cas[:1]) {
+		t.Errorf("expected the serving certificate signed by the new CA")
+	}
+}
+
+func TestEnsureServingCertConflict(t *testing.T) {
+	other := newTestCerts(t, 10*certValidity)
+
+	kubeCli := fake.NewSimpleClientset(newTestSecret(map[string][]byte{corev1.TLSCertKey: []byte("expired")}))
+	kubeCli.PrependReactor("update", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		// another replica renews the certificate first
+		tracker := kubeCli.Tracker()
+		gvr := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+		if err := tracker.Update(gvr, newTestSecret(other), testNamespace); err != nil {
+			return true, nil, err
+		}
+		return true, nil, apierrors.NewConflict(gvr.GroupResource(), testSecretName, nil)
+	})
+
+	cert, err := EnsureServingCert(kubeCli, testNamespace, testSecretName, testServiceName)
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	if !bytes.Equal(cert.CertPEM, other[corev1.TLSCertKey]) || !bytes.Equal(cert.KeyPEM, other[corev1.TLSPrivateKeyKey]) {
+		t.Errorf("expected the certificate renewed by another replica")
+	}
+}
+
+func TestCertManagerSync(t *testing.T) {
+	kubeCli := fake.NewSimpleClientset(
+		&admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: testWebhookConfig},
+			Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "validate.kstone.tkestack.io"}},
+		},
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: testWebhookConfig},
+			Webhooks:   []admissionregistrationv1.MutatingWebhook{{Name: "mutate.kstone.tkestack.io"}},
+		},
+	)
+	extCli := extfake.NewSimpleClientset(&apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: testCRDName},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Conversion: &apiextensionsv1.CustomResourceConversion{
+				Strategy: apiextensionsv1.WebhookConverter,
+				Webhook: &apiextensionsv1.WebhookConversion{
+					ClientConfig: &apiextensionsv1.WebhookClientConfig{},
+				},
+			},
+		},
+	})
+	m := NewCertManager(kubeCli, extCli, testNamespace, testSecretName, testServiceName, testCRDName, testWebhookConfig)
+
+	if _, err := m.GetCertificate(nil); err == nil {
+		t.Errorf("expected an error before the certificate is loaded")
+	}
+	if err := m.Sync(); err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	first, err := m.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+
+	// the certificate is about to expire, it is renewed by the next sync
+	secret, err := kubeCli.CoreV1().Secrets(testNamespace).Get(context.TODO(), testSecretName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	firstCABundle := secret.Data[caCertKey]
+	secret.Data[corev1.TLSCertKey] = []byte("expired")
+	if _, err = kubeCli.CoreV1().Secrets(testNamespace).Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	if err = m.Sync(); err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	second, err := m.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	if first == second || bytes.Equal(first.Certificate[0], second.Certificate[0]) {
+		t.Errorf("expected the renewed certificate to be served")
+	}
+
+	secret, err = kubeCli.CoreV1().Secrets(testNamespace).Get(context.TODO(), testSecretName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	// the CA is not changed by renewing the serving certificate
+	caBundle := secret.Data[caCertKey]
+	if !bytes.Equal(caBundle, firstCABundle) {
+		t.Errorf("expected the caBundle not changed")
+	}
+	crd, err := extCli.ApiextensionsV1().CustomResourceDefinitions().Get(context.TODO(), testCRDName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	if !bytes.Equal(crd.Spec.Conversion.Webhook.ClientConfig.CABundle, caBundle) {
+		t.Errorf("expected the caBundle of the crd to be renewed")
+	}
+	validating, err := kubeCli.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(context.TODO(), testWebhookConfig, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	if !bytes.Equal(validating.Webhooks[0].ClientConfig.CABundle, caBundle) {
+		t.Errorf("expected the caBundle of the validating webhook to be renewed")
+	}
+	mutating, err := kubeCli.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(context.TODO(), testWebhookConfig, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	if !bytes.Equal(mutating.Webhooks[0].ClientConfig.CABundle, caBundle) {
+		t.Errorf("expected the caBundle of the mutating webhook to be renewed")
+	}
+}
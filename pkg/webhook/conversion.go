@@ -0,0 +1,122 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	kstonev1alpha3 "tkestack.io/kstone/pkg/apis/kstone/v1alpha3"
+)
+
+// serveConvert handles the ConversionReview of EtcdCluster sent by kube-apiserver
+func (s *Server) serveConvert(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	review := &apiextensionsv1.ConversionReview{}
+	if err = json.Unmarshal(body, review); err != nil || review.Request == nil {
+		klog.Errorf("failed to decode conversion review, err is %v", err)
+		http.Error(w, "invalid conversion review", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = convert(review.Request)
+	review.Request = nil
+	data, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// convert converts all the objects of the request to the desired version
+func convert(req *apiextensionsv1.ConversionRequest) *apiextensionsv1.ConversionResponse {
+	resp := &apiextensionsv1.ConversionResponse{
+		UID: req.UID,
+		Result: metav1.Status{
+			Status: metav1.StatusSuccess,
+		},
+	}
+	for _, obj := range req.Objects {
+		converted, err := convertEtcdCluster(obj.Raw, req.DesiredAPIVersion)
+		if err != nil {
+			klog.Errorf("failed to convert etcdcluster to %s, err is %v", req.DesiredAPIVersion, err)
+			resp.ConvertedObjects = nil
+			resp.Result = metav1.Status{
+				Status:  metav1.StatusFailure,
+				Message: err.Error(),
+			}
+			return resp
+		}
+		resp.ConvertedObjects = append(resp.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+	return resp
+}
+
+// convertEtcdCluster converts the raw EtcdCluster between v1alpha2 and v1alpha3
+func convertEtcdCluster(raw []byte, desiredAPIVersion string) ([]byte, error) {
+	typeMeta := &metav1.TypeMeta{}
+	if err := json.Unmarshal(raw, typeMeta); err != nil {
+		return nil, err
+	}
+	if typeMeta.APIVersion == desiredAPIVersion {
+		return raw, nil
+	}
+
+	var (
+		out interface{}
+		err error
+	)
+	switch {
+	case typeMeta.APIVersion == kstonev1alpha2.SchemeGroupVersion.String() &&
+		desiredAPIVersion == kstonev1alpha3.SchemeGroupVersion.String():
+		cluster := &kstonev1alpha2.EtcdCluster{}
+		if err = json.Unmarshal(raw, cluster); err != nil {
+			return nil, err
+		}
+		out, err = kstonev1alpha3.ConvertFromV1alpha2(cluster)
+	case typeMeta.APIVersion == kstonev1alpha3.SchemeGroupVersion.String() &&
+		desiredAPIVersion == kstonev1alpha2.SchemeGroupVersion.String():
+		cluster := &kstonev1alpha3.EtcdCluster{}
+		if err = json.Unmarshal(raw, cluster); err != nil {
+			return nil, err
+		}
+		out, err = kstonev1alpha3.ConvertToV1alpha2(cluster)
+	default:
+		return nil, fmt.Errorf("unsupported conversion from %s to %s", typeMeta.APIVersion, desiredAPIVersion)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
+}
@@ -0,0 +1,318 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/backup"
+	_ "tkestack.io/kstone/pkg/backup/providers" // register backup storage provider
+	"tkestack.io/kstone/pkg/clusterprovider"
+	_ "tkestack.io/kstone/pkg/clusterprovider/providers" // register cluster provider
+	"tkestack.io/kstone/pkg/featureprovider"
+	_ "tkestack.io/kstone/pkg/featureprovider/providers" // register feature provider
+	"tkestack.io/kstone/pkg/inspection"
+	"tkestack.io/kstone/pkg/monitor"
+)
+
+const (
+	// DefaultEtcdVersion is the version of the etcd created by kstone if not specified
+	DefaultEtcdVersion = "3.4.13"
+	// DefaultRepository is the image of the etcd created by kstone if not specified
+	DefaultRepository = "quay.io/coreos/etcd"
+
+	annoRepository = "repository"
+)
+
+// validSizes are the supported member counts of the etcd created by kstone
+var validSizes = sets.NewInt(1, 3, 5, 7)
+
+// defaultEtcdCluster sets the default values of EtcdCluster, the version and
+// the image are only defaulted on creation to keep the existing clusters untouched
+func defaultEtcdCluster(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	cluster := &kstonev1alpha2.EtcdCluster{}
+	if err := json.Unmarshal(req.Object.Raw, cluster); err != nil {
+		return errored(err)
+	}
+
+	var patches []patchOperation
+	spec := &cluster.Spec
+	if spec.StorageBackend == "" {
+		patches = append(patches, patchOperation{
+			Op:    "add",
+			Path:  "/spec/storageBackend",
+			Value: string(kstonev1alpha2.EtcdStorageV3),
+		})
+	}
+	if req.Operation == admissionv1.Create && spec.ClusterType == kstonev1alpha2.EtcdClusterKstone {
+		if spec.Version == "" {
+			patches = append(patches, patchOperation{Op: "add", Path: "/spec/version", Value: DefaultEtcdVersion})
+		}
+		if spec.Repository == "" {
+			repository := cluster.Annotations[annoRepository]
+			if repository == "" {
+				repository = DefaultRepository
+			}
+			patches = append(patches, patchOperation{Op: "add", Path: "/spec/repository", Value: repository})
+		}
+	}
+	return patched(patches)
+}
+
+// validateEtcdCluster validates EtcdCluster, only the changed fields are
+// validated on update, so that the existing clusters are still reconciled
+func validateEtcdCluster(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	cluster := &kstonev1alpha2.EtcdCluster{}
+	if err := json.Unmarshal(req.Object.Raw, cluster); err != nil {
+		return errored(err)
+	}
+	if cluster.DeletionTimestamp != nil {
+		return allowed()
+	}
+
+	var old *kstonev1alpha2.EtcdCluster
+	if req.Operation == admissionv1.Update {
+		old = &kstonev1alpha2.EtcdCluster{}
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			return errored(err)
+		}
+	}
+
+	errs := validateEtcdClusterSpec(cluster, old)
+	errs = append(errs, validateEtcdClusterAnnotations(cluster, old)...)
+	if old != nil {
+		errs = append(errs, validateEtcdClusterUpdate(cluster, old)...)
+	}
+	if len(errs) > 0 {
+		return denied(req, errs)
+	}
+	return allowed()
+}
+
+// validateEtcdClusterSpec validates the spec of EtcdCluster
+func validateEtcdClusterSpec(cluster, old *kstonev1alpha2.EtcdCluster) field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+	spec := &cluster.Spec
+
+	if old == nil || spec.ClusterType != old.Spec.ClusterType {
+		if spec.ClusterType == "" {
+			errs = append(errs, field.Required(specPath.Child("clusterType"), ""))
+		} else if !clusterprovider.IsEtcdClusterProviderRegistered(spec.ClusterType) {
+			errs = append(errs, field.NotSupported(
+				specPath.Child("clusterType"),
+				spec.ClusterType,
+				[]string{string(kstonev1alpha2.EtcdClusterKstone), string(kstonev1alpha2.EtcdClusterImported)},
+			))
+		}
+	}
+
+	if old == nil || spec.StorageBackend != old.Spec.StorageBackend {
+		switch kstonev1alpha2.EtcdStorageBackend(spec.StorageBackend) {
+		case "", kstonev1alpha2.EtcdStorageV2, kstonev1alpha2.EtcdStorageV3:
+		default:
+			errs = append(errs, field.NotSupported(
+				specPath.Child("storageBackend"),
+				spec.StorageBackend,
+				[]string{string(kstonev1alpha2.EtcdStorageV2), string(kstonev1alpha2.EtcdStorageV3)},
+			))
+		}
+	}
+
+	// the size and the version of imported clusters are discovered from the clusters
+	if spec.ClusterType != kstonev1alpha2.EtcdClusterKstone {
+		return errs
+	}
+	if (old == nil || spec.Size != old.Spec.Size) && !validSizes.Has(int(spec.Size)) {
+		errs = append(errs, field.Invalid(specPath.Child("size"), int(spec.Size), "must be one of 1, 3, 5, 7"))
+	}
+	if (old == nil || spec.DiskSize != old.Spec.DiskSize) && spec.DiskSize == 0 {
+		errs = append(errs, field.Invalid(specPath.Child("diskSize"), int(spec.DiskSize), "must be greater than 0"))
+	}
+	if old == nil || spec.Version != old.Spec.Version {
+		if _, err := parseEtcdVersion(spec.Version); err != nil {
+			errs = append(errs, field.Invalid(specPath.Child("version"), spec.Version, err.Error()))
+		}
+	}
+	return errs
+}
+
+// validateEtcdClusterAnnotations validates the configurations stored in the annotations
+func validateEtcdClusterAnnotations(cluster, old *kstonev1alpha2.EtcdCluster) field.ErrorList {
+	var errs field.ErrorList
+	annoPath := field.NewPath("metadata", "annotations")
+	changed := func(key string) (string, bool) {
+		value, found := cluster.Annotations[key]
+		if !found {
+			return "", false
+		}
+		return value, old == nil || old.Annotations[key] != value
+	}
+
+	if value, ok := changed(kstonev1alpha2.KStoneFeatureAnno); ok {
+		errs = append(errs, validateFeatureGates(annoPath.Key(kstonev1alpha2.KStoneFeatureAnno), value)...)
+	}
+	if value, ok := changed(kstonev1alpha2.AnnoBackup); ok {
+		cfg := &backup.Config{}
+		if err := json.Unmarshal([]byte(value), cfg); err != nil {
+			errs = append(errs, field.Invalid(annoPath.Key(kstonev1alpha2.AnnoBackup), value, err.Error()))
+		} else if err = cfg.Validate(); err != nil {
+			errs = append(errs, field.Invalid(annoPath.Key(kstonev1alpha2.AnnoBackup), value, err.Error()))
+		}
+	}
+	if value, ok := changed(kstonev1alpha2.AnnoRequest); ok {
+		info := &inspection.RequestInfo{}
+		if err := json.Unmarshal([]byte(value), info); err != nil {
+			errs = append(errs, field.Invalid(annoPath.Key(kstonev1alpha2.AnnoRequest), value, err.Error()))
+		} else if err = info.Validate(); err != nil {
+			errs = append(errs, field.Invalid(annoPath.Key(kstonev1alpha2.AnnoRequest), value, err.Error()))
+		}
+	}
+	if value, ok := changed(kstonev1alpha2.AnnoKeyspace); ok {
+		info := &inspection.KeyspaceInfo{}
+		if err := json.Unmarshal([]byte(value), info); err != nil {
+			errs = append(errs, field.Invalid(annoPath.Key(kstonev1alpha2.AnnoKeyspace), value, err.Error()))
+		} else if err = info.Validate(); err != nil {
+			errs = append(errs, field.Invalid(annoPath.Key(kstonev1alpha2.AnnoKeyspace), value, err.Error()))
+		}
+	}
+	if value, ok := changed(kstonev1alpha2.AnnoDefrag); ok {
+		info := &inspection.DefragInfo{}
+		if err := json.Unmarshal([]byte(value), info); err != nil {
+			errs = append(errs, field.Invalid(annoPath.Key(kstonev1alpha2.AnnoDefrag), value, err.Error()))
+		} else if err = info.Validate(); err != nil {
+			errs = append(errs, field.Invalid(annoPath.Key(kstonev1alpha2.AnnoDefrag), value, err.Error()))
+		}
+	}
+	if value, ok := changed(kstonev1alpha2.AnnoConsistency); ok {
+		info := &inspection.ConsistencyInfo{}
+		if err := json.Unmarshal([]byte(value), info); err != nil {
+			errs = append(errs, field.Invalid(annoPath.Key(kstonev1alpha2.AnnoConsistency), value, err.Error()))
+		} else if err = info.Validate(); err != nil {
+			errs = append(errs, field.Invalid(annoPath.Key(kstonev1alpha2.AnnoConsistency), value, err.Error()))
+		}
+	}
+	if value, ok := changed(kstonev1alpha2.AnnoAlertThresholds); ok {
+		thresholds := &monitor.AlertThresholds{}
+		if err := json.Unmarshal([]byte(value), thresholds); err != nil {
+			errs = append(errs, field.Invalid(annoPath.Key(kstonev1alpha2.AnnoAlertThresholds), value, err.Error()))
+		} else if err = thresholds.Validate(); err != nil {
+			errs = append(errs, field.Invalid(annoPath.Key(kstonev1alpha2.AnnoAlertThresholds), value, err.Error()))
+		}
+	}
+	if value, ok := changed(kstonev1alpha2.AnnoExtClientURL); ok {
+		if _, err := kstonev1alpha2.ParseExtClientURLs(value); err != nil {
+			errs = append(errs, field.Invalid(annoPath.Key(kstonev1alpha2.AnnoExtClientURL), value, err.Error()))
+		}
+	}
+	if value, ok := changed(kstonev1alpha2.AnnoQuotaBackendBytes); ok {
+		if quota, err := strconv.ParseInt(value, 10, 64); err != nil || quota <= 0 {
+			errs = append(errs, field.Invalid(
+				annoPath.Key(kstonev1alpha2.AnnoQuotaBackendBytes),
+				value,
+				"must be a positive integer",
+			))
+		}
+	}
+	if _, ok := changed(kstonev1alpha2.AnnoRestart); ok && cluster.Spec.ClusterType != kstonev1alpha2.EtcdClusterKstone {
+		errs = append(errs, field.Forbidden(
+			annoPath.Key(kstonev1alpha2.AnnoRestart),
+			fmt.Sprintf("restart is not supported by %s cluster", cluster.Spec.ClusterType),
+		))
+	}
+	if _, ok := changed(backup.AnnoRestoreConfig); ok {
+		if _, err := backup.GetRestoreConfig(cluster); err != nil {
+			errs = append(errs, field.Invalid(
+				annoPath.Key(backup.AnnoRestoreConfig),
+				cluster.Annotations[backup.AnnoRestoreConfig],
+				err.Error(),
+			))
+		}
+	}
+	return errs
+}
+
+// validateFeatureGates validates the featureGates annotation, such as monitor=true,backup=false,
+// it is parsed the same way as the controllers parse it
+func validateFeatureGates(path *field.Path, gates string) field.ErrorList {
+	var errs field.ErrorList
+	parsed, err := kstonev1alpha2.ParseFeatureGates(gates)
+	if err != nil {
+		errs = append(errs, field.Invalid(path, gates, fmt.Sprintf("%v, must be in the form of <feature>=<true|false>", err)))
+	}
+	names := sets.NewString()
+	for name := range parsed {
+		names.Insert(string(name))
+	}
+	features := sets.NewString(featureprovider.ListFeatureProvider()...)
+	for _, name := range names.List() {
+		if !features.Has(name) {
+			errs = append(errs, field.NotSupported(path, name, features.List()))
+		}
+	}
+	return errs
+}
+
+// validateEtcdClusterUpdate blocks the unsafe updates of EtcdCluster
+func validateEtcdClusterUpdate(cluster, old *kstonev1alpha2.EtcdCluster) field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+	spec, oldSpec := &cluster.Spec, &old.Spec
+
+	if spec.ClusterType != oldSpec.ClusterType {
+		errs = append(errs, field.Forbidden(specPath.Child("clusterType"), "field is immutable"))
+	}
+	if spec.ClusterType != kstonev1alpha2.EtcdClusterKstone {
+		return errs
+	}
+	if spec.DiskSize < oldSpec.DiskSize {
+		errs = append(errs, field.Forbidden(
+			specPath.Child("diskSize"),
+			fmt.Sprintf("disk can not be shrunk from %d to %d", oldSpec.DiskSize, spec.DiskSize),
+		))
+	}
+	if spec.Version != oldSpec.Version {
+		oldVersion, oldErr := parseEtcdVersion(oldSpec.Version)
+		newVersion, newErr := parseEtcdVersion(spec.Version)
+		if oldErr == nil && newErr == nil && newVersion.LessThan(oldVersion) {
+			errs = append(errs, field.Forbidden(
+				specPath.Child("version"),
+				fmt.Sprintf("etcd can not be downgraded from %s to %s", oldSpec.Version, spec.Version),
+			))
+		}
+	}
+	return errs
+}
+
+// parseEtcdVersion parses the etcd version, such as 3.4.13 or v3.4.13
+func parseEtcdVersion(v string) (*version.Version, error) {
+	if v == "" {
+		return nil, fmt.Errorf("version is required")
+	}
+	return version.ParseSemantic(strings.TrimPrefix(v, "v"))
+}
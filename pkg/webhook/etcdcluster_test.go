@@ -0,0 +1,56 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package webhook
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+)
+
+func TestValidateFeatureGates(t *testing.T) {
+	tests := []struct {
+		name    string
+		gates   string
+		wantErr bool
+	}{
+		{name: "empty", gates: ""},
+		{name: "enabled", gates: "monitor=true,backup=false"},
+		{name: "space after comma", gates: "backup=true, monitor=true", wantErr: true},
+		{name: "missing value", gates: "monitor", wantErr: true},
+		{name: "invalid value", gates: "monitor=yes", wantErr: true},
+		{name: "unknown feature", gates: "unknown=true", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateFeatureGates(field.NewPath("metadata", "annotations"), tt.gates)
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("validateFeatureGates() errs are %v, wantErr %v", errs, tt.wantErr)
+			}
+			// the gates accepted by the webhook are parsed by the controllers
+			if len(errs) == 0 {
+				if _, err := kstonev1alpha2.ParseFeatureGates(tt.gates); err != nil {
+					t.Errorf("ParseFeatureGates() err is %v", err)
+				}
+			}
+		})
+	}
+}
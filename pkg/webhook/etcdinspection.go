@@ -0,0 +1,79 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package webhook
+
+import (
+	"encoding/json"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/featureprovider"
+)
+
+// validateEtcdInspection validates EtcdInspection
+func validateEtcdInspection(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	task := &kstonev1alpha2.EtcdInspection{}
+	if err := json.Unmarshal(req.Object.Raw, task); err != nil {
+		return errored(err)
+	}
+	if task.DeletionTimestamp != nil {
+		return allowed()
+	}
+
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+	spec := &task.Spec
+	if req.Operation == admissionv1.Update {
+		// the spec is validated on creation, only the immutable fields are checked on update
+		old := &kstonev1alpha2.EtcdInspection{}
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			return errored(err)
+		}
+		if spec.ClusterName != old.Spec.ClusterName {
+			errs = append(errs, field.Forbidden(specPath.Child("clusterName"), "field is immutable"))
+		}
+		if spec.InspectionType != old.Spec.InspectionType {
+			errs = append(errs, field.Forbidden(specPath.Child("inspectionType"), "field is immutable"))
+		}
+		if spec.IntervalInSecond != old.Spec.IntervalInSecond && spec.IntervalInSecond < 0 {
+			errs = append(errs, field.Invalid(specPath.Child("intervalInSecond"), spec.IntervalInSecond, "must not be negative"))
+		}
+	} else {
+		if spec.ClusterName == "" {
+			errs = append(errs, field.Required(specPath.Child("clusterName"), ""))
+		}
+		features := sets.NewString(featureprovider.ListFeatureProvider()...)
+		if spec.InspectionType == "" {
+			errs = append(errs, field.Required(specPath.Child("inspectionType"), ""))
+		} else if !features.Has(spec.InspectionType) {
+			errs = append(errs, field.NotSupported(specPath.Child("inspectionType"), spec.InspectionType, features.List()))
+		}
+		if spec.IntervalInSecond < 0 {
+			errs = append(errs, field.Invalid(specPath.Child("intervalInSecond"), spec.IntervalInSecond, "must not be negative"))
+		}
+	}
+
+	if len(errs) > 0 {
+		return denied(req, errs)
+	}
+	return allowed()
+}
@@ -0,0 +1,61 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package webhook
+
+import (
+	"encoding/json"
+	"reflect"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	kstonev1alpha2 "tkestack.io/kstone/pkg/apis/kstone/v1alpha2"
+	"tkestack.io/kstone/pkg/backup"
+)
+
+// validateEtcdRestore validates EtcdRestore
+func validateEtcdRestore(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	restore := &kstonev1alpha2.EtcdRestore{}
+	if err := json.Unmarshal(req.Object.Raw, restore); err != nil {
+		return errored(err)
+	}
+	if restore.DeletionTimestamp != nil {
+		return allowed()
+	}
+
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+	if req.Operation == admissionv1.Update {
+		// the spec is validated on creation, a running restore must not be changed
+		old := &kstonev1alpha2.EtcdRestore{}
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			return errored(err)
+		}
+		if !reflect.DeepEqual(restore.Spec, old.Spec) {
+			errs = append(errs, field.Forbidden(specPath, "field is immutable"))
+		}
+	} else if err := backup.ValidateRestoreSpec(&restore.Spec); err != nil {
+		errs = append(errs, field.Invalid(specPath, restore.Spec, err.Error()))
+	}
+
+	if len(errs) > 0 {
+		return denied(req, errs)
+	}
+	return allowed()
+}
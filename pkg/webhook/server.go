@@ -0,0 +1,85 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultPort is the port the webhook server listens on
+	DefaultPort = 9443
+
+	shutdownTimeout = 10 * time.Second
+)
+
+// Server serves the webhooks of kstone for kube-apiserver over https
+type Server struct {
+	port   int
+	server *http.Server
+}
+
+// NewServer generates the webhook server, the serving certificate is got from
+// getCertificate on each handshake so that it can be renewed without restart
+func NewServer(port int, getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)) *Server {
+	s := &Server{
+		port: port,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/convert", s.serveConvert)
+	mux.HandleFunc("/validate", s.serveValidate)
+	mux.HandleFunc("/mutate", s.serveMutate)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	s.server = &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: mux,
+		TLSConfig: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: getCertificate,
+		},
+	}
+	return s
+}
+
+// Run starts the webhook server, it blocks until stopCh is closed
+func (s *Server) Run(stopCh <-chan struct{}) error {
+	errCh := make(chan error, 1)
+	go func() {
+		klog.Infof("webhook server listens on %s", s.server.Addr)
+		errCh <- s.server.ListenAndServeTLS("", "")
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-stopCh:
+		klog.Info("shutting down webhook server")
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return s.server.Shutdown(ctx)
+	}
+}
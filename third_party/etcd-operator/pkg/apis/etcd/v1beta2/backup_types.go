@@ -94,12 +94,25 @@ type BackupSpec struct {
 	//    "client-ca.crt": <pem-encoded-ca-cert>
 	ClientTLSSecret string `json:"clientTLSSecret,omitempty"`
 	// insecure-skip-tsl-verify
-	InsecureSkipVerify bool `json:"insecureSkipVerify, omitempty"`
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
 	// BasicAuthSecret is the secret containing the etcd user password
 	// data:
 	//    "username": xx
 	//    "password": xxxx
 	BasicAuthSecret string `json:"basicAuthSecret,omitempty"`
+	// Encryption encrypts the backup files before they are written to the backup storage.
+	Encryption *BackupEncryption `json:"encryption,omitempty"`
+}
+
+// BackupEncryption provides the spec how to encrypt backup files.
+type BackupEncryption struct {
+	// EncryptionSecret is the name of the secret storing the key encryption keys,
+	// the key of the data is the key ID, the value is a 32 bytes key or encoded in base64.
+	// Keep the old keys in the secret after rotation to decrypt the old backup files.
+	EncryptionSecret string `json:"encryptionSecret"`
+	// KeyID is the ID of the key used to encrypt new backup files,
+	// it is appended to the name of backup files as <name>.<keyID>.enc.
+	KeyID string `json:"keyID"`
 }
 
 // BackupSource contains the supported backup sources.
@@ -247,5 +260,7 @@ type HostPathBackupSource struct {
 	// Path is the full Host path where the backup is saved.
 	// The format of the path is relative
 	// e.g: "etcd.backup"
+	// The path is <dir>/<suffix>, backups are saved under the dir relative to
+	// HOST_PATH_NAME, and one-shot backups are saved as etcdbackup<suffix>.
 	Path string `json:"path"`
 } 
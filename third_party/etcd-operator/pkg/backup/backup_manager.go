@@ -18,6 +18,7 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"regexp"
 	"sort"
 	"time"
 
@@ -30,6 +31,10 @@ import (
 	"k8s.io/client-go/kubernetes"
 )
 
+// periodicBackupRegexp matches the names of periodic backups, <path>_v<revision>_<time>_<etcd version>,
+// and <path>_v<revision>_<time> of the backups taken by the previous versions.
+var periodicBackupRegexp = regexp.MustCompile(`_v\d+_\d{4}-\d{2}-\d{2}-\d{2}:\d{2}:\d{2}`)
+
 // BackupManager backups an etcd cluster.
 type BackupManager struct {
 	kubecli kubernetes.Interface
@@ -78,6 +83,11 @@ func (bm *BackupManager) SaveSnap(ctx context.Context, s3Path string, isPeriodic
 	defer rc.Close()
 	if isPeriodic {
 		s3Path = fmt.Sprintf(s3Path+"_v%d_%s", rev, now.Format("2006-01-02-15:04:05"))
+		// the version of etcd taking the snapshot is kept in the name, so it is
+		// still known after the cluster is upgraded
+		if resp.Version != "" {
+			s3Path += "_" + resp.Version
+		}
 	}
 	_, err = bm.bw.Write(ctx, s3Path, rc)
 	if err != nil {
@@ -89,10 +99,17 @@ func (bm *BackupManager) SaveSnap(ctx context.Context, s3Path string, isPeriodic
 // EnsureMaxBackup to ensure the number of snapshot is under maxcount
 // if the number of snapshot exceeded than maxcount, delete oldest snapshot
 func (bm *BackupManager) EnsureMaxBackup(ctx context.Context, basePath string, maxCount int) error {
-	savedSnapShots, err := bm.bw.List(ctx, basePath)
+	paths, err := bm.bw.List(ctx, basePath)
 	if err != nil {
 		return fmt.Errorf("failed to get exisiting snapshots: %v", err)
 	}
+	// one-shot backups under the same path are not rotated
+	savedSnapShots := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if periodicBackupRegexp.MatchString(path) {
+			savedSnapShots = append(savedSnapShots, path)
+		}
+	}
 	sort.Sort(sort.Reverse(sort.StringSlice(savedSnapShots)))
 	for i, snapshotPath := range savedSnapShots {
 		if i < maxCount {
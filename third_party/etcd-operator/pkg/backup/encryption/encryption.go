@@ -0,0 +1,413 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+// Package encryption implements the envelope encryption of backup files.
+//
+// Every backup file is encrypted with a random data key by AES-256-GCM in
+// chunks, and the data key is encrypted(wrapped) by the key encryption key
+// identified by the key ID. The encrypted file is laid out as:
+//
+//	magic | header length(uint32) | header(json) | chunk length(uint32) | chunk | ...
+//
+// The last chunk is flagged in its additional data, so a truncated file is
+// detected. The key ID is also appended to the name of the backup file, which
+// is <name>.<keyID>.enc, so the key can be rotated while old backup files
+// are still decrypted by the old key. The name of the secret storing the key
+// is recorded in the header, so the old backup files are still decrypted after
+// the encryption is disabled or another secret is configured.
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const (
+	// Algorithm is the algorithm of data encryption and key wrapping.
+	Algorithm = "AES-256-GCM"
+	// Suffix is the suffix of the names of encrypted backup files.
+	Suffix = ".enc"
+	// KeySize is the size of the key encryption key and the data key.
+	KeySize = 32
+
+	chunkSize      = 1024 * 1024
+	maxHeaderSize  = 4096
+	noncePrefixLen = 4
+)
+
+var (
+	magic = []byte("KSENC01\n")
+
+	keyIDRegexp      = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+	objectNameRegexp = regexp.MustCompile(`^(.*)\.([A-Za-z0-9_-]{1,64})\.enc$`)
+
+	// ErrNotEncrypted is returned if the data does not start with the magic.
+	ErrNotEncrypted = errors.New("backup file is not encrypted")
+)
+
+// header describes how the backup file is encrypted.
+type header struct {
+	KeySecret   string `json:"keySecret,omitempty"`
+	KeyID       string `json:"keyID"`
+	Algorithm   string `json:"algorithm"`
+	WrappedKey  string `json:"wrappedKey"`
+	NoncePrefix string `json:"noncePrefix"`
+	ChunkSize   int    `json:"chunkSize"`
+}
+
+// KeyFunc returns the key encryption key of the key ID stored in the secret,
+// keySecret is empty if it is not recorded.
+type KeyFunc func(keySecret, keyID string) ([]byte, error)
+
+// ValidateKeyID checks whether the key ID can be used in the name of backup files.
+func ValidateKeyID(keyID string) error {
+	if !keyIDRegexp.MatchString(keyID) {
+		return fmt.Errorf("invalid key ID %q, it must match %s", keyID, keyIDRegexp.String())
+	}
+	return nil
+}
+
+// ParseKey parses the key encryption key, which is 32 raw bytes or encoded in base64.
+func ParseKey(data []byte) ([]byte, error) {
+	if len(data) == KeySize {
+		return data, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes or encoded in base64", KeySize)
+	}
+	return key, nil
+}
+
+// ObjectName returns the name of the backup file encrypted by the key ID.
+func ObjectName(name, keyID string) string {
+	return fmt.Sprintf("%s.%s%s", name, keyID, Suffix)
+}
+
+// ParseObjectName returns the original name and the key ID of encrypted backup file.
+func ParseObjectName(name string) (string, string, bool) {
+	matches := objectNameRegexp.FindStringSubmatch(name)
+	if len(matches) != 3 {
+		return name, "", false
+	}
+	return matches[1], matches[2], true
+}
+
+// NewEncryptReader returns a reader of the encrypted data of r, keySecret is
+// the name of the secret storing the key encryption key.
+func NewEncryptReader(r io.Reader, keySecret, keyID string, kek []byte) (io.Reader, error) {
+	if err := ValidateKeyID(keyID); err != nil {
+		return nil, err
+	}
+
+	dataKey := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+	wrappedKey, err := seal(kek, dataKey, []byte(keyID))
+	if err != nil {
+		return nil, err
+	}
+	noncePrefix := make([]byte, noncePrefixLen)
+	if _, err = io.ReadFull(rand.Reader, noncePrefix); err != nil {
+		return nil, err
+	}
+
+	h, err := json.Marshal(&header{
+		KeySecret:   keySecret,
+		KeyID:       keyID,
+		Algorithm:   Algorithm,
+		WrappedKey:  base64.StdEncoding.EncodeToString(wrappedKey),
+		NoncePrefix: base64.StdEncoding.EncodeToString(noncePrefix),
+		ChunkSize:   chunkSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	buf.Write(magic)
+	writeUint32(buf, uint32(len(h)))
+	buf.Write(h)
+
+	return &encryptReader{
+		src:         r,
+		aead:        aead,
+		noncePrefix: noncePrefix,
+		headerSum:   sha256.Sum256(h),
+		plain:       make([]byte, chunkSize),
+		out:         buf,
+	}, nil
+}
+
+// NewDecryptReader returns a reader of the decrypted data of r, the key
+// encryption key is got by the secret and the key ID in the header.
+func NewDecryptReader(r io.Reader, keyFunc KeyFunc) (io.Reader, error) {
+	m := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, m); err != nil || !bytes.Equal(m, magic) {
+		return nil, ErrNotEncrypted
+	}
+	size, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxHeaderSize {
+		return nil, errors.New("header of encrypted backup file is too large")
+	}
+	raw := make([]byte, size)
+	if _, err = io.ReadFull(r, raw); err != nil {
+		return nil, err
+	}
+	h := &header{}
+	if err = json.Unmarshal(raw, h); err != nil {
+		return nil, fmt.Errorf("failed to parse header of encrypted backup file, err is %v", err)
+	}
+	if h.Algorithm != Algorithm {
+		return nil, fmt.Errorf("unsupported algorithm %s", h.Algorithm)
+	}
+
+	kek, err := keyFunc(h.KeySecret, h.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	wrappedKey, err := base64.StdEncoding.DecodeString(h.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := open(kek, wrappedKey, []byte(h.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key by key %s, err is %v", h.KeyID, err)
+	}
+	noncePrefix, err := base64.StdEncoding.DecodeString(h.NoncePrefix)
+	if err != nil || len(noncePrefix) != noncePrefixLen {
+		return nil, errors.New("invalid nonce of encrypted backup file")
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if h.ChunkSize <= 0 {
+		return nil, errors.New("invalid chunk size of encrypted backup file")
+	}
+
+	return &decryptReader{
+		src:         r,
+		aead:        aead,
+		noncePrefix: noncePrefix,
+		headerSum:   sha256.Sum256(raw),
+		maxChunk:    h.ChunkSize + aead.Overhead(),
+		out:         &bytes.Buffer{},
+	}, nil
+}
+
+type encryptReader struct {
+	src         io.Reader
+	aead        cipher.AEAD
+	noncePrefix []byte
+	headerSum   [sha256.Size]byte
+	counter     uint64
+	plain       []byte
+	// next is the first byte of the next chunk, it tells whether the current chunk is the last one
+	next    []byte
+	out     *bytes.Buffer
+	done    bool
+	lastErr error
+}
+
+func (e *encryptReader) Read(p []byte) (int, error) {
+	for e.out.Len() == 0 {
+		if e.lastErr != nil {
+			return 0, e.lastErr
+		}
+		if e.done {
+			return 0, io.EOF
+		}
+		e.lastErr = e.sealChunk()
+	}
+	return e.out.Read(p)
+}
+
+// sealChunk reads a chunk from src and encrypts it, a chunk is the last one if
+// no more data follows it.
+func (e *encryptReader) sealChunk() error {
+	n := copy(e.plain, e.next)
+	m, err := io.ReadFull(e.src, e.plain[n:])
+	n += m
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+
+	last := err != nil
+	e.next = e.next[:0]
+	if !last {
+		// peek one byte to find whether there is more data
+		one := make([]byte, 1)
+		k, err := io.ReadFull(e.src, one)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		last = k == 0
+		e.next = append(e.next, one[:k]...)
+	}
+
+	sealed := e.aead.Seal(nil, nonce(e.noncePrefix, e.counter), e.plain[:n], additionalData(e.headerSum, last))
+	e.counter++
+	writeUint32(e.out, uint32(len(sealed)))
+	e.out.Write(sealed)
+	e.done = last
+	return nil
+}
+
+type decryptReader struct {
+	src         io.Reader
+	aead        cipher.AEAD
+	noncePrefix []byte
+	headerSum   [sha256.Size]byte
+	counter     uint64
+	maxChunk    int
+	out         *bytes.Buffer
+	done        bool
+	lastErr     error
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for d.out.Len() == 0 {
+		if d.lastErr != nil {
+			return 0, d.lastErr
+		}
+		if d.done {
+			return 0, io.EOF
+		}
+		d.lastErr = d.openChunk()
+	}
+	return d.out.Read(p)
+}
+
+// openChunk reads a chunk from src and decrypts it.
+func (d *decryptReader) openChunk() error {
+	size, err := readUint32(d.src)
+	if err != nil {
+		if err == io.EOF {
+			return errors.New("encrypted backup file is truncated")
+		}
+		return err
+	}
+	if int(size) > d.maxChunk {
+		return errors.New("chunk of encrypted backup file is too large")
+	}
+	sealed := make([]byte, size)
+	if _, err = io.ReadFull(d.src, sealed); err != nil {
+		return errors.New("encrypted backup file is truncated")
+	}
+
+	n := nonce(d.noncePrefix, d.counter)
+	plain, err := d.aead.Open(nil, n, sealed, additionalData(d.headerSum, false))
+	if err != nil {
+		plain, err = d.aead.Open(nil, n, sealed, additionalData(d.headerSum, true))
+		if err != nil {
+			return fmt.Errorf("failed to decrypt chunk %d, the backup file is corrupted", d.counter)
+		}
+		d.done = true
+	}
+	d.counter++
+	d.out.Write(plain)
+	return nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the data key by the key encryption key, the nonce is prepended.
+func seal(kek, plain, ad []byte) ([]byte, error) {
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, n); err != nil {
+		return nil, err
+	}
+	return aead.Seal(n, n, plain, ad), nil
+}
+
+// open decrypts the data key sealed by seal.
+func open(kek, sealed, ad []byte) ([]byte, error) {
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("wrapped key is too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], ad)
+}
+
+// nonce is the prefix followed by the chunk counter.
+func nonce(prefix []byte, counter uint64) []byte {
+	n := make([]byte, noncePrefixLen+8)
+	copy(n, prefix)
+	binary.BigEndian.PutUint64(n[noncePrefixLen:], counter)
+	return n
+}
+
+// additionalData binds the chunk to the header and flags the last chunk.
+func additionalData(headerSum [sha256.Size]byte, last bool) []byte {
+	ad := make([]byte, sha256.Size+1)
+	copy(ad, headerSum[:])
+	if last {
+		ad[sha256.Size] = 1
+	}
+	return ad
+}
+
+func writeUint32(w *bytes.Buffer, v uint32) {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	w.Write(b)
+}
+
+func readUint32(r io.Reader) (uint32, error) {
+	b := make([]byte, 4)
+	if _, err := io.ReadFull(r, b); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, errors.New("encrypted backup file is truncated")
+		}
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
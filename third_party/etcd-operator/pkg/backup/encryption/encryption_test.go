@@ -0,0 +1,438 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const (
+	testKeySecret = "backup-keys"
+	testKeyID     = "key-1"
+)
+
+func newKey(t *testing.T) []byte {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	return key
+}
+
+func keyFuncOf(keys map[string][]byte) KeyFunc {
+	return func(_, keyID string) ([]byte, error) {
+		key, ok := keys[keyID]
+		if !ok {
+			return nil, fmt.Errorf("key %s not found", keyID)
+		}
+		return key, nil
+	}
+}
+
+func encrypt(t *testing.T, plain []byte, keyID string, kek []byte) []byte {
+	r, err := NewEncryptReader(bytes.NewReader(plain), testKeySecret, keyID, kek)
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	return data
+}
+
+func decrypt(data []byte, keyFunc KeyFunc) ([]byte, error) {
+	r, err := NewDecryptReader(bytes.NewReader(data), keyFunc)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+// split splits the encrypted data into the header part and the chunks,
+// each chunk contains its length.
+func split(t *testing.T, data []byte) ([]byte, [][]byte) {
+	headerEnd := len(magic) + 4 + int(binary.BigEndian.Uint32(data[len(magic):]))
+	var chunks [][]byte
+	for rest := data[headerEnd:]; len(rest) > 0; {
+		size := 4 + int(binary.BigEndian.Uint32(rest))
+		if size > len(rest) {
+			t.Fatalf("invalid chunk size %d", size)
+		}
+		chunks = append(chunks, rest[:size])
+		rest = rest[size:]
+	}
+	return data[:headerEnd], chunks
+}
+
+func join(head []byte, chunks [][]byte) []byte {
+	data := append([]byte{}, head...)
+	for _, c := range chunks {
+		data = append(data, c...)
+	}
+	return data
+}
+
+func TestRoundTrip(t *testing.T) {
+	kek := newKey(t)
+	tests := []struct {
+		name   string
+		size   int
+		chunks int
+	}{
+		{name: "empty", size: 0, chunks: 1},
+		{name: "smaller than one chunk", size: 100, chunks: 1},
+		{name: "one byte less than one chunk", size: chunkSize - 1, chunks: 1},
+		{name: "exactly one chunk", size: chunkSize, chunks: 1},
+		{name: "one byte more than one chunk", size: chunkSize + 1, chunks: 2},
+		{name: "exactly many chunks", size: 3 * chunkSize, chunks: 3},
+		{name: "many chunks", size: 3*chunkSize + 17, chunks: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := make([]byte, tt.size)
+			if _, err := rand.Read(plain); err != nil {
+				t.Fatalf("err is %v", err)
+			}
+			data := encrypt(t, plain, testKeyID, kek)
+			if !bytes.HasPrefix(data, magic) {
+				t.Errorf("expected the encrypted data to start with the magic")
+			}
+			if _, chunks := split(t, data); len(chunks) != tt.chunks {
+				t.Errorf("expected %d chunks, got %d", tt.chunks, len(chunks))
+			}
+
+			got, err := decrypt(data, keyFuncOf(map[string][]byte{testKeyID: kek}))
+			if err != nil {
+				t.Fatalf("err is %v", err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Errorf("expected the decrypted data to equal the plain data")
+			}
+
+			// the source returns one byte on each read
+			r, err := NewDecryptReader(iotest.OneByteReader(bytes.NewReader(data)), keyFuncOf(map[string][]byte{testKeyID: kek}))
+			if err != nil {
+				t.Fatalf("err is %v", err)
+			}
+			got, err = ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatalf("err is %v", err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Errorf("expected the decrypted data to equal the plain data with one byte reads")
+			}
+		})
+	}
+}
+
+func TestDecryptByRecordedKeySecret(t *testing.T) {
+	kek := newKey(t)
+	data := encrypt(t, []byte("backup"), testKeyID, kek)
+
+	var gotSecret string
+	keyFunc := func(keySecret, keyID string) ([]byte, error) {
+		gotSecret = keySecret
+		return kek, nil
+	}
+	if _, err := decrypt(data, keyFunc); err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	if gotSecret != testKeySecret {
+		t.Errorf("expected key secret %s, got %s", testKeySecret, gotSecret)
+	}
+}
+
+func TestEncryptedDataDiffers(t *testing.T) {
+	kek := newKey(t)
+	plain := []byte("the same backup file")
+	a := encrypt(t, plain, testKeyID, kek)
+	b := encrypt(t, plain, testKeyID, kek)
+	if bytes.Equal(a, b) {
+		t.Errorf("expected a new data key and nonce for every file")
+	}
+	if bytes.Contains(a, plain) {
+		t.Errorf("expected the plain data not to be in the encrypted data")
+	}
+}
+
+func TestDecryptWithWrongKey(t *testing.T) {
+	kek := newKey(t)
+	data := encrypt(t, []byte("backup"), testKeyID, kek)
+
+	// changes the key ID in the header, the data key is bound to the key ID
+	head, chunks := split(t, data)
+	h := &header{}
+	if err := json.Unmarshal(head[len(magic)+4:], h); err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	h.KeyID = "key-2"
+	raw, err := json.Marshal(h)
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	buf := &bytes.Buffer{}
+	buf.Write(magic)
+	writeUint32(buf, uint32(len(raw)))
+	buf.Write(raw)
+	renamed := join(buf.Bytes(), chunks)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		keyFunc KeyFunc
+	}{
+		{
+			name:    "wrong key",
+			data:    data,
+			keyFunc: keyFuncOf(map[string][]byte{testKeyID: newKey(t)}),
+		},
+		{
+			name:    "key not found",
+			data:    data,
+			keyFunc: keyFuncOf(map[string][]byte{"key-2": kek}),
+		},
+		{
+			name:    "invalid key size",
+			data:    data,
+			keyFunc: keyFuncOf(map[string][]byte{testKeyID: kek[:16]}),
+		},
+		{
+			name:    "key ID changed in header",
+			data:    renamed,
+			keyFunc: keyFuncOf(map[string][]byte{testKeyID: kek, "key-2": kek}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decrypt(tt.data, tt.keyFunc); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	kek := newKey(t)
+	keyFunc := keyFuncOf(map[string][]byte{testKeyID: kek})
+	plain := make([]byte, 2*chunkSize+10)
+	if _, err := rand.Read(plain); err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	data := encrypt(t, plain, testKeyID, kek)
+	head, chunks := split(t, data)
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks, got %d", len(chunks))
+	}
+
+	flip := func(chunk []byte, i int) []byte {
+		c := append([]byte{}, chunk...)
+		c[i] ^= 0x01
+		return c
+	}
+	other := encrypt(t, plain, testKeyID, kek)
+	_, otherChunks := split(t, other)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "last chunk dropped",
+			data: join(head, chunks[:2]),
+		},
+		{
+			name: "only the header",
+			data: head,
+		},
+		{
+			name: "last chunk cut",
+			data: data[:len(data)-1],
+		},
+		{
+			name: "chunk length cut",
+			data: join(head, append(chunks[:2:2], chunks[2][:2])),
+		},
+		{
+			name: "chunks reordered",
+			data: join(head, [][]byte{chunks[1], chunks[0], chunks[2]}),
+		},
+		{
+			name: "chunk duplicated",
+			data: join(head, [][]byte{chunks[0], chunks[0], chunks[1], chunks[2]}),
+		},
+		{
+			name: "bit flipped in first chunk",
+			data: join(head, [][]byte{flip(chunks[0], 100), chunks[1], chunks[2]}),
+		},
+		{
+			name: "bit flipped in last chunk",
+			data: join(head, [][]byte{chunks[0], chunks[1], flip(chunks[2], len(chunks[2])-1)}),
+		},
+		{
+			name: "chunk from another file",
+			data: join(head, [][]byte{chunks[0], otherChunks[1], chunks[2]}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decrypt(tt.data, keyFunc); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+
+	// the reader stops at the last chunk, the data after it is never returned
+	got, err := decrypt(join(head, [][]byte{chunks[0], chunks[1], chunks[2], chunks[2]}), keyFunc)
+	if err != nil {
+		t.Fatalf("err is %v", err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Errorf("expected the data up to the last chunk")
+	}
+}
+
+func TestDecryptInvalidHeader(t *testing.T) {
+	kek := newKey(t)
+	keyFunc := keyFuncOf(map[string][]byte{testKeyID: kek})
+
+	if _, err := NewDecryptReader(bytes.NewReader([]byte("plain backup file")), keyFunc); err != ErrNotEncrypted {
+		t.Errorf("expected ErrNotEncrypted, got %v", err)
+	}
+	if _, err := NewDecryptReader(bytes.NewReader(nil), keyFunc); err != ErrNotEncrypted {
+		t.Errorf("expected ErrNotEncrypted, got %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	buf.Write(magic)
+	writeUint32(buf, maxHeaderSize+1)
+	if _, err := NewDecryptReader(buf, keyFunc); err == nil {
+		t.Errorf("expected an error for a too large header")
+	}
+
+	raw, _ := json.Marshal(&header{KeyID: testKeyID, Algorithm: "AES-128-CBC"})
+	buf = &bytes.Buffer{}
+	buf.Write(magic)
+	writeUint32(buf, uint32(len(raw)))
+	buf.Write(raw)
+	if _, err := NewDecryptReader(buf, keyFunc); err == nil {
+		t.Errorf("expected an error for an unsupported algorithm")
+	}
+}
+
+func TestNewEncryptReaderInvalidKeyID(t *testing.T) {
+	if _, err := NewEncryptReader(bytes.NewReader(nil), testKeySecret, "key.1", newKey(t)); err == nil {
+		t.Errorf("expected an error for an invalid key ID")
+	}
+}
+
+func TestValidateKeyID(t *testing.T) {
+	tests := []struct {
+		keyID   string
+		wantErr bool
+	}{
+		{keyID: "k", wantErr: false},
+		{keyID: "key_ID-2023", wantErr: false},
+		{keyID: strings.Repeat("a", 64), wantErr: false},
+		{keyID: "", wantErr: true},
+		{keyID: strings.Repeat("a", 65), wantErr: true},
+		{keyID: "key.1", wantErr: true},
+		{keyID: "key/1", wantErr: true},
+		{keyID: "key 1", wantErr: true},
+		{keyID: "密钥", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keyID, func(t *testing.T) {
+			if err := ValidateKeyID(tt.keyID); (err != nil) != tt.wantErr {
+				t.Errorf("expected wantErr %v, err is %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseObjectName(t *testing.T) {
+	tests := []struct {
+		name      string
+		wantName  string
+		wantKeyID string
+		wantOK    bool
+	}{
+		{name: "etcdbackup-1600000000-10.key-1.enc", wantName: "etcdbackup-1600000000-10", wantKeyID: "key-1", wantOK: true},
+		{name: "ns/cluster/etcdbackup.k.enc", wantName: "ns/cluster/etcdbackup", wantKeyID: "k", wantOK: true},
+		{name: "a.b.c.key-1.enc", wantName: "a.b.c", wantKeyID: "key-1", wantOK: true},
+		{name: ".key-1.enc", wantName: "", wantKeyID: "key-1", wantOK: true},
+		{name: "etcdbackup-1600000000-10", wantName: "etcdbackup-1600000000-10", wantOK: false},
+		{name: "etcdbackup.enc", wantName: "etcdbackup.enc", wantOK: false},
+		{name: "etcdbackup..enc", wantName: "etcdbackup..enc", wantOK: false},
+		{name: "etcdbackup.key$1.enc", wantName: "etcdbackup.key$1.enc", wantOK: false},
+		{name: "etcdbackup.key-1.enc.tmp", wantName: "etcdbackup.key-1.enc.tmp", wantOK: false},
+		{name: "etcdbackup.key-1.ENC", wantName: "etcdbackup.key-1.ENC", wantOK: false},
+		{name: "etcdbackup." + strings.Repeat("a", 65) + ".enc", wantName: "etcdbackup." + strings.Repeat("a", 65) + ".enc", wantOK: false},
+		{name: "", wantName: "", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, keyID, ok := ParseObjectName(tt.name)
+			if name != tt.wantName || keyID != tt.wantKeyID || ok != tt.wantOK {
+				t.Errorf("expected (%q, %q, %v), got (%q, %q, %v)", tt.wantName, tt.wantKeyID, tt.wantOK, name, keyID, ok)
+			}
+		})
+	}
+
+	name, keyID, ok := ParseObjectName(ObjectName("etcdbackup", testKeyID))
+	if name != "etcdbackup" || keyID != testKeyID || !ok {
+		t.Errorf("expected ObjectName to be parsed, got (%q, %q, %v)", name, keyID, ok)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	raw := bytes.Repeat([]byte{0xab}, KeySize)
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{name: "raw", data: raw, wantErr: false},
+		{name: "base64", data: []byte(base64.StdEncoding.EncodeToString(raw)), wantErr: false},
+		{name: "base64 with newline", data: []byte(base64.StdEncoding.EncodeToString(raw) + "\n"), wantErr: false},
+		{name: "short", data: raw[:16], wantErr: true},
+		{name: "short base64", data: []byte(base64.StdEncoding.EncodeToString(raw[:16])), wantErr: true},
+		{name: "invalid base64", data: []byte("not a key"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParseKey(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected wantErr %v, err is %v", tt.wantErr, err)
+			}
+			if err == nil && !bytes.Equal(key, raw) {
+				t.Errorf("expected the parsed key to equal the raw key")
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2023 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package encryption
+
+import (
+	"context"
+	"io"
+
+	"github.com/coreos/etcd-operator/pkg/backup/writer"
+)
+
+var _ writer.Writer = &encryptWriter{}
+
+type encryptWriter struct {
+	w         writer.Writer
+	keySecret string
+	keyID     string
+	key       []byte
+}
+
+// NewWriter creates a writer which encrypts backup files by the key of the
+// secret before writing them by w.
+func NewWriter(w writer.Writer, keySecret, keyID string, key []byte) writer.Writer {
+	return &encryptWriter{w: w, keySecret: keySecret, keyID: keyID, key: key}
+}
+
+// Write encrypts the backup file and writes it to the given path with the
+// suffix of the key ID, "<path>.<keyID>.enc".
+func (ew *encryptWriter) Write(ctx context.Context, path string, r io.Reader) (int64, error) {
+	er, err := NewEncryptReader(r, ew.keySecret, ew.keyID, ew.key)
+	if err != nil {
+		return 0, err
+	}
+	return ew.w.Write(ctx, ObjectName(path, ew.keyID), er)
+}
+
+func (ew *encryptWriter) List(ctx context.Context, basePath string) ([]string, error) {
+	return ew.w.List(ctx, basePath)
+}
+
+func (ew *encryptWriter) Delete(ctx context.Context, path string) error {
+	return ew.w.Delete(ctx, path)
+}
@@ -29,7 +29,7 @@ import (
 
 // handleABS saves etcd cluster's backup to specificed ABS path.
 func handleABS(ctx context.Context, kubecli kubernetes.Interface, s *api.ABSBackupSource, endpoints []string, clientTLSSecret, basicAuthSecret,
-	namespace string, insecureSkipVerify, isPeriodic bool, maxBackup int) (*api.BackupStatus, error) {
+	namespace string, insecureSkipVerify, isPeriodic bool, maxBackup int, encryption *api.BackupEncryption) (*api.BackupStatus, error) {
 	// TODO: controls NewClientFromSecret with ctx. This depends on upstream kubernetes to support API calls with ctx.
 	cli, err := absfactory.NewClientFromSecret(kubecli, namespace, s.ABSSecret)
 	if err != nil {
@@ -46,7 +46,12 @@ func handleABS(ctx context.Context, kubecli kubernetes.Interface, s *api.ABSBack
 		return nil, err
 	}
 
-	bm := backup.NewBackupManagerFromWriter(kubecli, writer.NewABSWriter(cli.ABS), tlsConfig, endpoints, namespace, username, password)
+	bw, err := newBackupWriter(kubecli, namespace, writer.NewABSWriter(cli.ABS), encryption)
+	if err != nil {
+		return nil, err
+	}
+
+	bm := backup.NewBackupManagerFromWriter(kubecli, bw, tlsConfig, endpoints, namespace, username, password)
 
 	rev, etcdVersion, now, err := bm.SaveSnap(ctx, s.Path, isPeriodic)
 	if err != nil {
@@ -29,7 +29,7 @@ import (
 )
 
 // handleCOS saves etcd cluster's backup to specificed COS path.
-func handleCOS(ctx context.Context, kubecli kubernetes.Interface, s *api.COSBackupSource, endpoints []string, clientTLSSecret, basicAuthSecret, namespace string, insecureSkipVerify bool, isPeriodic bool, maxBackup int) (bs *api.BackupStatus, err error) {
+func handleCOS(ctx context.Context, kubecli kubernetes.Interface, s *api.COSBackupSource, endpoints []string, clientTLSSecret, basicAuthSecret, namespace string, insecureSkipVerify bool, isPeriodic bool, maxBackup int, encryption *api.BackupEncryption) (bs *api.BackupStatus, err error) {
 	var cli *cosfactory.COSClient
 	if len(s.COSSecret) > 0 {
 		cli, err = cosfactory.NewClientFromSecret(kubecli, namespace, s.COSSecret)
@@ -57,7 +57,12 @@ func handleCOS(ctx context.Context, kubecli kubernetes.Interface, s *api.COSBack
 		return nil, err
 	}
 
-	bm := backup.NewBackupManagerFromWriter(kubecli, writer.NewCOSWriter(cli.COS), tlsConfig, endpoints, namespace, username, password)
+	bw, err := newBackupWriter(kubecli, namespace, writer.NewCOSWriter(cli.COS), encryption)
+	if err != nil {
+		return nil, err
+	}
+
+	bm := backup.NewBackupManagerFromWriter(kubecli, bw, tlsConfig, endpoints, namespace, username, password)
 
 	rev, etcdVersion, now, err := bm.SaveSnap(ctx, s.Path, isPeriodic)
 	if err != nil {
@@ -29,7 +29,7 @@ import (
 
 // handleGCS saves etcd cluster's backup to specificed GCS path.
 func handleGCS(ctx context.Context, kubecli kubernetes.Interface, s *api.GCSBackupSource, endpoints []string, clientTLSSecret, basicAuthSecret,
-	namespace string, insecureSkipVerify, isPeriodic bool, maxBackup int) (*api.BackupStatus, error) {
+	namespace string, insecureSkipVerify, isPeriodic bool, maxBackup int, encryption *api.BackupEncryption) (*api.BackupStatus, error) {
 	// TODO: controls NewClientFromSecret with ctx. This depends on upstream kubernetes to support API calls with ctx.
 	cli, err := gcsfactory.NewClientFromSecret(ctx, kubecli, namespace, s.GCPSecret)
 	if err != nil {
@@ -47,7 +47,12 @@ func handleGCS(ctx context.Context, kubecli kubernetes.Interface, s *api.GCSBack
 		return nil, err
 	}
 
-	bm := backup.NewBackupManagerFromWriter(kubecli, writer.NewGCSWriter(cli.GCS), tlsConfig, endpoints, namespace, username, password)
+	bw, err := newBackupWriter(kubecli, namespace, writer.NewGCSWriter(cli.GCS), encryption)
+	if err != nil {
+		return nil, err
+	}
+
+	bm := backup.NewBackupManagerFromWriter(kubecli, bw, tlsConfig, endpoints, namespace, username, password)
 
 	rev, etcdVersion, now, err := bm.SaveSnap(ctx, s.Path, isPeriodic)
 	if err != nil {
@@ -19,6 +19,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"os"
+	"strings"
 
 	api "github.com/coreos/etcd-operator/pkg/apis/etcd/v1beta2"
 	"github.com/coreos/etcd-operator/pkg/backup"
@@ -28,7 +29,7 @@ import (
 )
 const etcdBackFilePrefix = "etcdbackup"
 // handleHostPath saves etcd cluster's backup to specificed host path.
-func handleHostPath(ctx context.Context, kubecli kubernetes.Interface, s *api.HostPathBackupSource,endpoints []string, clientTLSSecret, basicAuthSecret, namespace string, insecureSkipVerify bool, isPeriodic bool, maxBackup int) (bs *api.BackupStatus, err error) {
+func handleHostPath(ctx context.Context, kubecli kubernetes.Interface, s *api.HostPathBackupSource,endpoints []string, clientTLSSecret, basicAuthSecret, namespace string, insecureSkipVerify bool, isPeriodic bool, maxBackup int, encryption *api.BackupEncryption) (bs *api.BackupStatus, err error) {
 	hostPath := os.Getenv("HOST_PATH_NAME")
 	if len(hostPath) == 0 {
 		return nil, fmt.Errorf("HOST_PATH_NAME env must be set")
@@ -44,14 +45,30 @@ func handleHostPath(ctx context.Context, kubecli kubernetes.Interface, s *api.Ho
 		return nil, err
 	}
 
-	bm := backup.NewBackupManagerFromWriter(kubecli, writer.NewHostPathWriter(hostPath), tlsConfig, endpoints, namespace, username, password)
+	bw, err := newBackupWriter(kubecli, namespace, writer.NewHostPathWriter(hostPath), encryption)
+	if err != nil {
+		return nil, err
+	}
 
-	rev, etcdVersion, now, err := bm.SaveSnap(ctx, hostPath + etcdBackFilePrefix, isPeriodic)
+	bm := backup.NewBackupManagerFromWriter(kubecli, bw, tlsConfig, endpoints, namespace, username, password)
+
+	// the path is <dir>/<suffix>, backups are saved under the dir and
+	// one-shot backups are saved as etcdbackup<suffix> to not overwrite each other
+	i := strings.LastIndex(s.Path, "/") + 1
+	dir, suffix := hostPath+s.Path[:i], s.Path[i:]
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory %s (%v)", dir, err)
+	}
+	backupPath := dir + etcdBackFilePrefix
+	if !isPeriodic {
+		backupPath += suffix
+	}
+	rev, etcdVersion, now, err := bm.SaveSnap(ctx, backupPath, isPeriodic)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save snapshot (%v)", err)
 	}
 	if maxBackup > 0 {
-		err := bm.EnsureMaxBackup(ctx,hostPath, maxBackup)
+		err := bm.EnsureMaxBackup(ctx, dir, maxBackup)
 		if err != nil {
 			return nil, fmt.Errorf("succeeded in saving snapshot but failed to delete old snapshot (%v)", err)
 		}
@@ -28,7 +28,7 @@ import (
 
 // handleOSS saves etcd cluster's backup to specificed OSS path.
 func handleOSS(ctx context.Context, kubecli kubernetes.Interface, s *api.OSSBackupSource, endpoints []string, clientTLSSecret, basicAuthSecret,
-	namespace string, insecureSkipVerify, isPeriodic bool, maxBackup int) (*api.BackupStatus, error) {
+	namespace string, insecureSkipVerify, isPeriodic bool, maxBackup int, encryption *api.BackupEncryption) (*api.BackupStatus, error) {
 	if s.Endpoint == "" {
 		s.Endpoint = "http://oss-cn-hangzhou.aliyuncs.com"
 	}
@@ -48,7 +48,12 @@ func handleOSS(ctx context.Context, kubecli kubernetes.Interface, s *api.OSSBack
 		return nil, err
 	}
 
-	bm := backup.NewBackupManagerFromWriter(kubecli, writer.NewOSSWriter(cli.OSS), tlsConfig, endpoints, namespace, username, password)
+	bw, err := newBackupWriter(kubecli, namespace, writer.NewOSSWriter(cli.OSS), encryption)
+	if err != nil {
+		return nil, err
+	}
+
+	bm := backup.NewBackupManagerFromWriter(kubecli, bw, tlsConfig, endpoints, namespace, username, password)
 
 	rev, etcdVersion, now, err := bm.SaveSnap(ctx, s.Path, isPeriodic)
 	if err != nil {
@@ -273,42 +273,42 @@ func (b *Backup) handleBackup(parentContext *context.Context, eb *api.EtcdBackup
 	switch spec.StorageType {
 	case api.BackupStorageTypeS3:
 		bs, err := handleS3(ctx, b.kubecli, spec.S3, spec.EtcdEndpoints, spec.ClientTLSSecret, spec.BasicAuthSecret,
-			eb.Namespace, spec.InsecureSkipVerify, isPeriodic, backupMaxCount)
+			eb.Namespace, spec.InsecureSkipVerify, isPeriodic, backupMaxCount, spec.Encryption)
 		if err != nil {
 			return nil, err
 		}
 		return bs, nil
 	case api.BackupStorageTypeABS:
 		bs, err := handleABS(ctx, b.kubecli, spec.ABS, spec.EtcdEndpoints, spec.ClientTLSSecret, spec.BasicAuthSecret,
-			eb.Namespace, spec.InsecureSkipVerify, isPeriodic, backupMaxCount)
+			eb.Namespace, spec.InsecureSkipVerify, isPeriodic, backupMaxCount, spec.Encryption)
 		if err != nil {
 			return nil, err
 		}
 		return bs, nil
 	case api.BackupStorageTypeGCS:
 		bs, err := handleGCS(ctx, b.kubecli, spec.GCS, spec.EtcdEndpoints, spec.ClientTLSSecret, spec.BasicAuthSecret,
-			eb.Namespace, spec.InsecureSkipVerify, isPeriodic, backupMaxCount)
+			eb.Namespace, spec.InsecureSkipVerify, isPeriodic, backupMaxCount, spec.Encryption)
 		if err != nil {
 			return nil, err
 		}
 		return bs, nil
 	case api.BackupStorageTypeOSS:
 		bs, err := handleOSS(ctx, b.kubecli, spec.OSS, spec.EtcdEndpoints, spec.ClientTLSSecret, spec.BasicAuthSecret,
-			eb.Namespace, spec.InsecureSkipVerify, isPeriodic, backupMaxCount)
+			eb.Namespace, spec.InsecureSkipVerify, isPeriodic, backupMaxCount, spec.Encryption)
 		if err != nil {
 			return nil, err
 		}
 		return bs, nil
 	case api.BackupStorageTypeCOS:
 		bs, err := handleCOS(ctx, b.kubecli, spec.COS, spec.EtcdEndpoints, spec.ClientTLSSecret, spec.BasicAuthSecret,
-			eb.Namespace, spec.InsecureSkipVerify, isPeriodic, backupMaxCount)
+			eb.Namespace, spec.InsecureSkipVerify, isPeriodic, backupMaxCount, spec.Encryption)
 		if err != nil {
 			return nil, err
 		}
 		return bs, nil
 	case api.BackupStorageTypeHostPath:
 		bs, err := handleHostPath(ctx, b.kubecli, spec.HostPath, spec.EtcdEndpoints, spec.ClientTLSSecret, spec.BasicAuthSecret,
-			eb.Namespace, spec.InsecureSkipVerify, isPeriodic, backupMaxCount)
+			eb.Namespace, spec.InsecureSkipVerify, isPeriodic, backupMaxCount, spec.Encryption)
 		if err != nil {
 			return nil, err
 		}
@@ -19,6 +19,8 @@ import (
 	"fmt"
 
 	api "github.com/coreos/etcd-operator/pkg/apis/etcd/v1beta2"
+	"github.com/coreos/etcd-operator/pkg/backup/encryption"
+	"github.com/coreos/etcd-operator/pkg/backup/writer"
 	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
 	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
 
@@ -65,6 +67,29 @@ func generateUsernamePassword(kubecli kubernetes.Interface, basicAuthSecret, nam
 	return "", "", nil
 }
 
+// newBackupWriter wraps the writer to encrypt backup files if encryption is configured.
+func newBackupWriter(kubecli kubernetes.Interface, namespace string, w writer.Writer, e *api.BackupEncryption) (writer.Writer, error) {
+	if e == nil {
+		return w, nil
+	}
+	if err := encryption.ValidateKeyID(e.KeyID); err != nil {
+		return nil, err
+	}
+	secret, err := kubecli.CoreV1().Secrets(namespace).Get(e.EncryptionSecret, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encryption secret %s: %v", e.EncryptionSecret, err)
+	}
+	data, ok := secret.Data[e.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found in encryption secret %s", e.KeyID, e.EncryptionSecret)
+	}
+	key, err := encryption.ParseKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key %s in encryption secret %s: %v", e.KeyID, e.EncryptionSecret, err)
+	}
+	return encryption.NewWriter(w, e.EncryptionSecret, e.KeyID, key), nil
+}
+
 func isPeriodicBackup(ebSpec *api.BackupSpec) bool {
 	if ebSpec.BackupPolicy != nil {
 		return ebSpec.BackupPolicy.BackupIntervalInSecond != 0